@@ -0,0 +1,246 @@
+// Command go-org converts, lints, formats and lists the agenda of Org mode files.
+//
+//	go-org convert [-to html|org|md|latex|json] [-o output] [file]
+//	go-org lint [-strict] file...
+//	go-org fmt [-check] [file...]
+//	go-org agenda [-from 2006-01-02] [-days 7] [-match +work/!] [-all] file...
+//
+// Files are read from stdin if none (or "-") are given. convert writes html (see org.HTMLWriter), pretty printed
+// Org mode (see org.OrgWriter), markdown (see org.MarkdownWriter), a LaTeX document (see org.LaTeXWriter) or the
+// organice JSON format (see package organice). lint prints the diagnostics of
+// package lint and exits with status 1 if there are errors (any diagnostics with -strict). fmt formats files in place
+// (see org.Format) - with -check, it lists unformatted files and exits with status 1 instead. agenda lists the
+// scheduled and deadline entries (all active timestamps with -all) of the days starting at -from (today by default).
+// Like timestamps, -from is read as a UTC date.
+// Usage errors and files that cannot be read or written result in exit status 2.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexispurslane/go-org/org"
+	"github.com/alexispurslane/go-org/org/agenda"
+	"github.com/alexispurslane/go-org/org/lint"
+	"github.com/alexispurslane/go-org/organice"
+)
+
+const usage = `usage: go-org <command> [flags] [file...]
+
+commands:
+  convert  convert a file to html, org, md, latex or json
+  lint     print diagnostics
+  fmt      format files in place
+  agenda   list scheduled and deadline entries
+
+Run go-org <command> -h for the flags of a command.
+`
+
+// errUsage is returned by commands for invalid arguments - the usage has been printed already.
+var errUsage = errors.New("invalid usage")
+
+// errFailed is returned by commands that ran successfully but found problems, e.g. lint errors.
+var errFailed = errors.New("failed")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command of args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	commands := map[string]func([]string, io.Reader, io.Writer, io.Writer) error{
+		"convert": convert,
+		"lint":    lintFiles,
+		"fmt":     format,
+		"agenda":  listAgenda,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "go-org: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+	switch err := command(args[1:], stdin, stdout, stderr); {
+	case err == nil:
+		return 0
+	case errors.Is(err, errFailed):
+		return 1
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "go-org %s: %s\n", args[0], err)
+		return 2
+	}
+}
+
+func flagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("go-org "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	return flags
+}
+
+// parse parses the files (stdin for none or "-") with the configuration c.
+func parse(c *org.Configuration, paths []string, stdin io.Reader) ([]*org.Document, error) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	documents := []*org.Document{}
+	for _, path := range paths {
+		input, name := stdin, "stdin"
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("could not read file: %w", err)
+			}
+			defer f.Close()
+			input, name = f, path
+		}
+		d := c.Parse(input, name)
+		if d.FatalError != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", name, d.FatalError)
+		}
+		documents = append(documents, d)
+	}
+	return documents, nil
+}
+
+func convert(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flagSet("convert", stderr)
+	to := flags.String("to", "html", "output format: html, org, md, latex or json")
+	output := flags.String("o", "", "output file (stdout by default)")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 1 {
+		return errUsage
+	}
+	documents, err := parse(org.New().Silent(), flags.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	d, out := documents[0], ""
+	switch *to {
+	case "html":
+		out, err = d.Write(org.NewHTMLWriter())
+	case "org":
+		out, err = d.Write(org.NewOrgWriter())
+	case "md":
+		out, err = d.Write(org.NewMarkdownWriter())
+	case "latex":
+		out, err = d.Write(org.NewLaTeXWriter())
+	case "json":
+		var data []byte
+		data, err = json.MarshalIndent(organice.Export(d), "", "  ")
+		out = string(data) + "\n"
+	default:
+		return fmt.Errorf("unsupported format %q (supported: html, org, md, latex, json)", *to)
+	}
+	if err != nil {
+		return fmt.Errorf("could not convert: %w", err)
+	}
+	if *output == "" {
+		_, err = io.WriteString(stdout, out)
+		return err
+	}
+	return os.WriteFile(*output, []byte(out), 0644)
+}
+
+func lintFiles(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flagSet("lint", stderr)
+	strict := flags.Bool("strict", false, "fail on warnings and infos as well")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	documents, err := parse(org.New().Silent(), flags.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	failed := false
+	for _, d := range documents {
+		for _, diagnostic := range append(append([]*org.ParseError{}, d.Errors...), lint.Lint(d)...) {
+			fmt.Fprintf(stdout, "%s [%s]\n", diagnostic, diagnostic.Severity)
+			failed = failed || *strict || diagnostic.Severity >= org.SeverityError
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func format(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flagSet("fmt", stderr)
+	check := flags.Bool("check", false, "list unformatted files instead of formatting them")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() == 0 || flags.NArg() == 1 && flags.Arg(0) == "-" {
+		out, err := org.Format(stdin, org.NewFormatOptions())
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(stdout, out)
+		return err
+	}
+	unformatted := false
+	for _, path := range flags.Args() {
+		input, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read file: %w", err)
+		}
+		options := org.NewFormatOptions()
+		options.Path = path
+		out, err := org.Format(strings.NewReader(string(input)), options)
+		if err != nil {
+			return fmt.Errorf("could not format %s: %w", path, err)
+		} else if out == string(input) {
+			continue
+		} else if *check {
+			fmt.Fprintln(stdout, path)
+			unformatted = true
+		} else if err := os.WriteFile(path, []byte(out), 0644); err != nil {
+			return fmt.Errorf("could not write file: %w", err)
+		}
+	}
+	if unformatted {
+		return errFailed
+	}
+	return nil
+}
+
+func listAgenda(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flagSet("agenda", stderr)
+	from := flags.String("from", time.Now().UTC().Format("2006-01-02"), "first day of the agenda (UTC)")
+	days := flags.Int("days", 7, "number of days of the agenda")
+	match := flags.String("match", "", "tags/property/todo match, e.g. +work/!")
+	all := flags.Bool("all", false, "list all active timestamps rather than just scheduled and deadline entries")
+	if err := flags.Parse(args); err != nil || *days < 1 {
+		return errUsage
+	}
+	start, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		return fmt.Errorf("bad -from date: %w", err)
+	}
+	filter := agenda.Filter{From: start, To: start.AddDate(0, 0, *days)}
+	if *match != "" {
+		if filter.Match, err = org.CompileTagMatch(*match); err != nil {
+			return err
+		}
+	}
+	documents, err := parse(org.New().Silent(), flags.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	for _, entry := range agenda.Entries(filter, documents...) {
+		if *all || entry.Kind != agenda.Timestamp {
+			fmt.Fprintln(stdout, entry)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "todo.org")
+	input := "* TODO Write report :work:\nSCHEDULED: <2024-06-03 Mon>\n| a |bb|\n* Call [[#missing]]\nDEADLINE: <2024-06-05 Wed>\n"
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		args     []string
+		stdin    string
+		status   int
+		expected string
+	}{
+		{[]string{"convert", "-to", "org"}, "* A\n| a |bb|\n", 0, "* A\n| a | bb |\n"},
+		{[]string{"convert"}, "*a*\n", 0, "<p><strong>a</strong></p>\n"},
+		{[]string{"convert", "-to", "json"}, "* A :x:\n", 0, `"tags": [`},
+		{[]string{"convert", "-to", "md"}, "* A\n/b/\n", 0, "## A\n\n*b*\n"},
+		{[]string{"convert", "-to", "latex"}, "* A\n/b/\n", 0, "\\section*{A}\n\\label{headline-1}\n\n\\emph{b}\n\n\\end{document}\n"},
+		{[]string{"convert", "-to", "pdf"}, "", 2, ""},
+		{[]string{"lint", path}, "", 1, path + ":3:7-19: broken internal link [[#missing]] [error]\n"},
+		{[]string{"lint"}, "* A\n", 0, ""},
+		{[]string{"agenda", "-from", "2024-06-01", path}, "", 0, "2024-06-03 todo: scheduled TODO Write report\n2024-06-05 todo: deadline Call #missing\n"},
+		{[]string{"agenda", "-from", "2024-06-01", "-match", "+work", path}, "", 0, "2024-06-03 todo: scheduled TODO Write report\n"},
+		{[]string{"agenda", "-from", "2024-06-04", "-days", "1", path}, "", 0, ""},
+		{[]string{"fmt", "-check", path}, "", 1, path + "\n"},
+		{[]string{"fmt", path}, "", 0, ""},
+		{[]string{"fmt", "-check", path}, "", 0, ""},
+		{[]string{"fmt"}, "* A\n* B\n", 0, "* A\n\n* B\n"},
+		{[]string{"unknown"}, "", 2, ""},
+		{[]string{"lint", filepath.Join(dir, "missing.org")}, "", 2, ""},
+	} {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		status := run(test.args, strings.NewReader(test.stdin), stdout, stderr)
+		if status != test.status || !strings.Contains(stdout.String(), test.expected) || test.expected == "" && stdout.Len() != 0 {
+			t.Errorf("%v: got status %d and output %q (%s), expected %d and %q", test.args, status, stdout, stderr, test.status, test.expected)
+		}
+	}
+	if formatted, _ := os.ReadFile(path); !strings.Contains(string(formatted), "| a | bb |") {
+		t.Errorf("expected file to be formatted in place, got %q", formatted)
+	}
+}
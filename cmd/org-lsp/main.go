@@ -0,0 +1,18 @@
+// Command org-lsp is a language server for Org mode documents that communicates via stdin and stdout.
+// See package github.com/alexispurslane/go-org/org/lsp.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/alexispurslane/go-org/org"
+	"github.com/alexispurslane/go-org/org/lsp"
+)
+
+func main() {
+	c := org.New(org.WithLogger(log.New(os.Stderr, "org-lsp: ", 0)))
+	if err := lsp.NewServer(c).Serve(os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
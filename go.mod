@@ -3,6 +3,9 @@ module github.com/alexispurslane/go-org
 go 1.25.6
 
 require (
+	github.com/alecthomas/chroma/v2 v2.27.0
 	github.com/pmezard/go-difflib v1.0.0
 	golang.org/x/net v0.38.0
 )
+
+require github.com/dlclark/regexp2/v2 v2.2.1 // indirect
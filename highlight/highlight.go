@@ -0,0 +1,56 @@
+// Package highlight provides a chroma based implementation of org.HTMLWriter.HighlightCodeBlock.
+//
+//	writer := org.NewHTMLWriter()
+//	writer.HighlightCodeBlock = highlight.HighlightCodeBlock
+package highlight
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+// HighlightCodeBlock highlights source code using the chroma style "friendly" and inline styles.
+var HighlightCodeBlock = New("friendly")
+
+// New returns a function to be used as org.HTMLWriter.HighlightCodeBlock that highlights source code
+// using the chroma style with the given name and the given chroma html formatter options.
+// The :hl_lines parameter of src blocks (e.g. ":hl_lines 1 3-5") is used to highlight lines and lines are numbered
+// if the block has a -n or +n switch.
+func New(style string, options ...html.Option) func(source, lang string, inline bool, params map[string]string) string {
+	return func(source, lang string, inline bool, params map[string]string) string {
+		lexer := lexers.Get(lang)
+		if lexer == nil {
+			lexer = lexers.Fallback
+		}
+		iterator, err := chroma.Coalesce(lexer).Tokenise(nil, source)
+		if err != nil {
+			return org.DefaultHighlightCodeBlock(source, lang, inline, params)
+		}
+		blockOptions, offset := append([]html.Option{}, options...), 0
+		if start, err := strconv.Atoi(params[":linenostart"]); err == nil {
+			blockOptions, offset = append(blockOptions, html.WithLineNumbers(true), html.BaseLineNumber(start)), start-1
+		}
+		// :hl_lines counts the lines of the block - chroma counts from the base line number
+		if ranges := org.ParseRanges(params[":hl_lines"]); len(ranges) != 0 {
+			for i := range ranges {
+				ranges[i] = [2]int{ranges[i][0] + offset, ranges[i][1] + offset}
+			}
+			blockOptions = append(blockOptions, html.HighlightLines(ranges))
+		}
+		out := strings.Builder{}
+		if err := html.New(blockOptions...).Format(&out, styles.Get(style), iterator); err != nil {
+			return org.DefaultHighlightCodeBlock(source, lang, inline, params)
+		}
+		if inline {
+			return `<div class="highlight-inline">` + "\n" + out.String() + "\n</div>"
+		}
+		return `<div class="highlight">` + "\n" + out.String() + "\n</div>"
+	}
+}
@@ -0,0 +1,90 @@
+package highlight
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/formatters/html"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+var lineRegexp = regexp.MustCompile(`<span style="display:flex;( background-color:#d8d8d8)?">(?:<span style="[^"]*user-select:none[^"]*">(\d+)</span>)?`)
+
+// lines returns the line numbers of the highlighted source - prefixed with "*" for highlighted lines.
+func lines(out string) []string {
+	result := []string{}
+	for _, m := range lineRegexp.FindAllStringSubmatch(out, -1) {
+		if m[1] != "" {
+			m[2] = "*" + m[2]
+		}
+		result = append(result, m[2])
+	}
+	return result
+}
+
+func TestHighlightCodeBlock(t *testing.T) {
+	source := "x := 1\ny := 2\nz := 3"
+	for _, test := range []struct {
+		name     string
+		params   map[string]string
+		expected []string
+	}{
+		{"plain", nil, []string{"", "", ""}},
+		{"hl_lines", map[string]string{":hl_lines": "1 3"}, []string{"*", "", "*"}},
+		{"line numbers", map[string]string{":linenostart": "1"}, []string{"1", "2", "3"}},
+		{"offset line numbers", map[string]string{":linenostart": "5", ":hl_lines": "2-3"}, []string{"5", "*6", "*7"}},
+	} {
+		out := HighlightCodeBlock(source, "go", false, test.params)
+		if !strings.HasPrefix(out, `<div class="highlight">`+"\n<pre") || !strings.HasSuffix(out, "</pre>\n</div>") {
+			t.Errorf("%s: got %q, expected a highlight div", test.name, out)
+		}
+		if actual := lines(out); !slices.Equal(actual, test.expected) {
+			t.Errorf("%s: got lines %q, expected %q", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestHighlightCodeBlockLanguages(t *testing.T) {
+	out := HighlightCodeBlock("x := 1", "go", false, nil)
+	if !strings.Contains(out, `<span style="color:#40a070">1</span>`) {
+		t.Errorf("got %q, expected the number to be highlighted", out)
+	}
+	out = HighlightCodeBlock("<x> & y", "not-a-language", true, nil)
+	if !strings.HasPrefix(out, `<div class="highlight-inline">`) || !strings.Contains(out, "&lt;x&gt; &amp; y") {
+		t.Errorf("got %q, expected the escaped source in an inline div", out)
+	}
+	out = New("monokai", html.WithClasses(true))("x := 1", "go", false, nil)
+	if !strings.Contains(out, `<pre class="chroma">`) || !strings.Contains(out, `<span class="mi">1</span>`) {
+		t.Errorf("got %q, expected chroma classes", out)
+	}
+}
+
+func TestHTMLWriter(t *testing.T) {
+	input := strings.Join([]string{
+		"#+begin_src go -n :hl_lines 2",
+		"x := 1",
+		"y := 2",
+		"#+end_src",
+		"",
+		"#+begin_src go +n :hl_lines 1",
+		"z := 3",
+		"#+end_src",
+		"",
+		"src_go{w := 4}",
+	}, "\n")
+	w := org.NewHTMLWriter()
+	w.HighlightCodeBlock = HighlightCodeBlock
+	out, err := org.New().Silent().Parse(strings.NewReader(input), "./highlight.org").Write(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := lines(out), []string{"1", "*2", "*3", ""}; !slices.Equal(actual, expected) {
+		t.Errorf("got lines %q, expected %q", actual, expected)
+	}
+	if strings.Count(out, `<div class="highlight">`) != 2 || strings.Count(out, `<div class="highlight-inline">`) != 1 {
+		t.Errorf("got %q, expected two src blocks and one inline src block", out)
+	}
+}
@@ -0,0 +1,448 @@
+// Package htmlimport converts a sanitized subset of HTML into Org mode - e.g. to clip web content into Org files.
+//
+//	d, err := org.New().Read(htmlimport.Importer{BaseURL: pageURL}, resp.Body, "./clipping.org")
+//	out, err := d.Write(org.NewOrgWriter())
+//
+// Headings, paragraphs, lists (including description lists), tables, quotes, preformatted code, horizontal rules,
+// links, images and inline markup (bold, italic, underline, strikethrough, code, sub- and superscripts, line breaks)
+// are converted. Scripts, styles, forms and embedded content are dropped and all other elements are replaced by
+// their content.
+package htmlimport
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/alexispurslane/go-org/org"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Importer converts HTML into Org mode. The zero value is ready to use.
+type Importer struct {
+	BaseURL *url.URL // BaseURL is used to resolve relative link and image URLs, e.g. the URL of the clipped page.
+}
+
+// dropped contains the elements that are removed including their content.
+var dropped = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true, atom.Iframe: true,
+	atom.Object: true, atom.Embed: true, atom.Form: true, atom.Button: true, atom.Select: true, atom.Textarea: true,
+	atom.Input: true, atom.Svg: true, atom.Math: true, atom.Canvas: true, atom.Audio: true, atom.Video: true,
+}
+
+// blockElements contains the elements that are converted to (or unwrapped into) Org mode elements rather than inline markup.
+var blockElements = map[atom.Atom]bool{
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.P: true,
+	atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Table: true, atom.Pre: true, atom.Blockquote: true, atom.Hr: true,
+	atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true, atom.Header: true, atom.Footer: true,
+	atom.Aside: true, atom.Nav: true, atom.Figure: true, atom.Figcaption: true, atom.Body: true, atom.Html: true,
+	atom.Address: true, atom.Details: true, atom.Summary: true, atom.Li: true, atom.Dt: true, atom.Dd: true,
+}
+
+var inlineMarkers = map[atom.Atom][2]string{
+	atom.Strong: {"*", "*"}, atom.B: {"*", "*"},
+	atom.Em: {"/", "/"}, atom.I: {"/", "/"}, atom.Cite: {"/", "/"}, atom.Dfn: {"/", "/"},
+	atom.U: {"_", "_"}, atom.Ins: {"_", "_"},
+	atom.S: {"+", "+"}, atom.Del: {"+", "+"}, atom.Strike: {"+", "+"},
+	atom.Sub: {"_{", "}"}, atom.Sup: {"^{", "}"},
+}
+
+var whitespaceRegexp = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// elementLineRegexp matches the starts of lines that would be parsed as something other than paragraph text.
+var elementLineRegexp = regexp.MustCompile(`(?m)^(\*+(?:[ \t]|$)|#|\||:|[-+] |[0-9]+[.)] )`)
+
+// inlineMarkupRegexps match the starts of inline markup in text (e.g. emphasis, links, footnotes, timestamps and
+// latex fragments) - a zero width space is inserted between their two groups to keep the text literal.
+var inlineMarkupRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`(^|[\s\-({'"])([*/_+~=])`),
+	regexp.MustCompile(`(\[)(\[|fn:|[0-9]*/[0-9]*\]|[0-9]*%\]|[0-9]{4}-)`),
+	regexp.MustCompile(`(<)([<0-9%])`),
+	regexp.MustCompile(`([_^])(\{)`),
+	regexp.MustCompile(`(\\)([(\[])`),
+	regexp.MustCompile(`(@)(@)`),
+	regexp.MustCompile(`(\{)(\{\{)`),
+	regexp.MustCompile(`\b(src|call)(_)`),
+}
+
+// dollarMathRegexp matches $$...$$ and $...$ latex fragments (see Configuration.DollarMath) in text.
+var dollarMathRegexp = regexp.MustCompile(`\$(\$[^$]*\$\$|[^\s,;.$](?:[^$]*[^\s,.$])?\$(?:[^\pL\pN$_]|$))`)
+
+// headingKeywordRegexp matches the starts of heading titles that would be parsed as the todo keyword, priority or
+// COMMENT keyword of a headline.
+var headingKeywordRegexp = regexp.MustCompile(`^((?:TODO|DONE|COMMENT)(?:\s|$)|\[#.\])`)
+
+// headingTagsRegexp matches the ends of heading titles that would be parsed as the tags of a headline.
+var headingTagsRegexp = regexp.MustCompile(`(\s:[\p{L}0-9_@#%:]+:)$`)
+
+// escapedBlockLineRegexp matches the lines of src and example blocks that have to be escaped with a comma.
+var escapedBlockLineRegexp = regexp.MustCompile(`(?m)^(\s*)(,*(\*|#\+))`)
+
+var languageRegexp = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([A-Za-z0-9_+#-]+)`)
+
+// block is a converted Org mode element.
+type block struct {
+	text string
+	list bool // list is true for lists, which directly follow the text of their parent list item
+}
+
+// Convert returns the Org mode markup of the HTML read from r.
+func (i Importer) Convert(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", fmt.Errorf("could not parse html: %w", err)
+	}
+	c := &converter{Importer: i, minHeading: minHeading(doc)}
+	if text := join(c.blocks(doc), false); text != "" {
+		return text + "\n", nil
+	}
+	return "", nil
+}
+
+// Read implements org.Reader: it returns the document parsed with c from the Org mode markup of the HTML read from
+// input. See Convert.
+func (i Importer) Read(c *org.Configuration, input io.Reader, path string) (*org.Document, error) {
+	text, err := i.Convert(input)
+	if err != nil {
+		return nil, err
+	}
+	d := c.Parse(strings.NewReader(text), path)
+	if d.FatalError != nil {
+		return d, fmt.Errorf("could not import html: %w", d.FatalError)
+	}
+	return d, nil
+}
+
+type converter struct {
+	Importer
+	minHeading int // minHeading is the level of the highest heading - it becomes a level 1 headline
+}
+
+// minHeading returns the lowest level of the headings (h1 - h6) of the document - or 1 if it has none.
+func minHeading(doc *html.Node) int {
+	level := 7
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && dropped[n.DataAtom] {
+			return
+		} else if l := headingLevel(n); l != 0 {
+			level = min(level, l)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(doc)
+	if level == 7 {
+		return 1
+	}
+	return level
+}
+
+func headingLevel(n *html.Node) int {
+	if n.Type != html.ElementNode {
+		return 0
+	}
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		return int(n.Data[1] - '0')
+	}
+	return 0
+}
+
+// blocks returns the Org mode elements of the children of n. Runs of inline children become paragraphs.
+func (c *converter) blocks(n *html.Node) []block {
+	blocks, inline := []block{}, &strings.Builder{}
+	flush := func() {
+		if text := paragraph(inline.String()); text != "" {
+			blocks = append(blocks, block{text: text})
+		}
+		inline.Reset()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && dropped[child.DataAtom] {
+			continue
+		} else if child.Type != html.ElementNode || !blockElements[child.DataAtom] {
+			inline.WriteString(c.inline(child))
+			continue
+		}
+		flush()
+		blocks = append(blocks, c.block(child)...)
+	}
+	flush()
+	return blocks
+}
+
+func (c *converter) block(n *html.Node) []block {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		title := strings.ReplaceAll(strings.TrimSpace(c.inlineChildren(n)), "\n", " ")
+		title = headingKeywordRegexp.ReplaceAllString(title, "\u200b$1")
+		title = headingTagsRegexp.ReplaceAllString(title, "$1\u200b")
+		return []block{{text: strings.Repeat("*", headingLevel(n)-c.minHeading+1) + " " + title}}
+	case atom.Ul, atom.Ol:
+		if text := c.list(n); text != "" {
+			return []block{{text: text, list: true}}
+		}
+		return nil
+	case atom.Dl:
+		if text := c.descriptionList(n); text != "" {
+			return []block{{text: text, list: true}}
+		}
+		return nil
+	case atom.Table:
+		if text := c.table(n); text != "" {
+			return []block{{text: text}}
+		}
+		return nil
+	case atom.Pre:
+		return []block{{text: c.pre(n)}}
+	case atom.Blockquote:
+		return []block{{text: "#+BEGIN_QUOTE\n" + join(c.blocks(n), false) + "\n#+END_QUOTE"}}
+	case atom.Hr:
+		return []block{{text: "-----"}}
+	}
+	return c.blocks(n)
+}
+
+// join returns the texts of the blocks separated by blank lines. Lists are separated from preceding lists by two blank
+// lines (which end lists) and - if tight, i.e. inside of list items - directly follow other preceding blocks.
+func join(blocks []block, tight bool) string {
+	out := &strings.Builder{}
+	for i, b := range blocks {
+		if i > 0 && b.list && blocks[i-1].list {
+			out.WriteString("\n\n\n")
+		} else if i > 0 && b.list && tight {
+			out.WriteString("\n")
+		} else if i > 0 {
+			out.WriteString("\n\n")
+		}
+		out.WriteString(b.text)
+	}
+	return out.String()
+}
+
+func (c *converter) list(n *html.Node) string {
+	items, number := []string{}, 1
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.DataAtom != atom.Li {
+			continue
+		}
+		bullet := "- "
+		if n.DataAtom == atom.Ol {
+			bullet = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		items = append(items, listItem(bullet, strings.Repeat(" ", len(bullet)), join(c.blocks(child), true)))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (c *converter) descriptionList(n *html.Node) string {
+	items, term := []string{}, ""
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		} else if child.DataAtom == atom.Dt {
+			term = strings.ReplaceAll(strings.TrimSpace(c.inlineChildren(child)), "\n", " ")
+		} else if child.DataAtom == atom.Dd {
+			items = append(items, listItem("- "+term+" :: ", "  ", join(c.blocks(child), true)))
+		}
+	}
+	return strings.Join(items, "\n")
+}
+
+// listItem returns the list item with the bullet and content - continuation lines are indented by indent.
+func listItem(bullet, indent, content string) string {
+	lines := strings.Split(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.TrimRight(bullet+strings.Join(lines, "\n"), " ")
+}
+
+func (c *converter) table(n *html.Node) string {
+	rows, columns := [][]string{}, 0
+	headerRows := 0
+	var visit func(*html.Node, bool)
+	visit = func(n *html.Node, header bool) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Thead:
+				visit(child, true)
+			case atom.Tbody, atom.Tfoot:
+				visit(child, false)
+			case atom.Tr:
+				row, isHeader := []string{}, header
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						text := strings.Join(strings.Fields(c.inlineChildren(cell)), " ")
+						row = append(row, strings.ReplaceAll(text, "|", `\vert{}`))
+						isHeader = isHeader || (cell.DataAtom == atom.Th && len(rows) == headerRows)
+					}
+				}
+				if isHeader && len(rows) == headerRows {
+					headerRows++
+				}
+				rows, columns = append(rows, row), max(columns, len(row))
+			}
+		}
+	}
+	visit(n, false)
+	if len(rows) == 0 || columns == 0 {
+		return ""
+	}
+	lines := []string{}
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == headerRows-1 && headerRows < len(rows) {
+			lines = append(lines, "|"+strings.Repeat("---+", columns-1)+"---|")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *converter) pre(n *html.Node) string {
+	language := languageRegexp.FindStringSubmatch(attribute(n, "class"))
+	if code := n.FirstChild; language == nil && code != nil && code.Type == html.ElementNode && code.DataAtom == atom.Code {
+		language = languageRegexp.FindStringSubmatch(attribute(code, "class"))
+	}
+	content := strings.TrimSuffix(strings.TrimPrefix(textContent(n), "\n"), "\n")
+	content = escapedBlockLineRegexp.ReplaceAllString(content, "$1,$2")
+	if language != nil {
+		return "#+BEGIN_SRC " + strings.ToLower(language[1]) + "\n" + content + "\n#+END_SRC"
+	}
+	return "#+BEGIN_EXAMPLE\n" + content + "\n#+END_EXAMPLE"
+}
+
+// inlineChildren returns the inline markup of the children of n.
+func (c *converter) inlineChildren(n *html.Node) string {
+	out := &strings.Builder{}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		out.WriteString(c.inline(child))
+	}
+	return out.String()
+}
+
+// inline returns the inline markup of n. Block elements inside of inline elements are treated as inline elements.
+func (c *converter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return literal(whitespaceRegexp.ReplaceAllString(n.Data, " "))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if dropped[n.DataAtom] {
+		return ""
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		return "\\\\\n"
+	case atom.Img:
+		src := c.url(attribute(n, "src"))
+		if src == "" {
+			return attribute(n, "alt")
+		}
+		return "[[" + src + "]]"
+	case atom.A:
+		description, href := strings.TrimSpace(c.inlineChildren(n)), c.url(attribute(n, "href"))
+		if href == "" {
+			return description
+		} else if description == "" || description == href {
+			return "[[" + href + "]]"
+		}
+		return "[[" + href + "][" + strings.NewReplacer("[[", "[", "]]", "]").Replace(description) + "]]"
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt, atom.Var:
+		return emphasis(strings.ReplaceAll(textContent(n), "\n", " "), [2]string{"~", "~"}, true)
+	}
+	if markers, ok := inlineMarkers[n.DataAtom]; ok {
+		return emphasis(c.inlineChildren(n), markers, false)
+	}
+	return c.inlineChildren(n)
+}
+
+// literal returns the text with its inline markup escaped with zero width spaces (see inlineMarkupRegexps).
+// Zero width spaces do not keep $...$ from being parsed as a latex fragment - its opening $ is replaced with the
+// \dollar entity instead.
+func literal(text string) string {
+	for _, r := range inlineMarkupRegexps {
+		text = r.ReplaceAllString(text, "${1}\u200b${2}")
+	}
+	return dollarMathRegexp.ReplaceAllString(text, `\dollar{}$1`)
+}
+
+// emphasis returns the content surrounded by the markers. Whitespace at the start and end of the content is moved
+// outside of the markers as it is not allowed directly inside of them.
+func emphasis(content string, markers [2]string, verbatim bool) string {
+	text := strings.TrimSpace(content)
+	if text == "" {
+		return content
+	}
+	if verbatim && strings.Contains(text, "~") {
+		markers = [2]string{"=", "="}
+	}
+	leading, trailing := content[:strings.Index(content, text)], content[strings.Index(content, text)+len(text):]
+	return leading + markers[0] + text + markers[1] + trailing
+}
+
+// paragraph returns the inline markup as the lines of a paragraph. Lines that would be parsed as other elements
+// (e.g. "* not a headline") are escaped with a zero width space.
+func paragraph(inline string) string {
+	lines := strings.Split(inline, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text := strings.Trim(strings.Join(lines, "\n"), "\n")
+	return elementLineRegexp.ReplaceAllString(text, "\u200b$1")
+}
+
+// url returns the URL resolved against the BaseURL - or "" for unsafe URLs (e.g. javascript:).
+func (c *converter) url(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if raw == "" || err != nil {
+		return ""
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto", "ftp", "file":
+	default:
+		return ""
+	}
+	if c.BaseURL != nil {
+		u = c.BaseURL.ResolveReference(u)
+	}
+	return strings.NewReplacer("[", "%5B", "]", "%5D").Replace(u.String())
+}
+
+func attribute(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// textContent returns the text of n and its descendants.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	out := &strings.Builder{}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		out.WriteString(textContent(child))
+	}
+	return out.String()
+}
@@ -0,0 +1,149 @@
+package htmlimport
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+const input = `<html><head><title>Post</title><script>alert(1)</script></head><body>
+<nav><a href="/">Home</a></nav>
+<h2>Title <em>here</em></h2>
+<p>Some <b>bold </b>and <i>italic</i> text with <a href="/about">a link</a> and <code>x := 1</code>.<br>Second line</p>
+<p>* not a headline</p>
+<ul><li>one</li><li>two<ul><li>nested <a href="javascript:alert(1)">unsafe</a></li></ul></li><li><p>first</p><p>second</p></li></ul>
+<ol><li>first</li><li>second</li></ol>
+<h3>Table</h3>
+<table><thead><tr><th>a</th><th>b|c</th></tr></thead><tbody><tr><td>1</td><td>2</td></tr><tr><td>3</td></tr></tbody></table>
+<pre><code class="language-Go">func main() {
+* not a headline
+}
+</code></pre>
+<blockquote><p>quoted</p></blockquote>
+<dl><dt>term</dt><dd>definition</dd></dl>
+<img src="img/x.png" alt="x"><hr>
+<form><input name="q"></form>
+</body></html>`
+
+const expected = `[[https://example.com/][Home]]
+
+* Title /here/
+
+Some *bold* and /italic/ text with [[https://example.com/about][a link]] and ~x := 1~.\\
+Second line
+
+` + "\u200b" + `* not a headline
+
+- one
+- two
+  - nested unsafe
+- first
+
+  second
+
+
+1. first
+2. second
+
+** Table
+
+| a | b\vert{}c |
+|---+---|
+| 1 | 2 |
+| 3 |  |
+
+#+BEGIN_SRC go
+func main() {
+,* not a headline
+}
+#+END_SRC
+
+#+BEGIN_QUOTE
+quoted
+#+END_QUOTE
+
+- term :: definition
+
+[[https://example.com/blog/img/x.png]]
+
+-----
+`
+
+func TestConvert(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post.html")
+	actual, err := Importer{BaseURL: base}.Convert(strings.NewReader(input))
+	if err != nil || actual != expected {
+		t.Errorf("got error %v or output:\n%s\nexpected:\n%s", err, actual, expected)
+	}
+}
+
+func TestImport(t *testing.T) {
+	d, err := org.New().Silent().Read(Importer{}, strings.NewReader(input), "./clipping.org")
+	if err != nil || d.HasErrors() {
+		t.Fatalf("could not import: %v %v", err, d.Errors)
+	}
+	nodeTypes := func(nodes []org.Node) string {
+		types := []string{}
+		for _, n := range nodes {
+			if org.String(n) != "\n" { // blank lines
+				types = append(types, strings.TrimPrefix(fmt.Sprintf("%T", n), "org."))
+			}
+		}
+		return strings.Join(types, " ")
+	}
+	if actual, expected := nodeTypes(d.Nodes), "Paragraph Headline"; actual != expected {
+		t.Errorf("got %s, expected %s", actual, expected)
+	}
+	title := d.Outline.Children[0].Headline
+	if actual, expected := nodeTypes(title.Children), "Paragraph Paragraph List List Headline"; actual != expected {
+		t.Errorf("got %s, expected %s", actual, expected)
+	}
+	table := title.Children[len(title.Children)-1].(org.Headline)
+	if actual, expected := nodeTypes(table.Children), "Table Block Block List Paragraph HorizontalRule"; actual != expected {
+		t.Errorf("got %s, expected %s", actual, expected)
+	}
+	if out, err := (Importer{}).Convert(strings.NewReader("")); err != nil || out != "" {
+		t.Errorf("expected empty output for empty input, got %q (%v)", out, err)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	input := "<h2>TODO list</h2><h2>[#A] grade</h2><h2>Notes :work:</h2>" +
+		"<p><b>Note:</b> text</p><p>** not a headline</p><ul><li><b>Bold</b> item</li></ul>"
+	d, err := org.New().Silent().Read(Importer{}, strings.NewReader(input), "./escaping.org")
+	if err != nil {
+		t.Fatalf("could not import: %v", err)
+	}
+	for i, title := range []string{"\u200bTODO list", "\u200b[#A] grade", "Notes :work:\u200b"} {
+		h := d.Outline.Children[i].Headline
+		if h.Status != "" || h.Priority != "" || len(h.Tags) != 0 || org.String(h.Title...) != title {
+			t.Errorf("expected heading %q to be a plain title, got %#v", title, h)
+		}
+	}
+	expected := "*Note:* text\n\n\u200b** not a headline\n\n- *Bold* item\n"
+	if out, err := (Importer{}).Convert(strings.NewReader(input)); err != nil || !strings.HasSuffix(out, expected) {
+		t.Errorf("got %q (%v), expected it to end with %q", out, err, expected)
+	}
+}
+
+func TestLiteralMarkup(t *testing.T) {
+	for _, text := range []string{
+		"*b*", "/x/ and _u_", "=v= ~c~ +s+", "[[x]]", "[fn:1]", "[1/2] [50%]", "x^{2} a_{b}", "$x$", "$$x$$", "$5 and $10",
+		`\(x\)`, "{{{m(1)}}}", "<2024-01-01 Mon>", "[2024-01-01 Mon]", "<<target>>", "@@html:x@@", "src_go{x}",
+	} {
+		d, err := org.New().Silent().Read(Importer{}, strings.NewReader("<p>"+html.EscapeString(text)+"</p>"), "./literal.org")
+		if err != nil {
+			t.Fatalf("could not import %q: %v", text, err)
+		}
+		children := d.Nodes[0].(org.Paragraph).Children
+		if content, ok := children[0].(org.Text); len(children) != 1 || !ok {
+			t.Errorf("%q: expected a single text node, got %#v", text, children)
+		} else if literal := strings.NewReplacer("\u200b", "", `\dollar{}`, "$").Replace(content.Content); literal != text {
+			t.Errorf("%q: got text %q", text, literal)
+		}
+	}
+}
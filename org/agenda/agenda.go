@@ -0,0 +1,139 @@
+// Package agenda collects the scheduled, deadline and timestamped headlines of parsed Org mode documents.
+//
+//	d := org.New().Silent().Parse(input, "./todo.org")
+//	week := agenda.Filter{From: monday, To: monday.AddDate(0, 0, 7), Match: org.MustCompileTagMatch("+work/!")}
+//	for _, entry := range agenda.Entries(week, d) {
+//	    log.Print(entry)
+//	}
+package agenda
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+// Kind is the kind of timestamp an Entry was created for.
+type Kind int
+
+const (
+	Scheduled Kind = iota // Scheduled is used for the SCHEDULED timestamp of the planning line of a headline.
+	Deadline              // Deadline is used for the DEADLINE timestamp of the planning line of a headline.
+	Timestamp             // Timestamp is used for active timestamps in the title or body of a headline.
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Scheduled:
+		return "scheduled"
+	case Deadline:
+		return "deadline"
+	case Timestamp:
+		return "timestamp"
+	default:
+		return "unknown"
+	}
+}
+
+// Entry is a timestamp of a headline.
+type Entry struct {
+	Kind      Kind
+	Date      time.Time     // Date is the time of the timestamp - or of one of its repetitions.
+	Timestamp org.Timestamp // Timestamp is the timestamp node of the entry.
+	Headline  org.Headline
+	Document  *org.Document // Document is the document containing the headline.
+	Category  string        // Category is the category of the headline. See Headline.Category.
+	Todo      string        // Todo is the todo keyword of the headline.
+	Priority  string        // Priority is the priority of the headline - empty if it has none.
+	Tags      []string      // Tags contains the tags of the headline including inherited tags. See Headline.AllTags.
+}
+
+// String returns a one line description of the entry, e.g. "2024-06-01 work: scheduled TODO Write report".
+func (e Entry) String() string {
+	s := fmt.Sprintf("%s %s: %s", e.Date.Format("2006-01-02"), e.Category, e.Kind)
+	if e.Todo != "" {
+		s += " " + e.Todo
+	}
+	return s + " " + org.PlainText(e.Headline.Title...)
+}
+
+// Filter restricts the entries returned by Entries. The zero Filter matches all entries.
+type Filter struct {
+	From  time.Time    // From excludes entries before From - unless it is zero.
+	To    time.Time    // To excludes entries at or after To - unless it is zero.
+	Match org.TagMatch // Match excludes entries of headlines that are not matched - unless it is nil. See org.CompileTagMatch.
+}
+
+// Entries returns the entries of the headlines of the documents that pass the filter, ordered by date.
+// Entries with the same date keep the order of the documents. If the filter has both a From and a To date, repeating
+// timestamps result in an entry for each occurrence within the range - see Timestamp.Occurrences.
+func Entries(f Filter, docs ...*org.Document) []Entry {
+	entries := []Entry{}
+	// headline contains the planning, category and tags of a headline - they are computed once per headline
+	type headline struct {
+		planning map[string]org.Timestamp
+		category string
+		tags     []string
+	}
+	for _, d := range docs {
+		headlines := map[org.Position]*headline{}
+		org.Walk(d, org.Visitor{Enter: func(c *org.Cursor) {
+			t, ok := c.Node().(org.Timestamp)
+			if !ok {
+				return
+			}
+			h, ok := headlineOf(c.Parents())
+			if !ok || (f.Match != nil && !f.Match(d, h)) {
+				return
+			}
+			dates := []time.Time{t.Time}
+			if !f.From.IsZero() && !f.To.IsZero() {
+				dates = t.Occurrences(f.From, f.To)
+			} else if !f.includes(t.Time) {
+				return
+			}
+			data, ok := headlines[h.Pos]
+			if !ok {
+				data = &headline{h.Planning(), h.Category(d), h.AllTags(d)}
+				headlines[h.Pos] = data
+			}
+			kind := Timestamp
+			if scheduled, ok := data.planning["SCHEDULED"]; ok && scheduled.Pos == t.Pos {
+				kind = Scheduled
+			} else if deadline, ok := data.planning["DEADLINE"]; ok && deadline.Pos == t.Pos {
+				kind = Deadline
+			}
+			for _, date := range dates {
+				entries = append(entries, Entry{
+					Kind:      kind,
+					Date:      date,
+					Timestamp: t,
+					Headline:  h,
+					Document:  d,
+					Category:  data.category,
+					Todo:      h.Status,
+					Priority:  h.Priority,
+					Tags:      data.tags,
+				})
+			}
+		}})
+	}
+	slices.SortStableFunc(entries, func(a, b Entry) int { return a.Date.Compare(b.Date) })
+	return entries
+}
+
+func (f Filter) includes(t time.Time) bool {
+	return (f.From.IsZero() || !t.Before(f.From)) && (f.To.IsZero() || t.Before(f.To))
+}
+
+// headlineOf returns the innermost headline of the given ancestors.
+func headlineOf(parents []org.Node) (org.Headline, bool) {
+	for i := len(parents) - 1; i >= 0; i-- {
+		if h, ok := parents[i].(org.Headline); ok {
+			return h, true
+		}
+	}
+	return org.Headline{}, false
+}
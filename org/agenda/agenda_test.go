@@ -0,0 +1,94 @@
+package agenda
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+func TestEntries(t *testing.T) {
+	work := org.New().Silent().Parse(strings.NewReader(strings.Join([]string{
+		"#+CATEGORY: job",
+		"* TODO [#A] Write report :work:",
+		"SCHEDULED: <2024-06-03 Mon> DEADLINE: <2024-06-07 Fri>",
+		"** Meeting <2024-06-04 Tue 10:00>",
+		"Prepare slides for <2024-06-05 Wed>",
+		"* DONE Old task :work:",
+		"SCHEDULED: <2024-05-01 Wed>",
+	}, "\n")), "./work.org")
+	home := org.New().Silent().Parse(strings.NewReader("* Groceries :home:\n<2024-06-04 Tue>\n* Laundry\n<2024-05-29 Wed +1w>\n"), "./home.org")
+	date := func(day int) time.Time { return time.Date(2024, 6, day, 0, 0, 0, 0, time.UTC) }
+	for _, c := range []struct {
+		filter   Filter
+		expected []string
+	}{
+		{Filter{}, []string{
+			"2024-05-01 job: scheduled DONE Old task",
+			"2024-05-29 home: timestamp Laundry",
+			"2024-06-03 job: scheduled TODO Write report",
+			"2024-06-04 home: timestamp Groceries",
+			"2024-06-04 job: timestamp Meeting <2024-06-04 Tue 10:00>",
+			"2024-06-05 job: timestamp Meeting <2024-06-04 Tue 10:00>",
+			"2024-06-07 job: deadline TODO Write report",
+		}},
+		{Filter{From: date(4), To: date(7)}, []string{
+			"2024-06-04 home: timestamp Groceries",
+			"2024-06-04 job: timestamp Meeting <2024-06-04 Tue 10:00>",
+			"2024-06-05 home: timestamp Laundry",
+			"2024-06-05 job: timestamp Meeting <2024-06-04 Tue 10:00>",
+		}},
+		{Filter{Match: org.MustCompileTagMatch("+work/!")}, []string{
+			"2024-06-03 job: scheduled TODO Write report",
+			"2024-06-07 job: deadline TODO Write report",
+		}},
+	} {
+		actual := []string{}
+		for _, e := range Entries(c.filter, home, work) {
+			actual = append(actual, e.String())
+		}
+		if actual, expected := strings.Join(actual, "\n"), strings.Join(c.expected, "\n"); actual != expected {
+			t.Errorf("%#v: got\n%s\nexpected\n%s", c.filter, actual, expected)
+		}
+	}
+	entry := Entries(Filter{Match: org.MustCompileTagMatch("PRIORITY=\"A\"")}, work)[0]
+	if entry.Priority != "A" || strings.Join(entry.Tags, ",") != "work" || entry.Document != work || entry.Kind != Scheduled {
+		t.Errorf("bad entry %#v", entry)
+	}
+	chores := org.New().Silent().Parse(strings.NewReader("* Chores :home:\n:PROPERTIES:\n:CATEGORY: chores\n:END:\n** Dishes <2024-06-06 Thu>\n"), "./chores.org")
+	if entry := Entries(Filter{}, chores)[0]; entry.Category != "chores" || strings.Join(entry.Tags, ",") != "home" {
+		t.Errorf("expected inherited category and tags, got %#v", entry)
+	}
+}
+
+func TestUrgency(t *testing.T) {
+	d := org.New().Silent().Parse(strings.NewReader(strings.Join([]string{
+		"* TODO [#A] Report",
+		"DEADLINE: <2024-06-14 Fri -3d>",
+		"* TODO Taxes",
+		"DEADLINE: <2024-06-10 Mon>",
+		"* DONE Old",
+		"DEADLINE: <2024-06-01 Sat>",
+		"* TODO [#C] Cleanup",
+		"SCHEDULED: <2024-06-09 Sun>",
+		"* Party <2024-06-20 Thu>",
+	}, "\n")), "./urgency.org")
+	now := time.Date(2024, 6, 12, 18, 0, 0, 0, time.UTC)
+	actual := []string{}
+	for _, e := range Entries(Filter{}, d) {
+		actual = append(actual, fmt.Sprintf("%s: days %d, warning days %d, done %t, overdue %t, warning %t, urgency %d",
+			org.PlainText(e.Headline.Title...), e.DaysUntil(now), e.WarningDays(), e.IsDone(), e.IsOverdue(now), e.IsWarning(now), e.Urgency(now)))
+	}
+	expected := []string{
+		"Old: days -11, warning days 14, done true, overdue false, warning false, urgency 1011",
+		"Cleanup: days -3, warning days 14, done false, overdue true, warning false, urgency 102",
+		"Taxes: days -2, warning days 14, done false, overdue true, warning true, urgency 1002",
+		"Report: days 2, warning days 3, done false, overdue false, warning true, urgency 1998",
+		"Party <2024-06-20 Thu>: days 8, warning days 14, done false, overdue false, warning false, urgency 1000",
+	}
+	if actual, expected := strings.Join(actual, "\n"), strings.Join(expected, "\n"); actual != expected {
+		t.Errorf("got\n%s\nexpected\n%s", actual, expected)
+	}
+}
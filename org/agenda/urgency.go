@@ -0,0 +1,70 @@
+package agenda
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// DeadlineWarningDays is the default warning period of deadlines in days - see org-deadline-warning-days.
+const DeadlineWarningDays = 14
+
+var delayRegexp = regexp.MustCompile(`^--?(\d+)([hdwmy])$`)
+
+// delayUnitDays contains the length of the units of warning periods in days - like org-get-wdays.
+var delayUnitDays = map[string]float64{"h": 1.0 / 24, "d": 1, "w": 7, "m": 30.4, "y": 365.25}
+
+// DaysUntil returns the number of days from the day of now until the day of the entry - negative for past entries.
+func (e Entry) DaysUntil(now time.Time) int {
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+	return int(math.Round(day(e.Date).Sub(day(now)).Hours() / 24))
+}
+
+// WarningDays returns the number of days before a deadline it is shown as upcoming, i.e. the warning period of its
+// timestamp (e.g. -3d) or DeadlineWarningDays.
+func (e Entry) WarningDays() int {
+	if m := delayRegexp.FindStringSubmatch(e.Timestamp.Delay); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return int(math.Floor(float64(n) * delayUnitDays[m[2]]))
+	}
+	return DeadlineWarningDays
+}
+
+// IsDone reports whether the headline of the entry is marked with a done keyword. See Document.DoneKeywords.
+func (e Entry) IsDone() bool {
+	return e.Todo != "" && e.Document != nil && slices.Contains(e.Document.DoneKeywords(), e.Todo)
+}
+
+// IsOverdue reports whether the entry is a deadline or scheduled entry before the day of now that is not done.
+func (e Entry) IsOverdue(now time.Time) bool {
+	return e.Kind != Timestamp && !e.IsDone() && e.DaysUntil(now) < 0
+}
+
+// IsWarning reports whether the entry is a deadline that is not done and lies within its warning period
+// (see WarningDays) - including overdue deadlines.
+func (e Entry) IsWarning(now time.Time) bool {
+	return e.Kind == Deadline && !e.IsDone() && e.DaysUntil(now) <= e.WarningDays()
+}
+
+// Urgency returns the priority score org-agenda uses to sort entries - higher is more urgent.
+// The priority of the headline contributes 2000 (A), 1000 (B or none) or 0 (C). Deadlines gain a point for each day
+// closer to (or past) the deadline and scheduled entries get 99 points plus a point for each day since they were scheduled.
+func (e Entry) Urgency(now time.Time) int {
+	score := 1000
+	switch e.Priority {
+	case "A":
+		score = 2000
+	case "C":
+		score = 0
+	}
+	switch e.Kind {
+	case Deadline:
+		return score - e.DaysUntil(now)
+	case Scheduled:
+		return score + 99 - e.DaysUntil(now)
+	default:
+		return score
+	}
+}
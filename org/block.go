@@ -3,6 +3,8 @@ package org
 import (
 	"math"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -36,22 +38,22 @@ var endBlockRegexp = regexp.MustCompile(`(?i)^(\s*)#\+END_(\w+)`)
 var beginLatexBlockRegexp = regexp.MustCompile(`(?i)^(\s*)\\begin{([^}]+)}(\s*)$`)
 var endLatexBlockRegexp = regexp.MustCompile(`(?i)^(\s*)\\end{([^}]+)}(\s*)$`)
 var resultRegexp = regexp.MustCompile(`(?i)^(\s*)#\+RESULTS:`)
-var exampleBlockEscapeRegexp = regexp.MustCompile(`(^|\n)([ \t]*),([ \t]*)(\*|,\*|#\+|,#\+)`)
+var escapedBlockLineRegexp = regexp.MustCompile(`(?m)^([ \t]*,*),(\*|#\+)`)
 
 func lexBlock(line string) (token, bool) {
 	if m := beginBlockRegexp.FindStringSubmatch(line); m != nil {
-		return token{kind: "beginBlock", lvl: len(m[1]), content: strings.ToUpper(m[2]), matches: m}, true
+		return token{kind: "beginBlock", lvl: len(m[1]), content: upper(m[2]), matches: m}, true
 	} else if m := endBlockRegexp.FindStringSubmatch(line); m != nil {
-		return token{kind: "endBlock", lvl: len(m[1]), content: strings.ToUpper(m[2]), matches: m}, true
+		return token{kind: "endBlock", lvl: len(m[1]), content: upper(m[2]), matches: m}, true
 	}
 	return nilToken, false
 }
 
 func lexLatexBlock(line string) (token, bool) {
 	if m := beginLatexBlockRegexp.FindStringSubmatch(line); m != nil {
-		return token{kind: "beginLatexBlock", lvl: len(m[1]), content: strings.ToUpper(m[2]), matches: m}, true
+		return token{kind: "beginLatexBlock", lvl: len(m[1]), content: upper(m[2]), matches: m}, true
 	} else if m := endLatexBlockRegexp.FindStringSubmatch(line); m != nil {
-		return token{kind: "endLatexBlock", lvl: len(m[1]), content: strings.ToUpper(m[2]), matches: m}, true
+		return token{kind: "endLatexBlock", lvl: len(m[1]), content: upper(m[2]), matches: m}, true
 	}
 	return nilToken, false
 }
@@ -72,23 +74,46 @@ func lexExample(line string) (token, bool) {
 
 func isRawTextBlock(name string) bool { return name == "SRC" || name == "EXAMPLE" || name == "EXPORT" }
 
+// BlockHandler turns a block (e.g. #+BEGIN_NOTES ... #+END_NOTES) into a custom node - see
+// Configuration.AddBlockHandler. b is the block as parsed by default. It returns nil to keep the block as is.
+type BlockHandler func(d *Document, b Block) Node
+
+// AddBlockHandler registers h for blocks with the given name. Custom nodes are written by writers implementing
+// CustomNodeWriter, e.g. via HTMLWriter.AddNodeWriter.
+func (c *Configuration) AddBlockHandler(name string, h BlockHandler) *Configuration {
+	if c.BlockHandlers == nil {
+		c.BlockHandlers = map[string]BlockHandler{}
+	}
+	c.BlockHandlers[strings.ToUpper(name)] = h
+	return c
+}
+
 func (d *Document) parseBlock(i int, parentStop stopFn) (int, Node) {
 	t, start := d.tokens[i], i
 	name, parameters := t.content, splitParameters(t.matches[3])
-	trim := trimIndentUpTo(d.tokens[i].lvl)
 	stop := func(d *Document, i int) bool {
 		return i >= len(d.tokens) || (d.tokens[i].kind == "endBlock" && d.tokens[i].content == name)
 	}
-	block, i := Block{Name: name, Parameters: parameters, Children: nil, Result: nil}, i+1
+	block := Block{Name: name, Parameters: parameters, Children: nil, Result: nil}
+	trim := trimIndentUpTo(d.tokens[i].lvl, d.TabWidth)
+	if block.Switches().PreserveIndentation {
+		trim = func(line string) string { return line }
+	}
+	i++
 	if isRawTextBlock(name) {
 		rawText := ""
 		for ; !stop(d, i); i++ {
 			rawText += trim(d.tokens[i].matches[0]) + "\n"
 		}
-		if name == "EXAMPLE" || (name == "SRC" && len(parameters) >= 1 && parameters[0] == "org") {
-			rawText = exampleBlockEscapeRegexp.ReplaceAllString(rawText, "$1$2$3$4")
-		}
+		// lines starting with * or #+ are escaped with a comma (see org-escape-code-in-region)
+		rawText = escapedBlockLineRegexp.ReplaceAllString(rawText, "$1$2")
 		block.Children = d.parseRawInline(rawText)
+	} else if name == "VERSE" {
+		rawText := ""
+		for ; !stop(d, i); i++ {
+			rawText += trim(d.tokens[i].matches[0]) + "\n"
+		}
+		block.Children = d.parseInline(rawText) // verse blocks contain lines of inline markup rather than paragraphs
 	} else {
 		consumed, nodes := d.parseMany(i, stop)
 		block.Children = nodes
@@ -96,7 +121,7 @@ func (d *Document) parseBlock(i int, parentStop stopFn) (int, Node) {
 	}
 
 	if i >= len(d.tokens) || d.tokens[i].kind != "endBlock" || d.tokens[i].content != name {
-		d.AddError(ErrorTypeInvalidStructure, "unterminated block", getPositionFromToken(t), t, nil)
+		d.AddError(ErrorTypeInvalidStructure, "unterminated block", d.getPositionFromToken(t), t, nil)
 		d.tokens[start].kind = "text"
 		return 0, nil
 	}
@@ -109,20 +134,31 @@ func (d *Document) parseBlock(i int, parentStop stopFn) (int, Node) {
 			i += consumed
 		}
 	}
-	block.Pos = Position{
-		StartLine:   d.tokens[start].line,
-		StartColumn: d.tokens[start].startCol,
-		EndLine:     d.tokens[i-1].line,
-		EndColumn:   d.tokens[i-1].endCol,
+	block.Pos = d.getPositionBetweenTokens(d.tokens[start], d.tokens[i-1])
+	if h := d.BlockHandlers[name]; h != nil {
+		if n := h(d, block); n != nil {
+			return i - start, n
+		}
 	}
 	return i - start, block
 }
 
 func (d *Document) parseLatexBlock(i int, parentStop stopFn) (int, Node) {
 	t, start := d.tokens[i], i
-	name, rawText, trim := t.content, "", trimIndentUpTo(int(math.Max((float64(d.baseLvl)), float64(t.lvl))))
+	name, rawText, trim := t.content, "", trimIndentUpTo(int(math.Max((float64(d.baseLvl)), float64(t.lvl))), d.TabWidth)
+	depth := 0 // environments can be nested, e.g. \begin{array} inside of \begin{array}
 	stop := func(d *Document, i int) bool {
-		return i >= len(d.tokens) || (d.tokens[i].kind == "endLatexBlock" && d.tokens[i].content == name)
+		if i >= len(d.tokens) || d.tokens[i].content != name {
+			return i >= len(d.tokens)
+		} else if d.tokens[i].kind == "beginLatexBlock" && i != start {
+			depth++
+		} else if d.tokens[i].kind == "endLatexBlock" {
+			if depth == 0 {
+				return true
+			}
+			depth--
+		}
+		return false
 	}
 	for ; !stop(d, i); i++ {
 		rawText += trim(d.tokens[i].matches[0]) + "\n"
@@ -132,17 +168,12 @@ func (d *Document) parseLatexBlock(i int, parentStop stopFn) (int, Node) {
 		rawText += trim(d.tokens[i].matches[0])
 		i++
 	} else {
-		d.AddError(ErrorTypeInvalidStructure, "unterminated latex block", getPositionFromToken(t), t, nil)
+		d.AddError(ErrorTypeInvalidStructure, "unterminated latex block", d.getPositionFromToken(t), t, nil)
 		d.tokens[start].kind = "text"
 		return 0, nil
 	}
 	latexBlock := LatexBlock{Content: d.parseRawInline(rawText)}
-	latexBlock.Pos = Position{
-		StartLine:   d.tokens[start].line,
-		StartColumn: d.tokens[start].startCol,
-		EndLine:     d.tokens[i-1].line,
-		EndColumn:   d.tokens[i-1].endCol,
-	}
+	latexBlock.Pos = d.getPositionBetweenTokens(d.tokens[start], d.tokens[i-1])
 	return i - start, latexBlock
 }
 
@@ -163,12 +194,7 @@ func (d *Document) parseExample(i int, parentStop stopFn) (int, Node) {
 		example.Children = append(example.Children, Text{Content: d.tokens[i].content, IsRaw: true})
 	}
 	endTokenIndex := i - 1
-	example.Pos = Position{
-		StartLine:   d.tokens[start].line,
-		StartColumn: d.tokens[start].startCol,
-		EndLine:     d.tokens[endTokenIndex].line,
-		EndColumn:   d.tokens[endTokenIndex].endCol,
-	}
+	example.Pos = d.getPositionBetweenTokens(d.tokens[start], d.tokens[endTokenIndex])
 	return i - start, example
 }
 
@@ -176,26 +202,23 @@ func (d *Document) parseResult(i int, parentStop stopFn) (int, Node) {
 	start := i
 	result := Result{}
 	if i+1 >= len(d.tokens) {
-		d.AddError(ErrorTypeInvalidStructure, "expected node after #+RESULTS:", getPositionFromToken(d.tokens[i]), d.tokens[i], nil)
+		d.addWarning(ErrorTypeInvalidStructure, "expected node after #+RESULTS:", d.getPositionFromToken(d.tokens[i]), d.tokens[i], nil)
 		result.Node = nil
 	} else {
 		consumed, node := d.parseOne(i+1, parentStop)
 		result.Node = node
 		i += consumed
 	}
-	result.Pos = Position{
-		StartLine:   d.tokens[start].line,
-		StartColumn: d.tokens[start].startCol,
-		EndLine:     d.tokens[start].line,
-		EndColumn:   d.tokens[start].endCol,
-	}
+	result.Pos = d.getPositionFromToken(d.tokens[start])
 	return i + 1 - start, result
 }
 
-func trimIndentUpTo(max int) func(string) string {
+// trimIndentUpTo returns a function that trims the leading whitespace of a line up to column max. See TabWidth.
+func trimIndentUpTo(max, tabWidth int) func(string) string {
 	return func(line string) string {
-		i := 0
-		for ; i < len(line) && i < max && unicode.IsSpace(rune(line[i])); i++ {
+		i, col := 0, 0
+		for ; i < len(line) && col < max && unicode.IsSpace(rune(line[i])); i++ {
+			col = columnAfter(line[i:i+1], col, tabWidth)
 		}
 		return line[i:]
 	}
@@ -222,6 +245,125 @@ func splitParameters(s string) []string {
 	return parameters
 }
 
+// BlockSwitches are the switches of src and example blocks, e.g. "-n 10 -r" in "#+BEGIN_SRC go -n 10 -r".
+type BlockSwitches struct {
+	LineNumbers         bool   // LineNumbers is true if lines should be numbered (-n or +n).
+	ContinueNumbering   bool   // ContinueNumbering is true if numbering continues from the previous numbered block (+n).
+	LineNumberOffset    int    // LineNumberOffset is the optional argument of -n / +n - the first line number for -n, an offset for +n.
+	RemoveLabels        bool   // RemoveLabels is true if links to code references should use line numbers rather than labels (-r).
+	KeepLabels          bool   // KeepLabels is true if code references like "(ref:label)" should be kept in the exported code (-k).
+	PreserveIndentation bool   // PreserveIndentation is true if the indentation of the code should be preserved (-i).
+	LabelFormat         string // LabelFormat is the format of code references (-l) - defaults to "(ref:%s)".
+}
+
+// Switches returns the switches of src and example blocks. Switches are stored in Parameters alongside the
+// header arguments (see HeaderArguments) - the switches of other blocks are always empty.
+func (b Block) Switches() BlockSwitches {
+	switches := BlockSwitches{LabelFormat: "(ref:%s)"}
+	args := b.Parameters
+	switch {
+	case b.Name == "SRC" && len(args) != 0:
+		args = args[1:] // language
+	case b.Name != "EXAMPLE":
+		return switches
+	}
+	fields := strings.Fields(strings.Join(args, " "))
+	for i := 0; i < len(fields); i++ {
+		k, v := fields[i], ""
+		if i+1 < len(fields) && !strings.ContainsRune("-+:", rune(fields[i+1][0])) {
+			v, i = fields[i+1], i+1
+		}
+		switch k {
+		case "-n", "+n":
+			switches.LineNumbers, switches.ContinueNumbering = true, k == "+n"
+			switches.LineNumberOffset, _ = strconv.Atoi(v)
+		case "-r":
+			switches.RemoveLabels = true
+		case "-k":
+			switches.KeepLabels = true
+		case "-i":
+			switches.PreserveIndentation = true
+		case "-l":
+			if v = strings.Trim(v, `"`); strings.Contains(v, "%s") {
+				switches.LabelFormat = v
+			}
+		}
+	}
+	return switches
+}
+
+// labelRegexp returns a regexp matching code references (see LabelFormat) at the end of lines.
+func (s BlockSwitches) labelRegexp() *regexp.Regexp {
+	parts := strings.SplitN(s.LabelFormat, "%s", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	return regexp.MustCompile(`(?m)[ \t]*` + regexp.QuoteMeta(parts[0]) + `[-\w]+` + regexp.QuoteMeta(parts[1]) + `[ \t]*$`)
+}
+
+// Citation splits the content of a quote block into the quote and its citation: the last line of the block if it
+// starts with a dash ("—", "---" or "--"), e.g. "— Albert Einstein". The citation does not contain the dash.
+// citation is nil for other blocks and quotes without citation.
+func (b Block) Citation() (quote, citation []Node) {
+	if b.Name != "QUOTE" || len(b.Children) == 0 {
+		return b.Children, nil
+	}
+	p, ok := b.Children[len(b.Children)-1].(Paragraph)
+	if !ok {
+		return b.Children, nil
+	}
+	children := p.InlineNodes()
+	lineStart := 0
+	for i, n := range children {
+		if _, ok := n.(LineBreak); ok {
+			lineStart = i + 1
+		}
+	}
+	if lineStart == len(children) {
+		return b.Children, nil
+	}
+	text, ok := children[lineStart].(Text)
+	if !ok {
+		return b.Children, nil
+	}
+	content := strings.TrimLeft(text.Content, " \t")
+	for _, dash := range []string{"—", "---", "--"} {
+		if after, ok := strings.CutPrefix(content, dash); ok {
+			text.Content = strings.TrimLeft(after, " \t")
+			citation = append([]Node{text}, children[lineStart+1:]...)
+			if text.Content == "" {
+				citation = citation[1:]
+			}
+			quote = slices.Clone(b.Children[:len(b.Children)-1])
+			if lineStart > 1 { // the line break before the citation is not part of the quote
+				quote = append(quote, Paragraph{Children: children[:lineStart-1], Pos: p.Pos})
+			}
+			return quote, citation
+		}
+	}
+	return b.Children, nil
+}
+
+// removeLabels removes code references from content unless they are kept via -k.
+func (s BlockSwitches) removeLabels(content string) string {
+	if re := s.labelRegexp(); re != nil && !s.KeepLabels {
+		return re.ReplaceAllString(content, "")
+	}
+	return content
+}
+
+// HeaderArguments returns the header arguments of the block (e.g. ":results output") - unlike ParameterMap
+// without the language and switches.
+func (b Block) HeaderArguments() map[string]string {
+	m := map[string]string{}
+	for i := 1; i+1 < len(b.Parameters); i += 2 {
+		if strings.HasPrefix(b.Parameters[i], ":") {
+			m[b.Parameters[i]] = b.Parameters[i+1]
+		}
+	}
+	return m
+}
+
 func (b Block) ParameterMap() map[string]string {
 	if len(b.Parameters) == 0 {
 		return nil
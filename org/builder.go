@@ -0,0 +1,153 @@
+package org
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// NewHeadline returns a headline with the given level, title and children - e.g. nodes created with
+// NewParagraphFromString and child headlines created with NewHeadline. The title is parsed like the text after the
+// stars of a headline line, i.e. it may start with a todo keyword and priority and end with tags: `TODO [#A] Task :tag:`.
+// NewHeadline returns an error if the level is not positive or the title starts with stars like a headline line.
+//
+// The positions of the returned nodes are those of its Org mode string (see String), i.e. the headline starts at line 0
+// and the positions of the children are shifted to follow each other. Structure is validated by Document.Append.
+func NewHeadline(lvl int, title string, children ...Node) (Headline, error) {
+	if lvl < 1 {
+		return Headline{}, fmt.Errorf("could not create headline %q: level %d must be positive", title, lvl)
+	} else if headlineRegexp.MatchString(title) {
+		return Headline{}, fmt.Errorf("could not create headline %q: title must not start with headline stars", title)
+	}
+	line := strings.Repeat("*", lvl) + " " + strings.ReplaceAll(title, "\n", " ")
+	h, ok := New().Silent().Parse(strings.NewReader(line), "").Nodes[0].(Headline)
+	if !ok {
+		h = Headline{Lvl: lvl, Title: []Node{Text{Content: title}}}
+	}
+	line = strings.TrimSuffix(String(h), "\n")
+	lines, offset := 1, len(line)+1
+	for _, child := range children {
+		pos := child.Position()
+		child = shiftNode(child.Copy(), lines-pos.StartLine, offset-pos.StartOffset)
+		h.Children = append(h.Children, child)
+		text := String(child)
+		lines, offset = lines+strings.Count(text, "\n"), offset+len(text)
+	}
+	h.Pos = stringPosition(String(h))
+	return numberHeadlines(h, new(int)).(Headline), nil
+}
+
+// numberHeadlines sets the Index of n and its descendant headlines to their (1-based) index in document order.
+func numberHeadlines(n Node, index *int) Node {
+	if h, ok := n.(Headline); ok {
+		*index++
+		h.Index = *index
+		n = h
+	}
+	return mapChildren(n, func(child Node) Node { return numberHeadlines(child, index) })
+}
+
+// NewParagraphFromString returns a paragraph containing the inline Org mode markup s. See NewHeadline.
+func NewParagraphFromString(s string) Paragraph {
+	d := New().Silent().Parse(strings.NewReader(s), "")
+	if len(d.Nodes) == 1 {
+		if p, ok := d.Nodes[0].(Paragraph); ok {
+			return p
+		}
+	}
+	s = strings.TrimRight(s, "\n") // s contains markup of other elements, e.g. a headline or table
+	return Paragraph{Children: d.parseInline(s), Pos: stringPosition(s)}
+}
+
+// stringPosition returns the position of s as the complete parse input - excluding its final newline.
+func stringPosition(s string) Position {
+	s = strings.TrimSuffix(s, "\n")
+	lastLine := s[strings.LastIndexByte(s, '\n')+1:]
+	return Position{EndLine: strings.Count(s, "\n"), EndColumn: len(lastLine), EndOffset: len(s)}
+}
+
+// Append appends the Org mode string of the node (see String) to the parse input of the document and reparses it (see
+// Reparse), i.e. Nodes, Outline and NamedNodes are updated and the appended node gets the positions of its source.
+// Documents without parse input (e.g. parsed with ParseStream) cannot be reparsed - the parsed node is appended to
+// their Nodes instead.
+//
+// Append returns an error if the node is not well formed - e.g. if a child headline does not have a higher level than
+// its parent - or if it would not be parsed as a top level node of the document, e.g. a paragraph appended after
+// the last headline would become part of that headline.
+func (d *Document) Append(n Node) error {
+	if err := validateNode(n, 0); err != nil {
+		return fmt.Errorf("could not append node: %w", err)
+	} else if _, isHeadline := n.(Headline); !isHeadline && len(d.Outline.Children) != 0 {
+		last := d.Outline.Children[len(d.Outline.Children)-1].Headline
+		return fmt.Errorf("could not append node: %T would become part of headline %q", n, String(last.Title...))
+	}
+	text := String(n)
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	hasInput := d.input != "" || len(d.Nodes) == 0
+	if lines := len(d.source); lines != 0 {
+		if hasInput && !strings.HasSuffix(d.input, "\n") {
+			text = "\n" + text
+		}
+		if _, isHeadline := n.(Headline); !isHeadline && strings.TrimSpace(d.source[lines-1]) != "" {
+			text = "\n" + text // keep the node from continuing the last node of the document
+		}
+	}
+	if !hasInput {
+		return d.appendParsed(text)
+	}
+	fatalError := d.FatalError
+	if d.Reparse(TextEdit{StartLine: len(d.source), EndLine: len(d.source), NewText: text}); d.FatalError != fatalError {
+		return fmt.Errorf("could not append node: %w", d.FatalError)
+	}
+	return nil
+}
+
+// appendParsed parses text with the buffer settings of the document and appends the resulting nodes to Nodes, Outline
+// and NamedNodes. Their positions follow the last source line of the document.
+func (d *Document) appendParsed(text string) (err error) {
+	p := d.Configuration.newDocument(d.Path)
+	p.sourceLines = map[Position][2]int{}
+	maps.Copy(p.BufferSettings, d.BufferSettings)
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			abort, ok := recovered.(abortParse)
+			if !ok {
+				panic(recovered)
+			}
+			err = fmt.Errorf("could not append node: %w", abort.err)
+		}
+	}()
+	p.parsing = true
+	p.tokenize(strings.NewReader(text))
+	_, nodes, _ := p.parseTopLevel(len(p.tokens))
+	lines, bytes := 0, 0
+	if last := len(d.source) - 1; last >= 0 {
+		lines, bytes = d.firstLine+last+1, d.lineOffsets[last]+len(d.source[last])+1
+	} else if pos := d.Nodes[len(d.Nodes)-1].Position(); pos != (Position{}) {
+		lines, bytes = pos.EndLine+1, pos.EndOffset+1
+	}
+	for _, n := range shiftNodes(nodes, lines, bytes) {
+		d.Nodes = append(d.Nodes, d.addToOutline(n))
+	}
+	maps.Copy(d.NamedNodes, p.NamedNodes)
+	d.Errors = append(d.Errors, p.Errors...)
+	return nil
+}
+
+// validateNode returns an error if n or one of its descendants is a headline that does not have a higher level than
+// its closest ancestor headline of level lvl.
+func validateNode(n Node, lvl int) (err error) {
+	if h, ok := n.(Headline); ok {
+		if h.Lvl <= lvl {
+			return fmt.Errorf("headline %q of level %d must have a level greater than %d", String(h.Title...), h.Lvl, lvl)
+		}
+		lvl = h.Lvl
+	}
+	n.Range(func(child Node) bool {
+		err = validateNode(child, lvl)
+		return err == nil
+	})
+	return err
+}
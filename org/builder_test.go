@@ -0,0 +1,84 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	if h := newHeadline(t, 1, "TODO [#A] Project :work:"); h.Status != "TODO" || h.Priority != "A" || len(h.Tags) != 1 || String(h.Title...) != "Project" {
+		t.Errorf("bad headline %#v", h)
+	}
+	h := newHeadline(t, 1, "Project",
+		NewParagraphFromString("Some *bold* text\nover two lines"),
+		newHeadline(t, 2, "Task", NewParagraphFromString("Details")))
+	expected := "* Project\nSome *bold* text\nover two lines\n** Task\nDetails\n"
+	if actual := String(h); actual != expected {
+		t.Errorf("got:\n%s", diff(actual, expected))
+	}
+	fresh := New().Silent().Parse(strings.NewReader(expected), "")
+	if actual, expected := dumpNodes([]Node{h}), dumpNodes(fresh.Nodes); actual != expected {
+		t.Errorf("expected positions to match a fresh parse:\n%s", diff(actual, expected))
+	}
+
+	d := New().Silent().Parse(strings.NewReader("#+TITLE: Built\nIntro"), "./builder.org")
+	if err := d.Append(NewParagraphFromString("More intro")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := d.Append(h); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := d.Append(NewParagraphFromString("Orphan")); err == nil {
+		t.Errorf("expected error appending paragraph after headline")
+	}
+	if err := d.Append(newHeadline(t, 2, "Parent", newHeadline(t, 2, "Sibling"))); err == nil {
+		t.Errorf("expected error for child headline without higher level")
+	}
+	if expected := "#+TITLE: Built\nIntro\n\nMore intro\n" + expected; d.input != expected {
+		t.Errorf("got:\n%s", diff(d.input, expected))
+	}
+	if len(d.Outline.Children) != 1 || len(d.Outline.Children[0].Children) != 1 {
+		t.Errorf("expected outline to be updated")
+	}
+}
+
+func TestNewHeadlineValidation(t *testing.T) {
+	for _, c := range []struct {
+		lvl   int
+		title string
+	}{{0, "Zero"}, {-1, "Negative"}, {1, "* Stars"}, {2, "** Stars"}} {
+		if h, err := NewHeadline(c.lvl, c.title); err == nil {
+			t.Errorf("expected error for level %d and title %q, got %q", c.lvl, c.title, String(h))
+		}
+	}
+	if h := newHeadline(t, 1, "*bold* title"); String(h) != "* *bold* title\n" {
+		t.Errorf("expected emphasis at the start of the title to be allowed, got %q", String(h))
+	}
+}
+
+func TestAppendStream(t *testing.T) {
+	d := New().Silent().ParseStream(strings.NewReader("#+TODO: TODO WAIT | DONE\n* A\ntext\n* B\n"), func(Node) bool { return true })
+	if err := d.Append(newHeadline(t, 1, "WAIT C", NewParagraphFromString("more"))); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(d.Nodes) != 2 || len(d.Outline.Children) != 2 {
+		t.Fatalf("expected appended headline to be added to nodes and outline, got %s", dumpNodes(d.Nodes))
+	}
+	h := d.Nodes[1].(Headline)
+	if h.Status != "WAIT" || h.Index != 3 || h.Pos.StartLine != 4 || h.Pos.StartOffset != len("#+TODO: TODO WAIT | DONE\n* A\ntext\n* B\n") {
+		t.Errorf("bad appended headline %#v", h)
+	}
+	if err := d.Append(NewParagraphFromString("Orphan")); err == nil {
+		t.Errorf("expected error appending paragraph after headline")
+	}
+}
+
+// newHeadline returns the headline of NewHeadline and fails the test on errors.
+func newHeadline(t *testing.T, lvl int, title string, children ...Node) Headline {
+	t.Helper()
+	h, err := NewHeadline(lvl, title, children...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return h
+}
@@ -0,0 +1,169 @@
+// Package capture expands org-capture style templates and files the resulting entries into parsed Org mode documents.
+//
+//	d := org.New().Silent().Parse(input, "./inbox.org")
+//	c := capture.Context{Time: time.Now(), Annotation: "[[https://example.com][Example]]"}
+//	err := capture.Capture(d, capture.Target{Headline: []string{"Inbox"}}, "* TODO %?\n%U\n%a", c)
+//	out, err := d.Write(org.NewOrgWriter())
+package capture
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+// Context provides the values of the %-escapes of a template.
+type Context struct {
+	Time       time.Time // Time is used for the timestamp escapes %U, %u, %T and %t and for date trees.
+	Annotation string    // Annotation is the link inserted for %a, e.g. [[file:notes.org::*Headline][Headline]].
+	Initial    string    // Initial is the content inserted for %i, e.g. the selected text.
+	// Prompt returns the answer for a %^{Prompt} or %^{Prompt|default|option...} escape. If Prompt is nil, the default
+	// (i.e. the first option) - or the empty string - is used.
+	Prompt func(prompt string, options []string) string
+}
+
+// Target is the location entries are filed to by Capture.
+type Target struct {
+	Headline []string // Headline is the outline path of the parent headline, e.g. {"Projects", "Go"}. Empty for the top level.
+	DateTree bool     // DateTree files entries below a year/month/day tree for Context.Time under Headline.
+}
+
+var escapeRegexp = regexp.MustCompile(`%(\^\{([^}]*)\}|[?UuTtai%])`)
+
+// Expand returns the template with all supported %-escapes expanded and the offset of the cursor position %? within
+// the result - -1 if the template has none. The supported escapes are:
+//
+//	%?          the cursor position - removed from the result
+//	%U, %u      an inactive timestamp of Context.Time with and without time, e.g. [2024-01-15 Mon 10:30]
+//	%T, %t      an active timestamp of Context.Time with and without time, e.g. <2024-01-15 Mon>
+//	%a          Context.Annotation
+//	%i          Context.Initial - additional lines are indented like the line containing %i
+//	%^{Prompt}  the answer to Context.Prompt - options and a default are given as %^{Prompt|default|option...}
+//	%%          a literal %
+//
+// Unknown escapes are kept as is.
+func Expand(template string, c Context) (text string, cursor int) {
+	b, cursor, last := &strings.Builder{}, -1, 0
+	for _, m := range escapeRegexp.FindAllStringSubmatchIndex(template, -1) {
+		b.WriteString(template[last:m[0]])
+		last = m[1]
+		switch escape := template[m[2]:m[3]]; {
+		case escape == "?":
+			cursor = b.Len()
+		case escape == "U":
+			b.WriteString("[" + c.Time.Format("2006-01-02 Mon 15:04") + "]")
+		case escape == "u":
+			b.WriteString("[" + c.Time.Format("2006-01-02 Mon") + "]")
+		case escape == "T":
+			b.WriteString("<" + c.Time.Format("2006-01-02 Mon 15:04") + ">")
+		case escape == "t":
+			b.WriteString("<" + c.Time.Format("2006-01-02 Mon") + ">")
+		case escape == "a":
+			b.WriteString(c.Annotation)
+		case escape == "i":
+			line := b.String()[strings.LastIndexByte(b.String(), '\n')+1:]
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			b.WriteString(strings.ReplaceAll(c.Initial, "\n", "\n"+indent))
+		case escape == "%":
+			b.WriteString("%")
+		default:
+			b.WriteString(c.prompt(template[m[4]:m[5]]))
+		}
+	}
+	b.WriteString(template[last:])
+	return b.String(), cursor
+}
+
+func (c Context) prompt(s string) string {
+	parts := strings.Split(s, "|")
+	if c.Prompt != nil {
+		return c.Prompt(parts[0], parts[1:])
+	} else if len(parts) > 1 {
+		return parts[1]
+	}
+	return ""
+}
+
+// Parse expands the template (see Expand) and parses the result using the configuration.
+func Parse(conf *org.Configuration, template string, c Context) *org.Document {
+	text, _ := Expand(template, c)
+	return conf.Parse(strings.NewReader(text), "")
+}
+
+// Capture expands the template (see Expand) and files the resulting headlines at the end of the target in d - adjusting
+// their levels to make them children of the target headline. Like Document.Refile, Capture edits the parse input of d
+// and reparses it. The target headline must exist; missing date tree headlines are created in sorted order.
+func Capture(d *org.Document, t Target, template string, c Context) error {
+	text, _ := Expand(template, c)
+	entry := d.Configuration.Parse(strings.NewReader(text), "")
+	if len(entry.Outline.Children) == 0 {
+		return fmt.Errorf("could not capture: template does not expand to a headline")
+	} else if h := entry.Outline.Children[0].Headline; strings.TrimSpace(text[:h.Pos.StartOffset]) != "" {
+		return fmt.Errorf("could not capture: template has content before its first headline")
+	}
+	path := append([]string{}, t.Headline...)
+	if findSection(d, path) == nil {
+		return fmt.Errorf("could not capture: target headline %q does not exist", strings.Join(path, "/"))
+	}
+	if t.DateTree {
+		for _, title := range []string{c.Time.Format("2006"), c.Time.Format("2006-01 January"), c.Time.Format("2006-01-02 Monday")} {
+			if err := ensureHeadline(d, path, title); err != nil {
+				return err
+			}
+			path = append(path, title)
+		}
+	}
+	for len(entry.Outline.Children) != 0 {
+		if err := entry.Refile(*entry.Outline.Children[0].Headline, d, findSection(d, path).Headline); err != nil {
+			return fmt.Errorf("could not capture: %w", err)
+		}
+	}
+	return nil
+}
+
+// ensureHeadline creates the child headline title of the headline at path unless it exists. New headlines are moved
+// before the first sibling with a greater title.
+func ensureHeadline(d *org.Document, path []string, title string) error {
+	for _, s := range findSection(d, path).Children {
+		if headlineTitle(s) == title {
+			return nil
+		}
+	}
+	h := d.Configuration.Parse(strings.NewReader("* "+title+"\n"), "")
+	if err := h.Refile(*h.Outline.Children[0].Headline, d, findSection(d, path).Headline); err != nil {
+		return fmt.Errorf("could not capture: %w", err)
+	}
+	siblings := findSection(d, path).Children
+	for _, s := range siblings {
+		if headlineTitle(s) > title {
+			return d.MoveBefore(*siblings[len(siblings)-1].Headline, *s.Headline)
+		}
+	}
+	return nil
+}
+
+// findSection returns the section of the headline with the given outline path - the root section for an empty path.
+func findSection(d *org.Document, path []string) *org.Section {
+	s := d.Outline.Section
+	for _, title := range path {
+		var next *org.Section
+		for _, child := range s.Children {
+			if headlineTitle(child) == title {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		s = next
+	}
+	return s
+}
+
+func headlineTitle(s *org.Section) string {
+	return strings.TrimSpace(org.String(s.Headline.Title...))
+}
@@ -0,0 +1,75 @@
+package capture
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+var now = time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+func TestExpand(t *testing.T) {
+	c := Context{
+		Time:       now,
+		Annotation: "[[file:notes.org][Notes]]",
+		Initial:    "first\nsecond",
+		Prompt: func(prompt string, options []string) string {
+			return strings.ToUpper(prompt) + strings.Join(options, ",")
+		},
+	}
+	text, cursor := Expand("* TODO %^{Task} %?\n%U %u %T %t\n  %i\n%a 100%% %x", c)
+	expected := "* TODO TASK \n[2024-01-15 Mon 10:30] [2024-01-15 Mon] <2024-01-15 Mon 10:30> <2024-01-15 Mon>\n  first\n  second\n[[file:notes.org][Notes]] 100% %x"
+	if text != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, text)
+	}
+	if cursor != len("* TODO TASK ") {
+		t.Errorf("expected cursor at %d got %d", len("* TODO TASK "), cursor)
+	}
+	if text, cursor := Expand("%^{Size|medium|large}", Context{}); text != "medium" || cursor != -1 {
+		t.Errorf("expected default answer got %q (%d)", text, cursor)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	input := "* Inbox\n** Old\n* Journal\n** 2024\n*** 2024-03 March\n* Other\n"
+	for _, c := range []struct {
+		name     string
+		target   Target
+		template string
+		expected string
+	}{
+		{"headline", Target{Headline: []string{"Inbox"}}, "* TODO %^{Task|Call}\n%U\n** Subtask",
+			"* Inbox\n** Old\n** TODO Call\n[2024-01-15 Mon 10:30]\n*** Subtask\n* Journal\n** 2024\n*** 2024-03 March\n* Other\n"},
+		{"top level", Target{}, "* Note %?",
+			"* Inbox\n** Old\n* Journal\n** 2024\n*** 2024-03 March\n* Other\n* Note \n"},
+		{"date tree", Target{Headline: []string{"Journal"}, DateTree: true}, "* Entry",
+			"* Inbox\n** Old\n* Journal\n** 2024\n*** 2024-01 January\n**** 2024-01-15 Monday\n***** Entry\n*** 2024-03 March\n* Other\n"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			d := org.New().Silent().Parse(strings.NewReader(input), "./capture.org")
+			if err := Capture(d, c.target, c.template, Context{Time: now}); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			expected := org.New().Silent().Parse(strings.NewReader(c.expected), "./capture.org")
+			actual, _ := d.Write(org.NewOrgWriter())
+			if expected, _ := expected.Write(org.NewOrgWriter()); actual != expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestCaptureErrors(t *testing.T) {
+	d := org.New().Silent().Parse(strings.NewReader("* Inbox\n"), "./capture.org")
+	if err := Capture(d, Target{Headline: []string{"Missing"}}, "* Entry", Context{}); err == nil {
+		t.Errorf("expected error for missing target")
+	}
+	if err := Capture(d, Target{}, "Some text\n* Entry", Context{}); err == nil {
+		t.Errorf("expected error for content before the first headline")
+	}
+	if err := Capture(d, Target{}, "- item", Context{}); err == nil {
+		t.Errorf("expected error for template without headline")
+	}
+}
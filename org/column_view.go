@@ -0,0 +1,276 @@
+package org
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ColumnDefinition is a column of a column view format like `%25ITEM(Task) %Effort{:}` (see #+COLUMNS).
+type ColumnDefinition struct {
+	Property string // Property is the (special) property displayed in the column, e.g. ITEM, TODO or Effort.
+	Title    string // Title is the title of the column. Defaults to Property.
+	Width    int    // Width is the display width of the column in characters - 0 if it was not specified.
+	Summary  string // Summary is the summary operator used to compute the values of parents from their children, e.g. + or X/.
+}
+
+// defaultColumns is the column view format used if no #+COLUMNS are defined - see org-columns-default-format.
+const defaultColumns = "%25ITEM %TODO %3PRIORITY %TAGS"
+
+var columnDefinitionRegexp = regexp.MustCompile(`^%(\d*)([^\s({]+)(?:\(([^)]*)\))?(?:\{([^}]*)\})?$`)
+
+// columnSummaries are the supported summary operators.
+var columnSummaries = map[string]bool{"+": true, ":": true, "X": true, "X/": true, "X%": true, "min": true, "max": true, "mean": true}
+
+// ParseColumnDefinitions parses a column view format like `%25ITEM %TODO %5Effort(Time){:}`.
+// Supported summary operators are {+} (sum), {:} (sum of durations, see ParseDuration - plain numbers are minutes like
+// in Org mode), {X} (all checkboxes checked), {X/} (number of checked checkboxes), {X%} (percentage of checked
+// checkboxes), {min}, {max} and {mean}.
+func ParseColumnDefinitions(format string) ([]ColumnDefinition, error) {
+	columns := []ColumnDefinition{}
+	for _, field := range strings.Fields(format) {
+		m := columnDefinitionRegexp.FindStringSubmatch(field)
+		if m == nil {
+			return nil, fmt.Errorf("invalid column definition %q", field)
+		} else if m[4] != "" && !columnSummaries[m[4]] {
+			return nil, fmt.Errorf("invalid column definition %q: unknown summary operator {%s}", field, m[4])
+		}
+		column := ColumnDefinition{Property: m[2], Title: m[3], Summary: m[4]}
+		if column.Title == "" {
+			column.Title = column.Property
+		}
+		column.Width, _ = strconv.Atoi(m[1])
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// Columns returns the column view format of the document, i.e. its #+COLUMNS or the default format of Org mode.
+func (d *Document) Columns() ([]ColumnDefinition, error) {
+	if format := d.Get("COLUMNS"); format != "" {
+		return ParseColumnDefinitions(format)
+	}
+	return ParseColumnDefinitions(defaultColumns)
+}
+
+// ColumnView returns the column view of the headlines of the document as a table - like the columnview dynamic block.
+// The table contains a header row with the column titles and a row for each headline in document order. If h is not
+// nil, only h and its descendants are included. Values of headlines whose children have values for a column with a
+// summary operator are replaced by the summary of the values of their children. Checkbox summaries count the
+// checkboxes of all descendants, i.e. {X/} of a parent whose children are [1/2] and [X] is [2/3].
+func (d *Document) ColumnView(columns []ColumnDefinition, h *Headline) Table {
+	header := []string{}
+	for _, column := range columns {
+		header = append(header, column.Title)
+	}
+	rows := [][]string{header, nil}
+	var collect func(h Headline) []columnValue
+	collect = func(h Headline) []columnValue {
+		i := len(rows)
+		rows = append(rows, nil)
+		children := [][]columnValue{}
+		for _, n := range h.Children {
+			if child, ok := n.(Headline); ok {
+				children = append(children, collect(child))
+			}
+		}
+		values, row := make([]columnValue, len(columns)), make([]string, len(columns))
+		for j, column := range columns {
+			values[j] = newColumnValue(h.propertyValue(d, strings.ToUpper(column.Property)))
+			if column.Summary != "" {
+				childValues := []columnValue{}
+				for _, child := range children {
+					if child[j].value != "" {
+						childValues = append(childValues, child[j])
+					}
+				}
+				if len(childValues) != 0 {
+					values[j] = summarizeColumn(column.Summary, childValues)
+				}
+			}
+			row[j] = values[j].value
+		}
+		rows[i] = row
+		return values
+	}
+	if h != nil {
+		collect(*h)
+	} else {
+		for _, n := range d.Nodes {
+			if h, ok := n.(Headline); ok {
+				collect(h)
+			}
+		}
+	}
+	return d.newTable(rows)
+}
+
+// UpdateColumnViews returns a copy of the document whose columnview dynamic blocks (#+BEGIN: columnview ... #+END:)
+// contain the current column view of the document - see ColumnView. The :id parameter of a block selects the
+// headlines of the view: local (the default) for the subtree containing the block, global for the whole document
+// and otherwise the headline with that CUSTOM_ID or ID. The :format parameter overrides the #+COLUMNS of the
+// document. Blocks with unknown ids or invalid formats are kept as they are.
+func (d *Document) UpdateColumnViews() *Document {
+	var update func(nodes []Node, parent *Headline) []Node
+	update = func(nodes []Node, parent *Headline) []Node {
+		updated := make([]Node, 0, len(nodes))
+		for i := 0; i < len(nodes); i++ {
+			switch n := nodes[i].(type) {
+			case Headline:
+				original := n
+				n.Children = update(n.Children, &original)
+				updated = append(updated, n)
+			case Keyword:
+				end := slices.IndexFunc(nodes[i+1:], func(n Node) bool { k, ok := n.(Keyword); return ok && k.Key == "END" })
+				table, ok := d.columnViewBlock(n, parent)
+				if n.Key != "BEGIN" || end == -1 || !ok {
+					updated = append(updated, n)
+					continue
+				}
+				i += end + 1
+				updated = append(updated, n, table, nodes[i])
+			default:
+				updated = append(updated, n)
+			}
+		}
+		return updated
+	}
+	updated := *d
+	updated.Nodes = update(d.Nodes, nil)
+	updated.Outline, updated.index = outlineOf(&updated, updated.Nodes), &outlineIndexCache{}
+	return &updated
+}
+
+var dynamicBlockParameterRegexp = regexp.MustCompile(`:(\w+)\s+("[^"]*"|\S+)`)
+
+// columnViewBlock returns the column view for the #+BEGIN keyword k of a columnview dynamic block inside the
+// subtree of parent (nil for blocks before the first headline).
+func (d *Document) columnViewBlock(k Keyword, parent *Headline) (Table, bool) {
+	name, parameters, _ := strings.Cut(k.Value, " ")
+	if !strings.EqualFold(name, "columnview") {
+		return Table{}, false
+	}
+	h, format := parent, d.Get("COLUMNS")
+	for _, m := range dynamicBlockParameterRegexp.FindAllStringSubmatch(parameters, -1) {
+		switch value := strings.Trim(m[2], `"`); m[1] {
+		case "id":
+			if value == "global" {
+				h = nil
+			} else if value != "local" {
+				if h = d.HeadlineByID(value); h == nil {
+					return Table{}, false
+				}
+			}
+		case "format":
+			format = value
+		}
+	}
+	if format == "" {
+		format = defaultColumns
+	}
+	columns, err := ParseColumnDefinitions(format)
+	if err != nil {
+		return Table{}, false
+	}
+	return d.ColumnView(columns, h), true
+}
+
+// columnValue is the value of a headline in a column view column. checked and total are the number of (checked)
+// checkboxes the value stands for - e.g. 1 and 1 for [X] and 2 and 3 for the summary [2/3].
+type columnValue struct {
+	value          string
+	checked, total int
+}
+
+var checkboxCountRegexp = regexp.MustCompile(`^\[(\d+)/(\d+)\]$`)
+
+func newColumnValue(value string) columnValue {
+	v := columnValue{value: value}
+	if value == "[X]" {
+		v.checked, v.total = 1, 1
+	} else if value == "[ ]" || value == "[-]" {
+		v.total = 1
+	} else if m := checkboxCountRegexp.FindStringSubmatch(value); m != nil {
+		v.checked, _ = strconv.Atoi(m[1])
+		v.total, _ = strconv.Atoi(m[2])
+	}
+	return v
+}
+
+// summarizeColumn returns the summary of values for the given summary operator.
+func summarizeColumn(summary string, values []columnValue) columnValue {
+	switch summary {
+	case "X", "X/", "X%":
+		v := columnValue{}
+		for _, child := range values {
+			v.checked, v.total = v.checked+child.checked, v.total+child.total
+		}
+		switch {
+		case summary == "X/":
+			v.value = fmt.Sprintf("[%d/%d]", v.checked, v.total)
+		case summary == "X%":
+			v.value = fmt.Sprintf("[%d%%]", int(math.Round(100*float64(v.checked)/float64(max(v.total, 1)))))
+		case v.checked == v.total:
+			v.value = "[X]"
+		default:
+			v.value = "[ ]"
+		}
+		return v
+	case ":":
+		total := Duration(0)
+		for _, v := range values {
+			if duration, err := ParseDuration(v.value); err == nil {
+				total += duration
+			}
+		}
+		return columnValue{value: total.String()}
+	}
+	numbers := []float64{}
+	for _, v := range values {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v.value), 64); err == nil {
+			numbers = append(numbers, f)
+		}
+	}
+	if len(numbers) == 0 {
+		return columnValue{}
+	}
+	result := numbers[0]
+	for _, f := range numbers[1:] {
+		switch summary {
+		case "min":
+			result = math.Min(result, f)
+		case "max":
+			result = math.Max(result, f)
+		default:
+			result += f
+		}
+	}
+	if summary == "mean" {
+		result /= float64(len(numbers))
+	}
+	return columnValue{value: strconv.FormatFloat(result, 'f', -1, 64)}
+}
+
+// newTable returns a table containing the given cells. nil rows are separators.
+func (d *Document) newTable(rawRows [][]string) Table {
+	table := Table{ColumnInfos: getColumnInfos(rawRows, stringWidth)}
+	for i, rawColumns := range rawRows {
+		row := Row{IsSpecial: isSpecialRow(rawColumns)}
+		if rawColumns == nil {
+			table.SeparatorIndices = append(table.SeparatorIndices, i)
+		} else {
+			for j := range table.ColumnInfos {
+				column := Column{ColumnInfo: &table.ColumnInfos[j]}
+				if j < len(rawColumns) {
+					column.Children = d.parseInline(rawColumns[j])
+				}
+				row.Columns = append(row.Columns, column)
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
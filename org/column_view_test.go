@@ -0,0 +1,151 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseColumnDefinitions(t *testing.T) {
+	columns, err := ParseColumnDefinitions("%25ITEM(Task) %TODO %5Effort{:} %Done{X/}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []ColumnDefinition{
+		{Property: "ITEM", Title: "Task", Width: 25},
+		{Property: "TODO", Title: "TODO"},
+		{Property: "Effort", Title: "Effort", Width: 5, Summary: ":"},
+		{Property: "Done", Title: "Done", Summary: "X/"},
+	}
+	if len(columns) != len(expected) {
+		t.Fatalf("expected %d columns got %#v", len(expected), columns)
+	}
+	for i := range expected {
+		if columns[i] != expected[i] {
+			t.Errorf("column %d: expected %#v got %#v", i, expected[i], columns[i])
+		}
+	}
+	for _, format := range []string{"ITEM", "%Effort{?}"} {
+		if _, err := ParseColumnDefinitions(format); err == nil {
+			t.Errorf("expected error for %q", format)
+		}
+	}
+}
+
+func TestColumnView(t *testing.T) {
+	input := `#+COLUMNS: %ITEM %TODO %Effort{:} %Cost{+} %Done{X/}
+* Project
+** TODO Design
+:PROPERTIES:
+:Effort: 1:30
+:Cost: 10
+:Done: [X]
+:END:
+** Build
+*** TODO Backend
+:PROPERTIES:
+:Effort: 2:00
+:Cost: 5.5
+:Done: [ ]
+:END:
+*** Frontend
+:PROPERTIES:
+:Effort: 0:45
+:END:
+*** DONE Deploy
+:PROPERTIES:
+:Effort: 15
+:Done: [X]
+:END:
+`
+	d := New().Silent().Parse(strings.NewReader(input), "./columns.org")
+	columns, err := d.Columns()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := `| ITEM     | TODO | Effort | Cost | Done  |
+|----------+------+--------+------+-------|
+| Project  |      | 4:30   | 15.5 | [2/3] |
+| Design   | TODO | 1:30   |   10 | [X]   |
+| Build    |      | 3:00   |  5.5 | [1/2] |
+| Backend  | TODO | 2:00   |  5.5 | [ ]   |
+| Frontend |      | 0:45   |      |       |
+| Deploy   | DONE | 15     |      | [X]   |
+`
+	if actual := String(d.ColumnView(columns, nil)); actual != expected {
+		t.Errorf("got:\n%s", diff(actual, expected))
+	}
+	build := findHeadline(t, d, "Build")
+	if actual := String(d.ColumnView(columns, &build)); !strings.Contains(actual, "| Build ") || strings.Contains(actual, "Project") {
+		t.Errorf("expected column view of subtree got:\n%s", actual)
+	}
+}
+
+func TestUpdateColumnViews(t *testing.T) {
+	input := `#+COLUMNS: %ITEM %Done{X/}
+#+BEGIN: columnview :id global
+| outdated |
+#+END:
+* Project
+#+BEGIN: columnview :format "%ITEM %Effort{:}"
+#+END:
+** Design
+:PROPERTIES:
+:EFFORT: 1:30
+:DONE: [X]
+:END:
+** Build
+:PROPERTIES:
+:CUSTOM_ID: build
+:EFFORT: 2:00
+:DONE: [ ]
+:END:
+#+BEGIN: columnview :id build
+#+END:
+#+BEGIN: columnview :id missing
+| kept |
+#+END:
+`
+	expected := `#+COLUMNS: %ITEM %Done{X/}
+#+BEGIN: columnview :id global
+| ITEM    | Done  |
+|---------+-------|
+| Project | [1/2] |
+| Design  | [X]   |
+| Build   | [ ]   |
+#+END:
+* Project
+#+BEGIN: columnview :format "%ITEM %Effort{:}"
+| ITEM    | Effort |
+|---------+--------|
+| Project | 3:30   |
+| Design  | 1:30   |
+| Build   | 2:00   |
+#+END:
+** Design
+:PROPERTIES:
+:EFFORT: 1:30
+:DONE: [X]
+:END:
+** Build
+:PROPERTIES:
+:CUSTOM_ID: build
+:EFFORT: 2:00
+:DONE: [ ]
+:END:
+#+BEGIN: columnview :id build
+| ITEM  | Done |
+|-------+------|
+| Build | [ ]  |
+#+END:
+#+BEGIN: columnview :id missing
+| kept |
+#+END:
+`
+	d := New().Silent().Parse(strings.NewReader(input), "./columns.org")
+	if actual, err := d.UpdateColumnViews().Write(NewOrgWriter()); err != nil || actual != expected {
+		t.Errorf("got %v:\n%s", err, diff(actual, expected))
+	}
+	if actual, _ := d.Write(NewOrgWriter()); actual != input {
+		t.Errorf("expected document to be unchanged got:\n%s", diff(actual, input))
+	}
+}
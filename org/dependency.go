@@ -0,0 +1,78 @@
+package org
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DependencyKind identifies how a file is pulled into a document.
+type DependencyKind int
+
+const (
+	IncludeDependency DependencyKind = iota
+	SetupFileDependency
+	ImageDependency
+)
+
+func (k DependencyKind) String() string {
+	switch k {
+	case IncludeDependency:
+		return "include"
+	case SetupFileDependency:
+		return "setupfile"
+	case ImageDependency:
+		return "image"
+	default:
+		return "unknown"
+	}
+}
+
+// Dependency is an edge of the dependency graph of a document: the file From depends on the file Path.
+type Dependency struct {
+	Kind DependencyKind
+	From string   // From is the path of the file containing the reference.
+	Path string   // Path is the path of the referenced file - relative paths are resolved against From.
+	Pos  Position // Pos is the position of the reference in From.
+}
+
+// Dependencies returns all files the document depends on, i.e. files pulled in via #+INCLUDE and #+SETUPFILE
+// (including the dependencies of setup files) and linked local images.
+// Build systems can use it to rebuild a document when any of its inputs change.
+func (d *Document) Dependencies() []Dependency {
+	dependencies := append([]Dependency{}, d.dependencies...)
+	walkNodes(d.Nodes, func(n Node) {
+		l, ok := n.(RegularLink)
+		if !ok || l.Kind() != "image" || (l.Protocol != "" && l.Protocol != "file") {
+			return
+		}
+		path := strings.TrimPrefix(l.URL, "file:")
+		if strings.Contains(path, "://") {
+			return
+		}
+		dependencies = append(dependencies, Dependency{ImageDependency, d.Path, d.resolvePath(path), l.Pos})
+	})
+	return dependencies
+}
+
+// DependencyPaths returns the unique paths of all files returned by Dependencies.
+func (d *Document) DependencyPaths() []string {
+	paths, seen := []string{}, map[string]bool{}
+	for _, dependency := range d.Dependencies() {
+		if !seen[dependency.Path] {
+			seen[dependency.Path] = true
+			paths = append(paths, dependency.Path)
+		}
+	}
+	return paths
+}
+
+func (d *Document) addDependency(kind DependencyKind, path string, pos Position) {
+	d.dependencies = append(d.dependencies, Dependency{kind, d.Path, path, pos})
+}
+
+func (d *Document) resolvePath(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(d.Path), path)
+	}
+	return path
+}
@@ -0,0 +1,43 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDependencies(t *testing.T) {
+	fsys := fstest.MapFS{
+		"notes/setup.org":        {Data: []byte("#+SETUPFILE: ../shared/macros.org\n")},
+		"shared/macros.org":      {Data: []byte("#+MACRO: m x\n")},
+		"notes/chapters/one.org": {Data: []byte("chapter one\n")},
+	}
+	input := strings.Join([]string{
+		"#+SETUPFILE: setup.org",
+		"#+INCLUDE: \"chapters/one.org\" src org",
+		"[[./images/a.png]] [[file:/abs/b.png]] [[https://example.com/c.png]] [[./images/a.png]] [[./doc.pdf]]",
+	}, "\n")
+	c := New().Silent()
+	c.FS = fsys
+	d := c.Parse(strings.NewReader(input), "notes/index.org")
+	actual := []string{}
+	for _, dependency := range d.Dependencies() {
+		actual = append(actual, fmt.Sprintf("%s %s -> %s (%d)", dependency.Kind, dependency.From, dependency.Path, dependency.Pos.StartLine))
+	}
+	expected := []string{
+		"setupfile notes/index.org -> notes/setup.org (0)",
+		"setupfile notes/setup.org -> shared/macros.org (0)",
+		"include notes/index.org -> notes/chapters/one.org (1)",
+		"image notes/index.org -> notes/images/a.png (2)",
+		"image notes/index.org -> /abs/b.png (2)",
+		"image notes/index.org -> notes/images/a.png (2)",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got dependencies\n%s\nexpected\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+	paths := strings.Join(d.DependencyPaths(), " ")
+	if expected := "notes/setup.org shared/macros.org notes/chapters/one.org notes/images/a.png /abs/b.png"; paths != expected {
+		t.Errorf("got dependency paths %q, expected %q", paths, expected)
+	}
+}
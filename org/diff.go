@@ -0,0 +1,175 @@
+package org
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	ChangeInserted ChangeKind = iota // ChangeInserted is used for nodes that only exist in the new document.
+	ChangeRemoved                    // ChangeRemoved is used for nodes that only exist in the old document.
+	ChangeModified                   // ChangeModified is used for nodes whose own content (excluding their children) changed.
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeInserted:
+		return "inserted"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is a structural difference between two documents - see Diff.
+// Paths contain the index of the node and each of its ancestors among the children of their parent, starting at
+// the top level nodes of the document. Children are ordered like in Walk, e.g. the properties of a headline come
+// before its title and its title before its content.
+type Change struct {
+	Kind    ChangeKind
+	Old     Node  // Old is the node in the old document - nil for inserted nodes.
+	New     Node  // New is the node in the new document - nil for removed nodes.
+	OldPath []int // OldPath is the path of Old in the old document - or of the position New was inserted at.
+	NewPath []int // NewPath is the path of New in the new document - or of the position Old was removed from.
+}
+
+// String returns a human readable description of the change, e.g. "modified Text at 3:2-3:8".
+func (c Change) String() string {
+	n, pos := c.New, Position{}
+	if c.Kind == ChangeRemoved {
+		n = c.Old
+	}
+	if n != nil {
+		pos = n.Position()
+	}
+	return fmt.Sprintf("%s %s at %d:%d-%d:%d", c.Kind, strings.TrimPrefix(fmt.Sprintf("%T", n), "org."),
+		pos.StartLine, pos.StartColumn, pos.EndLine, pos.EndColumn)
+}
+
+// Diff returns the structural differences between the nodes of the documents a (old) and b (new) in document order.
+// Unchanged nodes are matched by type and content (see String) - a longest common subsequence of matching nodes is
+// kept and the remaining nodes are paired up by type. Paired nodes are compared recursively, i.e. a changed word in
+// a paragraph results in a single modified Text node rather than a removed and an inserted paragraph.
+func Diff(a, b *Document) []Change {
+	return diffNodes(diffTrees(a.Nodes), diffTrees(b.Nodes), nil, nil)
+}
+
+// diffTree is a node with its children and the keys used to match and compare it - they are computed once,
+// bottom-up, for the whole document. See diffTrees.
+type diffTree struct {
+	node     Node
+	own      string            // own is the content of the node without its children - see withoutChildren.
+	key      [sha256.Size]byte // key is the hash of the type, own content and children of the node.
+	children []*diffTree
+}
+
+// diffTrees returns the diffTrees of the nodes.
+func diffTrees(nodes []Node) []*diffTree {
+	trees := make([]*diffTree, len(nodes))
+	for i, n := range nodes {
+		t := &diffTree{node: n, own: String(withoutChildren(n)), children: diffTrees(childNodes(n))}
+		h := sha256.New()
+		fmt.Fprintf(h, "%T\n%d\n%s", n, len(t.own), t.own)
+		for _, child := range t.children {
+			h.Write(child.key[:])
+		}
+		h.Sum(t.key[:0])
+		trees[i] = t
+	}
+	return trees
+}
+
+func diffNodes(as, bs []*diffTree, aPath, bPath []int) []Change {
+	// lcs[i][j] is the length of the longest common subsequence of the keys of as[i:] and bs[j:]
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			if as[i].key == bs[j].key {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	changes, i, j, gapI, gapJ := []Change{}, 0, 0, 0, 0
+	for i < len(as) || j < len(bs) {
+		if i < len(as) && j < len(bs) && as[i].key == bs[j].key {
+			changes = append(changes, diffGap(as, bs, gapI, i, gapJ, j, aPath, bPath)...)
+			i, j = i+1, j+1
+			gapI, gapJ = i, j
+		} else if j == len(bs) || (i < len(as) && lcs[i+1][j] >= lcs[i][j+1]) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return append(changes, diffGap(as, bs, gapI, i, gapJ, j, aPath, bPath)...)
+}
+
+// diffGap returns the changes between as[aStart:aEnd] and bs[bStart:bEnd], i.e. nodes without a common node.
+func diffGap(as, bs []*diffTree, aStart, aEnd, bStart, bEnd int, aPath, bPath []int) []Change {
+	changes, j := []Change{}, bStart
+	for i := aStart; i < aEnd; i++ {
+		k := j
+		for k < bEnd && reflect.TypeOf(bs[k].node) != reflect.TypeOf(as[i].node) {
+			k++
+		}
+		if k == bEnd {
+			changes = append(changes, Change{ChangeRemoved, as[i].node, nil, appendPath(aPath, i), appendPath(bPath, j)})
+			continue
+		}
+		for ; j < k; j++ {
+			changes = append(changes, Change{ChangeInserted, nil, bs[j].node, appendPath(aPath, i), appendPath(bPath, j)})
+		}
+		changes = append(changes, diffNode(as[i], bs[j], appendPath(aPath, i), appendPath(bPath, j))...)
+		j++
+	}
+	for ; j < bEnd; j++ {
+		changes = append(changes, Change{ChangeInserted, nil, bs[j].node, appendPath(aPath, aEnd), appendPath(bPath, j)})
+	}
+	return changes
+}
+
+// diffNode returns the changes between two nodes of the same type.
+func diffNode(a, b *diffTree, aPath, bPath []int) []Change {
+	changes := []Change{}
+	if a.own != b.own {
+		changes = append(changes, Change{ChangeModified, a.node, b.node, aPath, bPath})
+	}
+	return append(changes, diffNodes(a.children, b.children, aPath, bPath)...)
+}
+
+// childNodes returns the children of n in the order they are visited by mapChildren.
+func childNodes(n Node) []Node {
+	nodes := []Node{}
+	mapChildren(n, func(child Node) Node {
+		nodes = append(nodes, child)
+		return child
+	})
+	return nodes
+}
+
+// withoutChildren returns a copy of n with all children (see mapChildren) removed. Removing all children does not
+// modify the child slices of n - only tables and captions are removed in place and must be copied first.
+func withoutChildren(n Node) Node {
+	switch n.(type) {
+	case Table, NodeWithMeta:
+		n = n.Copy()
+	}
+	return mapChildren(n, func(Node) Node { return nil })
+}
+
+func appendPath(path []int, i int) []int {
+	return append(append([]int(nil), path...), i)
+}
@@ -0,0 +1,51 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	parse := func(input string) *Document { return New().Silent().Parse(strings.NewReader(input), "./diff.org") }
+	a := parse("* TODO Headline\nSome *bold* text\n\n- a\n- b\n* Second\n| x | y |\n* Removed\n")
+	b := parse("* DONE Headline\nSome *bold* words\n\n- a\n- c\n- b\n* Second\n| x | z |\n* Inserted\n")
+	actual := []string{}
+	for _, c := range Diff(a, b) {
+		actual = append(actual, fmt.Sprintf("%s %v %v", c, c.OldPath, c.NewPath))
+	}
+	expected := []string{
+		"modified Headline at 0:0-5:3 [0] [0]",
+		"modified Text at 1:11-1:17 [0 1 2] [0 1 2]",
+		"inserted ListItem at 4:0-4:3 [0 3 1] [0 3 1]",
+		"modified Text at 0:0-0:0 [1 1 1] [1 1 1]",
+		"modified Text at 8:2-8:10 [2 0] [2 0]",
+	}
+	if actual, expected := strings.Join(actual, "\n"), strings.Join(expected, "\n"); actual != expected {
+		t.Errorf("got:\n%s", diff(actual, expected))
+	}
+	if changes := Diff(b, a); len(changes) != 5 || changes[2].Kind != ChangeRemoved || String(changes[2].Old) != "- c\n" {
+		t.Errorf("expected reversed diff to remove the list item, got %v", changes)
+	}
+	for _, path := range orgTestFiles() {
+		d := parse(fileString(t, path))
+		before := String(d.Nodes...)
+		if changes := Diff(d, parse(fileString(t, path))); len(changes) != 0 {
+			t.Errorf("%s: expected no changes, got %v", path, changes)
+		} else if after := String(d.Nodes...); after != before {
+			t.Errorf("%s: expected the document to be unchanged by Diff:\n%s", path, diff(after, before))
+		}
+	}
+}
+
+func BenchmarkDiffNested(b *testing.B) {
+	input := &strings.Builder{}
+	for i := range 200 {
+		input.WriteString(strings.Repeat("  ", i) + "- item\n")
+	}
+	parse := func(input string) *Document { return New().Silent().Parse(strings.NewReader(input), "./diff.org") }
+	old, changed := parse(input.String()), parse(input.String()+"changed\n")
+	for b.Loop() {
+		Diff(old, changed)
+	}
+}
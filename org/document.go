@@ -15,29 +15,86 @@ package org
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
+	"log/slog"
+	"math"
 	"os"
+	"reflect"
+	"runtime"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Position represents the location of a node in the source text.
+// Lines and columns are 0-based. StartOffset and EndOffset are the corresponding byte offsets into the parse input.
 type Position struct {
 	StartLine   int
 	StartColumn int
 	EndLine     int
 	EndColumn   int
+	StartOffset int
+	EndOffset   int
 }
 
 type Configuration struct {
 	MaxEmphasisNewLines int                                   // Maximum number of newlines inside an emphasis. See org-emphasis-regexp-components newline.
 	AutoLink            bool                                  // Try to convert text passages that look like hyperlinks into hyperlinks.
 	DefaultSettings     map[string]string                     // Default values for settings that are overriden by setting the same key in BufferSettings.
-	Log                 *log.Logger                           // Log is used to print warnings during parsing.
+	Log                 *log.Logger                           // Log is used to print warnings during parsing and writing if StructuredLog is nil.
+	StructuredLog       *slog.Logger                          // StructuredLog receives warnings as records with a level, a "path" and - for warnings about nodes - 0-based "line" and "column" attributes.
 	ReadFile            func(filename string) ([]byte, error) // ReadFile is used to read e.g. #+INCLUDE files.
+	FS                  fs.FS                                 // FS is used instead of ReadFile if set, e.g. an embed.FS or a zip.Reader. Paths are made relative to its root.
 	ResolveLink         func(protocol string, description []Node, link string) Node
+	ResolveWikiLink     func(page string) (target string, exists bool)
+	ExcludeTags         []string                        // Headlines tagged with any of ExcludeTags are not exported. Overridden by #+EXPORT_EXCLUDE_TAGS / #+EXCLUDE_TAGS. Defaults to the EXCLUDE_TAGS of DefaultSettings (noexport).
+	SelectTags          []string                        // If any headline is tagged with one of SelectTags, only those subtrees (and their ancestors) are exported. Overridden by #+EXPORT_SELECT_TAGS / #+SELECT_TAGS. Defaults to the SELECT_TAGS of DefaultSettings (export).
+	ParagraphBreakMode  ParagraphBreakMode              // ParagraphBreakMode controls whether line breaks inside of paragraphs are preserved on export. See the \n export option.
+	FailOnSeverity      Severity                        // Document.Write fails if the document contains errors of at least this severity. Defaults to SeverityFatal.
+	Strict              bool                            // Strict aborts parsing on the first error (i.e. not on warnings) - it becomes the FatalError of the document and keeps its severity.
+	MaxErrors           int                             // MaxErrors aborts parsing once the document contains MaxErrors errors. Defaults to 0, i.e. no limit.
+	MaxLineLength       int                             // MaxLineLength aborts parsing on lines longer than MaxLineLength bytes. Defaults to 0, i.e. no limit.
+	TabWidth            int                             // TabWidth is the number of columns tabs in indentation advance to (the next multiple of). Defaults to 8.
+	MaxIncludeDepth     int                             // MaxIncludeDepth limits the nesting of files parsed during parsing (#+SETUPFILE). 0 means no limit - cycles are always detected.
+	LazyInline          bool                            // LazyInline defers parsing the content of paragraphs until it is accessed via Paragraph.InlineNodes or written. See Document.ParseInline.
+	ColumnMode          ColumnMode                      // ColumnMode determines the unit of the columns of positions. Defaults to ColumnBytes.
+	ExportFilters       map[reflect.Type][]ExportFilter // ExportFilters rewrite nodes of a type just before they are written. See AddExportFilter.
+	InlineParsers       map[byte][]InlineParser         // InlineParsers parse custom inline markup, keyed by trigger byte. See AddInlineParser.
+	KeywordHandlers     map[string]KeywordHandler       // KeywordHandlers turn keywords into custom nodes, keyed by (prefix of the) key. See AddKeywordHandler.
+	BlockHandlers       map[string]BlockHandler         // BlockHandlers turn blocks into custom nodes, keyed by block name. See AddBlockHandler.
+	EmojiShortcodes     map[string]string               // EmojiShortcodes maps the shortcodes of Emoji (e.g. "smile" for :smile:) to their unicode characters. Defaults to nil, i.e. no emoji.
+	ParseHashtags       bool                            // ParseHashtags parses #tags in inline text into Hashtag nodes - see Document.Hashtags.
+	ParseMentions       bool                            // ParseMentions parses @persons in inline text into Mention nodes - see Document.Mentions.
+	Hooks               Hooks                           // Hooks report the progress and durations of parsing and writing. Defaults to no hooks.
+	CompatibilityLevel  string                          // CompatibilityLevel selects strict Emacs parity for edge cases, see CompatibilityEmacs96. Defaults to "", i.e. go-org's relaxed rules.
+	ListEndBlankLines   int                             // ListEndBlankLines is the number of consecutive blank lines that end all (nested) lists. Defaults to 2 like Org mode, 1 is like org-list-empty-line-terminates-plain-lists and 0 disables ending lists by blank lines.
+	MaxEmphasisDepth    int                             // MaxEmphasisDepth limits the nesting of emphasis (e.g. *bold /italic/*) - deeper markers are kept as text. Defaults to 0, i.e. no limit.
+	EmphasisComponents  EmphasisComponents              // EmphasisComponents determines the emphasis markers and the characters allowed around them. Defaults to DefaultEmphasisComponents.
+	DollarMath          bool                            // DollarMath enables $...$ latex fragments (following Org's rules for the characters around the $). Disable it for documents where $ is used for currency - $$...$$ is not affected.
+}
+
+// EmphasisComponents configures the recognition of emphasis like org-emphasis-regexp-components and the markers of
+// org-emphasis-alist. Whitespace and the start / end of the input are always allowed before an opening and after a
+// closing marker - and never directly inside the markers.
+type EmphasisComponents struct {
+	Pre             string // Pre contains the additional characters allowed before an opening marker.
+	Post            string // Post contains the additional characters allowed after a closing marker.
+	Border          string // Border contains the additional characters not allowed directly inside the markers.
+	Markers         string // Markers contains the ASCII marker characters of emphasis with inline content, e.g. "*/_+".
+	VerbatimMarkers string // VerbatimMarkers contains the ASCII marker characters of emphasis with raw content, e.g. "=~".
+}
+
+// DefaultEmphasisComponents are the EmphasisComponents of Org mode.
+var DefaultEmphasisComponents = EmphasisComponents{
+	Pre:             `-({'"`,
+	Post:            `-.,:!?;'")}[\`,
+	Markers:         "*/_+",
+	VerbatimMarkers: "=~",
 }
 
 // Document contains the parsing results and a pointer to the Configuration.
@@ -45,7 +102,20 @@ type Document struct {
 	*Configuration
 	Path           string // Path of the file containing the parse input - used to resolve relative paths during parsing (e.g. INCLUDE).
 	tokens         []token
+	input          string              // input contains the parse input. See Source.
+	source         []string            // source contains the lines of the parse input. See OrgWriter.Lossless.
+	lineOffsets    []int               // lineOffsets contains the byte offsets of the lines of the parse input.
+	firstLine      int                 // firstLine is the line number of source[0] and lineOffsets[0] - see ParseStream.
+	sourceLines    map[Position][2]int // sourceLines maps the positions of parsed nodes to the first and last source line they consumed.
+	topLevelLines  [][2]int            // topLevelLines contains the first and last source line consumed by each node of Nodes. See Reparse.
 	baseLvl        int
+	lineIndents    map[int]int     // lineIndents maps the continuation lines of the paragraph being parsed to the bytes of indentation stripped from them.
+	parsing        bool            // parsing is true while Parse is running - errors may abort parsing (see Strict and MaxErrors).
+	ctx            context.Context // ctx is the context passed to ParseContext while parsing - nil if it cannot be canceled.
+	dependencies   []Dependency
+	buffers        *parseBuffers      // buffers are the pooled buffers of the running inline parsers - see parseBuffers.
+	includeChain   []string           // includeChain contains the paths of the documents including this document, outermost first.
+	index          *outlineIndexCache // index caches the outlineIndex of the document.
 	Macros         map[string]string
 	Links          map[string]string
 	Nodes          []Node
@@ -53,6 +123,7 @@ type Document struct {
 	Outline        Outline           // Outline is a Table Of Contents for the document and contains all sections (headline + content).
 	BufferSettings map[string]string // Settings contains all settings that were parsed from keywords.
 	Errors         []*ParseError     // Structured parsing errors with position information
+	footnoteErrors []*ParseError     // footnoteErrors are the footnote warnings of Errors - see Footnotes.
 	FatalError     *ParseError       // Fatal error that prevented successful parsing
 	Pos            Position          // Position tracks the location of this document in the source
 }
@@ -86,6 +157,7 @@ type token struct {
 	line     int
 	startCol int
 	endCol   int
+	offset   int // offset is the byte offset of the line in the parse input.
 }
 
 var lexFns = []lexFn{
@@ -104,32 +176,147 @@ var lexFns = []lexFn{
 }
 
 var nilToken = token{kind: "nil", lvl: -1, content: "", matches: nil}
-var orgWriterMutex = sync.Mutex{}
-var orgWriter = NewOrgWriter()
 
-// New returns a new Configuration with (hopefully) sane defaults.
-func New() *Configuration {
-	return &Configuration{
+// orgWriterPool contains the writers used by String. Writers are not safe for concurrent use - each call of String
+// uses its own writer instead.
+var orgWriterPool = sync.Pool{New: func() any { return NewOrgWriter() }}
+
+// Option configures the Configuration returned by New.
+type Option func(*Configuration)
+
+// WithAutoLink sets Configuration.AutoLink. Defaults to true.
+func WithAutoLink(autoLink bool) Option {
+	return func(c *Configuration) { c.AutoLink = autoLink }
+}
+
+// WithReadFile sets Configuration.ReadFile. Defaults to os.ReadFile. f must be safe for concurrent use if the
+// Configuration is shared by goroutines.
+func WithReadFile(f func(filename string) ([]byte, error)) Option {
+	return func(c *Configuration) { c.ReadFile = f }
+}
+
+// WithResolveLink sets Configuration.ResolveLink. f must be safe for concurrent use if the Configuration is shared
+// by goroutines.
+func WithResolveLink(f func(protocol string, description []Node, link string) Node) Option {
+	return func(c *Configuration) { c.ResolveLink = f }
+}
+
+// WithResolveWikiLink sets Configuration.ResolveWikiLink. Links without protocol that are not Org mode internal links
+// or paths (e.g. [[Page Name]], but not [[#custom-id]], [[*headline]] or [[./notes.org]]) are resolved via f to the
+// target of the page (e.g. "notes/page-name.org") and whether the page exists - see RegularLink.Wiki. Note that this
+// includes links to #+NAME: targets. f must be safe for concurrent use if the Configuration is shared by goroutines.
+func WithResolveWikiLink(f func(page string) (target string, exists bool)) Option {
+	return func(c *Configuration) { c.ResolveWikiLink = f }
+}
+
+// WithLogger sets Configuration.Log. Use log.New(io.Discard, "", 0) to disable logging (see Silent).
+func WithLogger(l *log.Logger) Option {
+	return func(c *Configuration) { c.Log = l }
+}
+
+// WithStructuredLog sets Configuration.StructuredLog.
+func WithStructuredLog(l *slog.Logger) Option {
+	return func(c *Configuration) { c.StructuredLog = l }
+}
+
+// WithHooks sets Configuration.Hooks.
+func WithHooks(hooks Hooks) Option {
+	return func(c *Configuration) { c.Hooks = hooks }
+}
+
+// WithCompatibilityLevel sets Configuration.CompatibilityLevel.
+func WithCompatibilityLevel(level string) Option {
+	return func(c *Configuration) { c.CompatibilityLevel = level }
+}
+
+// WithListEndBlankLines sets Configuration.ListEndBlankLines.
+func WithListEndBlankLines(n int) Option {
+	return func(c *Configuration) { c.ListEndBlankLines = n }
+}
+
+// WithMaxEmphasisDepth sets Configuration.MaxEmphasisDepth.
+func WithMaxEmphasisDepth(depth int) Option {
+	return func(c *Configuration) { c.MaxEmphasisDepth = depth }
+}
+
+// WithEmphasisComponents sets Configuration.EmphasisComponents. Markers should not be characters that start other
+// inline markup (e.g. "[" or "$"). Custom markers are written as span elements by the HTMLWriter unless configured
+// in HTMLWriter.EmphasisTags.
+func WithEmphasisComponents(components EmphasisComponents) Option {
+	return func(c *Configuration) { c.EmphasisComponents = components }
+}
+
+// WithDollarMath sets Configuration.DollarMath. Defaults to true.
+func WithDollarMath(dollarMath bool) Option {
+	return func(c *Configuration) { c.DollarMath = dollarMath }
+}
+
+// WithEmojiShortcodes sets Configuration.EmojiShortcodes, e.g. to DefaultEmojiShortcodes. Shortcodes of custom emoji
+// without a unicode character map to "" - see HTMLWriter.EmojiImageURL.
+func WithEmojiShortcodes(shortcodes map[string]string) Option {
+	return func(c *Configuration) { c.EmojiShortcodes = shortcodes }
+}
+
+// WithHashtags sets Configuration.ParseHashtags.
+func WithHashtags(parse bool) Option {
+	return func(c *Configuration) { c.ParseHashtags = parse }
+}
+
+// WithMentions sets Configuration.ParseMentions.
+func WithMentions(parse bool) Option {
+	return func(c *Configuration) { c.ParseMentions = parse }
+}
+
+// WithDefaults overrides the given DefaultSettings (e.g. "OPTIONS" or "TODO"). settings are copied, i.e. they
+// can be modified after New without affecting the Configuration.
+func WithDefaults(settings map[string]string) Option {
+	return func(c *Configuration) {
+		for k, v := range settings {
+			c.DefaultSettings[k] = v
+		}
+	}
+}
+
+// New returns a new Configuration with (hopefully) sane defaults that are overridden by opts.
+//
+// Parsing and writing never modify the Configuration, i.e. it can be shared by goroutines (e.g. concurrent calls
+// of Parse) as long as its fields are not assigned after New returns - configure it via opts instead.
+func New(opts ...Option) *Configuration {
+	c := &Configuration{
 		AutoLink:            true,
+		DollarMath:          true,
+		EmphasisComponents:  DefaultEmphasisComponents,
 		MaxEmphasisNewLines: 1,
 		DefaultSettings: map[string]string{
 			"TODO":         "TODO | DONE",
 			"EXCLUDE_TAGS": "noexport",
-			"OPTIONS":      "toc:t <:t e:t f:t pri:t todo:t tags:t title:t ealb:nil",
+			"SELECT_TAGS":  "export",
+			"OPTIONS":      "toc:t <:t e:t f:t pri:t todo:t tags:t title:t ealb:nil \\n:nil d:(not \"LOGBOOK\") num:nil H:nil fnsec:nil",
 		},
-		Log:      log.New(os.Stderr, "go-org: ", 0),
-		ReadFile: os.ReadFile,
+		FailOnSeverity:    SeverityFatal,
+		MaxIncludeDepth:   10,
+		ListEndBlankLines: 2,
+		TabWidth:          8,
+		Log:               log.New(os.Stderr, "go-org: ", 0),
+		ReadFile:          os.ReadFile,
 		ResolveLink: func(protocol string, description []Node, link string) Node {
 			return RegularLink{Protocol: protocol, Description: description, URL: link, AutoLink: false}
 		},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // String returns the pretty printed Org mode string for the given nodes (see OrgWriter).
+// It is safe for concurrent use.
 func String(nodes ...Node) string {
-	orgWriterMutex.Lock()
-	defer orgWriterMutex.Unlock()
-	return orgWriter.WriteNodesAsString(nodes...)
+	w := orgWriterPool.Get().(*OrgWriter)
+	out := w.WriteNodesAsString(nodes...)
+	w.err = nil          // errors of nodes that cannot be written are not reported by String
+	orgWriterPool.Put(w) // writers are only reused if writing did not panic
+	return out
 }
 
 // CopyNodes returns a deep copy of a slice of nodes.
@@ -145,38 +332,185 @@ func CopyNodes(nodes []Node) []Node {
 }
 
 // Write is called after with an instance of the Writer interface to export a parsed Document into another format.
+// The built-in writers return their errors (e.g. for nodes they cannot write) without panicking - panics of other
+// writers are returned as *WriterPanic errors. See NewWriterV2.
 func (d *Document) Write(w Writer) (out string, err error) {
-	defer func() {
-		if recovered := recover(); recovered != nil {
-			err = fmt.Errorf("could not write output: %s", recovered)
+	return d.WriteV2(NewWriterV2(w))
+}
+
+// WriteV2 is like Write for writers that return errors rather than panicking. The first error returned by the
+// writer is returned. Panics (e.g. of export filters) are returned as *WriterPanic errors.
+func (d *Document) WriteV2(w WriterV2) (out string, err error) {
+	defer d.phaseDone(PhaseWrite, time.Now())
+	defer recoverWriterPanic(&err)
+	if err := d.writable(); err != nil {
+		return "", err
+	}
+	d = d.exported()
+	if err := w.Before(d); err != nil {
+		return "", err
+	} else if err := w.WriteNodes(d.Nodes...); err != nil {
+		return "", err
+	} else if err := w.After(d); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+// WriteStream is like Write but writes the output to out while it is generated rather than returning it - e.g. to
+// export very large documents without keeping the complete output in memory. See Writer.WriteNodesTo.
+func (d *Document) WriteStream(out io.Writer, w Writer) (err error) {
+	defer d.phaseDone(PhaseWrite, time.Now())
+	defer recoverWriterPanic(&err)
+	if err := d.writable(); err != nil {
+		return err
+	}
+	d = d.exported()
+	if w.Before(d); reportedError(w) != nil {
+		return reportedError(w)
+	} else if err := w.WriteNodesTo(out, d.Nodes...); err != nil {
+		return err
+	}
+	if err := after(w, d); err != nil {
+		return err
+	} else if _, err := io.WriteString(out, w.String()); err != nil {
+		return fmt.Errorf("could not write output: %w", err)
+	}
+	return nil
+}
+
+// WriteParallel is like Write but writes the top level sections of the document concurrently using forks of w
+// (see ParallelWriter) and up to GOMAXPROCS goroutines. The output is the same as that of Write. Functions
+// configured on w (e.g. HTMLWriter.HighlightCodeBlock) must be safe for concurrent use.
+// Writers that do not implement ParallelWriter are written sequentially.
+func (d *Document) WriteParallel(w Writer) (out string, err error) {
+	pw, ok := w.(ParallelWriter)
+	if !ok {
+		return d.Write(w)
+	}
+	defer d.phaseDone(PhaseWrite, time.Now())
+	defer recoverWriterPanic(&err)
+	if err := d.writable(); err != nil {
+		return "", err
+	}
+	d = d.exported()
+	if w.Before(d); reportedError(w) != nil {
+		return "", reportedError(w)
+	}
+	sections := splitSections(d.Nodes)
+	forks := pw.Fork(sections)
+	if forks == nil {
+		for _, section := range sections {
+			WriteNodes(w, section...)
 		}
-	}()
+	} else {
+		errs, jobs, wg := make([]error, len(sections)), make(chan int), sync.WaitGroup{}
+		for range min(runtime.GOMAXPROCS(0), len(sections)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					errs[i] = writeSection(forks[i], sections[i])
+				}
+			}()
+		}
+		for i := range sections {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		for i, section := range sections {
+			if errs[i] != nil {
+				return "", errs[i]
+			} else if !pw.Join(forks[i]) {
+				WriteNodes(w, section...)
+			}
+		}
+	}
+	if err := after(w, d); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+// writeSection writes the nodes of a section using a fork and returns the error reported by the fork. Panics are
+// returned as *WriterPanic errors.
+func writeSection(fork Writer, nodes []Node) (err error) {
+	defer recoverWriterPanic(&err)
+	WriteNodes(fork, nodes...)
+	return reportedError(fork)
+}
+
+// splitSections splits top level nodes into sections for WriteParallel: each headline (i.e. its subtree) is a section
+// and so is each run of nodes between headlines.
+func splitSections(nodes []Node) [][]Node {
+	sections := [][]Node{}
+	for i, n := range nodes {
+		if _, ok := n.(Headline); ok || i == 0 {
+			sections = append(sections, []Node{n})
+		} else if _, ok := nodes[i-1].(Headline); ok {
+			sections = append(sections, []Node{n})
+		} else {
+			sections[len(sections)-1] = append(sections[len(sections)-1], n)
+		}
+	}
+	return sections
+}
+
+// writable returns an error if the document cannot be written - see Write.
+func (d *Document) writable() error {
 	if d.HasFatalError() {
-		return "", d.FatalError
+		return d.FatalError
+	} else if errs := d.GetErrorsBySeverity(d.FailOnSeverity); len(errs) != 0 {
+		return errs[0]
 	} else if d.Nodes == nil {
-		return "", fmt.Errorf("could not write output: parse was not called")
+		return fmt.Errorf("could not write output: parse was not called")
 	}
-	w.Before(d)
-	WriteNodes(w, d.Nodes...)
-	w.After(d)
-	return w.String(), err
+	return nil
+}
+
+// fragment returns a shallow copy of the document that only contains nodes.
+// options take precedence over the #+OPTIONS of the document.
+func (d *Document) fragment(nodes []Node, options string) *Document {
+	fragment := *d
+	fragment.Nodes = nodes
+	fragment.BufferSettings = make(map[string]string, len(d.BufferSettings))
+	for k, v := range d.BufferSettings {
+		fragment.BufferSettings[k] = v
+	}
+	fragment.BufferSettings["OPTIONS"] = strings.TrimSpace(options + " " + d.BufferSettings["OPTIONS"])
+	return &fragment
 }
 
 // Parse parses the input into an AST (and some other helpful fields like Outline).
 // To allow method chaining, errors are stored in document.Error rather than being returned.
 func (c *Configuration) Parse(input io.Reader, path string) (d *Document) {
-	outlineSection := &Section{}
-	d = &Document{
-		Configuration:  c,
-		Outline:        Outline{outlineSection, outlineSection, 0},
-		BufferSettings: map[string]string{},
-		NamedNodes:     map[string]Node{},
-		Links:          map[string]string{},
-		Macros:         map[string]string{},
-		Path:           path,
+	return c.ParseContext(context.Background(), input, path)
+}
+
+// ParseContext is like Parse but aborts parsing once ctx is done - e.g. to enforce a timeout for parsing user
+// uploads. The context is checked between source lines and elements. If parsing is aborted, the document has a
+// FatalError of type ErrorTypeCanceled whose Cause is the error of the context (see context.Canceled and
+// context.DeadlineExceeded).
+func (c *Configuration) ParseContext(ctx context.Context, input io.Reader, path string) *Document {
+	return c.parse(ctx, input, path, nil)
+}
+
+// parse parses input as a document included by the documents of includeChain - see loadSetupFile.
+func (c *Configuration) parse(ctx context.Context, input io.Reader, path string, includeChain []string) (d *Document) {
+	d = c.newDocument(path)
+	d.includeChain = includeChain
+	d.sourceLines = map[Position][2]int{}
+	if ctx.Done() != nil {
+		d.ctx = ctx
 	}
 	defer func() {
+		d.parsing, d.ctx = false, nil
 		if recovered := recover(); recovered != nil {
+			if abort, ok := recovered.(abortParse); ok {
+				d.Nodes, d.FatalError = nil, abort.err
+				return
+			}
 			d.AddFatalError(ErrorTypeInvalidStructure, "parse panic", d.Pos, token{}, fmt.Errorf("recovered from panic: %v", recovered))
 		}
 	}()
@@ -184,40 +518,209 @@ func (c *Configuration) Parse(input io.Reader, path string) (d *Document) {
 		d.AddFatalError(ErrorTypeValidation, "parse called multiple times", d.Pos, token{}, nil)
 		return nil
 	}
+	d.parsing = true
+	start := time.Now()
 	d.tokenize(input)
-	_, nodes := d.parseMany(0, func(d *Document, i int) bool { return i >= len(d.tokens) })
-	d.Nodes = nodes
+	d.phaseDone(PhaseTokenize, start)
+	start = time.Now()
+	_, d.Nodes, d.topLevelLines = d.parseTopLevel(len(d.tokens))
+	d.addFootnoteWarnings(d.Nodes)
+	d.phaseDone(PhaseParse, start)
 	return d
 }
 
-// Silent disables all logging of warnings during parsing.
+func (c *Configuration) newDocument(path string) *Document {
+	outlineSection := &Section{}
+	d := &Document{
+		Configuration:  c,
+		Outline:        Outline{outlineSection, outlineSection, 0},
+		BufferSettings: map[string]string{},
+		NamedNodes:     map[string]Node{},
+		Links:          map[string]string{},
+		Macros:         map[string]string{},
+		Path:           path,
+		index:          &outlineIndexCache{},
+	}
+	outlineSection.document = d
+	return d
+}
+
+// Silent disables all logging of warnings during parsing. Silent modifies c - see New.
 func (c *Configuration) Silent() *Configuration {
-	c.Log = log.New(io.Discard, "", 0)
+	c.Log, c.StructuredLog = log.New(io.Discard, "", 0), nil
 	return c
 }
 
+// logf logs a warning using StructuredLog or Log. n is the node the warning concerns - or nil.
+// Log only receives messages of at least slog.LevelInfo.
+func (d *Document) logf(level slog.Level, n Node, format string, args ...any) {
+	if d.StructuredLog == nil {
+		if level >= slog.LevelInfo {
+			d.Log.Printf(format, args...)
+		}
+		return
+	}
+	attrs := []slog.Attr{slog.String("path", d.Path)}
+	if n != nil {
+		pos := n.Position()
+		attrs = append(attrs, slog.Int("line", pos.StartLine), slog.Int("column", pos.StartColumn))
+	}
+	d.StructuredLog.LogAttrs(context.Background(), level, fmt.Sprintf(format, args...), attrs...)
+}
+
 func (d *Document) tokenize(input io.Reader) {
-	d.tokens = []token{}
+	err := d.readLines(input)
+	if lineNum := len(d.source); errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		d.checkContext(Position{StartLine: lineNum, EndLine: lineNum}, token{line: lineNum})
+	}
+	if d.Hooks.LinesTokenized == nil {
+		d.tokens = d.tokenizeLines(0, len(d.source))
+	} else {
+		d.tokens = []token{}
+		for from := 0; from < len(d.source); from += progressInterval {
+			end := min(from+progressInterval, len(d.source))
+			d.tokens = append(d.tokens, d.tokenizeLines(from, end)...)
+			d.Hooks.LinesTokenized(d, end, len(d.source))
+		}
+	}
+	if err != nil {
+		d.addReadError(err, len(d.source))
+	}
+}
+
+// addReadError adds the error err of reading the input at line lineNum (see lineScanner) as a fatal error.
+func (d *Document) addReadError(err error, lineNum int) {
+	if errors.Is(err, bufio.ErrTooLong) {
+		err = fmt.Errorf("line %d is longer than %d bytes (see MaxLineLength): %w", lineNum+1, d.MaxLineLength, err)
+		d.AddFatalError(ErrorTypeIO, "line too long", Position{StartLine: lineNum, EndLine: lineNum}, token{line: lineNum}, err)
+	} else {
+		d.AddFatalError(ErrorTypeIO, "tokenization failed", Position{StartLine: lineNum, StartColumn: 0, EndLine: lineNum, EndColumn: 0}, token{line: lineNum}, err)
+	}
+}
+
+// lineScanner returns a scanner of the lines of input that calls line with the raw bytes (including the line
+// ending) and the content of each line. Scanning fails with bufio.ErrTooLong for lines longer than MaxLineLength.
+func (d *Document) lineScanner(input io.Reader, line func(raw, content []byte)) *bufio.Scanner {
 	scanner := bufio.NewScanner(input)
-	lineNum := 0
+	scanner.Buffer(nil, math.MaxInt) // the buffer grows as needed - lines are limited by MaxLineLength
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if d.ctx != nil && d.ctx.Err() != nil {
+			return 0, nil, d.ctx.Err()
+		}
+		advance, content, err := bufio.ScanLines(data, atEOF)
+		if limit := d.MaxLineLength; limit > 0 && (len(content) > limit || content == nil && len(data) > limit+1) {
+			return 0, nil, bufio.ErrTooLong
+		} else if content != nil {
+			line(data[:advance], content)
+		}
+		return advance, content, err
+	})
+	return scanner
+}
+
+// readLines reads the input and splits it into source lines.
+func (d *Document) readLines(input io.Reader) error {
+	d.source, d.lineOffsets = []string{}, []int{}
+	offset, raw, lengths := 0, strings.Builder{}, []int{}
+	scanner := d.lineScanner(input, func(line, content []byte) {
+		d.lineOffsets = append(d.lineOffsets, offset)
+		lengths = append(lengths, len(content))
+		offset += len(line)
+		raw.Write(line)
+	})
 	for scanner.Scan() {
-		line := scanner.Text()
+	}
+	d.input = raw.String()
+	d.source = make([]string, len(lengths))
+	for i, n := range lengths {
+		d.source[i] = d.input[d.lineOffsets[i] : d.lineOffsets[i]+n] // lines share the memory of the input
+	}
+	return scanner.Err()
+}
+
+// LineEnding returns the line ending of the parse input: "\r\n" if its first line ends with "\r\n" and "\n" otherwise.
+// See OrgWriter.PreserveLineEndings.
+func (d *Document) LineEnding() string {
+	if i := strings.IndexByte(d.input, '\n'); i > 0 && d.input[i-1] == '\r' {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// FinalNewline returns true if the parse input is empty or ends with a line ending.
+func (d *Document) FinalNewline() bool {
+	return d.input == "" || strings.HasSuffix(d.input, "\n")
+}
+
+// tokenizeLines returns the tokens of the source lines from (inclusive) to end (exclusive).
+func (d *Document) tokenizeLines(from, end int) []token {
+	tokens := []token{}
+	for lineNum := from; lineNum < end; lineNum++ {
+		d.checkContext(Position{StartLine: lineNum, EndLine: lineNum}, token{line: lineNum})
+		line, _ := d.sourceLine(lineNum)
 		tok, ok := tokenize(line)
+		if ok && d.emacsCompatible() {
+			tok = emacsToken(line, tok)
+		}
 		if !ok {
 			pos := Position{StartLine: lineNum, StartColumn: 1, EndLine: lineNum, EndColumn: len(line) + 1}
 			d.AddError(ErrorTypeTokenization, "could not lex line", pos, token{line: lineNum}, fmt.Errorf("no lexer matched: %q", line))
-			lineNum++
 			continue
 		}
 		tok.line = lineNum
+		tok.offset = d.offset(lineNum, 0)
 		tok.startCol = 0
 		tok.endCol = len(line)
-		d.tokens = append(d.tokens, tok)
-		lineNum++
+		if indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]; tok.lvl == len(indent) && strings.Contains(indent, "\t") {
+			tok.lvl = d.indentWidth(indent)
+			if strings.Contains(indent, " ") {
+				t := token{line: lineNum, offset: tok.offset, endCol: len(indent)}
+				cause := fmt.Errorf("indentation is %d columns wide with a TabWidth of %d", tok.lvl, d.TabWidth)
+				d.addWarning(ErrorTypeInvalidSyntax, "mixed tabs and spaces in indentation", d.getPositionFromToken(t), t, cause)
+			}
+		}
+		tokens = append(tokens, tok)
 	}
-	if err := scanner.Err(); err != nil {
-		d.AddFatalError(ErrorTypeIO, "tokenization failed", Position{StartLine: lineNum, StartColumn: 0, EndLine: lineNum, EndColumn: 0}, token{line: lineNum}, err)
+	return tokens
+}
+
+// CompatibilityEmacs96 is the Configuration.CompatibilityLevel for parsing like Org mode 9.6 in Emacs rather than
+// using go-org's relaxed rules for the following edge cases:
+//   - headline stars must be followed by a space (rather than any whitespace) and * bullets of plain lists must be
+//     indented, e.g. a line containing just "*" is a paragraph rather than a list item.
+//   - list items end before the first line (that is not blank) indented less than or as much as their bullet rather
+//     than less than the end of their bullet, e.g. lines indented by two spaces continue an item with the bullet "10.".
+//     Lists end before two blank lines in both modes.
+//   - only ASCII whitespace delimits emphasis (see org-emphasis-regexp-components), e.g. no-break spaces do not.
+const CompatibilityEmacs96 = "emacs-9.6"
+
+// emacsCompatible returns true if the document is parsed with CompatibilityEmacs96.
+func (c *Configuration) emacsCompatible() bool { return c.CompatibilityLevel == CompatibilityEmacs96 }
+
+// emacsToken returns the token Emacs would lex the line as instead of t - see CompatibilityEmacs96.
+func emacsToken(line string, t token) token {
+	if t.kind == "headline" && line[len(t.matches[1])] != ' ' || t.kind == "unorderedList" && t.lvl == 0 && t.matches[2] == "*" {
+		t, _ = lexText(line)
+	}
+	return t
+}
+
+// indentWidth returns the width in columns of the whitespace s - tabs advance to the next multiple of TabWidth.
+func (d *Document) indentWidth(s string) int {
+	return columnAfter(s, 0, d.TabWidth)
+}
+
+// columnAfter returns the column reached after the whitespace s starting at column col. Tabs advance to the next
+// multiple of tabWidth - or by a single column if tabWidth is less than 1.
+func columnAfter(s string, col, tabWidth int) int {
+	for _, r := range s {
+		if r == '\t' && tabWidth > 0 {
+			col += tabWidth - col%tabWidth
+		} else {
+			col++
+		}
 	}
+	return col
 }
 
 // Get returns the value for key in BufferSettings or DefaultSettings if key does not exist in the former
@@ -241,7 +744,12 @@ func (d *Document) Get(key string) string {
 // - todo (export headline todo status)
 // - pri (export headline priority)
 // - tags (export headline tags)
+// - num (number headlines. an int limits the numbered org headline lvl. see the UNNUMBERED property)
+// - H (export headlines below the given lvl as list items)
+// - d (export drawers. t, nil, a list of drawer names ("A" "B") or an excluded list (not "A" "B") - see ExportsDrawer. defaults to (not "LOGBOOK"))
+// - \n (preserve line breaks inside of paragraphs - see ParagraphBreakMode)
 // - ealb (non-standard) (export with east asian line breaks / ignore line breaks between multi-byte characters)
+// - fnsec (non-standard) (export footnotes at the end of sections. t or an int for the depth of the sections - see FootnoteModeSections)
 // see https://orgmode.org/manual/Export-Settings.html for more information
 func (d *Document) GetOption(key string) string {
 	get := func(settings map[string]string) string {
@@ -258,12 +766,13 @@ func (d *Document) GetOption(key string) string {
 	}
 	if value == "" {
 		value = "nil"
-		d.Log.Printf("Missing value for export option %s", key)
+		d.logf(slog.LevelDebug, nil, "Missing value for export option %s", key)
 	}
 	return value
 }
 
 func (d *Document) parseOne(i int, stop stopFn) (consumed int, node Node) {
+	d.checkContext(d.getPositionFromToken(d.tokens[i]), d.tokens[i])
 	switch d.tokens[i].kind {
 	case "unorderedList", "orderedList":
 		consumed, node = d.parseList(i, stop)
@@ -294,14 +803,34 @@ func (d *Document) parseOne(i int, stop stopFn) (consumed int, node Node) {
 	}
 
 	if consumed != 0 {
+		if node != nil && d.sourceLines != nil {
+			d.sourceLines[node.Position()] = [2]int{d.tokens[i].line, d.tokens[i+consumed-1].line}
+		}
 		return consumed, node
 	}
-	d.AddError(ErrorTypeUnexpectedToken, "could not parse token", getPositionFromToken(d.tokens[i]), d.tokens[i], fmt.Errorf("no parser matched token kind %q", d.tokens[i].kind))
+	d.addWarning(ErrorTypeUnexpectedToken, "could not parse token", d.getPositionFromToken(d.tokens[i]), d.tokens[i], fmt.Errorf("no parser matched token kind %q", d.tokens[i].kind))
 	m := plainTextRegexp.FindStringSubmatch(d.tokens[i].matches[0])
-	d.tokens[i] = token{kind: "text", lvl: len(m[1]), content: m[2], matches: m}
+	d.tokens[i] = token{kind: "text", lvl: len(m[1]), content: m[2], matches: m, line: d.tokens[i].line, startCol: d.tokens[i].startCol, endCol: d.tokens[i].endCol, offset: d.tokens[i].offset}
 	return d.parseOne(i, stop)
 }
 
+// parseTopLevel parses the top level nodes starting in the tokens before end and returns the number of consumed tokens,
+// the nodes and the first and last source line consumed by each node. Nodes may consume tokens after end.
+func (d *Document) parseTopLevel(end int) (int, []Node, [][2]int) {
+	i, nodes, lines := 0, []Node{}, [][2]int{}
+	stop := func(d *Document, i int) bool { return i >= len(d.tokens) }
+	for i < end {
+		consumed, node := d.parseOne(i, stop)
+		if node != nil {
+			nodes, lines = append(nodes, node), append(lines, [2]int{d.tokens[i].line, d.tokens[i+consumed-1].line})
+		}
+		if i += consumed; node != nil && d.Hooks.NodeParsed != nil {
+			d.Hooks.NodeParsed(d, node, i, end)
+		}
+	}
+	return i, nodes, lines
+}
+
 func (d *Document) parseMany(i int, stop stopFn) (int, []Node) {
 	start, nodes := i, []Node{}
 	for i < len(d.tokens) && !stop(d, i) {
@@ -315,20 +844,11 @@ func (d *Document) parseMany(i int, stop stopFn) (int, []Node) {
 }
 
 func (d *Document) addHeadline(headline *Headline) int {
-	current := &Section{Headline: headline}
+	current := &Section{Headline: headline, document: d}
 	d.Outline.last.add(current)
-	if !headline.IsExcluded(d) {
+	if !headline.isExcludedByTags(d) {
 		d.Outline.count++
 	}
 	d.Outline.last = current
 	return d.Outline.count
 }
-
-func tokenize(line string) (token, bool) {
-	for _, lexFn := range lexFns {
-		if token, ok := lexFn(line); ok {
-			return token, true
-		}
-	}
-	return nilToken, false
-}
@@ -0,0 +1,246 @@
+package org
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNewOptions(t *testing.T) {
+	logs, defaults := &bytes.Buffer{}, map[string]string{"TODO": "TODO NEXT | DONE"}
+	c := New(
+		WithAutoLink(false),
+		WithLogger(log.New(logs, "", 0)),
+		WithDefaults(defaults),
+		WithReadFile(func(filename string) ([]byte, error) { return []byte("included " + filename + "\n"), nil }),
+		WithResolveLink(func(protocol string, description []Node, link string) Node {
+			return Text{Content: "resolved " + link}
+		}),
+	)
+	defaults["TODO"] = "modified"
+	if c.AutoLink || c.DefaultSettings["TODO"] != "TODO NEXT | DONE" || c.DefaultSettings["OPTIONS"] != New().DefaultSettings["OPTIONS"] {
+		t.Errorf("options were not applied: %#v", c)
+	}
+
+	input := "* NEXT headline\nhttps://example.com [[https://example.com]]\n#+INCLUDE: \"other.org\" src text\n"
+	documents, wg := make([]*Document, 8), sync.WaitGroup{}
+	for i := range documents {
+		wg.Go(func() { documents[i] = c.Parse(strings.NewReader(input), "./options.org") })
+	}
+	wg.Wait()
+	for _, d := range documents {
+		nodes := d.Nodes[0].(Headline).Children
+		if status := d.Nodes[0].(Headline).Status; status != "NEXT" {
+			t.Errorf("expected status NEXT, got %q", status)
+		}
+		if out, expected := String(nodes[0]), "https://example.com resolved https://example.com\n"; out != expected {
+			t.Errorf("unexpected output:\n%s", diff(out, expected))
+		}
+		if out, expected := String(nodes[1].(Include).Resolve()), "#+BEGIN_SRC text\nincluded other.org\n#+END_SRC\n"; out != expected {
+			t.Errorf("unexpected include output:\n%s", diff(out, expected))
+		}
+	}
+}
+
+func TestStructuredLog(t *testing.T) {
+	logs, records := &bytes.Buffer{}, []map[string]any{}
+	c := New(
+		WithStructuredLog(slog.New(slog.NewJSONHandler(logs, nil))),
+		WithReadFile(func(string) ([]byte, error) { return nil, errors.New("not found") }),
+	)
+	d := c.Parse(strings.NewReader("* headline\n\n#+INCLUDE: \"missing.org\" src go\n#+TOC: foo\n"), "./log.org")
+	if _, err := d.Write(NewHTMLWriter()); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		record := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("could not decode log record %q: %s", line, err)
+		}
+		delete(record, "time")
+		records = append(records, record)
+	}
+	expected := []map[string]any{
+		{"level": "WARN", "msg": `Bad include org.Keyword{Key:"INCLUDE", Value:"\"missing.org\" src go", Pos:org.Position{StartLine:2, StartColumn:0, EndLine:2, EndColumn:31, StartOffset:12, EndOffset:43}}: not found`, "path": "./log.org", "line": 2.0, "column": 0.0},
+		{"level": "WARN", "msg": "Bad TOC keyword: foo", "path": "./log.org", "line": 3.0, "column": 0.0},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("unexpected log records:\n%#v\nexpected\n%#v", records, expected)
+	}
+	if New(WithStructuredLog(slog.Default())).Silent().StructuredLog != nil {
+		t.Errorf("expected Silent to disable structured logging")
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	logs := &bytes.Buffer{}
+	d := New(WithLogger(log.New(logs, "", 0))).Parse(strings.NewReader("#+TOC: foo\n"), "./log.org")
+	if d.GetOption("missing") != "nil" || logs.Len() != 0 {
+		t.Errorf("expected debug messages not to be logged, got:\n%s", logs)
+	}
+	if _, err := d.Write(NewHTMLWriter()); err != nil || logs.String() != "Bad TOC keyword: foo\n" {
+		t.Errorf("expected warnings to be logged, got %v:\n%s", err, logs)
+	}
+}
+
+func TestDefaultExportOptions(t *testing.T) {
+	logs := &bytes.Buffer{}
+	d := New(WithLogger(log.New(logs, "", 0))).Parse(strings.NewReader("* A\n** B\n* C\n"), "./options.org")
+	for _, key := range []string{"toc", "<", "e", "f", "title", "todo", "pri", "tags", "num", "H", "d", "\\n", "ealb", "fnsec"} {
+		if d.GetOption(key) == "" {
+			t.Errorf("expected a default value for export option %q", key)
+		}
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no missing export options, got:\n%s", logs)
+	}
+}
+
+func TestCompatibilityLevel(t *testing.T) {
+	nodeTypes := func(nodes []Node) string {
+		types := []string{}
+		for _, n := range nodes {
+			types = append(types, strings.TrimPrefix(fmt.Sprintf("%T", n), "org."))
+			if l, ok := n.(List); ok {
+				types[len(types)-1] += fmt.Sprintf("(%d)", len(l.Items))
+			}
+		}
+		return strings.Join(types, " ")
+	}
+	cases := []struct{ input, relaxed, emacs string }{
+		{"*\n", "List(1)", "Paragraph"},
+		{"*\theadline\n", "Headline", "Paragraph"},
+		{"* headline\n", "Headline", "Headline"},
+		{"  * item\n", "List(1)", "List(1)"},
+		{"10. item\n  - nested\n11. item\n", "List(1) List(1) List(1)", "List(2)"},
+		{"- item\n\n\n- item\n", "List(1) Paragraph Paragraph List(1)", "List(1) Paragraph Paragraph List(1)"},
+		{"a *bold* b\n", "Paragraph[Text Emphasis Text]", "Paragraph[Text Emphasis Text]"},
+		{"a *bold* b\n", "Paragraph[Text Emphasis Text]", "Paragraph[Text]"},
+		{"*bold　*\n", "Paragraph[Text]", "Paragraph[Emphasis]"},
+	}
+	for _, c := range cases {
+		for level, expected := range map[string]string{"": c.relaxed, CompatibilityEmacs96: c.emacs} {
+			d := New(WithCompatibilityLevel(level)).Silent().Parse(strings.NewReader(c.input), "./compatibility.org")
+			actual := nodeTypes(d.Nodes)
+			if p, ok := d.Nodes[0].(Paragraph); ok && strings.HasPrefix(expected, "Paragraph[") {
+				actual = "Paragraph[" + nodeTypes(p.Children) + "]"
+			}
+			if actual != expected {
+				t.Errorf("%q with level %q: got %s, expected %s", c.input, level, actual, expected)
+			}
+		}
+	}
+}
+
+func TestDollarMath(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []string // Contents of the latex fragments
+	}{
+		{"$5 and $10", nil},
+		{"costs $5, or $10.", nil},
+		{"$2 + 2$, $3 - 3$", []string{"$2 + 2$", "$3 - 3$"}},
+		{"$x$ and ($y$)", []string{"$x$", "$y$"}},
+		{"$ x$ $x $ $x$y $x$$", nil},
+		{"$a\nb\nc\nd$", nil},
+		{"$$5 and 10$$", []string{"$$5 and 10$$"}},
+	}
+	for _, c := range cases {
+		for _, dollarMath := range []bool{true, false} {
+			d := New(WithDollarMath(dollarMath)).Silent().Parse(strings.NewReader(c.input), "./math.org")
+			actual := []string{}
+			walkNodes(d.Nodes, func(n Node) {
+				if f, ok := n.(LatexFragment); ok {
+					actual = append(actual, String(f))
+				}
+			})
+			expected := c.expected
+			if !dollarMath && len(expected) != 0 && !strings.HasPrefix(expected[0], "$$") {
+				expected = nil
+			}
+			if strings.Join(actual, " ") != strings.Join(expected, " ") {
+				t.Errorf("%q with DollarMath %v: got %q, expected %q", c.input, dollarMath, actual, expected)
+			}
+		}
+	}
+}
+
+func TestMaxEmphasisDepth(t *testing.T) {
+	input := "*a /b _c_ b/ a* [[https://example.com][/d *e* d/]]"
+	cases := map[int]string{
+		0: `<p><strong>a <em>b <span style="text-decoration: underline;">c</span> b</em> a</strong> <a href="https://example.com"><em>d <strong>e</strong> d</em></a></p>`,
+		2: `<p><strong>a <em>b _c_ b</em> a</strong> <a href="https://example.com"><em>d <strong>e</strong> d</em></a></p>`,
+		1: `<p><strong>a /b _c_ b/ a</strong> <a href="https://example.com"><em>d *e* d</em></a></p>`,
+	}
+	for depth, expected := range cases {
+		d := New(WithMaxEmphasisDepth(depth)).Silent().Parse(strings.NewReader(input), "./depth.org")
+		if actual, err := d.Write(NewHTMLWriter()); err != nil || strings.TrimSpace(actual) != expected {
+			t.Errorf("MaxEmphasisDepth %d: got %q (%v), expected %q", depth, actual, err, expected)
+		}
+		if actual := String(d.Nodes...); actual != input+"\n" {
+			t.Errorf("MaxEmphasisDepth %d: got %q, expected %q", depth, actual, input+"\n")
+		}
+	}
+}
+
+type revealOption struct {
+	Keyword
+	Option string
+}
+
+type speakerNotes struct{ Block }
+
+func TestKeywordAndBlockHandlers(t *testing.T) {
+	c := New().Silent().AddKeywordHandler("reveal_", func(d *Document, k Keyword) Node {
+		return revealOption{k, strings.ToLower(strings.TrimPrefix(k.Key, "REVEAL_"))}
+	}).AddKeywordHandler("REVEAL_THEME", func(d *Document, k Keyword) Node {
+		return nil
+	}).AddBlockHandler("notes", func(d *Document, b Block) Node {
+		return speakerNotes{b}
+	})
+	input := "#+REVEAL_TRANS: fade\n#+REVEAL_THEME: black\n#+TITLE: slides\n\n#+BEGIN_NOTES\nsay *hi*\n#+END_NOTES\n"
+	d := c.Parse(strings.NewReader(input), "./handlers.org")
+	types := []string{}
+	for _, n := range d.Nodes {
+		types = append(types, fmt.Sprintf("%T", n))
+	}
+	if expected := "org.revealOption org.Keyword org.Keyword org.Paragraph org.speakerNotes"; strings.Join(types, " ") != expected {
+		t.Errorf("got %v, expected %s", types, expected)
+	}
+	if o := d.Nodes[0].(revealOption); o.Option != "trans" || o.Value != "fade" || d.BufferSettings["REVEAL_TRANS"] != "fade" {
+		t.Errorf("unexpected keyword node %#v / settings %v", o, d.BufferSettings)
+	}
+	if n := d.Nodes[4].(speakerNotes); String(n.Children...) != "say *hi*\n" || n.Position().StartLine != 4 {
+		t.Errorf("unexpected block node %#v", n)
+	}
+	if actual := String(d.Nodes...); actual != input {
+		t.Errorf("got %q, expected %q", actual, input)
+	}
+	w := NewOrgWriter()
+	w.AddNodeWriter(revealOption{}, func(w *OrgWriter, n Node) { w.WriteKeyword(n.(revealOption).Keyword) })
+	w.AddNodeWriter(speakerNotes{}, func(w *OrgWriter, n Node) { w.WriteBlock(n.(speakerNotes).Block) })
+	if actual, err := d.Write(w); err != nil || actual != input {
+		t.Errorf("got %q (%v), expected %q", actual, err, input)
+	}
+}
+
+func TestRead(t *testing.T) {
+	c := New().Silent()
+	d, err := c.Read(OrgReader{}, strings.NewReader("* A\nsome text\n"), "./read.org")
+	if err != nil || len(d.Outline.Children) != 1 || d.Path != "./read.org" {
+		t.Errorf("unexpected document %v (%v)", d, err)
+	}
+	readErr := errors.New("broken input")
+	d, err = c.Read(OrgReader{}, iotest.ErrReader(readErr), "")
+	if !errors.Is(err, readErr) || !strings.HasPrefix(err.Error(), "could not read document") || !d.HasFatalError() {
+		t.Errorf("expected read error got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package org
 import (
 	"regexp"
 	"strings"
+	"time"
 )
 
 type Drawer struct {
@@ -16,29 +17,56 @@ type PropertyDrawer struct {
 	Pos        Position
 }
 
+// Logbook is the parsed content of a LOGBOOK drawer.
+type Logbook struct {
+	Clocks       []Clock
+	StateChanges []StateChange
+	Notes        []Note
+}
+
+// Clock is a CLOCK: line of a LOGBOOK drawer. Clocks that are still running have a zero End.
+type Clock struct {
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+}
+
+// StateChange is a logged todo state change like `- State "DONE" from "TODO" [2024-01-01 Mon 10:00]`.
+type StateChange struct {
+	From string
+	To   string
+	Time time.Time
+}
+
+// Note is a logged note like `- Note taken on [2024-01-01 Mon 10:00] \\`.
+type Note struct {
+	Time    time.Time
+	Content string
+}
+
 var beginDrawerRegexp = regexp.MustCompile(`^(\s*):(\S+):\s*$`)
 var endDrawerRegexp = regexp.MustCompile(`(?i)^(\s*):END:\s*$`)
 var propertyRegexp = regexp.MustCompile(`^(\s*):(\S+):(\s+(.*)$|$)`)
+var drawerOptionRegexp = regexp.MustCompile(`(?:^|\s)d:(\([^)]*\)|\S+)`)
+var logbookClockRegexp = regexp.MustCompile(`^\s*CLOCK:\s*\[([^\]]+)\](?:--\[([^\]]+)\])?`)
+var logbookStateRegexp = regexp.MustCompile(`^\s*- State "([^"]*)"\s+from(?:\s+"([^"]*)")?\s*\[([^\]]+)\]`)
+var logbookNoteRegexp = regexp.MustCompile(`^\s*- Note taken on \[([^\]]+)\]\s*(?:\\\\)?\s*$`)
 
 func lexDrawer(line string) (token, bool) {
 	if m := endDrawerRegexp.FindStringSubmatch(line); m != nil {
 		return token{kind: "endDrawer", lvl: len(m[1]), content: "", matches: m}, true
 	} else if m := beginDrawerRegexp.FindStringSubmatch(line); m != nil {
-		return token{kind: "beginDrawer", lvl: len(m[1]), content: strings.ToUpper(m[2]), matches: m}, true
+		return token{kind: "beginDrawer", lvl: len(m[1]), content: upper(m[2]), matches: m}, true
 	}
 	return nilToken, false
 }
 
 func (d *Document) parseDrawer(i int, parentStop stopFn) (int, Node) {
-	name := strings.ToUpper(d.tokens[i].content)
+	name := upper(d.tokens[i].content)
 	if name == "PROPERTIES" {
 		return d.parsePropertyDrawer(i, parentStop)
 	}
 	drawer, start := Drawer{Name: name}, i
-	drawer.Pos = Position{
-		StartLine:   d.tokens[start].line,
-		StartColumn: d.tokens[start].startCol,
-	}
 	i++
 	stop := func(d *Document, i int) bool {
 		if parentStop(d, i) {
@@ -62,15 +90,12 @@ func (d *Document) parseDrawer(i int, parentStop stopFn) (int, Node) {
 	if i < len(d.tokens) && d.tokens[i].kind == "endDrawer" {
 		i++
 	}
+	drawer.Pos = d.getPositionBetweenTokens(d.tokens[start], d.tokens[i-1])
 	return i - start, drawer
 }
 
 func (d *Document) parsePropertyDrawer(i int, parentStop stopFn) (int, Node) {
 	drawer, start := PropertyDrawer{}, i
-	drawer.Pos = Position{
-		StartLine:   d.tokens[start].line,
-		StartColumn: d.tokens[start].startCol,
-	}
 	i++
 	stop := func(d *Document, i int) bool {
 		return parentStop(d, i) || (d.tokens[i].kind != "text" && d.tokens[i].kind != "beginDrawer")
@@ -80,7 +105,7 @@ func (d *Document) parsePropertyDrawer(i int, parentStop stopFn) (int, Node) {
 		if m == nil {
 			return 0, nil
 		}
-		k, v := strings.ToUpper(m[2]), strings.TrimSpace(m[4])
+		k, v := upper(m[2]), strings.TrimSpace(m[4])
 		drawer.Properties = append(drawer.Properties, []string{k, v})
 	}
 	if i < len(d.tokens) && d.tokens[i].kind == "endDrawer" {
@@ -88,10 +113,7 @@ func (d *Document) parsePropertyDrawer(i int, parentStop stopFn) (int, Node) {
 	} else {
 		return 0, nil
 	}
-	if start < i && i-1 < len(d.tokens) {
-		drawer.Pos.EndLine = d.tokens[i-1].line
-		drawer.Pos.EndColumn = d.tokens[i-1].endCol
-	}
+	drawer.Pos = d.getPositionBetweenTokens(d.tokens[start], d.tokens[i-1])
 	return i - start, drawer
 }
 
@@ -107,6 +129,107 @@ func (d *PropertyDrawer) Get(key string) (string, bool) {
 	return "", false
 }
 
+// ExportsDrawer returns true if drawers with the given name should be exported according to the d export option.
+// Like in Org mode, LOGBOOK drawers are not exported by default. Only export writers (e.g. the HTMLWriter) drop
+// drawers - the OrgWriter writes all drawers as they are part of the Org mode source.
+func (d *Document) ExportsDrawer(name string) bool {
+	value := ""
+	for _, settings := range []map[string]string{d.BufferSettings, d.DefaultSettings} {
+		if m := drawerOptionRegexp.FindStringSubmatch(settings["OPTIONS"]); m != nil {
+			value = m[1]
+			break
+		}
+	}
+	switch value {
+	case "", "t":
+		return true
+	case "nil":
+		return false
+	}
+	names := strings.Fields(strings.Trim(value, "()"))
+	exclude := len(names) != 0 && names[0] == "not"
+	if exclude {
+		names = names[1:]
+	}
+	for _, n := range names {
+		if strings.EqualFold(strings.Trim(n, `"`), name) {
+			return !exclude
+		}
+	}
+	return exclude
+}
+
+// Drawer returns the first drawer with the given name directly below the headline (i.e. not inside of
+// subheadlines). Use Headline.Properties to access the PROPERTIES drawer.
+func (h Headline) Drawer(name string) (Drawer, bool) {
+	for _, n := range h.Children {
+		if d, ok := n.(Drawer); ok && strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return Drawer{}, false
+}
+
+// Logbook returns the parsed LOGBOOK drawer of the headline.
+func (h Headline) Logbook() (Logbook, bool) {
+	d, ok := h.Drawer("LOGBOOK")
+	if !ok {
+		return Logbook{}, false
+	}
+	return d.Logbook(), true
+}
+
+// Properties returns the key value pairs of all lines of the drawer that look like properties (:KEY: value).
+func (d Drawer) Properties() [][]string {
+	properties := [][]string{}
+	for _, line := range d.lines() {
+		if m := propertyRegexp.FindStringSubmatch(line); m != nil {
+			properties = append(properties, []string{upper(m[2]), strings.TrimSpace(m[4])})
+		}
+	}
+	return properties
+}
+
+// Get returns the value of the property (:KEY: value) key inside the drawer.
+func (d Drawer) Get(key string) (string, bool) {
+	for _, kvPair := range d.Properties() {
+		if kvPair[0] == strings.ToUpper(key) {
+			return kvPair[1], true
+		}
+	}
+	return "", false
+}
+
+// Logbook parses the content of the drawer as a LOGBOOK, i.e. CLOCK lines, state changes and notes.
+func (d Drawer) Logbook() Logbook {
+	logbook, lines := Logbook{}, d.lines()
+	for i := 0; i < len(lines); i++ {
+		if m := logbookClockRegexp.FindStringSubmatch(lines[i]); m != nil {
+			start, _ := parseTimeValue("["+m[1]+"]", time.Time{})
+			clock := Clock{Start: start}
+			if end, ok := parseTimeValue("["+m[2]+"]", time.Time{}); m[2] != "" && ok {
+				clock.End, clock.Duration = end, end.Sub(start)
+			}
+			logbook.Clocks = append(logbook.Clocks, clock)
+		} else if m := logbookStateRegexp.FindStringSubmatch(lines[i]); m != nil {
+			t, _ := parseTimeValue("["+m[3]+"]", time.Time{})
+			logbook.StateChanges = append(logbook.StateChanges, StateChange{From: m[2], To: m[1], Time: t})
+		} else if m := logbookNoteRegexp.FindStringSubmatch(lines[i]); m != nil {
+			t, _ := parseTimeValue("["+m[1]+"]", time.Time{})
+			content := []string{}
+			for ; i+1 < len(lines) && strings.HasPrefix(lines[i+1], "  ") && strings.TrimSpace(lines[i+1]) != ""; i++ {
+				content = append(content, strings.TrimSpace(lines[i+1]))
+			}
+			logbook.Notes = append(logbook.Notes, Note{Time: t, Content: strings.Join(content, "\n")})
+		}
+	}
+	return logbook
+}
+
+func (d Drawer) lines() []string {
+	return strings.Split(String(d.Children...), "\n")
+}
+
 func (n Drawer) String() string         { return String(n) }
 func (n PropertyDrawer) String() string { return String(n) }
 
@@ -0,0 +1,70 @@
+package org
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportsDrawer(t *testing.T) {
+	input := strings.Join([]string{
+		"* Task",
+		":LOGBOOK:",
+		"- State \"DONE\" from \"TODO\" [2024-01-02 Tue 11:00]",
+		"CLOCK: [2024-01-01 Mon 10:00]--[2024-01-01 Mon 11:30] =>  1:30",
+		"- Note taken on [2024-01-01 Mon 12:00] \\\\",
+		"  a note",
+		"  on two lines",
+		":END:",
+		":NOTES:",
+		"notes",
+		":END:",
+	}, "\n")
+	for _, test := range []struct {
+		options string
+		logbook bool
+		notes   bool
+	}{
+		{"", false, true},
+		{"d:t", true, true},
+		{"d:nil", false, false},
+		{`d:("LOGBOOK")`, true, false},
+		{`d:(not "NOTES")`, true, false},
+		{"toc:nil d:(notes) num:t", false, true},
+	} {
+		d := New().Silent().Parse(strings.NewReader("#+OPTIONS: "+test.options+"\n"+input), "./drawers.org")
+		if logbook, notes := d.ExportsDrawer("LOGBOOK"), d.ExportsDrawer("NOTES"); logbook != test.logbook || notes != test.notes {
+			t.Errorf("%q: got LOGBOOK %v and NOTES %v, expected %v and %v", test.options, logbook, notes, test.logbook, test.notes)
+		}
+		html, err := d.Write(NewHTMLWriter())
+		if err != nil || strings.Contains(html, "CLOCK") != test.logbook || strings.Contains(html, "notes") != test.notes {
+			t.Errorf("%q: got html %q (error: %v)", test.options, html, err)
+		}
+		if org, err := d.Write(NewOrgWriter()); err != nil || !strings.Contains(org, ":LOGBOOK:") || !strings.Contains(org, ":NOTES:") {
+			t.Errorf("%q: expected the OrgWriter to keep all drawers, got %q (error: %v)", test.options, org, err)
+		}
+	}
+
+	d := New().Silent().Parse(strings.NewReader(input), "./drawers.org")
+	h := *d.Outline.Children[0].Headline
+	if drawer, ok := h.Drawer("notes"); !ok || strings.TrimSpace(String(drawer.Children...)) != "notes" {
+		t.Errorf("got NOTES drawer %#v %v", drawer, ok)
+	}
+	logbook, ok := h.Logbook()
+	at := func(day, hour, minute int) time.Time { return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC) }
+	if !ok || len(logbook.Clocks) != 1 || len(logbook.StateChanges) != 1 || len(logbook.Notes) != 1 {
+		t.Fatalf("got logbook %#v %v", logbook, ok)
+	}
+	if c := logbook.Clocks[0]; !c.Start.Equal(at(1, 10, 0)) || !c.End.Equal(at(1, 11, 30)) || c.Duration != 90*time.Minute {
+		t.Errorf("got clock %#v", c)
+	}
+	if s := logbook.StateChanges[0]; s.From != "TODO" || s.To != "DONE" || !s.Time.Equal(at(2, 11, 0)) {
+		t.Errorf("got state change %#v", s)
+	}
+	if n := logbook.Notes[0]; n.Content != "a note\non two lines" || !n.Time.Equal(at(1, 12, 0)) {
+		t.Errorf("got note %#v", n)
+	}
+	if _, ok := (Headline{}).Logbook(); ok {
+		t.Errorf("expected headline without LOGBOOK not to have a logbook")
+	}
+}
@@ -0,0 +1,130 @@
+package org
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is an Org mode duration like `1:30` or `2d 4h` - e.g. the value of an Effort property or a clocked time.
+// Durations can be added and subtracted like time.Duration values.
+type Duration time.Duration
+
+// Duration formats for Duration.Format - see org-duration-format.
+const (
+	DurationHMM     = "h:mm"    // DurationHMM formats durations as hours and minutes, e.g. 27:30.
+	DurationHMMSS   = "h:mm:ss" // DurationHMMSS formats durations as hours, minutes and seconds, e.g. 27:30:00.
+	DurationDaysHMM = "d h:mm"  // DurationDaysHMM formats durations as days, hours and minutes, e.g. 1d 3:30. The default.
+	DurationUnits   = "units"   // DurationUnits formats durations with units, e.g. 1d 3h 30min.
+)
+
+// durationUnits are the units of Org mode durations in minutes - see org-duration-units.
+var durationUnits = map[string]float64{"min": 1, "h": 60, "d": 60 * 24, "w": 60 * 24 * 7, "m": 60 * 24 * 30, "y": 60 * 24 * 365.25}
+
+var durationHMMRegexp = regexp.MustCompile(`^(\d+):(\d\d)(?::(\d\d))?$`)
+var durationUnitRegexp = regexp.MustCompile(`^(\d+(?:\.\d*)?|\.\d+)\s*(min|h|d|w|m|y)\s*`)
+
+// ParseDuration parses an Org mode duration. Supported are the h:mm and h:mm:ss formats (`1:30`), durations with
+// units (`2d 4h`, `1.5h`, `3d 1:30`) and plain numbers of minutes (`90`).
+func ParseDuration(s string) (Duration, error) {
+	s = strings.TrimSpace(s)
+	if minutes, err := strconv.ParseFloat(s, 64); err == nil {
+		return minutesToDuration(minutes), nil
+	}
+	minutes, rest := 0.0, s
+	for rest != "" {
+		m := durationUnitRegexp.FindStringSubmatch(rest)
+		if m == nil {
+			break
+		}
+		v, _ := strconv.ParseFloat(m[1], 64)
+		minutes, rest = minutes+v*durationUnits[m[2]], rest[len(m[0]):]
+	}
+	if rest != "" {
+		m := durationHMMRegexp.FindStringSubmatch(rest)
+		if m == nil {
+			return 0, fmt.Errorf("could not parse duration %q", s)
+		}
+		h, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		sec, _ := strconv.Atoi(m[3])
+		minutes += float64(h*60+min) + float64(sec)/60
+	} else if s == "" {
+		return 0, fmt.Errorf("could not parse duration %q", s)
+	}
+	return minutesToDuration(minutes), nil
+}
+
+func minutesToDuration(minutes float64) Duration {
+	return Duration(math.Round(minutes*float64(time.Minute)/float64(time.Second)) * float64(time.Second))
+}
+
+// Minutes returns the duration as a floating point number of minutes.
+func (d Duration) Minutes() float64 { return time.Duration(d).Minutes() }
+
+// Hours returns the duration as a floating point number of hours.
+func (d Duration) Hours() float64 { return time.Duration(d).Hours() }
+
+// String returns the duration in the default format of Org mode - see DurationDaysHMM.
+func (d Duration) String() string { return d.Format(DurationDaysHMM) }
+
+// Format returns the duration in the given format - one of DurationHMM, DurationHMMSS, DurationDaysHMM and
+// DurationUnits. Durations are rounded to minutes unless the format includes seconds.
+func (d Duration) Format(format string) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	seconds := int64(time.Duration(d).Round(time.Second) / time.Second)
+	if format != DurationHMMSS {
+		seconds = int64(time.Duration(d).Round(time.Minute) / time.Second)
+	}
+	days, hours, minutes := seconds/(24*3600), seconds/3600, seconds/60%60
+	switch format {
+	case DurationHMMSS:
+		return fmt.Sprintf("%s%d:%02d:%02d", sign, hours, minutes, seconds%60)
+	case DurationDaysHMM:
+		if days != 0 {
+			return fmt.Sprintf("%s%dd %d:%02d", sign, days, hours%24, minutes)
+		}
+		return fmt.Sprintf("%s%d:%02d", sign, hours, minutes)
+	case DurationUnits:
+		parts := []string{}
+		for _, p := range []struct {
+			v    int64
+			unit string
+		}{{days, "d"}, {hours % 24, "h"}, {minutes, "min"}} {
+			if p.v != 0 {
+				parts = append(parts, fmt.Sprintf("%d%s", p.v, p.unit))
+			}
+		}
+		if len(parts) == 0 {
+			return "0min"
+		}
+		return sign + strings.Join(parts, " ")
+	default:
+		return fmt.Sprintf("%s%d:%02d", sign, hours, minutes)
+	}
+}
+
+// Effort returns the parsed Effort property of the headline. ok is false if the headline has no valid Effort.
+func (h Headline) Effort() (effort Duration, ok bool) {
+	v, ok := h.Properties.Get("EFFORT")
+	if !ok {
+		return 0, false
+	}
+	effort, err := ParseDuration(v)
+	return effort, err == nil
+}
+
+// Clocked returns the total duration of the finished clocks of the logbook.
+func (l Logbook) Clocked() Duration {
+	total := Duration(0)
+	for _, c := range l.Clocks {
+		total += Duration(c.Duration)
+	}
+	return total
+}
@@ -0,0 +1,78 @@
+package org
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	for _, c := range []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"1:30", 90 * time.Minute},
+		{"0:45:30", 45*time.Minute + 30*time.Second},
+		{"2d 4h", 52 * time.Hour},
+		{"1.5h", 90 * time.Minute},
+		{"3d 1:30", 73*time.Hour + 30*time.Minute},
+		{"1w 20min", 7*24*time.Hour + 20*time.Minute},
+		{"90", 90 * time.Minute},
+	} {
+		d, err := ParseDuration(c.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", c.input, err)
+		} else if time.Duration(d) != c.expected {
+			t.Errorf("%q: expected %s got %s", c.input, c.expected, time.Duration(d))
+		}
+	}
+	for _, input := range []string{"", "1:3", "2 days", "4h foo"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("%q: expected error", input)
+		}
+	}
+}
+
+func TestDurationFormat(t *testing.T) {
+	d := Duration(27*time.Hour + 30*time.Minute + 10*time.Second)
+	for format, expected := range map[string]string{
+		DurationHMM:     "27:30",
+		DurationHMMSS:   "27:30:10",
+		DurationDaysHMM: "1d 3:30",
+		DurationUnits:   "1d 3h 30min",
+	} {
+		if actual := d.Format(format); actual != expected {
+			t.Errorf("%s: expected %q got %q", format, expected, actual)
+		}
+	}
+	if actual := (Duration(45*time.Minute) - d).String(); actual != "-1d 2:45" {
+		t.Errorf("expected negative duration got %q", actual)
+	}
+}
+
+func TestEffortAndClocked(t *testing.T) {
+	input := `* Task
+:PROPERTIES:
+:Effort: 2h 30min
+:END:
+:LOGBOOK:
+CLOCK: [2024-01-01 Mon 10:00]--[2024-01-01 Mon 11:15] =>  1:15
+CLOCK: [2024-01-02 Tue 09:00]--[2024-01-02 Tue 09:30] =>  0:30
+CLOCK: [2024-01-03 Wed 09:00]
+:END:
+`
+	d := New().Silent().Parse(strings.NewReader(input), "./effort.org")
+	h := findHeadline(t, d, "Task")
+	if effort, ok := h.Effort(); !ok || effort.String() != "2:30" {
+		t.Errorf("expected effort 2:30 got %s (%v)", effort, ok)
+	}
+	var logbook Logbook
+	walkNodes(d.Nodes, func(n Node) {
+		if drawer, ok := n.(Drawer); ok && drawer.Name == "LOGBOOK" {
+			logbook = drawer.Logbook()
+		}
+	})
+	if clocked := logbook.Clocked(); clocked.String() != "1:45" {
+		t.Errorf("expected clocked 1:45 got %s", clocked)
+	}
+}
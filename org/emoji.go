@@ -0,0 +1,79 @@
+package org
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Emoji is an emoji shortcode like :smile: - see Configuration.EmojiShortcodes.
+// Emoji are written by the HTMLWriter and OrgWriter via WriteCustomNode.
+type Emoji struct {
+	Shortcode string // Shortcode is the name of the emoji without the surrounding colons, e.g. "smile".
+	Unicode   string // Unicode is the emoji character of the shortcode - empty for custom emoji (see HTMLWriter.EmojiImageURL).
+	Pos       Position
+}
+
+var emojiRegexp = regexp.MustCompile(`^:([a-z0-9_+\-]+):`)
+
+// DefaultEmojiShortcodes contains a selection of common shortcodes - see WithEmojiShortcodes.
+var DefaultEmojiShortcodes = map[string]string{
+	"+1":               "👍",
+	"-1":               "👎",
+	"100":              "💯",
+	"blush":            "😊",
+	"bug":              "🐛",
+	"check":            "✔️",
+	"clap":             "👏",
+	"confused":         "😕",
+	"cry":              "😢",
+	"eyes":             "👀",
+	"fire":             "🔥",
+	"grin":             "😁",
+	"heart":            "❤️",
+	"joy":              "😂",
+	"laughing":         "😆",
+	"memo":             "📝",
+	"ok_hand":          "👌",
+	"pray":             "🙏",
+	"question":         "❓",
+	"rocket":           "🚀",
+	"slightly_smiling": "🙂",
+	"smile":            "😄",
+	"sparkles":         "✨",
+	"star":             "⭐",
+	"sweat_smile":      "😅",
+	"tada":             "🎉",
+	"thinking":         "🤔",
+	"warning":          "⚠️",
+	"wave":             "👋",
+	"wink":             "😉",
+	"x":                "❌",
+}
+
+func (d *Document) parseEmojiWithPos(input string, start int, startLine, startColumn int) (int, Node) {
+	if d.EmojiShortcodes == nil || !isEmojiBorder(prevRune(input, start)) {
+		return 0, nil
+	}
+	m := emojiRegexp.FindStringSubmatch(input[start:])
+	if m == nil {
+		return 0, nil
+	}
+	end := start + len(m[0])
+	character, ok := d.EmojiShortcodes[m[1]]
+	if r, _ := utf8.DecodeRuneInString(input[end:]); !ok || !isEmojiBorder(r) {
+		return 0, nil
+	}
+	pos := d.positionFromChars(input, startLine, startColumn, start, end)
+	return len(m[0]), Emoji{Shortcode: m[1], Unicode: character, Pos: pos}
+}
+
+// isEmojiBorder returns true if r may directly precede or follow a shortcode, i.e. is not part of a word.
+func isEmojiBorder(r rune) bool {
+	return r == utf8.RuneError || !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func (n Emoji) String() string          { return String(n) }
+func (n Emoji) Copy() Node              { return n }
+func (n Emoji) Range(f func(Node) bool) {}
+func (n Emoji) Position() Position      { return n.Pos }
@@ -0,0 +1,144 @@
+package org
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encodingVersion is the version of the format written by Encode. It must be incremented whenever the encoded
+// nodes change (e.g. a field is added to a node) so that cached documents of older versions are rejected by Decode.
+const encodingVersion = 7
+
+// encodedDocument contains the encoded fields of a Document. Outline and NamedNodes are rebuilt from Nodes by Decode.
+type encodedDocument struct {
+	Version        int
+	Path           string
+	Input          string
+	FirstLine      int
+	SourceLines    map[Position][2]int
+	TopLevelLines  [][2]int
+	Dependencies   []Dependency
+	Macros         map[string]string
+	Links          map[string]string
+	Nodes          []Node
+	BufferSettings map[string]string
+	Errors         []*ParseError
+	FatalError     *ParseError
+	Pos            Position
+}
+
+// encodedCause replaces the Cause of encoded ParseErrors - only the message of the original cause is kept.
+type encodedCause string
+
+func (e encodedCause) Error() string { return string(e) }
+
+func init() {
+	for _, n := range []Node{Block{}, Comment{}, DescriptiveListItem{}, Drawer{}, Emoji{}, Emphasis{}, Example{}, ExplicitLineBreak{},
+		FootnoteDefinition{}, FootnoteLink{}, Hashtag{}, Headline{}, HorizontalRule{}, Include{}, InlineBlock{}, Keyword{}, LatexBlock{},
+		LatexFragment{}, LineBreak{}, List{}, ListItem{}, Macro{}, Mention{}, NodeWithMeta{}, NodeWithName{}, Paragraph{},
+		PropertyDrawer{}, RegularLink{}, Result{}, StatisticToken{}, Table{}, Text{}, Timestamp{}} {
+		gob.Register(n)
+	}
+	gob.Register(encodedCause(""))
+}
+
+// GobEncode implements gob.GobEncoder. Tokens are only meaningful during parsing and are not encoded.
+func (t token) GobEncode() ([]byte, error) { return nil, nil }
+
+// GobDecode implements gob.GobDecoder. See GobEncode.
+func (t *token) GobDecode([]byte) error { return nil }
+
+// Encode writes the parse results of the document (Nodes, BufferSettings, Errors, ...) to w in a compact binary format
+// (see encoding/gob) - e.g. to cache them keyed by a hash of the parse input and skip parsing unchanged files.
+// The Configuration of the document is not encoded. Custom nodes (e.g. returned by ResolveLink) must be registered
+// with gob.Register. The causes of errors are replaced by their messages. Lazy paragraphs are parsed (see ParseInline).
+func (d *Document) Encode(w io.Writer) error {
+	d.ParseInline()
+	e := encodedDocument{
+		Version:        encodingVersion,
+		Path:           d.Path,
+		Input:          d.input,
+		FirstLine:      d.firstLine,
+		SourceLines:    d.sourceLines,
+		TopLevelLines:  d.topLevelLines,
+		Dependencies:   d.dependencies,
+		Macros:         d.Macros,
+		Links:          d.Links,
+		Nodes:          d.Nodes,
+		BufferSettings: d.BufferSettings,
+		FatalError:     encodableError(d.FatalError),
+		Pos:            d.Pos,
+	}
+	for _, err := range d.Errors {
+		e.Errors = append(e.Errors, encodableError(err))
+	}
+	if err := gob.NewEncoder(w).Encode(e); err != nil {
+		return fmt.Errorf("could not encode document: %w", err)
+	}
+	return nil
+}
+
+// Decode reads a document written by Encode from r. The returned document uses the configuration c - which should
+// match the configuration the document was originally parsed with. Documents encoded by a different version of this
+// package are rejected.
+func (c *Configuration) Decode(r io.Reader) (*Document, error) {
+	e := encodedDocument{}
+	if err := gob.NewDecoder(r).Decode(&e); err != nil {
+		return nil, fmt.Errorf("could not decode document: %w", err)
+	} else if e.Version != encodingVersion {
+		return nil, fmt.Errorf("could not decode document: unsupported version %d (expected %d)", e.Version, encodingVersion)
+	}
+	d := c.newDocument(e.Path)
+	if err := d.readLines(strings.NewReader(e.Input)); err != nil {
+		return nil, fmt.Errorf("could not decode document: %w", err)
+	}
+	d.firstLine, d.sourceLines, d.topLevelLines, d.dependencies = e.FirstLine, e.SourceLines, e.TopLevelLines, e.Dependencies
+	d.Nodes, d.Errors, d.FatalError, d.Pos = e.Nodes, e.Errors, e.FatalError, e.Pos
+	if d.sourceLines == nil {
+		d.sourceLines = map[Position][2]int{}
+	}
+	if e.Macros != nil {
+		d.Macros = e.Macros
+	}
+	if e.Links != nil {
+		d.Links = e.Links
+	}
+	if e.BufferSettings != nil {
+		d.BufferSettings = e.BufferSettings
+	}
+	var restore func(n Node) Node
+	restore = func(n Node) Node {
+		switch n := n.(type) {
+		case Include:
+			n.Resolve = d.includeResolver(n.Keyword)
+			return n
+		case Table:
+			for i := range n.Rows {
+				for j := range n.Rows[i].Columns {
+					if j < len(n.ColumnInfos) {
+						n.Rows[i].Columns[j].ColumnInfo = &n.ColumnInfos[j] // gob does not preserve shared pointers
+					}
+				}
+			}
+		}
+		return mapChildren(n, restore)
+	}
+	for i, n := range d.Nodes {
+		d.Nodes[i] = restore(n)
+	}
+	addNamedNodes(d.NamedNodes, d.Nodes)
+	d.rebuildOutline()
+	return d, nil
+}
+
+// encodableError returns a copy of err whose cause can be encoded.
+func encodableError(err *ParseError) *ParseError {
+	if err == nil || err.Cause == nil {
+		return err
+	}
+	copied := *err
+	copied.Cause = encodedCause(err.Cause.Error())
+	return &copied
+}
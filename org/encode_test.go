@@ -0,0 +1,44 @@
+package org
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	for _, path := range orgTestFiles() {
+		t.Run(path, func(t *testing.T) {
+			d := New().Silent().Parse(strings.NewReader(fileString(t, path)), path)
+			buf := &bytes.Buffer{}
+			if err := d.Encode(buf); err != nil {
+				t.Fatalf("could not encode: %s", err)
+			}
+			decoded, err := New().Silent().Decode(buf)
+			if err != nil {
+				t.Fatalf("could not decode: %s", err)
+			}
+			if actual, expected := dumpDocument(decoded), dumpDocument(d); actual != expected {
+				t.Errorf("decoded document differs:\n%s", diff(actual, expected))
+			}
+			actual, _ := decoded.Write(NewHTMLWriter())
+			if expected, _ := d.Write(NewHTMLWriter()); actual != expected {
+				t.Errorf("decoded html differs:\n%s", diff(actual, expected))
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := New().Decode(strings.NewReader("not a document")); err == nil {
+		t.Errorf("expected error for invalid input")
+	}
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(encodedDocument{Version: encodingVersion + 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New().Decode(buf); err == nil || !strings.Contains(err.Error(), "unsupported version") {
+		t.Errorf("expected error for unsupported version got %v", err)
+	}
+}
@@ -15,17 +15,47 @@ const (
 	ErrorTypeInvalidStructure ErrorType = "invalid_structure"
 	ErrorTypeDuplicateNode    ErrorType = "duplicate_node"
 	ErrorTypeMissingNode      ErrorType = "missing_node"
+	ErrorTypeUnusedNode       ErrorType = "unused_node"
 	ErrorTypeValidation       ErrorType = "validation_error"
 	ErrorTypeTokenization     ErrorType = "tokenization_error"
 	ErrorTypeIO               ErrorType = "io_error"
+	ErrorTypeCanceled         ErrorType = "canceled"      // ErrorTypeCanceled is used if parsing was aborted by a context - see ParseContext.
+	ErrorTypeInclude          ErrorType = "include_error" // ErrorTypeInclude is used for include cycles and includes nested too deeply.
 )
 
+// Severity represents how severe a ParseError is. Severities are ordered, i.e. SeverityInfo < SeverityWarning <
+// SeverityError < SeverityFatal.
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota - 2 // SeverityInfo is used for stylistic diagnostics, e.g. trailing whitespace.
+	SeverityWarning                     // SeverityWarning is used for diagnostics that do not prevent a correct export, e.g. unused footnotes.
+	SeverityError                       // SeverityError is the default severity of a ParseError.
+	SeverityFatal                       // SeverityFatal is used for errors that prevented parsing. See Document.FatalError.
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityFatal:
+		return "fatal"
+	default:
+		return "unknown"
+	}
+}
+
 // ParseError is a structured error with detailed position information.
 // It provides precise location tracking for syntax and parsing errors.
 type ParseError struct {
-	Type    ErrorType
-	Message string
-	File    string
+	Type     ErrorType
+	Severity Severity
+	Message  string
+	File     string
 
 	// Position information
 	StartLine int
@@ -82,7 +112,7 @@ func (e *ParseError) locationString() string {
 
 // String provides a detailed string representation including all fields.
 func (e *ParseError) String() string {
-	s := fmt.Sprintf("%s (type: %s)", e.Error(), e.Type)
+	s := fmt.Sprintf("%s (type: %s, severity: %s)", e.Error(), e.Type, e.Severity)
 	if e.Cause != nil {
 		s += fmt.Sprintf("\n  caused by: %v", e.Cause)
 	}
@@ -107,12 +137,45 @@ func NewParseError(typ ErrorType, message, file string, pos Position, tok token,
 // AddError adds a new parsing error to the document with detailed position info.
 // This is the preferred method for reporting errors during parsing.
 func (d *Document) AddError(typ ErrorType, message string, pos Position, tok token, cause error) {
+	d.addError(SeverityError, typ, message, pos, tok, cause)
+}
+
+// addWarning adds a new parsing error with SeverityWarning to the document - i.e. an error the parser recovered from.
+func (d *Document) addWarning(typ ErrorType, message string, pos Position, tok token, cause error) {
+	d.addError(SeverityWarning, typ, message, pos, tok, cause)
+}
+
+// abortParse is used to abort parsing - see Configuration.Strict, Configuration.MaxErrors and ParseContext.
+type abortParse struct{ err *ParseError }
+
+func (d *Document) addError(severity Severity, typ ErrorType, message string, pos Position, tok token, cause error) {
 	if d.Errors == nil {
 		d.Errors = make([]*ParseError, 0)
 	}
 
 	err := NewParseError(typ, message, d.Path, pos, tok, cause)
+	err.Severity = severity
 	d.Errors = append(d.Errors, err)
+	if d.parsing && d.Strict && severity >= SeverityError {
+		panic(abortParse{err})
+	} else if d.parsing && d.MaxErrors > 0 && len(d.Errors) >= d.MaxErrors {
+		tooMany := NewParseError(ErrorTypeValidation, fmt.Sprintf("too many errors (%d)", len(d.Errors)), d.Path, pos, tok, nil)
+		tooMany.Severity = SeverityFatal
+		d.Errors = append(d.Errors, tooMany)
+		panic(abortParse{tooMany})
+	}
+}
+
+// checkContext aborts parsing if the context passed to ParseContext is done.
+func (d *Document) checkContext(pos Position, tok token) {
+	if d.ctx == nil || !d.parsing {
+		return
+	} else if err := d.ctx.Err(); err != nil {
+		canceled := NewParseError(ErrorTypeCanceled, "parsing canceled", d.Path, pos, tok, err)
+		canceled.Severity = SeverityFatal
+		d.Errors = append(d.Errors, canceled)
+		panic(abortParse{canceled})
+	}
 }
 
 // HasErrors returns true if the document contains any parsing errors.
@@ -129,6 +192,7 @@ func (d *Document) HasFatalError() bool {
 // This is used for unrecoverable errors where the parser cannot continue.
 func (d *Document) AddFatalError(typ ErrorType, message string, pos Position, tok token, cause error) {
 	err := NewParseError(typ, message, d.Path, pos, tok, cause)
+	err.Severity = SeverityFatal
 	d.FatalError = err
 	// Also add to Errors slice for completeness
 	if d.Errors == nil {
@@ -153,6 +217,17 @@ func (d *Document) ErrorCount() int {
 	return len(d.Errors)
 }
 
+// GetErrorsBySeverity returns all errors with at least the specified severity.
+func (d *Document) GetErrorsBySeverity(severity Severity) []*ParseError {
+	result := make([]*ParseError, 0)
+	for _, err := range d.Errors {
+		if err.Severity >= severity {
+			result = append(result, err)
+		}
+	}
+	return result
+}
+
 // GetErrorByType returns all errors of the specified type.
 func (d *Document) GetErrorByType(typ ErrorType) []*ParseError {
 	result := make([]*ParseError, 0)
@@ -166,12 +241,19 @@ func (d *Document) GetErrorByType(typ ErrorType) []*ParseError {
 
 // getPositionFromToken extracts a Position from a token.
 // This helper ensures consistent Position creation from tokens.
-func getPositionFromToken(tok token) Position {
+func (d *Document) getPositionFromToken(tok token) Position {
+	return d.getPositionBetweenTokens(tok, tok)
+}
+
+// getPositionBetweenTokens returns the Position spanning from the start of the start token to the end of the end token.
+func (d *Document) getPositionBetweenTokens(start, end token) Position {
 	return Position{
-		StartLine:   tok.line,
-		StartColumn: tok.startCol,
-		EndLine:     tok.line,
-		EndColumn:   tok.endCol,
+		StartLine:   start.line,
+		StartColumn: d.column(start.line, start.startCol),
+		EndLine:     end.line,
+		EndColumn:   d.column(end.line, end.endCol),
+		StartOffset: start.offset + start.startCol,
+		EndOffset:   end.offset + end.endCol,
 	}
 }
 
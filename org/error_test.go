@@ -0,0 +1,150 @@
+package org
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFailOnSeverity(t *testing.T) {
+	input := "#+BEGIN_SRC go\nunterminated block"
+	configuration := New().Silent()
+	d := configuration.Parse(strings.NewReader(input), "./failOnSeverityTests.org")
+	if errs := d.GetErrorsBySeverity(SeverityError); len(errs) != 1 || errs[0].Severity != SeverityError {
+		t.Fatalf("expected one error, got %v", d.Errors)
+	}
+	if _, err := d.Write(NewOrgWriter()); err != nil {
+		t.Errorf("expected write to succeed with FailOnSeverity %s: %s", configuration.FailOnSeverity, err)
+	}
+	configuration.FailOnSeverity = SeverityError
+	if _, err := d.Write(NewOrgWriter()); err == nil || !strings.Contains(err.Error(), "unterminated block") {
+		t.Errorf("expected write to fail with FailOnSeverity %s: %v", configuration.FailOnSeverity, err)
+	}
+}
+
+func TestStrictAndMaxErrors(t *testing.T) {
+	input := ":PROPERTIES:\nx\n:END:\n* a\n:PROPERTIES:\nx\n:END:\n"
+	configuration := New().Silent()
+	if d := configuration.Parse(strings.NewReader(input), "./strictTests.org"); d.HasFatalError() || len(d.Errors) != 4 {
+		t.Fatalf("expected 4 errors and no fatal error, got %v", d.Errors)
+	}
+	configuration.MaxErrors = 2
+	d := configuration.Parse(strings.NewReader(input), "./strictTests.org")
+	if !d.HasFatalError() || len(d.Errors) != 3 || !strings.Contains(d.FatalError.Error(), "too many errors (2)") {
+		t.Errorf("expected parsing to abort after 2 errors, got %v", d.Errors)
+	}
+	configuration.MaxErrors, configuration.Strict = 0, true
+	if d := configuration.Parse(strings.NewReader(input), "./strictTests.org"); d.HasFatalError() || len(d.Errors) != 4 {
+		t.Errorf("expected warnings not to abort parsing, got %v", d.Errors)
+	}
+	d = configuration.Parse(strings.NewReader(input+"#+BEGIN_SRC go\nx\n* b\n#+BEGIN_QUOTE\n"), "./strictTests.org")
+	if !d.HasFatalError() || len(d.Errors) != 5 || d.FatalError != d.Errors[4] || d.FatalError.Severity != SeverityError {
+		t.Errorf("expected parsing to abort on the first error, got %v", d.Errors)
+	}
+	if _, err := d.Write(NewOrgWriter()); err != d.FatalError {
+		t.Errorf("expected write to return the fatal error, got %v", err)
+	}
+}
+
+type cancelingReader struct {
+	lines  int
+	cancel func()
+}
+
+func (r *cancelingReader) Read(p []byte) (int, error) {
+	if r.lines--; r.lines == 0 {
+		r.cancel()
+	}
+	return copy(p, "* headline\ntext\n"), nil
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := New().Silent().ParseContext(ctx, &cancelingReader{100, cancel}, "./upload.org")
+	if !d.HasFatalError() || d.FatalError.Type != ErrorTypeCanceled || !errors.Is(d.FatalError, context.Canceled) || d.Nodes != nil {
+		t.Errorf("expected canceled parse got %v", d.FatalError)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if d := New().Silent().ParseContext(ctx, strings.NewReader("* A"), ""); !errors.Is(d.FatalError, context.DeadlineExceeded) || d.FatalError.Type != ErrorTypeCanceled {
+		t.Errorf("expected deadline exceeded got %v", d.FatalError)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if d := New().Silent().ParseContext(ctx, strings.NewReader("* A\ntext\n"), ""); d.FatalError == nil || d.FatalError.Type != ErrorTypeCanceled || !errors.Is(d.FatalError, context.Canceled) {
+		t.Errorf("expected canceled parse got %v", d.FatalError)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	d = New().Silent().ParseContext(ctx, strings.NewReader("* A\n"), "")
+	cancel()
+	if d.HasFatalError() || len(d.Outline.Children) != 1 {
+		t.Fatalf("unexpected error %v", d.FatalError)
+	}
+	if d.Reparse(TextEdit{StartLine: 1, EndLine: 1, NewText: "* B\n"}); d.HasFatalError() || len(d.Outline.Children) != 2 {
+		t.Errorf("expected reparse to ignore the context of the parse got %v", d.FatalError)
+	}
+}
+
+func TestIncludeCycles(t *testing.T) {
+	files := map[string]string{
+		"a.org":    "#+TITLE: a\n#+SETUPFILE: b.org\n",
+		"b.org":    "#+AUTHOR: b\n#+SETUPFILE: a.org\n",
+		"self.org": "#+SETUPFILE: self.org\n",
+		"1.org":    "#+SETUPFILE: 2.org\n",
+		"2.org":    "#+SETUPFILE: 3.org\n",
+		"3.org":    "#+OPTIONS: toc:nil\n",
+	}
+	configuration := New().Silent()
+	configuration.ReadFile = func(path string) ([]byte, error) {
+		if content, ok := files[path]; ok {
+			return []byte(content), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	parse := func(path string) *Document {
+		return configuration.Parse(strings.NewReader(files[path]), path)
+	}
+
+	if d := parse("self.org"); len(d.Errors) != 1 || d.Errors[0].Type != ErrorTypeInclude || d.Errors[0].StartLine != 0 {
+		t.Errorf("expected include cycle error at the keyword got %v", d.Errors)
+	}
+	d := parse("a.org")
+	if len(d.Errors) != 1 || d.Errors[0].File != "b.org" || d.Errors[0].StartLine != 1 {
+		t.Fatalf("expected include cycle error at the keyword of b.org got %v", d.Errors)
+	} else if !strings.Contains(d.Errors[0].Cause.Error(), "a.org -> b.org -> a.org") {
+		t.Errorf("expected cycle in cause got %v", d.Errors[0].Cause)
+	}
+
+	if d := parse("1.org"); d.HasErrors() || d.BufferSettings["OPTIONS"] != "toc:nil" {
+		t.Errorf("expected nested setup files to be loaded got %v %v", d.Errors, d.BufferSettings)
+	}
+	configuration.MaxIncludeDepth = 1
+	if d := parse("1.org"); len(d.Errors) != 1 || d.Errors[0].File != "2.org" || d.Errors[0].Message != "include depth exceeded" {
+		t.Errorf("expected include depth error in 2.org got %v", d.Errors)
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	input := "#+BEGIN_EXAMPLE\n" + long + "\n#+END_EXAMPLE\n"
+	configuration := New().Silent()
+	d := configuration.Parse(strings.NewReader(input), "")
+	if d.HasErrors() || !strings.Contains(String(d.Nodes...), long) {
+		t.Errorf("expected long line to be parsed, got %v", d.Errors)
+	}
+	configuration.MaxLineLength = 1024
+	d = configuration.Parse(strings.NewReader(input), "")
+	if !d.HasFatalError() || d.FatalError.Message != "line too long" || !errors.Is(d.FatalError, bufio.ErrTooLong) || d.FatalError.StartLine != 1 {
+		t.Errorf("expected line too long error on line 1, got %v", d.FatalError)
+	}
+	if d := configuration.Parse(strings.NewReader(long[:1024]+"\r\n"), ""); d.HasErrors() {
+		t.Errorf("expected line within limit to be parsed, got %v", d.Errors)
+	}
+}
@@ -0,0 +1,181 @@
+package org
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ExportFilter rewrites the node at the cursor just before it is written - see Configuration.AddExportFilter.
+// Like org-export filters, it can replace the node (Cursor.Replace) or drop it (Cursor.Delete).
+type ExportFilter func(c *Cursor, d *Document)
+
+// AddExportFilter registers f for all nodes with the same type as node, e.g. c.AddExportFilter(org.Drawer{}, f).
+// Filters run on a copy of the nodes when the document is written (see Document.Write) - children are filtered before
+// their parents and filters of the same type run in the order they were added. The writer is passed a copy of the
+// document with the filtered nodes and their Outline, i.e. the table of contents only lists the written headlines.
+func (c *Configuration) AddExportFilter(node Node, f ExportFilter) *Configuration {
+	if c.ExportFilters == nil {
+		c.ExportFilters = map[reflect.Type][]ExportFilter{}
+	}
+	typ := reflect.TypeOf(node)
+	c.ExportFilters[typ] = append(c.ExportFilters[typ], f)
+	return c
+}
+
+// exported returns the document as it is written, i.e. a copy with the nodes rewritten by the ExportFilters and
+// their Outline (e.g. for the table of contents). Without ExportFilters the document itself is returned.
+func (d *Document) exported() *Document {
+	if len(d.ExportFilters) == 0 {
+		return d
+	}
+	exported := *d
+	exported.Nodes = Walk(d, Visitor{Exit: func(c *Cursor) {
+		for _, f := range d.ExportFilters[reflect.TypeOf(c.Node())] {
+			if f(c, d); c.Node() == nil {
+				return
+			}
+		}
+	}})
+	exported.Outline, exported.index = outlineOf(&exported, exported.Nodes), &outlineIndexCache{}
+	return &exported
+}
+
+// ExportSubtree exports the subtree of the headline h as a standalone document using w.
+//
+// The title of the headline becomes the title of the exported document and its children are exported
+// with headline levels shifted so that direct child headlines become top-level headlines.
+// Properties prefixed with EXPORT_ override the corresponding buffer settings (e.g. EXPORT_TITLE overrides #+TITLE)
+// and EXPORT_OPTIONS take precedence over the #+OPTIONS of the document.
+// See Headline.ExportFileName for the EXPORT_FILE_NAME property.
+func (d *Document) ExportSubtree(h Headline, w Writer) (string, error) {
+	nodes := shiftHeadlines(CopyNodes(h.Children), -h.Lvl)
+	nodes = append(nodes, d.missingFootnoteDefinitions(nodes)...)
+	options, _ := h.Properties.Get("EXPORT_OPTIONS")
+	subtree := d.fragment(nodes, options)
+	subtree.BufferSettings["TITLE"] = strings.TrimSpace(String(h.Title...))
+	if h.Properties != nil {
+		for _, kv := range h.Properties.Properties {
+			if key := strings.TrimPrefix(kv[0], "EXPORT_"); key != kv[0] && key != "OPTIONS" && key != "FILE_NAME" {
+				subtree.BufferSettings[key] = kv[1]
+			}
+		}
+	}
+	subtree.Outline = outlineOf(subtree, nodes)
+	return subtree.Write(w)
+}
+
+// ExportFileName returns the value of the EXPORT_FILE_NAME property of the headline, i.e. the file name
+// (without extension) the subtree should be exported to. See Document.ExportSubtree.
+func (h Headline) ExportFileName() (string, bool) {
+	return h.Properties.Get("EXPORT_FILE_NAME")
+}
+
+// bufferSettingKeywords are the keywords that configure the whole document (rather than the element they precede or
+// their place in the document, like #+CAPTION or #+TOC) - see ExtractSubtree.
+var bufferSettingKeywords = map[string]bool{
+	"OPTIONS": true, "TODO": true, "SEQ_TODO": true, "TYP_TODO": true, "FILETAGS": true, "TAGS": true, "SETUPFILE": true,
+	"MACRO": true, "LINK": true, "STARTUP": true, "PRIORITIES": true, "PROPERTY": true, "COLUMNS": true, "CATEGORY": true,
+	"AUTHOR": true, "EMAIL": true, "DATE": true, "LANGUAGE": true, "EXCLUDE_TAGS": true, "SELECT_TAGS": true,
+	"EXPORT_EXCLUDE_TAGS": true, "EXPORT_SELECT_TAGS": true,
+}
+
+// ExtractSubtree returns a new document containing the subtree of the headline h re-leveled to level 1, the footnote
+// definitions it references and the buffer settings (e.g. #+TODO, #+OPTIONS, #+MACRO) declared outside of it -
+// except #+TITLE. Other keywords outside of the subtree (e.g. #+HTML or #+CAPTION) are not copied. The new document
+// is parsed from the extracted text, i.e. the original formatting is preserved.
+// If h does not belong to the document, the returned document contains a FatalError.
+func (d *Document) ExtractSubtree(h Headline) *Document {
+	if d.sectionOf(h) == nil {
+		extracted := d.Configuration.newDocument(d.Path)
+		extracted.AddFatalError(ErrorTypeValidation, "could not extract subtree", h.Pos, token{}, fmt.Errorf("headline %q does not belong to the document", String(h.Title...)))
+		return extracted
+	}
+	text := &strings.Builder{}
+	walkNodes(d.Nodes, func(n Node) {
+		pos := n.Position()
+		if k, ok := n.(Keyword); ok && bufferSettingKeywords[k.Key] && (pos.EndOffset <= h.Pos.StartOffset || pos.StartOffset >= h.Pos.EndOffset) {
+			text.WriteString(d.Source(k) + "\n")
+		}
+	})
+	subtree, _ := d.shiftedSubtreeText(h, 1-h.Lvl) // descendants are nested deeper than h - no level drops below 1
+	text.WriteString(subtree)
+	for _, definition := range d.missingFootnoteDefinitions([]Node{h}) {
+		text.WriteString(d.Source(definition) + "\n")
+	}
+	return d.Configuration.Parse(strings.NewReader(text.String()), d.Path)
+}
+
+// SparseTree returns a copy of the document that only contains the headlines matched by predicate and their
+// ancestors - like an Emacs sparse tree. Matched headlines keep their section content, ancestors only keep the
+// headlines leading to a match. If includeSubtrees is true, matched headlines keep their complete subtree.
+// The nodes before the first headline (e.g. buffer settings) are kept as well.
+func (d *Document) SparseTree(predicate func(*Headline) bool, includeSubtrees bool) *Document {
+	var filter func(nodes []Node) []Node
+	filter = func(nodes []Node) []Node {
+		filtered := []Node{}
+		for _, n := range nodes {
+			h, ok := n.(Headline)
+			if !ok {
+				continue
+			}
+			if predicate(&h) {
+				if !includeSubtrees {
+					h.Children = append(beforeHeadlines(h.Children), filter(h.Children)...)
+				}
+				filtered = append(filtered, h)
+			} else if children := filter(h.Children); len(children) != 0 {
+				h.Children = children
+				filtered = append(filtered, h)
+			}
+		}
+		return filtered
+	}
+	nodes := CopyNodes(d.Nodes)
+	nodes = append(beforeHeadlines(nodes), filter(nodes)...)
+	sparse := d.fragment(nodes, "")
+	sparse.Outline = outlineOf(sparse, nodes)
+	return sparse
+}
+
+// beforeHeadlines returns the nodes before the first headline.
+func beforeHeadlines(nodes []Node) []Node {
+	for i, n := range nodes {
+		if _, ok := n.(Headline); ok {
+			return nodes[:i:i]
+		}
+	}
+	return nodes[:len(nodes):len(nodes)]
+}
+
+// shiftHeadlines changes the level of all (nested) headlines in nodes by delta. Levels below 1 are clamped to 1.
+func shiftHeadlines(nodes []Node, delta int) []Node {
+	for i, n := range nodes {
+		if h, ok := n.(Headline); ok {
+			h.Lvl = max(h.Lvl+delta, 1)
+			h.Children = shiftHeadlines(h.Children, delta)
+			nodes[i] = h
+		}
+	}
+	return nodes
+}
+
+// outlineOf builds the Outline of the (nested) headlines in nodes.
+func outlineOf(d *Document, nodes []Node) Outline {
+	root := &Section{document: d}
+	outline := Outline{root, root, 0}
+	var add func(nodes []Node)
+	add = func(nodes []Node) {
+		for _, n := range nodes {
+			if h, ok := n.(Headline); ok {
+				current := &Section{Headline: &h, document: d}
+				outline.last.add(current)
+				outline.last = current
+				outline.count++
+				add(h.Children)
+			}
+		}
+	}
+	add(nodes)
+	return outline
+}
@@ -0,0 +1,156 @@
+package org
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSparseTree(t *testing.T) {
+	input := strings.Join([]string{
+		"#+TITLE: Sparse",
+		"* A",
+		"a content",
+		"** A1 :match:",
+		"a1 content",
+		"*** A1a",
+		"** A2",
+		"* B",
+		"** B1",
+		"*** B1a :match:",
+		"* C",
+	}, "\n")
+	d := New().Silent().Parse(strings.NewReader(input), "./sparse.org")
+	isMatch := func(h *Headline) bool { return len(h.Tags) != 0 && h.Tags[0] == "match" }
+	for _, c := range []struct {
+		includeSubtrees bool
+		expected        string
+	}{
+		{false, "#+TITLE: Sparse\n* A\n** A1 :match:\na1 content\n* B\n** B1\n*** B1a :match:\n"},
+		{true, "#+TITLE: Sparse\n* A\n** A1 :match:\na1 content\n*** A1a\n* B\n** B1\n*** B1a :match:\n"},
+	} {
+		sparse := d.SparseTree(isMatch, c.includeSubtrees)
+		w := NewOrgWriter()
+		w.TagsColumn = 0
+		actual, err := sparse.Write(w)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if actual != c.expected {
+			t.Errorf("includeSubtrees %t:\n%s", c.includeSubtrees, diff(actual, c.expected))
+		}
+		if len(sparse.Outline.Children) != 2 || len(sparse.Outline.Children[1].Children[0].Children) != 1 {
+			t.Errorf("includeSubtrees %t: bad outline", c.includeSubtrees)
+		}
+	}
+	if actual := String(d.Nodes...); !strings.Contains(actual, "* C") || !strings.Contains(actual, "a content") {
+		t.Errorf("expected document to be unchanged:\n%s", actual)
+	}
+}
+
+func TestExportSubtree(t *testing.T) {
+	input := strings.Join([]string{
+		"#+TITLE: Document",
+		"#+OPTIONS: toc:nil",
+		"* Chapter",
+		":PROPERTIES:",
+		":EXPORT_FILE_NAME: chapter",
+		":EXPORT_TITLE: Chapter One",
+		":EXPORT_AUTHOR: Someone",
+		":EXPORT_OPTIONS: title:nil",
+		":END:",
+		"text[fn:1]",
+		"** Section",
+		"*** Subsection",
+		"* Other",
+		"[fn:1] note",
+	}, "\n")
+	d := New().Silent().Parse(strings.NewReader(input), "./subtree.org")
+	chapter, other := d.Outline.Children[0].Headline, d.Outline.Children[1].Headline
+	if name, ok := chapter.ExportFileName(); !ok || name != "chapter" {
+		t.Errorf("got export file name %q %v, expected chapter", name, ok)
+	}
+	if name, ok := other.ExportFileName(); ok || name != "" {
+		t.Errorf("got export file name %q %v for headline without EXPORT_FILE_NAME", name, ok)
+	}
+	org, err := d.ExportSubtree(*chapter, NewOrgWriter())
+	if expected := "text[fn:1]\n* Section\n** Subsection\n[fn:1] note\n"; err != nil || org != expected {
+		t.Errorf("got org export %q (error: %v), expected %q", org, err, expected)
+	}
+	w := NewHTMLWriter()
+	html, err := d.ExportSubtree(*chapter, w)
+	if err != nil || strings.Contains(html, "Other") || !strings.Contains(html, "<h2") || !strings.Contains(html, "note") {
+		t.Errorf("got unexpected html export %q (error: %v)", html, err)
+	}
+	if title, author := w.document.Get("TITLE"), w.document.Get("AUTHOR"); title != "Chapter One" || author != "Someone" {
+		t.Errorf("got title %q and author %q, expected EXPORT_ properties to override buffer settings", title, author)
+	}
+	if option := w.document.GetOption("title"); option != "nil" {
+		t.Errorf("expected EXPORT_OPTIONS to take precedence, got title:%s", option)
+	}
+	if option := w.document.GetOption("toc"); option != "nil" {
+		t.Errorf("expected #+OPTIONS of the document to apply, got toc:%s", option)
+	}
+}
+
+func TestExportFilters(t *testing.T) {
+	c := New().Silent()
+	c.AddExportFilter(Drawer{}, func(c *Cursor, d *Document) { c.Delete() })
+	c.AddExportFilter(RegularLink{}, func(c *Cursor, d *Document) {
+		link := c.Node().(RegularLink)
+		link.URL = strings.Replace(link.URL, "http://", "https://", 1)
+		c.Replace(link)
+	})
+	c.AddExportFilter(Paragraph{}, func(c *Cursor, d *Document) {
+		if _, ok := c.Parent().(Headline); ok {
+			c.Replace(NodeWithMeta{Node: c.Node(), Meta: Metadata{HTMLAttributes: [][]string{{"class", "in-section"}}}})
+		}
+	})
+	input := "* Headline\n:NOTES:\nsecret\n:END:\nA [[http://example.com][link]]\n"
+	d := c.Parse(strings.NewReader(input), "./exportFilterTests.org")
+	actual, err := d.Write(NewHTMLWriter())
+	if err != nil {
+		t.Fatalf("%s\n got error: %s", input, err)
+	}
+	expected := `<p class="in-section">A <a href="https://example.com">link</a></p>`
+	if !strings.Contains(actual, expected) || strings.Contains(actual, "secret") {
+		t.Errorf("%s: expected output to contain %s and no drawer:\n%s", input, expected, actual)
+	}
+	if actual := String(d.Nodes...); actual != input {
+		t.Errorf("expected document to be unchanged:\n%s", diff(actual, input))
+	}
+
+	c.AddExportFilter(Headline{}, func(c *Cursor, d *Document) {
+		if slices.Contains(c.Node().(Headline).Tags, "draft") {
+			c.Delete()
+		}
+	})
+	d = c.Parse(strings.NewReader("* Published\n* Draft :draft:\n** Draft child\n"), "./exportFilterTests.org")
+	for name, write := range map[string]func(w Writer) (string, error){"write": d.Write, "parallel": d.WriteParallel} {
+		actual, err := write(NewHTMLWriter())
+		if err != nil || !strings.Contains(actual, `<a href="#headline-1">Published</a>`) || strings.Contains(actual, "Draft") {
+			t.Errorf("%s: expected the table of contents to only contain the published headline, got %q (%v)", name, actual, err)
+		}
+	}
+	if len(d.Outline.Children) != 2 {
+		t.Errorf("expected the outline of the document to be unchanged, got %d sections", len(d.Outline.Children))
+	}
+}
+
+func TestExtractSubtree(t *testing.T) {
+	input := "#+TITLE: Notes\n#+TODO: TODO WAIT | DONE\n#+HTML: <b>raw</b>\n#+TOC: headlines 2\n* A\n#+CAPTION: cap\n#+HTML: <i>a</i>\n" +
+		"** WAIT Project [fn:1]\nSome text\n#+MACRO: m value\n*** Task\n* B\n#+FILETAGS: :b:\n\n[fn:1] The footnote\n[fn:2] Unused\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./extract.org")
+	extracted := d.ExtractSubtree(findHeadline(t, d, "Project [fn:1]"))
+	expected := "#+TODO: TODO WAIT | DONE\n#+FILETAGS: :b:\n* WAIT Project [fn:1]\nSome text\n#+MACRO: m value\n** Task\n[fn:1] The footnote\n"
+	if extracted.input != expected {
+		t.Errorf("got:\n%s", diff(extracted.input, expected))
+	}
+	if h := extracted.Outline.Children[0].Headline; h.Lvl != 1 || h.Status != "WAIT" || extracted.Path != d.Path {
+		t.Errorf("bad extracted headline %#v", h)
+	}
+	other := New().Silent().Parse(strings.NewReader("* Other"), "./other.org")
+	if extracted := d.ExtractSubtree(findHeadline(t, other, "Other")); !extracted.HasFatalError() {
+		t.Errorf("expected error for headline of another document")
+	}
+}
@@ -0,0 +1,114 @@
+package org
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ParseFilesOption configures ParseFiles.
+type ParseFilesOption func(*parseFilesOptions)
+
+type parseFilesOptions struct {
+	configuration *Configuration
+	workers       int
+	fs            fs.FS
+}
+
+// WithConfiguration sets the Configuration used to parse all files. Defaults to New().
+// The Configuration is shared by all workers and must not be modified while ParseFiles is running -
+// custom ReadFile and ResolveLink functions must be safe for concurrent use.
+func WithConfiguration(c *Configuration) ParseFilesOption {
+	return func(o *parseFilesOptions) { o.configuration = c }
+}
+
+// WithFS sets the FS of the Configuration used to parse all files (see Configuration.FS) - paths are read from fsys.
+func WithFS(fsys fs.FS) ParseFilesOption {
+	return func(o *parseFilesOptions) { o.fs = fsys }
+}
+
+// WithWorkers sets the number of files that are parsed concurrently. Defaults to runtime.NumCPU().
+func WithWorkers(n int) ParseFilesOption {
+	return func(o *parseFilesOptions) { o.workers = n }
+}
+
+// ParseFiles reads and parses the files at paths concurrently using a pool of workers.
+// The returned documents are in the same order as paths. Files that could not be read result in a document
+// with an ErrorTypeIO FatalError. The returned error joins the fatal errors of all documents and the errors
+// of at least the FailOnSeverity of the Configuration - it is nil if all documents could be written.
+func ParseFiles(paths []string, opts ...ParseFilesOption) ([]*Document, error) {
+	o := &parseFilesOptions{workers: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.configuration == nil {
+		o.configuration = New()
+	}
+	if o.fs != nil {
+		c := *o.configuration
+		c.FS, o.configuration = o.fs, &c
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	documents, jobs, wg := make([]*Document, len(paths)), make(chan int), sync.WaitGroup{}
+	for range min(o.workers, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				documents[i] = o.configuration.parseFile(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	errs := []error{}
+	for _, d := range documents {
+		if d.HasFatalError() {
+			errs = append(errs, d.FatalError)
+			continue
+		}
+		for _, err := range d.GetErrorsBySeverity(d.FailOnSeverity) {
+			errs = append(errs, err)
+		}
+	}
+	return documents, errors.Join(errs...)
+}
+
+// parseFile reads the file at path using readFile and parses it.
+func (c *Configuration) parseFile(path string) *Document {
+	bs, err := c.readFile(path)
+	if err != nil {
+		d := c.newDocument(path)
+		d.AddFatalError(ErrorTypeIO, "could not read file", d.Pos, token{}, err)
+		return d
+	}
+	return c.Parse(bytes.NewReader(bs), path)
+}
+
+// readFile reads the file from FS if set and using ReadFile otherwise.
+func (c *Configuration) readFile(filename string) ([]byte, error) {
+	if c.FS == nil {
+		return c.ReadFile(filename)
+	}
+	return fs.ReadFile(c.FS, fsPath(filename))
+}
+
+// fsPath converts the (relative or absolute) file path to a path as expected by fs.FS, i.e. slash separated and
+// unrooted. Paths outside of the root of the FS (e.g. ../file.org) remain invalid.
+func fsPath(p string) string {
+	p = path.Clean(filepath.ToSlash(p))
+	if p = strings.TrimLeft(p, "/"); p == "" {
+		return "."
+	}
+	return p
+}
@@ -0,0 +1,70 @@
+package org
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseFiles(t *testing.T) {
+	paths := orgTestFiles()
+	c := New().Silent()
+	documents, err := ParseFiles(paths, WithConfiguration(c), WithWorkers(4))
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if len(documents) != len(paths) {
+		t.Fatalf("expected %d documents, got %d", len(paths), len(documents))
+	}
+	for i, path := range paths {
+		expected := c.Parse(strings.NewReader(fileString(t, path)), path)
+		if documents[i].Path != path || documents[i].Configuration != c {
+			t.Errorf("%s: bad document %q", path, documents[i].Path)
+		} else if actual, expected := dumpDocument(documents[i]), dumpDocument(expected); actual != expected {
+			t.Errorf("%s:\n%s", path, diff(actual, expected))
+		}
+	}
+}
+
+func TestParseFilesErrors(t *testing.T) {
+	paths := []string{"./testdata/headlines.org", "./testdata/does-not-exist.org"}
+	documents, err := ParseFiles(paths, WithConfiguration(New().Silent()))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected aggregated error to contain os.ErrNotExist, got %v", err)
+	}
+	if documents[0].HasFatalError() || documents[0].Nodes == nil {
+		t.Errorf("expected headlines.org to be parsed, got %v", documents[0].Errors)
+	}
+	if d := documents[1]; d.Path != paths[1] || !d.HasFatalError() || d.FatalError.Type != ErrorTypeIO {
+		t.Errorf("expected io error for missing file, got %v", d.FatalError)
+	}
+}
+
+func TestFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"notes/index.org":    {Data: []byte("#+SETUPFILE: ../setup.org\n#+INCLUDE: \"code/main.go\" src go\n")},
+		"notes/code/main.go": {Data: []byte("package main\n")},
+		"setup.org":          {Data: []byte("#+TITLE: from fs\n")},
+	}
+	c := New().Silent()
+	c.ReadFile = func(string) ([]byte, error) { return nil, errors.New("ReadFile must not be used") }
+	documents, err := ParseFiles([]string{"/notes/index.org", "./notes/missing.org"}, WithConfiguration(c), WithFS(fsys))
+	if !errors.Is(err, fs.ErrNotExist) || c.FS != nil {
+		t.Errorf("expected missing file error and unmodified configuration, got %v", err)
+	}
+	d := documents[0]
+	if d.HasErrors() || d.Get("TITLE") != "from fs" {
+		t.Errorf("expected setup file to be read from fs, got %v %v", d.Errors, d.BufferSettings)
+	}
+	if b, ok := d.Nodes[1].(Include).Resolve().(Block); !ok || String(b.Children...) != "package main\n" {
+		t.Errorf("expected include to be read from fs, got %#v", d.Nodes[1].(Include).Resolve())
+	}
+	for p, expected := range map[string]string{"/a/b.org": "a/b.org", "./a/../b.org": "b.org", "": ".", "../b.org": "../b.org"} {
+		if actual := fsPath(p); actual != expected {
+			t.Errorf("fsPath(%q): expected %q, got %q", p, expected, actual)
+		}
+	}
+}
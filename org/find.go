@@ -0,0 +1,135 @@
+package org
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// FindOption configures the title matching of FindHeadline and HeadlineByPath.
+type FindOption func(*findOptions)
+
+type findOptions struct {
+	ignoreCase bool
+	fuzzy      bool
+}
+
+// WithIgnoreCase matches titles case-insensitively.
+func WithIgnoreCase() FindOption {
+	return func(o *findOptions) { o.ignoreCase = true }
+}
+
+// WithFuzzyMatch matches titles that contain the characters of the query in order (e.g. "gorg" matches "go-org").
+// The title with the shortest matching span wins - ties are resolved in document order.
+func WithFuzzyMatch() FindOption {
+	return func(o *findOptions) { o.fuzzy = true }
+}
+
+// FindHeadline returns the first headline of the outline whose title (see PlainText) matches title - or nil if
+// there is none. Exact and case-insensitive lookups use an index of the titles that is built once per outline -
+// fuzzy matching compares title against all headlines.
+func (d *Document) FindHeadline(title string, opts ...FindOption) *Headline {
+	index, o := d.outlineIndex(), newFindOptions(opts)
+	var s *Section
+	switch title = strings.TrimSpace(title); {
+	case o.fuzzy:
+		s = bestMatch(index.order, title, o)
+	case o.ignoreCase:
+		s = index.foldedTitles[strings.ToLower(title)]
+	default:
+		s = index.titles[title]
+	}
+	if s == nil {
+		return nil
+	}
+	return s.Headline
+}
+
+// HeadlineByID returns the headline with the CUSTOM_ID id (see Lookup) - or else the first headline of the outline
+// whose ID property or generated id (see Headline.ID) is id. It returns nil if there is none.
+func (d *Document) HeadlineByID(id string) *Headline {
+	for _, symbol := range d.Lookup(CustomIDSymbol, id) {
+		if s := d.Outline.SectionOf(symbol.Node.(Headline)); s != nil {
+			return s.Headline
+		}
+	}
+	if s := d.outlineIndex().ids[id]; s != nil {
+		return s.Headline
+	}
+	return nil
+}
+
+// HeadlineByPath returns the headline at the slash separated path of titles, e.g. "Projects/Go/go-org" for the
+// headline go-org below the headline Go below the top level headline Projects - or nil if there is none.
+// Each title of the path is matched against the children of the previous match (see FindHeadline).
+func (d *Document) HeadlineByPath(path string, opts ...FindOption) *Headline {
+	s, o := d.Outline.Section, newFindOptions(opts)
+	for _, title := range strings.Split(path, "/") {
+		if s == nil {
+			return nil
+		}
+		s = bestMatch(s.Children, title, o)
+	}
+	if s == nil {
+		return nil
+	}
+	return s.Headline
+}
+
+func newFindOptions(opts []FindOption) findOptions {
+	o := findOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// bestMatch returns the first section with a title matching query - or for fuzzy matching the one with the
+// shortest match span.
+func bestMatch(sections []*Section, query string, o findOptions) *Section {
+	var best *Section
+	bestSpan := -1
+	for _, s := range sections {
+		span := o.match(strings.TrimSpace(PlainText(s.Headline.Title...)), strings.TrimSpace(query))
+		if span != -1 && (bestSpan == -1 || span < bestSpan) {
+			best, bestSpan = s, span
+			if !o.fuzzy {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// match returns the length (in runes) of the span of title matching query - or -1 if title does not match.
+func (o findOptions) match(title, query string) int {
+	if o.ignoreCase {
+		title, query = strings.ToLower(title), strings.ToLower(query)
+	}
+	if !o.fuzzy {
+		if title != query {
+			return -1
+		}
+		return utf8.RuneCountInString(title)
+	}
+	if query == "" {
+		return 0
+	}
+	best, runes, first := -1, []rune(title), []rune(query)[0]
+	for start := range runes {
+		if runes[start] != first {
+			continue
+		}
+		i, q := start, []rune(query)
+		for ; i < len(runes) && len(q) != 0; i++ {
+			if runes[i] == q[0] {
+				q = q[1:]
+			}
+		}
+		if len(q) != 0 {
+			break
+		} else if span := i - start; best == -1 || span < best {
+			best = span
+		}
+	}
+	return best
+}
@@ -0,0 +1,59 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFindHeadline(t *testing.T) {
+	input := strings.Join([]string{
+		"* Projects",
+		"** Go",
+		"*** go-org",
+		":PROPERTIES:",
+		":ID: 3f2a",
+		":END:",
+		"*** Other",
+		"** Rust",
+		"*** go-org",
+		"* Inbox",
+		":PROPERTIES:",
+		":CUSTOM_ID: inbox",
+		":END:",
+		"** Go *rganize* things",
+	}, "\n")
+	d := New().Silent().Parse(strings.NewReader(input), "./find.org")
+	title := func(h *Headline) string {
+		if h == nil {
+			return "<nil>"
+		}
+		return fmt.Sprintf("%d: %s", h.Pos.StartLine, String(h.Title...))
+	}
+	goOrg := d.Outline.Children[0].Children[0].Children[0].Headline
+	for _, test := range []struct {
+		name     string
+		actual   *Headline
+		expected *Headline
+	}{
+		{"title", d.FindHeadline("go-org"), goOrg},
+		{"title case", d.FindHeadline("GO-ORG"), nil},
+		{"title ignore case", d.FindHeadline("INBOX", WithIgnoreCase()), d.Outline.Children[1].Headline},
+		{"title plain text", d.FindHeadline("Go rganize things"), d.Outline.Children[1].Children[0].Headline},
+		{"title fuzzy", d.FindHeadline("gorg", WithFuzzyMatch()), goOrg},
+		{"title fuzzy ignore case", d.FindHeadline("GOORG", WithFuzzyMatch(), WithIgnoreCase()), goOrg},
+		{"title fuzzy missing", d.FindHeadline("xyz", WithFuzzyMatch()), nil},
+		{"id property", d.HeadlineByID("3f2a"), goOrg},
+		{"custom id", d.HeadlineByID("inbox"), d.Outline.Children[1].Headline},
+		{"generated id", d.HeadlineByID("headline-2"), d.Outline.Children[0].Children[0].Headline},
+		{"missing id", d.HeadlineByID("missing"), nil},
+		{"path", d.HeadlineByPath("Projects/Rust/go-org"), d.Outline.Children[0].Children[1].Children[0].Headline},
+		{"path fuzzy", d.HeadlineByPath("proj/go/org", WithFuzzyMatch(), WithIgnoreCase()), goOrg},
+		{"path missing", d.HeadlineByPath("Projects/Go/Rust"), nil},
+		{"path too deep", d.HeadlineByPath("Projects/Go/go-org/deeper"), nil},
+	} {
+		if test.actual != test.expected {
+			t.Errorf("%s: got %s, expected %s", test.name, title(test.actual), title(test.expected))
+		}
+	}
+}
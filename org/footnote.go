@@ -3,6 +3,7 @@ package org
 import (
 	"fmt"
 	"regexp"
+	"slices"
 )
 
 type FootnoteDefinition struct {
@@ -12,6 +13,22 @@ type FootnoteDefinition struct {
 	Pos      Position
 }
 
+// Footnote collects the definition and all references of a footnote. See Document.Footnotes.
+type Footnote struct {
+	Name       string              // Name is empty for anonymous inline footnotes.
+	Definition *FootnoteDefinition // Definition is nil if the footnote is referenced but never defined.
+	References []FootnoteLink
+}
+
+// FootnoteIndex is the result of analyzing the footnote usage of a document.
+type FootnoteIndex struct {
+	// Footnotes contains all footnotes ordered by their first reference.
+	// Unreferenced footnote definitions are appended in document order.
+	Footnotes []Footnote
+	// Diagnostics contains warnings for unreferenced and duplicate definitions as well as references without definition.
+	Diagnostics []*ParseError
+}
+
 var footnoteDefinitionRegexp = regexp.MustCompile(`^\[fn:([\w-]+)\](\s+(.+)|\s*$)`)
 
 func lexFootnoteDefinition(line string) (token, bool) {
@@ -25,10 +42,12 @@ func (d *Document) parseFootnoteDefinition(i int, parentStop stopFn) (int, Node)
 	start, name := i, d.tokens[i].content
 	startToken := d.tokens[start]
 	var ok bool
-	d.tokens[i], ok = tokenize(d.tokens[i].matches[2])
+	d.tokens[i], ok = tokenize(startToken.matches[2])
+	d.tokens[i].line, d.tokens[i].startCol, d.tokens[i].endCol = startToken.line, startToken.endCol-len(startToken.matches[2]), startToken.endCol
+	d.tokens[i].offset = startToken.offset
 	if !ok {
 		line := d.tokens[i].line
-		d.AddError(ErrorTypeTokenization, "could not lex line", getPositionFromToken(d.tokens[i]), d.tokens[i], fmt.Errorf("no lexer matched: %q", line))
+		d.AddError(ErrorTypeTokenization, "could not lex line", d.getPositionFromToken(d.tokens[i]), d.tokens[i], fmt.Errorf("no lexer matched: %q", line))
 	}
 	stop := func(d *Document, i int) bool {
 		return parentStop(d, i) ||
@@ -38,16 +57,128 @@ func (d *Document) parseFootnoteDefinition(i int, parentStop stopFn) (int, Node)
 	consumed, nodes := d.parseMany(i, stop)
 	definition := FootnoteDefinition{Name: name, Children: nodes, Inline: false}
 	if consumed > 0 {
-		definition.Pos = Position{
-			StartLine:   startToken.line,
-			StartColumn: startToken.startCol,
-			EndLine:     d.tokens[start+consumed-1].line,
-			EndColumn:   d.tokens[start+consumed-1].endCol,
-		}
+		definition.Pos = d.getPositionBetweenTokens(startToken, d.tokens[start+consumed-1])
 	}
 	return consumed, definition
 }
 
+// Footnotes returns all footnotes of the document with their definitions and reference sites.
+// The diagnostics of the returned FootnoteIndex are warnings - parsing adds them to Document.Errors as well.
+func (d *Document) Footnotes() FootnoteIndex {
+	index := FootnoteIndex{}
+	index.Footnotes = d.footnotes(d.Nodes, func(typ ErrorType, message string, pos Position) {
+		err := NewParseError(typ, message, d.Path, pos, token{}, nil)
+		err.Severity = SeverityWarning
+		index.Diagnostics = append(index.Diagnostics, err)
+	})
+	return index
+}
+
+// addFootnoteWarnings adds the diagnostics of Footnotes for nodes (i.e. usually Nodes) to the errors of the
+// document. The warnings added by previous calls are replaced - see Reparse.
+func (d *Document) addFootnoteWarnings(nodes []Node) {
+	d.Errors = d.errorsWithoutFootnotes()
+	count := len(d.Errors)
+	d.footnotes(nodes, func(typ ErrorType, message string, pos Position) {
+		d.addWarning(typ, message, pos, token{}, nil)
+	})
+	d.footnoteErrors = slices.Clone(d.Errors[count:])
+}
+
+// errorsWithoutFootnotes returns the errors of the document without the footnote warnings - e.g. for documents
+// parsed from a title or macro whose footnotes are defined in the surrounding document.
+func (d *Document) errorsWithoutFootnotes() []*ParseError {
+	return slices.DeleteFunc(slices.Clone(d.Errors), func(err *ParseError) bool { return slices.Contains(d.footnoteErrors, err) })
+}
+
+// footnotes collects the footnotes of nodes and calls warn for each footnote diagnostic.
+func (d *Document) footnotes(nodes []Node, warn func(typ ErrorType, message string, pos Position)) []Footnote {
+	index, footnotes := FootnoteIndex{}, map[string]int{}
+	get := func(name string) *Footnote {
+		if i, ok := footnotes[name]; ok && name != "" {
+			return &index.Footnotes[i]
+		}
+		index.Footnotes = append(index.Footnotes, Footnote{Name: name})
+		footnotes[name] = len(index.Footnotes) - 1
+		return &index.Footnotes[len(index.Footnotes)-1]
+	}
+	definitions := []FootnoteDefinition{}
+	walkNodes(nodes, func(n Node) {
+		switch n := n.(type) {
+		case FootnoteLink:
+			f := get(n.Name)
+			f.References = append(f.References, n)
+			if n.Definition != nil {
+				definitions = append(definitions, *n.Definition)
+			}
+		case FootnoteDefinition:
+			definitions = append(definitions, n)
+		}
+	})
+	for _, definition := range definitions {
+		if definition.Name == "" {
+			continue // anonymous inline footnote definitions are attached to their reference via FootnoteLink.Definition
+		}
+		f := get(definition.Name)
+		if f.Definition != nil {
+			warn(ErrorTypeDuplicateNode, fmt.Sprintf("duplicate definition of footnote [fn:%s]", definition.Name), definition.Pos)
+		}
+		f.Definition = &definition
+	}
+	for i := range index.Footnotes {
+		f := &index.Footnotes[i]
+		if f.Name == "" {
+			f.Definition = f.References[0].Definition
+		} else if f.Definition == nil {
+			for _, l := range f.References {
+				warn(ErrorTypeMissingNode, fmt.Sprintf("missing definition for footnote [fn:%s]", f.Name), l.Pos)
+			}
+		} else if len(f.References) == 0 {
+			warn(ErrorTypeUnusedNode, fmt.Sprintf("unreferenced footnote definition [fn:%s]", f.Name), f.Definition.Pos)
+		}
+	}
+	return index.Footnotes
+}
+
+// Get returns the footnote with the given name.
+func (index FootnoteIndex) Get(name string) (Footnote, bool) {
+	for _, f := range index.Footnotes {
+		if f.Name == name && name != "" {
+			return f, true
+		}
+	}
+	return Footnote{}, false
+}
+
+// missingFootnoteDefinitions returns the definitions of all footnotes referenced in nodes that are defined
+// elsewhere in the document.
+func (d *Document) missingFootnoteDefinitions(nodes []Node) []Node {
+	referenced, defined := []string{}, map[string]bool{}
+	walkNodes(nodes, func(n Node) {
+		switch n := n.(type) {
+		case FootnoteLink:
+			if n.Definition == nil && n.Name != "" {
+				referenced = append(referenced, n.Name)
+			}
+		case FootnoteDefinition:
+			defined[n.Name] = true
+		}
+	})
+	definitions := map[string]FootnoteDefinition{}
+	walkNodes(d.Nodes, func(n Node) {
+		if f, ok := n.(FootnoteDefinition); ok && !f.Inline {
+			definitions[f.Name] = f
+		}
+	})
+	missing := []Node{}
+	for _, name := range referenced {
+		if f, ok := definitions[name]; ok && !defined[name] {
+			missing, defined[name] = append(missing, f), true
+		}
+	}
+	return missing
+}
+
 func (n FootnoteDefinition) String() string { return String(n) }
 
 func (n FootnoteDefinition) Copy() Node {
@@ -0,0 +1,78 @@
+package org
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFootnotes(t *testing.T) {
+	input := strings.Join([]string{
+		"b[fn:b] a[fn:a] inline[fn::anonymous] named[fn:c: inline] again[fn:b] missing[fn:missing]",
+		"",
+		"[fn:a] a",
+		"",
+		"[fn:b] b",
+		"",
+		"[fn:unused] unused",
+		"",
+		"[fn:a] duplicate",
+	}, "\n")
+	d := New().Silent().Parse(strings.NewReader(input), "./footnotes.org")
+	index := d.Footnotes()
+
+	names, references := []string{}, []int{}
+	for _, f := range index.Footnotes {
+		names, references = append(names, f.Name), append(references, len(f.References))
+		if (f.Definition == nil) != (f.Name == "missing") {
+			t.Errorf("footnote %q: got definition %v", f.Name, f.Definition)
+		}
+	}
+	if expected := []string{"b", "a", "", "c", "missing", "unused"}; !slices.Equal(names, expected) {
+		t.Errorf("got footnotes %q, expected %q", names, expected)
+	}
+	if expected := []int{2, 1, 1, 1, 1, 0}; !slices.Equal(references, expected) {
+		t.Errorf("got reference counts %v, expected %v", references, expected)
+	}
+	if f, ok := index.Get("b"); !ok || f.References[1].Pos.StartColumn != 63 {
+		t.Errorf("got footnote b %v, expected the second reference at column 63", f)
+	}
+
+	diagnostics := []string{}
+	for _, err := range index.Diagnostics {
+		diagnostics = append(diagnostics, fmt.Sprintf("%s %d:%d %s", err.Severity, err.StartLine, err.StartCol, err.Message))
+	}
+	expected := []string{
+		"warning 8:0 duplicate definition of footnote [fn:a]",
+		"warning 0:77 missing definition for footnote [fn:missing]",
+		"warning 6:0 unreferenced footnote definition [fn:unused]",
+	}
+	if !slices.Equal(diagnostics, expected) {
+		t.Errorf("got diagnostics\n%s\nexpected\n%s", strings.Join(diagnostics, "\n"), strings.Join(expected, "\n"))
+	}
+	errors := []string{}
+	for _, err := range d.Errors {
+		errors = append(errors, fmt.Sprintf("%s %d:%d %s", err.Severity, err.StartLine, err.StartCol, err.Message))
+	}
+	if !slices.Equal(errors, expected) {
+		t.Errorf("got errors\n%s\nexpected\n%s", strings.Join(errors, "\n"), strings.Join(expected, "\n"))
+	}
+
+	d.Reparse(TextEdit{StartLine: 8, EndLine: 9, NewText: "[fn:missing] defined"})
+	if len(d.Errors) != 1 || d.Errors[0].Message != "unreferenced footnote definition [fn:unused]" {
+		t.Errorf("got errors %v after reparse, expected only the unused footnote", d.Errors)
+	}
+}
+
+func TestFootnoteWarningsOfFragments(t *testing.T) {
+	input := "#+TITLE: Title[fn:1]\n#+MACRO: note text[fn:1]\n\n{{{note}}} body[fn:1]\n\n[fn:1] note\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./footnotes.org")
+	if len(d.Errors) != 0 {
+		t.Errorf("got errors %v, expected none", d.Errors)
+	}
+	html, err := d.Write(NewHTMLWriter())
+	if err != nil || !strings.Contains(html, `<h1 class="title">Title<sup class="footnote-reference">`) {
+		t.Errorf("got %q (%v), expected the title to contain the footnote reference", html, err)
+	}
+}
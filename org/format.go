@@ -0,0 +1,124 @@
+package org
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FormatOptions configures Format. See NewFormatOptions for the defaults.
+type FormatOptions struct {
+	Configuration *Configuration // Configuration is used to parse the input. Defaults to New().Silent().
+	Path          string         // Path of the file containing the input - used to resolve relative paths (e.g. SETUPFILE).
+
+	AlignTables               bool   // AlignTables realigns tables. See AlignTable.
+	UnorderedBullet           string // UnorderedBullet ("-" or "+") replaces the bullets of unordered and descriptive lists. Empty keeps bullets.
+	OrderedBulletDelimiter    string // OrderedBulletDelimiter ("." or ")") replaces the delimiter of ordered list bullets. Empty keeps bullets.
+	BlankLinesBetweenSections int    // BlankLinesBetweenSections is the number of blank lines before each headline. -1 keeps blank lines.
+}
+
+// maxFormatPasses is the maximum number of passes Format runs to reach a fixed point.
+const maxFormatPasses = 3
+
+// NewFormatOptions returns the default FormatOptions: tables are realigned, unordered lists use "-" and ordered lists
+// "." bullets and each headline is preceded by exactly one blank line.
+func NewFormatOptions() FormatOptions {
+	return FormatOptions{
+		AlignTables:               true,
+		UnorderedBullet:           "-",
+		OrderedBulletDelimiter:    ".",
+		BlankLinesBetweenSections: 1,
+	}
+}
+
+// Format parses the input and returns it pretty printed and normalized according to the options.
+//
+// Format is idempotent, i.e. formatting the output of Format again does not change it - this makes it suitable
+// for pre-commit hooks. An error is returned if the input cannot be parsed or does not reach a fixed point.
+func Format(input io.Reader, options FormatOptions) (string, error) {
+	if options.UnorderedBullet != "" && options.UnorderedBullet != "-" && options.UnorderedBullet != "+" {
+		return "", fmt.Errorf("could not format: bad unordered bullet %q", options.UnorderedBullet)
+	} else if options.OrderedBulletDelimiter != "" && options.OrderedBulletDelimiter != "." && options.OrderedBulletDelimiter != ")" {
+		return "", fmt.Errorf("could not format: bad ordered bullet delimiter %q", options.OrderedBulletDelimiter)
+	}
+	bs, err := io.ReadAll(input)
+	if err != nil {
+		return "", err
+	}
+	out := string(bs)
+	for i := 0; i < maxFormatPasses; i++ {
+		formatted, err := formatPass(out, options)
+		if err != nil {
+			return "", err
+		} else if formatted == out {
+			return out, nil
+		}
+		out = formatted
+	}
+	return "", fmt.Errorf("could not format: output did not reach a fixed point after %d passes", maxFormatPasses)
+}
+
+func formatPass(input string, options FormatOptions) (string, error) {
+	c := options.Configuration
+	if c == nil {
+		c = New().Silent()
+	}
+	d := c.Parse(strings.NewReader(input), options.Path)
+	if d.HasFatalError() {
+		return "", d.FatalError
+	}
+	d.Nodes = normalizeBullets(d.Nodes, options.UnorderedBullet, options.OrderedBulletDelimiter)
+	w := NewOrgWriter()
+	w.AlignTables, w.BlankLinesBetweenSections = options.AlignTables, options.BlankLinesBetweenSections
+	return d.Write(w)
+}
+
+// normalizeBullets replaces the bullets of all (nested) list items in nodes. See FormatOptions.
+func normalizeBullets(nodes []Node, unordered, orderedDelimiter string) []Node {
+	for i, n := range nodes {
+		switch n := n.(type) {
+		case Headline:
+			n.Children = normalizeBullets(n.Children, unordered, orderedDelimiter)
+			nodes[i] = n
+		case List:
+			n.Items = normalizeBullets(n.Items, unordered, orderedDelimiter)
+			nodes[i] = n
+		case ListItem:
+			n.Bullet = normalizeBullet(n.Bullet, unordered, orderedDelimiter)
+			n.Children = normalizeBullets(n.Children, unordered, orderedDelimiter)
+			nodes[i] = n
+		case DescriptiveListItem:
+			n.Bullet = normalizeBullet(n.Bullet, unordered, orderedDelimiter)
+			n.Details = normalizeBullets(n.Details, unordered, orderedDelimiter)
+			nodes[i] = n
+		case Drawer:
+			n.Children = normalizeBullets(n.Children, unordered, orderedDelimiter)
+			nodes[i] = n
+		case FootnoteDefinition:
+			n.Children = normalizeBullets(n.Children, unordered, orderedDelimiter)
+			nodes[i] = n
+		case Block:
+			if !isRawTextBlock(n.Name) {
+				n.Children = normalizeBullets(n.Children, unordered, orderedDelimiter)
+				nodes[i] = n
+			}
+		case NodeWithMeta:
+			n.Node = normalizeBullets([]Node{n.Node}, unordered, orderedDelimiter)[0]
+			nodes[i] = n
+		case NodeWithName:
+			n.Node = normalizeBullets([]Node{n.Node}, unordered, orderedDelimiter)[0]
+			nodes[i] = n
+		}
+	}
+	return nodes
+}
+
+func normalizeBullet(bullet, unordered, orderedDelimiter string) string {
+	switch last := bullet[len(bullet)-1:]; {
+	case len(bullet) == 1 && unordered != "":
+		return unordered
+	case (last == "." || last == ")") && orderedDelimiter != "":
+		return bullet[:len(bullet)-1] + orderedDelimiter
+	}
+	return bullet
+}
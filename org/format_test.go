@@ -0,0 +1,35 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	input := "#+TITLE: title\n* A\n+ item\n  1) sub item\n| a | bb |\n|-+-|\n| ccc | 1 |\n\n\n\n* B\n** C\n"
+	expected := "#+TITLE: title\n\n* A\n- item\n  1. sub item\n| a   | bb |\n|-----+----|\n| ccc |  1 |\n\n* B\n\n** C\n"
+	actual, err := Format(strings.NewReader(input), NewFormatOptions())
+	if err != nil {
+		t.Fatalf("%s\n got error: %s", input, err)
+	} else if actual != expected {
+		t.Fatalf("%s:\n%s'", input, diff(actual, expected))
+	}
+	again, err := Format(strings.NewReader(actual), NewFormatOptions())
+	if err != nil || again != actual {
+		t.Fatalf("Format is not idempotent: %s\n%s", err, diff(again, actual))
+	}
+}
+
+func TestFormatTestdataIsIdempotent(t *testing.T) {
+	for _, path := range orgTestFiles() {
+		formatted, err := Format(strings.NewReader(fileString(t, path)), NewFormatOptions())
+		if err != nil {
+			t.Errorf("%s: got error: %s", path, err)
+			continue
+		}
+		again, _ := Format(strings.NewReader(formatted), NewFormatOptions())
+		if again != formatted {
+			t.Errorf("%s: Format is not idempotent:\n%s", path, diff(again, formatted))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package org
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Hashtag is a #tag in inline text - see Configuration.ParseHashtags.
+type Hashtag struct {
+	Name string // Name is the tag without the leading #.
+	Pos  Position
+}
+
+// Mention is an @person in inline text - see Configuration.ParseMentions.
+type Mention struct {
+	Name string // Name is the mentioned person without the leading @.
+	Pos  Position
+}
+
+func (d *Document) parseHashtagWithPos(input string, start int, startLine, startColumn int) (int, Node) {
+	if !d.ParseHashtags {
+		return 0, nil
+	}
+	name := socialName(input, start)
+	if name == "" {
+		return 0, nil
+	}
+	pos := d.positionFromChars(input, startLine, startColumn, start, start+1+len(name))
+	return 1 + len(name), Hashtag{Name: name, Pos: pos}
+}
+
+func (d *Document) parseMentionWithPos(input string, start int, startLine, startColumn int) (int, Node) {
+	if !d.ParseMentions {
+		return 0, nil
+	}
+	name := socialName(input, start)
+	if name == "" {
+		return 0, nil
+	}
+	pos := d.positionFromChars(input, startLine, startColumn, start, start+1+len(name))
+	return 1 + len(name), Mention{Name: name, Pos: pos}
+}
+
+// socialName returns the name following the # or @ at input[start] - or "" if there is none. The # or @ has to be
+// at the start of a word (e.g. not in foo@example.com) and names consist of letters, digits, "_" and "-" - at least
+// one of them a letter (e.g. not #1). Trailing "-" (e.g. in "@alice--") are not part of the name.
+func socialName(input string, start int) string {
+	if pre := prevRune(input, start); pre != utf8.RuneError && !unicode.IsSpace(pre) && !strings.ContainsRune(`([{"'`, pre) {
+		return ""
+	}
+	end, hasLetter := start+1, false
+	for end < len(input) {
+		r, size := utf8.DecodeRuneInString(input[end:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			break
+		}
+		hasLetter, end = hasLetter || unicode.IsLetter(r), end+size
+	}
+	if !hasLetter {
+		return ""
+	}
+	return strings.TrimRight(input[start+1:end], "-")
+}
+
+// Hashtags returns the hashtags of the document in document order.
+func (d *Document) Hashtags() []Hashtag {
+	hashtags := []Hashtag{}
+	walkNodes(d.Nodes, func(n Node) {
+		if h, ok := n.(Hashtag); ok {
+			hashtags = append(hashtags, h)
+		}
+	})
+	return hashtags
+}
+
+// Mentions returns the mentions of the document in document order.
+func (d *Document) Mentions() []Mention {
+	mentions := []Mention{}
+	walkNodes(d.Nodes, func(n Node) {
+		if m, ok := n.(Mention); ok {
+			mentions = append(mentions, m)
+		}
+	})
+	return mentions
+}
+
+func (n Hashtag) String() string          { return String(n) }
+func (n Hashtag) Copy() Node              { return n }
+func (n Hashtag) Range(f func(Node) bool) {}
+func (n Hashtag) Position() Position      { return n.Pos }
+
+func (n Mention) String() string          { return String(n) }
+func (n Mention) Copy() Node              { return n }
+func (n Mention) Range(f func(Node) bool) {}
+func (n Mention) Position() Position      { return n.Pos }
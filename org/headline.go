@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 )
 
@@ -18,6 +20,8 @@ type Section struct {
 	Headline *Headline
 	Parent   *Section
 	Children []*Section
+
+	document *Document
 }
 
 type Headline struct {
@@ -47,31 +51,33 @@ func (d *Document) parseHeadline(i int, parentStop stopFn) (int, Node) {
 	t, headline := d.tokens[i], Headline{}
 	headline.Lvl = len(t.matches[1])
 	text := t.content
+	column := t.startCol + len(t.matches[0]) - len(t.content) // column is the column of text in the source line
 	todoKeywords := trimFastTags(
 		strings.FieldsFunc(d.Get("TODO"), func(r rune) bool { return unicode.IsSpace(r) || r == '|' }),
 	)
 	for _, k := range todoKeywords {
 		if strings.HasPrefix(text, k) && len(text) > len(k) && unicode.IsSpace(rune(text[len(k)])) {
 			headline.Status = k
-			text = text[len(k)+1:]
+			text, column = text[len(k)+1:], column+len(k)+1
 			break
 		}
 	}
 
 	if len(text) >= 4 && text[0:2] == "[#" && strings.Contains("ABC", text[2:3]) && text[3] == ']' {
 		headline.Priority = text[2:3]
-		text = strings.TrimSpace(text[4:])
+		trimmed := strings.TrimSpace(text[4:])
+		text, column = trimmed, column+len(text)-len(strings.TrimLeft(text[4:], " \t"))
 	}
 	if strings.HasPrefix(text, "COMMENT ") {
 		headline.IsComment = true
-		text = strings.TrimPrefix(text, "COMMENT ")
+		text, column = strings.TrimPrefix(text, "COMMENT "), column+len("COMMENT ")
 	}
 	if m := tagRegexp.FindStringSubmatch(text); m != nil {
 		text = m[1]
 		headline.Tags = strings.FieldsFunc(m[2], func(r rune) bool { return r == ':' })
 	}
 	headline.Index = d.addHeadline(&headline)
-	headline.Title = d.parseInlineWithPos(text, d.tokens[i].line, d.tokens[i].startCol+len(headline.Status)+len(headline.Priority)+headline.Lvl+2)
+	headline.Title = d.parseInlineWithPos(text, d.tokens[i].line, column)
 
 	stop := func(d *Document, i int) bool {
 		return parentStop(d, i) || d.tokens[i].kind == "headline" && len(d.tokens[i].matches[1]) <= headline.Lvl
@@ -85,12 +91,7 @@ func (d *Document) parseHeadline(i int, parentStop stopFn) (int, Node) {
 	}
 	headline.Children = nodes
 	endToken := d.tokens[i+consumed]
-	headline.Pos = Position{
-		StartLine:   t.line,
-		StartColumn: t.startCol,
-		EndLine:     endToken.line,
-		EndColumn:   endToken.endCol,
-	}
+	headline.Pos = d.getPositionBetweenTokens(t, endToken)
 	return consumed + 1, headline
 }
 
@@ -116,16 +117,293 @@ func (h Headline) ID() string {
 	return fmt.Sprintf("headline-%d", h.Index)
 }
 
+// IsExcluded returns true if the headline (and thus its subtree) should not be exported.
+// That's the case for commented headlines, headlines tagged with one of the ExcludeTags of the document and
+// - if any headline of the document is tagged with one of the SelectTags - headlines that are neither selected
+// themselves, nor a descendant or ancestor of a selected headline.
 func (h Headline) IsExcluded(d *Document) bool {
-	if h.IsComment {
+	index := d.outlineIndex()
+	if s := index.sections[h.key()]; s != nil && s.Headline.IsComment == h.IsComment && slices.Equal(s.Headline.Tags, h.Tags) {
+		return index.excluded[s]
+	}
+	if h.isExcludedByTags(d) {
 		return true
 	}
-	for _, excludedTag := range strings.Fields(d.Get("EXCLUDE_TAGS")) {
-		if slices.Contains(h.Tags, excludedTag) {
+	selectTags := d.SelectTags()
+	if len(selectTags) == 0 || !d.hasSelectedHeadlines(selectTags) {
+		return false
+	}
+	if slices.ContainsFunc(h.AllTags(d), func(tag string) bool { return slices.Contains(selectTags, tag) }) {
+		return false
+	}
+	selected := false
+	walkNodes(h.Children, func(n Node) {
+		if h, ok := n.(Headline); ok && slices.ContainsFunc(h.Tags, func(tag string) bool { return slices.Contains(selectTags, tag) }) {
+			selected = true
+		}
+	})
+	return !selected
+}
+
+func (h Headline) isExcludedByTags(d *Document) bool {
+	return h.IsComment || slices.ContainsFunc(d.ExcludeTags(), func(tag string) bool { return slices.Contains(h.Tags, tag) })
+}
+
+// headlineKey identifies a headline of the outline - see Outline.SectionOf.
+type headlineKey struct {
+	index, lvl int
+	pos        Position
+}
+
+func (h Headline) key() headlineKey { return headlineKey{h.Index, h.Lvl, h.Pos} }
+
+// outlineIndex maps the headlines of the outline of a document to their sections and caches which of them are
+// excluded from export (see Headline.IsExcluded) and their numbers (see Section.Number) - computing either for a
+// single headline requires a walk of the whole outline. It also indexes the sections by title and id for
+// FindHeadline and HeadlineByID.
+type outlineIndex struct {
+	root         *Section
+	tags         string // tags are the exclude, select and file tags the index was built for.
+	sections     map[headlineKey]*Section
+	excluded     map[*Section]bool
+	numbers      map[*Section]string
+	order        []*Section          // order contains the sections in document order.
+	titles       map[string]*Section // titles maps the titles (see PlainText) of the headlines to the first section with them.
+	foldedTitles map[string]*Section // foldedTitles is titles with lower case keys.
+	ids          map[string]*Section // ids maps ID properties and generated ids (see Headline.ID) to the first section with them.
+}
+
+// outlineIndexCache holds the outlineIndex and symbolIndex of a document. It is shared by the fragments of the document.
+type outlineIndexCache struct {
+	sync.Mutex
+	index   *outlineIndex
+	symbols *symbolIndex
+}
+
+// outlineIndex returns the outlineIndex of the document - it is rebuilt if the Outline or the tags changed.
+func (d *Document) outlineIndex() *outlineIndex {
+	tags := strings.Join(d.ExcludeTags(), " ") + "|" + strings.Join(d.SelectTags(), " ") + "|" + strings.Join(d.FileTags(), " ")
+	if d.index == nil {
+		return d.buildOutlineIndex(tags)
+	}
+	d.index.Lock()
+	defer d.index.Unlock()
+	if index := d.index.index; index != nil && index.root == d.Outline.Section && index.tags == tags {
+		return index
+	}
+	d.index.index = d.buildOutlineIndex(tags)
+	return d.index.index
+}
+
+func (d *Document) buildOutlineIndex(tags string) *outlineIndex {
+	index := &outlineIndex{
+		root:         d.Outline.Section,
+		tags:         tags,
+		sections:     map[headlineKey]*Section{},
+		excluded:     map[*Section]bool{},
+		numbers:      map[*Section]string{},
+		titles:       map[string]*Section{},
+		foldedTitles: map[string]*Section{},
+		ids:          map[string]*Section{},
+	}
+	if d.Outline.Section == nil {
+		return index
+	}
+	excludeTags, selectTags := d.ExcludeTags(), d.SelectTags()
+	isSelectTag := func(tag string) bool { return slices.Contains(selectTags, tag) }
+	fileSelected := slices.ContainsFunc(d.FileTags(), isSelectTag)
+	hasSelected := len(selectTags) != 0 && (fileSelected || d.hasSelectedHeadlines(selectTags))
+	// walk records the sections in document order and returns true if s or one of its descendants is selected
+	var walk func(s *Section, inherited bool) bool
+	walk = func(s *Section, inherited bool) bool {
+		h := s.Headline
+		selected := slices.ContainsFunc(h.Tags, isSelectTag)
+		if _, ok := index.sections[h.key()]; !ok {
+			index.sections[h.key()] = s
+		}
+		index.order = append(index.order, s)
+		title := strings.TrimSpace(PlainText(h.Title...))
+		addFirst(index.titles, title, s)
+		addFirst(index.foldedTitles, strings.ToLower(title), s)
+		if _, ok := h.Properties.Get("CUSTOM_ID"); !ok {
+			addFirst(index.ids, h.ID(), s)
+		}
+		if id, _ := h.Properties.Get("ID"); id != "" {
+			addFirst(index.ids, id, s)
+		}
+		descendantSelected := false
+		for _, child := range s.Children {
+			descendantSelected = walk(child, inherited || selected) || descendantSelected
+		}
+		excludedByTags := h.IsComment || slices.ContainsFunc(excludeTags, func(tag string) bool { return slices.Contains(h.Tags, tag) })
+		index.excluded[s] = excludedByTags || hasSelected && !inherited && !selected && !descendantSelected
+		return selected || descendantSelected
+	}
+	for _, s := range d.Outline.Children {
+		walk(s, fileSelected)
+	}
+	isNumbered := func(s *Section) bool { return !s.isUnnumbered() && !index.excluded[s] }
+	numberSections(d.Outline.Children, "", isNumbered, index.numbers)
+	return index
+}
+
+func addFirst(sections map[string]*Section, key string, s *Section) {
+	if _, ok := sections[key]; !ok {
+		sections[key] = s
+	}
+}
+
+// ExcludeTags returns the tags that exclude a headline from export. See Configuration.ExcludeTags.
+func (d *Document) ExcludeTags() []string {
+	return d.exportTags("EXCLUDE_TAGS", d.Configuration.ExcludeTags)
+}
+
+// SelectTags returns the tags that select a headline for export. See Configuration.SelectTags.
+func (d *Document) SelectTags() []string {
+	return d.exportTags("SELECT_TAGS", d.Configuration.SelectTags)
+}
+
+func (d *Document) exportTags(key string, defaultTags []string) []string {
+	for _, k := range []string{"EXPORT_" + key, key} {
+		if v, ok := d.BufferSettings[k]; ok {
+			return strings.Fields(v)
+		}
+	}
+	if defaultTags != nil {
+		return defaultTags
+	}
+	return strings.Fields(d.DefaultSettings[key])
+}
+
+func (d *Document) hasSelectedHeadlines(selectTags []string) bool {
+	isSelectTag := func(tag string) bool { return slices.Contains(selectTags, tag) }
+	if slices.ContainsFunc(d.FileTags(), isSelectTag) {
+		return true
+	}
+	var find func(*Section) bool
+	find = func(s *Section) bool {
+		if s.Headline != nil && slices.ContainsFunc(s.Headline.Tags, isSelectTag) {
 			return true
 		}
+		return slices.ContainsFunc(s.Children, find)
+	}
+	return d.Outline.Section != nil && find(d.Outline.Section)
+}
+
+// Body returns the nodes between the headline of the section and the next headline, i.e. the content of
+// the section without its subsections. For the root section of the Outline, Body returns the nodes before the first headline.
+func (s *Section) Body() []Node {
+	var nodes []Node
+	if s.Headline != nil {
+		nodes = s.Headline.Children
+	} else if s.document != nil {
+		nodes = s.document.Nodes
+	}
+	body := []Node{}
+	for _, n := range nodes {
+		if _, ok := n.(Headline); ok {
+			break
+		}
+		body = append(body, n)
+	}
+	return body
+}
+
+// Export exports the headline and body of the section (without its subsections) using w.
+// The document title and table of contents are not exported. Definitions of footnotes that are referenced
+// inside the section but defined outside of it are exported as well.
+func (s *Section) Export(w Writer) (string, error) {
+	if s.document == nil {
+		return "", fmt.Errorf("could not export section: section does not belong to a document")
+	}
+	nodes := s.Body()
+	if s.Headline != nil {
+		headline := *s.Headline
+		headline.Children = nodes
+		nodes = []Node{headline}
+	}
+	return s.document.fragment(append(nodes, s.document.missingFootnoteDefinitions(nodes)...), "title:nil toc:nil").Write(w)
+}
+
+// NextSibling returns the section following s at the same level of the outline - or nil if s is the last one.
+func (s *Section) NextSibling() *Section {
+	if i := s.siblingIndex(); i != -1 && i+1 < len(s.Parent.Children) {
+		return s.Parent.Children[i+1]
+	}
+	return nil
+}
+
+// PreviousSibling returns the section preceding s at the same level of the outline - or nil if s is the first one.
+func (s *Section) PreviousSibling() *Section {
+	if i := s.siblingIndex(); i > 0 {
+		return s.Parent.Children[i-1]
+	}
+	return nil
+}
+
+func (s *Section) siblingIndex() int {
+	if s.Parent == nil {
+		return -1
+	}
+	for i, sibling := range s.Parent.Children {
+		if sibling == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// Number returns the hierarchical section number of s, e.g. "1.2" for the second subsection of the first section.
+// Like for the num export option, sections that are excluded from export or have the UNNUMBERED property - and
+// their descendants - are not numbered: Number returns "" for them (and the root section of the outline).
+func (s *Section) Number() string {
+	if s.Headline == nil {
+		return ""
+	}
+	if s.document != nil {
+		if index := s.document.outlineIndex(); index.sections[s.Headline.key()] == s {
+			return index.numbers[s]
+		}
+	}
+	root := s
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	numbers := map[*Section]string{}
+	numberSections(root.Children, "", (*Section).isNumbered, numbers)
+	return numbers[s]
+}
+
+// numberSections records the numbers (see Section.Number) of the numbered sections and their numbered descendants.
+func numberSections(sections []*Section, prefix string, isNumbered func(*Section) bool, numbers map[*Section]string) {
+	i := 0
+	for _, s := range sections {
+		if !isNumbered(s) {
+			continue
+		}
+		i++
+		numbers[s] = prefix + strconv.Itoa(i)
+		numberSections(s.Children, numbers[s]+".", isNumbered, numbers)
+	}
+}
+
+// isNumbered returns true unless the headline of the section is excluded from export or has the UNNUMBERED property.
+func (s *Section) isNumbered() bool {
+	return !s.isUnnumbered() && (s.document == nil || !s.Headline.IsExcluded(s.document))
+}
+
+func (s *Section) isUnnumbered() bool {
+	v, _ := s.Headline.Properties.Get("UNNUMBERED")
+	return v != "" && v != "nil"
+}
+
+// Path returns the titles (see PlainText) of the ancestors of s from the top level section down to its parent.
+func (s *Section) Path() []string {
+	path := []string{}
+	for parent := s.Parent; parent != nil && parent.Headline != nil; parent = parent.Parent {
+		path = append([]string{strings.TrimSpace(PlainText(parent.Headline.Title...))}, path...)
 	}
-	return false
+	return path
 }
 
 func (parent *Section) add(current *Section) {
@@ -0,0 +1,190 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOutlineNavigation(t *testing.T) {
+	input := strings.Join([]string{
+		"* A",
+		"** A.1",
+		"** Appendix",
+		":PROPERTIES:",
+		":UNNUMBERED: t",
+		":END:",
+		"*** Nested",
+		"** /A.2/ [[https://example.com][link]]",
+		"*** A.2.1",
+		"* COMMENT Hidden",
+		"* B",
+	}, "\n")
+	d := New().Silent().Parse(strings.NewReader(input), "./outline.org")
+	a, hidden, b := d.Outline.Children[0], d.Outline.Children[1], d.Outline.Children[2]
+	a1, appendix, a2 := a.Children[0], a.Children[1], a.Children[2]
+	nested, a21 := appendix.Children[0], a2.Children[0]
+	for _, test := range []struct {
+		section  *Section
+		number   string
+		path     string
+		previous *Section
+		next     *Section
+	}{
+		{a, "1", "", nil, hidden},
+		{a1, "1.1", "A", nil, appendix},
+		{appendix, "", "A", a1, a2},
+		{nested, "", "A/Appendix", nil, nil},
+		{a2, "1.2", "A", appendix, nil},
+		{a21, "1.2.1", "A/A.2 link", nil, nil},
+		{hidden, "", "", a, b},
+		{b, "2", "", hidden, nil},
+	} {
+		title := test.section.Headline.Title
+		if number := test.section.Number(); number != test.number {
+			t.Errorf("%s: got number %q, expected %q", String(title...), number, test.number)
+		}
+		if path := strings.Join(test.section.Path(), "/"); path != test.path {
+			t.Errorf("%s: got path %q, expected %q", String(title...), path, test.path)
+		}
+		if previous, next := test.section.PreviousSibling(), test.section.NextSibling(); previous != test.previous || next != test.next {
+			t.Errorf("%s: got siblings %v %v, expected %v %v", String(title...), previous, next, test.previous, test.next)
+		}
+		if d.Outline.SectionOf(*test.section.Headline) != test.section || test.section.Parent == nil {
+			t.Errorf("%s: bad parent or SectionOf", String(title...))
+		}
+	}
+	if d.Outline.Number() != "" || len(d.Outline.Path()) != 0 || d.Outline.NextSibling() != nil {
+		t.Errorf("expected root section to have neither number, path nor siblings")
+	}
+	root, first, second, child := &Section{}, &Section{Headline: &Headline{Lvl: 1}}, &Section{Headline: &Headline{Lvl: 1}}, &Section{Headline: &Headline{Lvl: 2}}
+	root.add(first)
+	root.add(second)
+	second.add(child)
+	if number := child.Number(); number != "2.1" {
+		t.Errorf("got number %q for section without document, expected %q", number, "2.1")
+	}
+}
+
+func TestTOC(t *testing.T) {
+	input := strings.Join([]string{
+		"#+OPTIONS: num:t tags:nil",
+		"* TODO [#A] A :work:",
+		"** A.1",
+		":PROPERTIES:",
+		":CUSTOM_ID: custom",
+		":END:",
+		"*** Too deep",
+		"** Appendix",
+		":PROPERTIES:",
+		":UNNUMBERED: t",
+		":END:",
+		"* COMMENT Hidden",
+		"* B",
+	}, "\n")
+	d := New().Silent().Parse(strings.NewReader(input), "./toc.org")
+	var flatten func(items []TOCItem) []string
+	flatten = func(items []TOCItem) []string {
+		lines := []string{}
+		for _, item := range items {
+			line := fmt.Sprintf("%d %s %s #%s %s %s %v", item.Level, item.Number, item.Status, item.Priority, String(item.Title...), item.Anchor, item.Tags)
+			lines = append(append(lines, line), flatten(item.Children)...)
+		}
+		return lines
+	}
+	expected := []string{
+		"1 1 TODO #A A headline-1 []",
+		"2 1.1  # A.1 custom []",
+		"2   # Appendix headline-4 []",
+		"1 2  # B headline-5 []",
+	}
+	if actual := flatten(d.TOC(2)); strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got\n%s\nexpected\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+	if items := d.TOC(0); len(items[0].Children[0].Children) != 1 || items[0].Children[0].Section != d.Outline.Children[0].Children[0] {
+		t.Errorf("expected unlimited TOC to include the level 3 headline of its section")
+	}
+}
+
+func TestSectionBodyAndExport(t *testing.T) {
+	input := strings.Join([]string{
+		"#+TITLE: Title",
+		"intro",
+		"* A",
+		"a[fn:1]",
+		"** A1",
+		"a1",
+		"* Footnotes",
+		"[fn:1] note",
+	}, "\n")
+	d := New().Silent().Parse(strings.NewReader(input), "./section.org")
+	a := d.Outline.Children[0]
+	if body := String(d.Outline.Body()...); body != "#+TITLE: Title\nintro\n" {
+		t.Errorf("got root body %q", body)
+	}
+	if body := String(a.Body()...); body != "a[fn:1]\n" {
+		t.Errorf("got body of A %q", body)
+	}
+	if body := a.Children[0].Body(); len(body) != 1 || String(body...) != "a1\n" {
+		t.Errorf("got body of A1 %q", String(body...))
+	}
+	org, err := a.Export(NewOrgWriter())
+	if expected := "* A\na[fn:1]\n[fn:1] note\n"; err != nil || org != expected {
+		t.Errorf("got org export %q (error: %v), expected %q", org, err, expected)
+	}
+	html, err := a.Export(NewHTMLWriter())
+	if err != nil || strings.Contains(html, "Title") || strings.Contains(html, "A1") || !strings.Contains(html, "note") {
+		t.Errorf("expected html export of A without title and subsection but with footnote, got %q (error: %v)", html, err)
+	}
+	if _, err := (&Section{}).Export(NewOrgWriter()); err == nil {
+		t.Errorf("expected error exporting a section without document")
+	}
+}
+
+func TestSelectAndExcludeTags(t *testing.T) {
+	input := strings.Join([]string{
+		"* A",
+		"** A1 :export:",
+		"*** A1a",
+		"** A2",
+		"* B :noexport:",
+		"* COMMENT C :export:",
+		"* D",
+		"** D1 :private:",
+	}, "\n")
+	titles := func(d *Document) string {
+		included := []string{}
+		walkNodes(d.Nodes, func(n Node) {
+			if h, ok := n.(Headline); ok && !h.IsExcluded(d) {
+				included = append(included, String(h.Title...))
+			}
+		})
+		return strings.Join(included, " ")
+	}
+	d := New().Silent().Parse(strings.NewReader(input), "./tags.org")
+	if excludeTags, selectTags := d.Get("EXCLUDE_TAGS"), d.Get("SELECT_TAGS"); excludeTags != "noexport" || selectTags != "export" {
+		t.Errorf("got default EXCLUDE_TAGS %q and SELECT_TAGS %q", excludeTags, selectTags)
+	}
+	if actual := titles(d); actual != "A A1 A1a" {
+		t.Errorf("select tags: got %q", actual)
+	}
+	d = New().Silent().Parse(strings.NewReader("#+SELECT_TAGS: none\n"+input), "./tags.org")
+	if actual := titles(d); actual != "A A1 A1a A2 D D1" {
+		t.Errorf("#+SELECT_TAGS: got %q", actual)
+	}
+	c := New().Silent()
+	c.ExcludeTags, c.SelectTags = []string{"private"}, []string{}
+	d = c.Parse(strings.NewReader(input), "./tags.org")
+	if actual := titles(d); actual != "A A1 A1a A2 B D" {
+		t.Errorf("Configuration.ExcludeTags: got %q", actual)
+	}
+	d.BufferSettings["EXCLUDE_TAGS"] = "export"
+	if actual := titles(d); actual != "A A1a A2 B D D1" { // descendants of excluded headlines are skipped by writers
+		t.Errorf("changed EXCLUDE_TAGS: got %q", actual)
+	}
+	h := *d.Outline.Children[0].Headline
+	h.Tags = []string{"export"}
+	if !h.IsExcluded(d) {
+		t.Errorf("expected modified headline to be excluded")
+	}
+}
@@ -0,0 +1,36 @@
+package org
+
+import "time"
+
+// Hooks are optional callbacks that report the progress and the durations of parsing and writing - e.g. to show
+// progress bars for batch conversions or to export timing metrics. Hooks are called synchronously by the goroutine
+// parsing or writing the document and must be safe for concurrent use if the Configuration is shared.
+type Hooks struct {
+	// LinesTokenized is called while the input of Parse is tokenized with the number of lines tokenized so far and the
+	// total number of lines - every progressInterval lines and once all lines have been tokenized.
+	LinesTokenized func(d *Document, lines, total int)
+	// NodeParsed is called for each parsed top level node with the number of tokens consumed so far and the total
+	// number of tokens to parse (i.e. of the parsed range for Reparse and ParseStream).
+	NodeParsed func(d *Document, n Node, tokens, total int)
+	// PhaseDone is called at the end of each phase of parsing and writing with its duration.
+	PhaseDone func(d *Document, phase Phase, duration time.Duration)
+}
+
+// Phase is a phase of parsing or writing a document. See Hooks.PhaseDone.
+type Phase string
+
+const (
+	PhaseTokenize Phase = "tokenize" // PhaseTokenize is reading and tokenizing the input of Parse.
+	PhaseParse    Phase = "parse"    // PhaseParse is parsing the tokens into nodes.
+	PhaseWrite    Phase = "write"    // PhaseWrite is writing the document using Write, WriteV2, WriteStream or WriteParallel.
+)
+
+// progressInterval is the number of lines between calls of Hooks.LinesTokenized.
+const progressInterval = 1024
+
+// phaseDone calls Hooks.PhaseDone for the phase that started at start.
+func (d *Document) phaseDone(phase Phase, start time.Time) {
+	if d.Hooks.PhaseDone != nil {
+		d.Hooks.PhaseDone(d, phase, time.Since(start))
+	}
+}
@@ -0,0 +1,37 @@
+package org
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	lines, nodes, phases := [][2]int{}, [][2]int{}, []Phase{}
+	c := New(WithHooks(Hooks{
+		LinesTokenized: func(d *Document, done, total int) { lines = append(lines, [2]int{done, total}) },
+		NodeParsed:     func(d *Document, n Node, tokens, total int) { nodes = append(nodes, [2]int{tokens, total}) },
+		PhaseDone: func(d *Document, phase Phase, duration time.Duration) {
+			if duration < 0 {
+				t.Errorf("negative duration %s for %s", duration, phase)
+			}
+			phases = append(phases, phase)
+		},
+	}))
+	input := strings.Repeat("text\n", 2*progressInterval) + "* A\n* B\n"
+	d := c.Parse(strings.NewReader(input), "./hooks.org")
+	if _, err := d.Write(NewHTMLWriter()); err != nil {
+		t.Fatal(err)
+	}
+	total := 2*progressInterval + 2
+	if expected := [][2]int{{progressInterval, total}, {2 * progressInterval, total}, {total, total}}; !reflect.DeepEqual(lines, expected) {
+		t.Errorf("unexpected tokenize progress: %v", lines)
+	}
+	if expected := [][2]int{{total - 2, total}, {total - 1, total}, {total, total}}; !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("unexpected parse progress: %v", nodes)
+	}
+	if expected := []Phase{PhaseTokenize, PhaseParse, PhaseWrite}; !reflect.DeepEqual(phases, expected) {
+		t.Errorf("unexpected phases: %v", phases)
+	}
+}
@@ -1,10 +1,16 @@
 package org
 
 import (
+	"encoding/json"
 	"fmt"
 	"html"
-	"log"
+	"html/template"
+	"io"
+	"log/slog"
+	"maps"
+	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode"
@@ -16,8 +22,15 @@ import (
 )
 
 // HTMLWriter exports an org document into a html document.
+// An HTMLWriter must not be used by multiple goroutines at once (see Document.WriteParallel).
 type HTMLWriter struct {
-	ExtendingWriter     Writer
+	ExtendingWriter Writer
+	// HighlightCodeBlock is used to render the content of src blocks and inline src blocks.
+	// inline is true for inline src blocks (which are called with nil params) and params contains the
+	// header arguments of src blocks (e.g. ":hl_lines"). If lines are to be numbered (see BlockSwitches), params additionally
+	// contains the number of the first line as ":linenostart". The returned string is written verbatim - i.e. it has to be escaped.
+	//
+	// Defaults to DefaultHighlightCodeBlock. See the highlight package for a chroma based implementation.
 	HighlightCodeBlock  func(source, lang string, inline bool, params map[string]string) string
 	PrettyRelativeLinks bool
 	// TopLevelHLevel determines what HTML heading to use for a
@@ -35,35 +48,235 @@ type HTMLWriter struct {
 	// :html-toplevel-hlevel export property and the associated
 	// org-html-toplevel-hlevel variable.
 	TopLevelHLevel int
+	// MathMode determines how latex fragments and latex blocks are rendered. Defaults to MathModeRaw.
+	MathMode MathMode
+	// MathEnvironments lists the latex environments (e.g. "equation" or "align*") that are rendered as display math
+	// according to MathMode. Other environments (e.g. "tikzpicture") are written verbatim as with MathModeRaw.
+	// Defaults to nil, i.e. all environments are rendered as math.
+	MathEnvironments []string
+	// RenderMath renders latex to HTML for MathModeMathML (returns MathML markup)
+	// and MathModeImage (returns the src of an image). display is true for display (block) math.
+	// If RenderMath is nil or returns an error, math is rendered as with MathModeMathJax.
+	RenderMath func(latex string, display bool) (string, error)
+	// HeadlineSlug generates the HTML ids of headlines (e.g. SlugifyHeadline) that do not have a CUSTOM_ID property.
+	// Duplicate slugs are made unique by appending -1, -2, ... in document order.
+	// Defaults to nil, i.e. positional ids (headline-1, headline-2, ...) - see Headline.ID.
+	HeadlineSlug func(headline Headline) string
+	// Template is used to render the page shell around the exported document. If Template is nil (default),
+	// the title, table of contents, content and footnotes are simply concatenated. See HTMLPage for the
+	// data passed to the template. Errors executing the template are returned by Document.Write.
+	Template *template.Template
+	// TOCDepth limits the headline levels included in tables of contents. It takes precedence over the level
+	// given via the toc export option and #+TOC: headlines n keywords. Defaults to 0, i.e. no additional limit.
+	TOCDepth int
+	// TOCNumbered adds section numbers (e.g. "1.2") to the headline entries of tables of contents - even if
+	// headlines are not numbered via the num export option.
+	TOCNumbered bool
+	// RenderTOC renders a table of contents from structured data - kind is one of "headlines", "tables" and "listings".
+	// Defaults to nil, i.e. nested <ul> lists inside of a <nav> element.
+	RenderTOC func(kind string, entries []TOCEntry) string
+	// NumberCaptions assigns numbered labels (e.g. "Figure 3") to captioned images, tables and src blocks.
+	// Captioned tables are rendered with a <caption> rather than wrapped in a <figure> and links to the #+NAME of
+	// a numbered node (e.g. [[my-table]]) render as cross references, i.e. using the label as their description.
+	NumberCaptions bool
+	// SafeMode makes the output safe to embed user-submitted content: raw HTML (#+BEGIN_EXPORT html,
+	// @@html:...@@ and #+HTML keywords) is dropped, links using unsafe protocols (javascript:, vbscript:, data:)
+	// are rendered as plain text and #+ATTR_HTML event handler attributes (onclick, ...) are ignored.
+	SafeMode bool
+	// FootnoteMode determines where footnote definitions are rendered. Defaults to FootnoteModeEndnotes.
+	// The fnsec export option (see Document.GetOption) switches it to FootnoteModeSections.
+	FootnoteMode FootnoteMode
+	// FootnoteSectionLevel is the depth of the sections that end with the definitions of the footnotes referenced in
+	// them in FootnoteModeSections, e.g. 2 to render footnotes at the end of each subsection. The level given via the
+	// fnsec export option takes precedence. Defaults to 0, i.e. top level sections.
+	FootnoteSectionLevel int
+	// ClassPrefix is prepended to all classes generated by the writer, e.g. "org-" turns class="todo" into class="org-todo".
+	// Classes generated by HighlightCodeBlock and classes set via #+ATTR_HTML are not prefixed.
+	ClassPrefix string
+	// ClassMap maps node kinds to additional (unprefixed) classes for the elements generated for them, e.g.
+	// {"Emphasis/bold": "font-bold", "Table": "table-auto"}. Kinds are node type names, optionally followed by a variant:
+	// Title, Paragraph, HorizontalRule, Timestamp, StatisticToken, Emoji, Hashtag, Mention, RegularLink,
+	// RegularLink/{wiki,missing} (wiki links, see Configuration.ResolveWikiLink), Image, Video, Figure, Caption/number,
+	// Headline, Headline/{title,number,text,todo,priority,tags,tag}, Headline/{list,item} (headlines exported as lists,
+	// see the H option), Emphasis/{bold,italic,underline,strikethrough,code,verbatim,subscript,superscript},
+	// Emphasis/<marker> (custom markers), List/{unordered,ordered,descriptive}, ListItem,
+	// ListItem/{checked,unchecked,indeterminate}, Table, TableCell, Example, Block/{src,example,quote,verse,center},
+	// Block/<name> (special blocks), Block/citation, InlineBlock/src, Math/{inline,display}, TOC/{headlines,tables,listings},
+	// TOC/number, FootnoteLink, Footnotes, Footnotes/separator, Footnotes/definitions, FootnoteDefinition,
+	// FootnoteDefinition/body and FootnoteDefinition/sidenote.
+	ClassMap map[string]string
+	// EmphasisTags overrides the HTML elements emphasis kinds ("*", "/", "_", "+", "=", "~", "_{}" and "^{}") are
+	// rendered as, e.g. {"_": {Element: "u"}, "+": {Element: "s"}}. Kinds not contained fall back to DefaultEmphasisTags.
+	// Custom markers (see Configuration.EmphasisComponents) fall back to span elements with the ClassMap kind
+	// Emphasis/<marker>.
+	EmphasisTags map[string]EmphasisTag
+	// DataPos adds data-pos="line:column" attributes containing the (0-based) start position of the source node to the
+	// elements generated for headlines, paragraphs, lists, list items, tables, blocks, examples and horizontal rules -
+	// e.g. to implement scroll sync for live previews.
+	DataPos bool
+	// SourceMap records the source positions of all elements with a data-pos attribute. See HTMLWriter.SourceMapJSON.
+	SourceMap bool
+	// SpecialBlocks overrides the HTML elements special blocks (i.e. blocks without a predefined meaning, e.g.
+	// #+BEGIN_aside) are rendered as, keyed by the lower case name of the block, e.g. {"aside": {Element: "aside"},
+	// "details": {Element: "details", Summary: "summary"}}. Blocks not contained are rendered as <div class="name-block">.
+	SpecialBlocks map[string]SpecialBlock
+	// StatisticProgress renders statistic cookies ([3/7] and [42%]) as <progress> elements labeled with (and - for
+	// browsers without support - containing) the cookie text. Cookies without a valid ratio are rendered as <code>.
+	StatisticProgress bool
+	// EmojiImageURL returns the URL of the image an Emoji is rendered as (e.g. for custom emoji) - or "" to render
+	// its unicode character. Defaults to nil, i.e. all emoji are rendered as unicode characters.
+	EmojiImageURL func(shortcode string) string
+	// NodeWriters write custom nodes (e.g. the nodes of InlineParsers), keyed by their type. See AddNodeWriter.
+	NodeWriters map[reflect.Type]func(*HTMLWriter, Node)
 
 	strings.Builder
-	document   *Document
-	htmlEscape bool
-	log        *log.Logger
-	footnotes  *footnotes
+	document    *Document
+	htmlEscape  bool
+	inRawText   bool
+	footnotes   *footnotes
+	headlineIDs map[headlineKey]string
+	// sectionNumbers contains the section numbers of numbered headlines, see generateSectionNumbers.
+	sectionNumbers map[Position]string
+	// footnoteMode and footnoteLevel are the FootnoteMode and FootnoteSectionLevel resolved against the fnsec export option.
+	footnoteMode  FootnoteMode
+	footnoteLevel int
+	captions      map[Position]captionLabel
+	namedCaptions map[string]captionLabel
+	page          *HTMLPage
+	err           error // err is the first error of writing the document, e.g. of executing the Template - see After.
+	headlines     []Headline
+	// nextLineNumber is the line number following the last numbered src block (see BlockSwitches).
+	nextLineNumber int
+	sourceMap      []SourceMapEntry
+	sourceMapPos   map[string]bool // sourceMapPos contains the keys of the entries of sourceMap.
+	// fork is set for the writers returned by Fork.
+	fork *htmlFork
+	// defaultEmphasisTags is the copy of DefaultEmphasisTags made by NewHTMLWriter.
+	defaultEmphasisTags map[string]EmphasisTag
+}
+
+// SourceMapEntry maps an element with a data-pos attribute to the position of the node it was generated for.
+// See HTMLWriter.SourceMap.
+type SourceMapEntry struct {
+	DataPos     string `json:"dataPos"` // DataPos is the value of the data-pos attribute of the element.
+	Kind        string `json:"kind"`    // Kind is the type name of the node, e.g. "Paragraph".
+	StartLine   int    `json:"startLine"`
+	StartColumn int    `json:"startColumn"`
+	EndLine     int    `json:"endLine"`
+	EndColumn   int    `json:"endColumn"`
+}
+
+// key identifies the entry - nodes of different kinds (e.g. a list item and its first paragraph) may start at the
+// same position.
+func (e SourceMapEntry) key() string { return e.Kind + " " + e.DataPos }
+
+type captionLabel struct {
+	id     string
+	kind   string
+	number int
+}
+
+var captionKindLabels = map[string]string{"figures": "Figure", "tables": "Table", "listings": "Listing"}
+
+// TOCEntry is an entry of a table of contents rendered by the HTMLWriter. See HTMLWriter.RenderTOC.
+type TOCEntry struct {
+	ID       string // ID is the HTML id of the referenced element (HTML escaped).
+	Title    string // Title is the rendered HTML title of the entry (the caption for tables and listings).
+	Number   string // Number is the section number (e.g. "1.2") of headline entries if headlines are numbered.
+	Level    int    // Level is the headline level of headline entries and 0 otherwise.
+	Children []TOCEntry
+}
+
+// HTMLPage is the data passed to HTMLWriter.Template.
+type HTMLPage struct {
+	Title     template.HTML     // Title is the rendered #+TITLE (empty if the title export option is disabled).
+	TOC       template.HTML     // TOC is the rendered table of contents (empty if the toc export option is disabled).
+	Content   template.HTML     // Content is the rendered document.
+	Footnotes template.HTML     // Footnotes is the rendered footnotes section.
+	Settings  map[string]string // Settings contains the DefaultSettings overridden by the BufferSettings (e.g. AUTHOR, DATE).
+	Document  *Document
 }
 
 type footnotes struct {
 	mapping map[string]int
 	list    []*FootnoteDefinition
 	unused  map[string]*FootnoteDefinition
+	written int            // written is the number of definitions of list that have already been written.
+	index   *FootnoteIndex // index is used to resolve definitions that have not been written yet (see FootnoteMode).
+}
+
+// htmlFork contains the state of a writer returned by HTMLWriter.Fork that is needed to Join it.
+type htmlFork struct {
+	footnotes *footnotes // footnotes is the predicted footnote state at the start of the section.
+	// numberedLines is true once the fork has written a src block with line numbers and continuedLines is true if
+	// that block continued the numbering of the previous block, i.e. of a previous section.
+	numberedLines, continuedLines bool
+}
+
+// FootnoteMode determines where the HTMLWriter renders footnote definitions.
+type FootnoteMode int
+
+const (
+	FootnoteModeEndnotes  FootnoteMode = iota // FootnoteModeEndnotes renders all definitions at the end of the document.
+	FootnoteModeSections                      // FootnoteModeSections renders definitions at the end of the section referencing them. See HTMLWriter.FootnoteSectionLevel.
+	FootnoteModeSidenotes                     // FootnoteModeSidenotes renders definitions as inline <span> elements next to their reference.
+)
+
+// EmphasisTag is the HTML element an emphasis is rendered as. See HTMLWriter.EmphasisTags.
+type EmphasisTag struct {
+	Element    string            // Element is the name of the element, e.g. "em". The content is written as is if Element is empty.
+	Attributes map[string]string // Attributes are written in alphabetical order. The class attribute is subject to ClassPrefix.
 }
 
-var emphasisTags = map[string][]string{
-	"/":   {"<em>", "</em>"},
-	"*":   {"<strong>", "</strong>"},
-	"+":   {"<del>", "</del>"},
-	"~":   {"<code>", "</code>"},
-	"=":   {`<code class="verbatim">`, "</code>"},
-	"_":   {`<span style="text-decoration: underline;">`, "</span>"},
-	"_{}": {"<sub>", "</sub>"},
-	"^{}": {"<sup>", "</sup>"},
+// SpecialBlock is the HTML element a special block is rendered as. See HTMLWriter.SpecialBlocks.
+type SpecialBlock struct {
+	Element    string            // Element is the name of the element, e.g. "aside". Defaults to "div".
+	Attributes map[string]string // Attributes are written in alphabetical order. The class attribute replaces the default class "name-block".
+	// Summary is the name of the element the parameters of the block (e.g. "Title" in "#+BEGIN_details Title") are
+	// written as, e.g. "summary". The element is written as first child if the block has parameters.
+	Summary string
 }
 
+// DefaultEmphasisTags maps emphasis kinds (e.g. "*" for bold) to the HTML elements they are rendered as by default.
+// NewHTMLWriter copies it - changes only affect writers created afterwards.
+var DefaultEmphasisTags = map[string]EmphasisTag{
+	"/":   {"em", nil},
+	"*":   {"strong", nil},
+	"+":   {"del", nil},
+	"~":   {"code", nil},
+	"=":   {"code", map[string]string{"class": "verbatim"}},
+	"_":   {"span", map[string]string{"style": "text-decoration: underline;"}},
+	"_{}": {"sub", nil},
+	"^{}": {"sup", nil},
+}
+
+// emphasisKinds contains the names of the emphasis kinds used for ClassMap.
+var emphasisKinds = map[string]string{
+	"/":   "italic",
+	"*":   "bold",
+	"+":   "strikethrough",
+	"~":   "code",
+	"=":   "verbatim",
+	"_":   "underline",
+	"_{}": "subscript",
+	"^{}": "superscript",
+}
+
+// MathMode determines how the HTMLWriter renders latex fragments and latex blocks.
+type MathMode int
+
+const (
+	MathModeRaw     MathMode = iota // MathModeRaw writes math as is, i.e. with the delimiters used in the Org mode source.
+	MathModeMathJax                 // MathModeMathJax normalizes delimiters to \(...\) for inline and \[...\] for display math.
+	MathModeKaTeX                   // MathModeKaTeX wraps math into <span class="math math-inline"> / <span class="math math-display"> elements.
+	MathModeMathML                  // MathModeMathML renders math server side into MathML using HTMLWriter.RenderMath.
+	MathModeImage                   // MathModeImage renders math into images using HTMLWriter.RenderMath.
+)
+
 var listTags = map[ListKind][]string{
-	UnorderedList:   {"<ul>", "</ul>"},
-	OrderedList:     {"<ol>", "</ol>"},
-	DescriptiveList: {"<dl>", "</dl>"},
+	UnorderedList:   {"ul", "unordered"},
+	OrderedList:     {"ol", "ordered"},
+	DescriptiveList: {"dl", "descriptive"},
 }
 
 var listItemStatuses = map[string]string{
@@ -73,21 +286,15 @@ var listItemStatuses = map[string]string{
 }
 
 var cleanHeadlineTitleForHTMLAnchorRegexp = regexp.MustCompile(`</?a[^>]*>`) // nested a tags are not valid HTML
-var tocHeadlineMaxLvlRegexp = regexp.MustCompile(`headlines\s+(\d+)`)
+var tocKeywordRegexp = regexp.MustCompile(`^(headlines|tables|listings)(?:\s+(\d+))?(\s+local)?\s*$`)
 
 func NewHTMLWriter() *HTMLWriter {
-	defaultConfig := New()
 	return &HTMLWriter{
-		document:   &Document{Configuration: defaultConfig},
-		log:        defaultConfig.Log,
-		htmlEscape: true,
-		HighlightCodeBlock: func(source, lang string, inline bool, params map[string]string) string {
-			if inline {
-				return fmt.Sprintf("<div class=\"highlight-inline\">\n<pre>\n%s\n</pre>\n</div>", html.EscapeString(source))
-			}
-			return fmt.Sprintf("<div class=\"highlight\">\n<pre>\n%s\n</pre>\n</div>", html.EscapeString(source))
-		},
-		TopLevelHLevel: 2,
+		document:            &Document{Configuration: New()},
+		htmlEscape:          true,
+		HighlightCodeBlock:  DefaultHighlightCodeBlock,
+		TopLevelHLevel:      2,
+		defaultEmphasisTags: copyEmphasisTags(DefaultEmphasisTags),
 		footnotes: &footnotes{
 			mapping: map[string]int{},
 			unused:  map[string]*FootnoteDefinition{},
@@ -95,6 +302,42 @@ func NewHTMLWriter() *HTMLWriter {
 	}
 }
 
+func copyEmphasisTags(tags map[string]EmphasisTag) map[string]EmphasisTag {
+	out := make(map[string]EmphasisTag, len(tags))
+	for kind, tag := range tags {
+		out[kind] = EmphasisTag{tag.Element, maps.Clone(tag.Attributes)}
+	}
+	return out
+}
+
+// DefaultHighlightCodeBlock is the default HTMLWriter.HighlightCodeBlock. It does not highlight anything and
+// just escapes the source code.
+func DefaultHighlightCodeBlock(source, lang string, inline bool, params map[string]string) string {
+	if inline {
+		return fmt.Sprintf("<div class=\"highlight-inline\">\n<pre>\n%s\n</pre>\n</div>", html.EscapeString(source))
+	}
+	if params[":linenostart"] == "" && params[":hl_lines"] == "" {
+		return fmt.Sprintf("<div class=\"highlight\">\n<pre>\n%s\n</pre>\n</div>", html.EscapeString(source))
+	}
+	lineNumber, _ := strconv.Atoi(params[":linenostart"])
+	ranges, lines := ParseRanges(params[":hl_lines"]), []string{}
+	for i, line := range strings.Split(source, "\n") {
+		class := "line"
+		for _, r := range ranges {
+			if i+1 >= r[0] && i+1 <= r[1] {
+				class += " highlighted"
+				break
+			}
+		}
+		lineNumberHTML := ""
+		if lineNumber != 0 {
+			lineNumberHTML = fmt.Sprintf(`<span class="line-number">%d</span>`, lineNumber+i)
+		}
+		lines = append(lines, fmt.Sprintf(`<span class="%s">%s%s</span>`, class, lineNumberHTML, html.EscapeString(line)))
+	}
+	return fmt.Sprintf("<div class=\"highlight\">\n<pre>\n%s\n</pre>\n</div>", strings.Join(lines, "\n"))
+}
+
 func (w *HTMLWriter) WriteNodesAsString(nodes ...Node) string {
 	original := w.Builder
 	w.Builder = strings.Builder{}
@@ -104,6 +347,20 @@ func (w *HTMLWriter) WriteNodesAsString(nodes ...Node) string {
 	return out
 }
 
+// WriteNodesTo implements Writer. If a Template is used, the output of Document.WriteStream is only moved to out
+// once the complete page has been rendered (see After).
+func (w *HTMLWriter) WriteNodesTo(out io.Writer, nodes ...Node) error {
+	return writeNodesTo(w, &w.Builder, out, func(s string, last bool) int {
+		if w.page != nil {
+			return len(s)
+		}
+		return 0
+	}, nodes)
+}
+
+// Backend implements Writer. Only export blocks and snippets for html are written.
+func (w *HTMLWriter) Backend() string { return "html" }
+
 func (w *HTMLWriter) WriterWithExtensions() Writer {
 	if w.ExtendingWriter != nil {
 		return w.ExtendingWriter
@@ -111,40 +368,282 @@ func (w *HTMLWriter) WriterWithExtensions() Writer {
 	return w
 }
 
+// AddNodeWriter registers f to write all nodes with the same type as node, e.g. the nodes of an InlineParser.
+func (w *HTMLWriter) AddNodeWriter(node Node, f func(*HTMLWriter, Node)) *HTMLWriter {
+	if w.NodeWriters == nil {
+		w.NodeWriters = map[reflect.Type]func(*HTMLWriter, Node){}
+	}
+	w.NodeWriters[reflect.TypeOf(node)] = f
+	return w
+}
+
+// WriteCustomNode implements CustomNodeWriter using the NodeWriters. Emoji, hashtags and mentions are written unless
+// overridden.
+func (w *HTMLWriter) WriteCustomNode(n Node) bool {
+	if f, ok := w.NodeWriters[reflect.TypeOf(n)]; ok {
+		f(w, n)
+		return true
+	}
+	switch n := n.(type) {
+	case Emoji:
+		w.writeEmoji(n)
+	case Hashtag:
+		w.WriteString("<span" + w.class("Hashtag", "hashtag") + ">#" + html.EscapeString(n.Name) + "</span>")
+	case Mention:
+		w.WriteString("<span" + w.class("Mention", "mention") + ">@" + html.EscapeString(n.Name) + "</span>")
+	default:
+		return false
+	}
+	return true
+}
+
+// writeEmoji writes e as its unicode character - or as an image if HTMLWriter.EmojiImageURL returns a URL for it.
+func (w *HTMLWriter) writeEmoji(e Emoji) {
+	if w.EmojiImageURL != nil {
+		if url := w.EmojiImageURL(e.Shortcode); url != "" {
+			shortcode := html.EscapeString(":" + e.Shortcode + ":")
+			w.WriteString(`<img` + w.class("Emoji") + ` src="` + html.EscapeString(url) + `" alt="` + shortcode + `" title="` + shortcode + `" />`)
+			return
+		}
+	}
+	if e.Unicode == "" {
+		w.WriteString(html.EscapeString(":" + e.Shortcode + ":"))
+		return
+	}
+	w.WriteString(e.Unicode)
+}
+
 func (w *HTMLWriter) Before(d *Document) {
-	w.document = d
-	w.log = d.Log
-	if title := d.Get("TITLE"); title != "" && w.document.GetOption("title") != "nil" {
-		titleDocument := d.Parse(strings.NewReader(title), d.Path)
-		if !titleDocument.HasErrors() {
+	w.document, w.err = d, nil
+	w.headlineIDs = w.generateHeadlineIDs(d)
+	w.sectionNumbers = w.generateSectionNumbers(d)
+	w.nextLineNumber = 1
+	w.sourceMap, w.sourceMapPos = nil, map[string]bool{}
+	w.resolveFootnoteMode()
+	if w.footnoteMode != FootnoteModeEndnotes {
+		index := d.Footnotes()
+		w.footnotes.index = &index
+	}
+	w.captions, w.namedCaptions = w.generateCaptionLabels(d)
+	w.page = nil
+	title := ""
+	if rawTitle := d.Get("TITLE"); rawTitle != "" && w.document.GetOption("title") != "nil" {
+		title = rawTitle
+		titleDocument := d.Parse(strings.NewReader(rawTitle), d.Path)
+		if len(titleDocument.errorsWithoutFootnotes()) == 0 {
 			simpleTitle := false
 			if len(titleDocument.Nodes) == 1 {
 				switch p := titleDocument.Nodes[0].(type) {
 				case Paragraph:
 					simpleTitle = true
-					title = w.WriteNodesAsString(p.Children...)
+					title = w.WriteNodesAsString(p.InlineNodes()...)
 				}
 			}
 			if !simpleTitle {
 				title = w.WriteNodesAsString(titleDocument.Nodes...)
 			}
 		}
-		w.WriteString(fmt.Sprintf(`<h1 class="title">%s</h1>`+"\n", title))
 	}
+	toc := ""
 	if w.document.GetOption("toc") != "nil" {
 		maxLvl, _ := strconv.Atoi(w.document.GetOption("toc"))
-		w.WriteOutline(d, maxLvl)
+		toc = w.captureString(func() { w.WriteOutline(d, maxLvl) })
+	}
+	if w.Template != nil {
+		w.page = &HTMLPage{Title: template.HTML(title), TOC: template.HTML(toc), Settings: map[string]string{}, Document: d}
+		for _, settings := range []map[string]string{d.DefaultSettings, d.BufferSettings} {
+			for k, v := range settings {
+				w.page.Settings[k] = v
+			}
+		}
+		return
+	}
+	if title != "" {
+		w.WriteString(fmt.Sprintf(`<h1%s>%s</h1>`+"\n", w.class("Title", "title"), title))
 	}
+	w.WriteString(toc)
 }
 
 func (w *HTMLWriter) After(d *Document) {
-	w.WriteFootnotes(d)
+	if w.page == nil {
+		w.WriteFootnotes(d)
+		return
+	}
+	w.page.Footnotes = template.HTML(w.captureString(func() { w.WriteFootnotes(d) }))
+	w.page.Content = template.HTML(w.String())
+	w.Builder = strings.Builder{}
+	if err := w.Template.Execute(&w.Builder, w.page); err != nil {
+		w.reportError(fmt.Errorf("could not execute template: %w", err))
+	}
+}
+
+// writeError implements errorReporter.
+func (w *HTMLWriter) writeError() error { return w.err }
+
+// reportError implements errorReporter.
+func (w *HTMLWriter) reportError(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// Fork implements ParallelWriter. The footnote numbering at the start of each section is predicted from the
+// footnotes referenced in the preceding sections - sections whose prediction turns out wrong (e.g. because of
+// footnotes in excluded drawers) are written sequentially. Writers with an ExtendingWriter cannot be forked.
+func (w *HTMLWriter) Fork(sections [][]Node) []Writer {
+	if w.ExtendingWriter != nil {
+		return nil
+	}
+	predicted, forks := w.footnotes.clone(), make([]Writer, len(sections))
+	for i, section := range sections {
+		fork := *w
+		fork.Builder, fork.headlines, fork.sourceMap, fork.sourceMapPos = strings.Builder{}, nil, nil, map[string]bool{}
+		fork.footnotes, fork.fork = predicted.clone(), &htmlFork{footnotes: predicted.clone()}
+		forks[i] = &fork
+		w.predictFootnotes(predicted, section, 0)
+	}
+	return forks
+}
+
+// Join implements ParallelWriter.
+func (w *HTMLWriter) Join(fork Writer) bool {
+	f := fork.(*HTMLWriter)
+	if !w.footnotes.equal(f.fork.footnotes) || f.fork.continuedLines {
+		return false
+	}
+	w.WriteString(f.String())
+	w.footnotes = f.footnotes
+	if f.fork.numberedLines {
+		w.nextLineNumber = f.nextLineNumber
+	}
+	for _, entry := range f.sourceMap {
+		if !w.sourceMapPos[entry.key()] {
+			w.sourceMapPos[entry.key()] = true
+			w.sourceMap = append(w.sourceMap, entry)
+		}
+	}
+	return true
+}
+
+// predictFootnotes updates fs with the footnote references and definitions of nodes in the order they are written.
+// depth is the number of headlines containing the nodes. See Fork.
+func (w *HTMLWriter) predictFootnotes(fs *footnotes, nodes []Node, depth int) {
+	writeDefinitions := func() {
+		for ; fs.written < len(fs.list); fs.written++ {
+			if definition := fs.list[fs.written]; definition != nil {
+				w.predictFootnotes(fs, definition.Children, depth+1)
+			}
+		}
+	}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case Headline:
+			if n.IsExcluded(w.document) {
+				continue
+			}
+			sections, level := w.footnoteMode == FootnoteModeSections, w.footnoteLevel
+			if sections && depth != 0 && depth < level {
+				writeDefinitions()
+			}
+			w.predictFootnotes(fs, n.Title, depth+1)
+			w.predictFootnotes(fs, n.Children, depth+1)
+			if sections && depth < level {
+				writeDefinitions()
+			}
+			continue
+		case RegularLink:
+			w.predictFootnotes(fs, n.Description, depth)
+		case FootnoteLink:
+			if w.document.GetOption("f") == "nil" {
+				continue
+			}
+			if i, isNew := fs.add(n); isNew && w.footnoteMode == FootnoteModeSidenotes {
+				if definition := fs.list[i]; definition != nil {
+					w.predictFootnotes(fs, definition.Children, depth)
+				}
+				fs.written = len(fs.list)
+			}
+		case FootnoteDefinition:
+			fs.updateDefinition(n)
+			continue
+		case nil:
+			continue
+		}
+		n.Range(func(child Node) bool {
+			w.predictFootnotes(fs, []Node{child}, depth)
+			return true
+		})
+	}
+}
+
+// class returns the class attribute (e.g. ` class="org-src org-src-go"`) for an element generated for a node of the
+// given kind with the given default classes - or "" if there are no classes. See ClassPrefix and ClassMap.
+func (w *HTMLWriter) class(kind string, classes ...string) string {
+	out := []string{}
+	for _, class := range classes {
+		if class != "" {
+			out = append(out, w.ClassPrefix+class)
+		}
+	}
+	if class := w.ClassMap[kind]; class != "" {
+		out = append(out, class)
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return ` class="` + html.EscapeString(strings.Join(out, " ")) + `"`
+}
+
+// dataPos returns the data-pos attribute (e.g. ` data-pos="3:0"`) for the element generated for the node - or "" if
+// DataPos is disabled. See DataPos and SourceMap.
+func (w *HTMLWriter) dataPos(n Node) string {
+	if !w.DataPos {
+		return ""
+	}
+	pos := n.Position()
+	value := fmt.Sprintf("%d:%d", pos.StartLine, pos.StartColumn)
+	if w.SourceMap {
+		kind := strings.TrimPrefix(fmt.Sprintf("%T", n), "org.")
+		if entry := (SourceMapEntry{value, kind, pos.StartLine, pos.StartColumn, pos.EndLine, pos.EndColumn}); !w.sourceMapPos[entry.key()] {
+			if w.sourceMapPos == nil {
+				w.sourceMapPos = map[string]bool{}
+			}
+			w.sourceMapPos[entry.key()] = true
+			w.sourceMap = append(w.sourceMap, entry)
+		}
+	}
+	return fmt.Sprintf(` data-pos="%s"`, value)
+}
+
+// SourceMapJSON returns the source map recorded during the last Document.Write as JSON array of SourceMapEntry
+// in document order. See SourceMap.
+func (w *HTMLWriter) SourceMapJSON() ([]byte, error) {
+	if w.sourceMap == nil {
+		return json.Marshal([]SourceMapEntry{})
+	}
+	return json.Marshal(w.sourceMap)
+}
+
+// captureString returns the output written by f instead of writing it.
+func (w *HTMLWriter) captureString(f func()) string {
+	original := w.Builder
+	w.Builder = strings.Builder{}
+	f()
+	out := w.String()
+	w.Builder = original
+	return out
 }
 
 func (w *HTMLWriter) WriteComment(Comment)               {}
 func (w *HTMLWriter) WritePropertyDrawer(PropertyDrawer) {}
 
 func (w *HTMLWriter) WriteBlock(b Block) {
+	if b.Name == "VERSE" {
+		w.WriteString("<p" + w.class("Block/verse", "verse") + w.dataPos(b) + ">\n")
+		w.writeVerse(b.Children)
+		w.WriteString("</p>\n")
+		return
+	}
 	content, params := w.blockContent(b.Name, b.Children), b.ParameterMap()
 
 	switch b.Name {
@@ -160,22 +659,52 @@ func (w *HTMLWriter) WriteBlock(b Block) {
 		if len(b.Parameters) >= 1 {
 			lang = strings.ToLower(b.Parameters[0])
 		}
-		content = w.HighlightCodeBlock(content, lang, false, params)
-		w.WriteString(fmt.Sprintf("<div class=\"src src-%s\">\n%s\n</div>\n", lang, content))
+		switches := b.Switches()
+		content = switches.removeLabels(content)
+		if switches.LineNumbers {
+			if w.fork != nil && !w.fork.numberedLines {
+				w.fork.numberedLines, w.fork.continuedLines = true, switches.ContinueNumbering
+			}
+			start := w.nextLineNumber + switches.LineNumberOffset
+			if !switches.ContinueNumbering {
+				start = max(switches.LineNumberOffset, 1)
+			}
+			params[":linenostart"] = strconv.Itoa(start)
+			w.nextLineNumber = start + strings.Count(content, "\n") + 1
+		}
+		content = w.highlightCodeBlock(content, lang, false, params)
+		w.WriteString(fmt.Sprintf("<div%s%s>\n%s\n</div>\n", w.class("Block/src", "src", "src-"+lang), w.dataPos(b), content))
 	case "EXAMPLE":
-		w.WriteString(`<pre class="example">` + "\n" + html.EscapeString(content) + "\n</pre>\n")
+		content = b.Switches().removeLabels(content)
+		w.WriteString(`<pre` + w.class("Block/example", "example") + w.dataPos(b) + ">\n" + html.EscapeString(content) + "\n</pre>\n")
 	case "EXPORT":
-		if len(b.Parameters) >= 1 && strings.ToLower(b.Parameters[0]) == "html" {
+		if !w.SafeMode && exportsTo(w, b.Parameters) {
 			w.WriteString(content + "\n")
 		}
 	case "QUOTE":
-		w.WriteString("<blockquote>\n" + content + "</blockquote>\n")
+		citation := ""
+		if quote, cite := b.Citation(); cite != nil {
+			content = w.WriteNodesAsString(quote...)
+			citation = "<footer" + w.class("Block/citation", "citation") + ">&#8212; " + w.WriteNodesAsString(cite...) + "</footer>\n"
+		}
+		w.WriteString("<blockquote" + w.class("Block/quote") + w.dataPos(b) + ">\n" + content + citation + "</blockquote>\n")
 	case "CENTER":
-		w.WriteString(`<div class="center-block" style="text-align: center; margin-left: auto; margin-right: auto;">` + "\n")
+		w.WriteString(`<div` + w.class("Block/center", "center-block") + w.dataPos(b) + ` style="text-align: center; margin-left: auto; margin-right: auto;">` + "\n")
 		w.WriteString(content + "</div>\n")
 	default:
-		w.WriteString(fmt.Sprintf(`<div class="%s-block">`, strings.ToLower(b.Name)) + "\n")
-		w.WriteString(content + "</div>\n")
+		name := strings.ToLower(b.Name)
+		special, element, class := w.SpecialBlocks[name], "div", name+"-block"
+		if special.Element != "" {
+			element = special.Element
+		}
+		if c, ok := special.Attributes["class"]; ok {
+			class = c
+		}
+		w.WriteString("<" + element + w.class("Block/"+name, class) + attributes(special.Attributes) + w.dataPos(b) + ">\n")
+		if summary := strings.Join(strings.Fields(strings.Join(b.Parameters, " ")), " "); special.Summary != "" && summary != "" {
+			w.WriteString("<" + special.Summary + ">" + html.EscapeString(summary) + "</" + special.Summary + ">\n")
+		}
+		w.WriteString(content + "</" + element + ">\n")
 	}
 
 	if b.Result != nil && params[":exports"] != "code" && params[":exports"] != "none" {
@@ -183,8 +712,35 @@ func (w *HTMLWriter) WriteBlock(b Block) {
 	}
 }
 
+// writeVerse writes the lines of a verse block - preserving line breaks and the leading whitespace of lines.
+func (w *HTMLWriter) writeVerse(nodes []Node) {
+	lineStart := true
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case LineBreak:
+			w.WriteString(strings.Repeat("<br />\n", n.Count))
+			lineStart = true
+			continue
+		case Text:
+			if lineStart {
+				content := strings.TrimLeft(n.Content, " \t")
+				w.WriteString(strings.Repeat("&#xa0;", len(n.Content)-len(content)))
+				n.Content = content
+			}
+			WriteNodes(w, n)
+		default:
+			WriteNodes(w, n)
+		}
+		lineStart = false
+	}
+}
+
 func (w *HTMLWriter) WriteLatexBlock(b LatexBlock) {
-	WriteNodes(w, b.Content...)
+	if w.MathMode == MathModeRaw || !w.isMathEnvironment(String(b.Content...)) {
+		WriteNodes(w, b.Content...)
+	} else {
+		w.writeMath(String(b.Content...), true, true)
+	}
 	w.WriteString("\n")
 }
 
@@ -195,26 +751,54 @@ func (w *HTMLWriter) WriteInlineBlock(b InlineBlock) {
 	switch b.Name {
 	case "src":
 		lang := strings.ToLower(b.Parameters[0])
-		content = w.HighlightCodeBlock(content, lang, true, nil)
-		w.WriteString(fmt.Sprintf("<div class=\"src src-inline src-%s\">\n%s\n</div>", lang, content))
+		content = w.highlightCodeBlock(content, lang, true, nil)
+		w.WriteString(fmt.Sprintf("<div%s>\n%s\n</div>", w.class("InlineBlock/src", "src", "src-inline", "src-"+lang), content))
 	case "export":
-		if strings.ToLower(b.Parameters[0]) == "html" {
+		if !w.SafeMode && exportsTo(w, b.Parameters) {
 			w.WriteString(content)
 		}
 	}
 }
 
+func (w *HTMLWriter) highlightCodeBlock(source, lang string, inline bool, params map[string]string) string {
+	if w.HighlightCodeBlock == nil {
+		return DefaultHighlightCodeBlock(source, lang, inline, params)
+	}
+	return w.HighlightCodeBlock(source, lang, inline, params)
+}
+
 func (w *HTMLWriter) WriteDrawer(d Drawer) {
-	WriteNodes(w, d.Children...)
+	if w.document.ExportsDrawer(d.Name) {
+		WriteNodes(w, d.Children...)
+	}
 }
 
 func (w *HTMLWriter) WriteKeyword(k Keyword) {
 	if k.Key == "HTML" {
-		w.WriteString(k.Value + "\n")
+		if !w.SafeMode {
+			w.WriteString(k.Value + "\n")
+		}
 	} else if k.Key == "TOC" {
-		if m := tocHeadlineMaxLvlRegexp.FindStringSubmatch(k.Value); m != nil {
-			maxLvl, _ := strconv.Atoi(m[1])
+		m := tocKeywordRegexp.FindStringSubmatch(k.Value)
+		switch {
+		case m == nil:
+			w.document.logf(slog.LevelWarn, k, "Bad TOC keyword: %s", k.Value)
+		case m[1] == "headlines" && m[3] != "":
+			if len(w.headlines) != 0 {
+				h := w.headlines[len(w.headlines)-1]
+				maxLvl, _ := strconv.Atoi(m[2]) // the depth of local tocs is relative to the current headline
+				if maxLvl != 0 {
+					maxLvl += h.Lvl
+				}
+				if section := w.document.Outline.SectionOf(h); section != nil {
+					w.writeTOC("headlines", w.tocEntries(section.Children, maxLvl))
+				}
+			}
+		case m[1] == "headlines":
+			maxLvl, _ := strconv.Atoi(m[2])
 			w.WriteOutline(w.document, maxLvl)
+		default:
+			w.writeTOC(m[1], w.captionTOCEntries(m[1]))
 		}
 	}
 }
@@ -228,67 +812,276 @@ func (w *HTMLWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
 }
 
 func (w *HTMLWriter) WriteFootnotes(d *Document) {
-	if w.document.GetOption("f") == "nil" || len(w.footnotes.list) == 0 {
+	if w.document.GetOption("f") == "nil" || w.footnotes.written == len(w.footnotes.list) {
 		return
 	}
-	w.WriteString(`<div class="footnotes">` + "\n")
-	w.WriteString(`<hr class="footnotes-separatator"/>` + "\n")
-	w.WriteString(`<div class="footnote-definitions">` + "\n")
+	w.WriteString(`<div` + w.class("Footnotes", "footnotes") + ">\n")
+	w.WriteString(`<hr` + w.class("Footnotes/separator", "footnotes-separatator") + "/>\n")
+	w.WriteString(`<div` + w.class("Footnotes/definitions", "footnote-definitions") + ">\n")
 
 	// iterate by index instead of ranging, since new footnotes can be added when writing the definitions
-	for i := 0; i < len(w.footnotes.list); i++ {
+	for ; w.footnotes.written < len(w.footnotes.list); w.footnotes.written++ {
+		i := w.footnotes.written
 		definition := w.footnotes.list[i]
 		id := i + 1
 		if definition == nil {
-			name := ""
-			for k, v := range w.footnotes.mapping {
-				if v == i {
-					name = k
-				}
-			}
-			w.log.Printf("Missing footnote definition for [fn:%s] (#%d)", name, id)
+			w.document.logf(slog.LevelWarn, nil, "Missing footnote definition for [fn:%s] (#%d)", w.footnotes.name(i), id)
 			continue
 		}
-		w.WriteString(`<div class="footnote-definition">` + "\n")
+		w.WriteString(`<div` + w.class("FootnoteDefinition", "footnote-definition") + ">\n")
 		w.WriteString(fmt.Sprintf(`<sup id="footnote-%d"><a href="#footnote-reference-%d">%d</a></sup>`, id, id, id) + "\n")
-		w.WriteString(`<div class="footnote-body">` + "\n")
+		w.WriteString(`<div` + w.class("FootnoteDefinition/body", "footnote-body") + ">\n")
 		WriteNodes(w, definition.Children...)
 		w.WriteString("</div>\n</div>\n")
 	}
 	w.WriteString("</div>\n</div>\n")
 }
 
+func (w *HTMLWriter) writeSidenote(i int) {
+	id, definition := i+1, w.footnotes.list[i]
+	if definition == nil {
+		w.document.logf(slog.LevelWarn, nil, "Missing footnote definition for [fn:%s] (#%d)", w.footnotes.name(i), id)
+		return
+	}
+	// sidenotes are written inside the paragraph containing their reference - which only allows phrasing content.
+	// paragraphs are therefore flattened to their inline nodes and separated by line breaks.
+	w.WriteString(fmt.Sprintf(`<span id="footnote-%d"%s><sup>%d</sup> `, id, w.class("FootnoteDefinition/sidenote", "sidenote"), id))
+	separate := false
+	for _, n := range definition.Children {
+		p, ok := n.(Paragraph)
+		if !ok {
+			WriteNodes(w, n)
+			separate = false
+			continue
+		}
+		inline := p.InlineNodes()
+		for len(inline) != 0 && isLineBreak(inline[0]) {
+			inline = inline[1:]
+		}
+		for len(inline) != 0 && isLineBreak(inline[len(inline)-1]) {
+			inline = inline[:len(inline)-1]
+		}
+		if len(inline) == 0 {
+			continue
+		} else if separate {
+			w.WriteString("<br>")
+		}
+		WriteNodes(w, inline...)
+		separate = true
+	}
+	w.WriteString("</span>")
+}
+
+func isLineBreak(n Node) bool {
+	_, ok := n.(LineBreak)
+	return ok
+}
+
 func (w *HTMLWriter) WriteOutline(d *Document, maxLvl int) {
 	if len(d.Outline.Children) != 0 {
-		w.WriteString("<nav>\n<ul>\n")
-		for _, section := range d.Outline.Children {
-			w.writeSection(section, maxLvl)
-		}
-		w.WriteString("</ul>\n</nav>\n")
+		w.writeTOC("headlines", w.tocEntries(d.Outline.Children, maxLvl))
 	}
 }
 
-func (w *HTMLWriter) writeSection(section *Section, maxLvl int) {
-	if (maxLvl != 0 && section.Headline.Lvl > maxLvl) || section.Headline.IsExcluded(w.document) {
+func (w *HTMLWriter) writeTOC(kind string, entries []TOCEntry) {
+	if w.RenderTOC != nil {
+		w.WriteString(w.RenderTOC(kind, entries))
+		return
+	} else if len(entries) == 0 {
 		return
 	}
-	// NOTE: To satisfy hugo ExtractTOC() check we cannot use `<li>\n` here. Doesn't really matter, just a note.
-	w.WriteString("<li>")
-	h := section.Headline
-	title := cleanHeadlineTitleForHTMLAnchorRegexp.ReplaceAllString(w.WriteNodesAsString(h.Title...), "")
-	w.WriteString(fmt.Sprintf("<a href=\"#%s\">%s</a>\n", h.ID(), title))
-	hasChildren := false
-	for _, section := range section.Children {
-		hasChildren = hasChildren || maxLvl == 0 || section.Headline.Lvl <= maxLvl
+	if kind == "headlines" {
+		w.WriteString("<nav" + w.class("TOC/headlines") + ">\n")
+	} else {
+		w.WriteString(fmt.Sprintf(`<nav%s>`, w.class("TOC/"+kind, "toc-"+kind)) + "\n")
 	}
-	if hasChildren {
-		w.WriteString("<ul>\n")
-		for _, section := range section.Children {
-			w.writeSection(section, maxLvl)
+	w.writeTOCEntries(entries)
+	w.WriteString("</nav>\n")
+}
+
+func (w *HTMLWriter) writeTOCEntries(entries []TOCEntry) {
+	w.WriteString("<ul>\n")
+	for _, entry := range entries {
+		// NOTE: To satisfy hugo ExtractTOC() check we cannot use `<li>\n` here. Doesn't really matter, just a note.
+		w.WriteString("<li>")
+		if entry.Number != "" {
+			w.WriteString(fmt.Sprintf(`<span%s>%s</span> `, w.class("TOC/number", "section-number"), entry.Number))
+		}
+		w.WriteString(fmt.Sprintf("<a href=\"#%s\">%s</a>\n", entry.ID, entry.Title))
+		if len(entry.Children) != 0 {
+			w.writeTOCEntries(entry.Children)
 		}
-		w.WriteString("</ul>\n")
+		w.WriteString("</li>\n")
 	}
-	w.WriteString("</li>\n")
+	w.WriteString("</ul>\n")
+}
+
+func (w *HTMLWriter) tocEntries(sections []*Section, maxLvl int) []TOCEntry {
+	if w.TOCDepth != 0 && (maxLvl == 0 || w.TOCDepth < maxLvl) {
+		maxLvl = w.TOCDepth
+	}
+	if hLvl := w.headlineLevelLimit(); hLvl != 0 && (maxLvl == 0 || hLvl < maxLvl) {
+		maxLvl = hLvl
+	}
+	entries := []TOCEntry{}
+	for _, section := range sections {
+		h := section.Headline
+		if (maxLvl != 0 && h.Lvl > maxLvl) || h.IsExcluded(w.document) {
+			continue
+		}
+		title := cleanHeadlineTitleForHTMLAnchorRegexp.ReplaceAllString(w.WriteNodesAsString(h.Title...), "")
+		entry := TOCEntry{ID: w.headlineID(*h), Title: title, Level: h.Lvl, Number: w.sectionNumbers[h.Pos]}
+		entry.Children = w.tocEntries(section.Children, maxLvl)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// generateSectionNumbers assigns hierarchical section numbers (e.g. "1.2") to the headlines of the document if
+// the num export option is enabled (num:t or num:n to only number headlines up to level n) or TOCNumbered is set.
+// Headlines with the UNNUMBERED property are not numbered - and neither are their descendants.
+func (w *HTMLWriter) generateSectionNumbers(d *Document) map[Position]string {
+	num, maxLvl := d.GetOption("num"), 0
+	if num == "" || num == "nil" {
+		if !w.TOCNumbered {
+			return nil
+		}
+	} else if n, err := strconv.Atoi(num); err == nil {
+		maxLvl = n
+	}
+	numbers := map[Position]string{}
+	var walk func(sections []*Section, prefix string)
+	walk = func(sections []*Section, prefix string) {
+		i := 0
+		for _, section := range sections {
+			h := section.Headline
+			if !section.isNumbered() || (maxLvl != 0 && h.Lvl > maxLvl) {
+				continue
+			}
+			i++
+			numbers[h.Pos] = prefix + strconv.Itoa(i)
+			walk(section.Children, numbers[h.Pos]+".")
+		}
+	}
+	walk(d.Outline.Children, "")
+	return numbers
+}
+
+// numberHeadlines returns true if headlines are numbered via the num export option.
+func (w *HTMLWriter) numberHeadlines() bool {
+	num := w.document.GetOption("num")
+	return num != "" && num != "nil"
+}
+
+// resolveFootnoteMode sets the footnote mode and section level of the writer - the fnsec export option switches
+// to FootnoteModeSections and takes precedence over FootnoteSectionLevel.
+func (w *HTMLWriter) resolveFootnoteMode() {
+	fnsec := w.document.GetOption("fnsec")
+	w.footnoteMode, w.footnoteLevel = w.FootnoteMode, 1
+	if fnsec != "" && fnsec != "nil" {
+		w.footnoteMode = FootnoteModeSections
+	}
+	if n, err := strconv.Atoi(fnsec); err == nil && n > 0 {
+		w.footnoteLevel = n
+	} else if w.FootnoteSectionLevel > 0 {
+		w.footnoteLevel = w.FootnoteSectionLevel
+	}
+}
+
+// headlineLevelLimit returns the level given via the H export option - headlines below it are exported as list items.
+// It returns 0 if there is no limit.
+func (w *HTMLWriter) headlineLevelLimit() int {
+	n, _ := strconv.Atoi(w.document.GetOption("H"))
+	return n
+}
+
+func (w *HTMLWriter) captionTOCEntries(kind string) []TOCEntry {
+	entries := []TOCEntry{}
+	walkNodes(w.document.Nodes, func(n Node) {
+		if m, ok := n.(NodeWithMeta); ok && captionKind(m) == kind {
+			entries = append(entries, TOCEntry{ID: w.captions[m.Pos].id, Title: w.captionString(m)})
+		}
+	})
+	return entries
+}
+
+func (w *HTMLWriter) captionString(n NodeWithMeta) string {
+	caption := []string{}
+	for _, ns := range n.Meta.Caption {
+		caption = append(caption, w.WriteNodesAsString(ns...))
+	}
+	return strings.Join(caption, " ")
+}
+
+// captionKind returns "figures" for captioned images, "tables" for captioned tables,
+// "listings" for captioned src blocks and "" otherwise.
+func captionKind(n NodeWithMeta) string {
+	if len(n.Meta.Caption) == 0 {
+		return ""
+	}
+	node := n.Node
+	if named, ok := node.(NodeWithName); ok {
+		node = named.Node
+	}
+	switch node := node.(type) {
+	case Table:
+		return "tables"
+	case Block:
+		if node.Name == "SRC" {
+			return "listings"
+		}
+	case Paragraph:
+		if children := node.InlineNodes(); len(children) == 1 && isImageOrVideoLink(children[0]) {
+			return "figures"
+		}
+	}
+	return ""
+}
+
+// generateCaptionLabels assigns ids and numbers (figure-1, table-1, listing-1, ...) to captioned images, tables
+// and src blocks - if NumberCaptions is set or the document contains a #+TOC: tables or #+TOC: listings keyword.
+// Labels of named nodes are additionally indexed by name for cross references.
+func (w *HTMLWriter) generateCaptionLabels(d *Document) (map[Position]captionLabel, map[string]captionLabel) {
+	hasCaptionTOC := false
+	walkNodes(d.Nodes, func(n Node) {
+		if k, ok := n.(Keyword); ok && k.Key == "TOC" && (strings.HasPrefix(k.Value, "tables") || strings.HasPrefix(k.Value, "listings")) {
+			hasCaptionTOC = true
+		}
+	})
+	if !hasCaptionTOC && !w.NumberCaptions {
+		return nil, nil
+	}
+	labels, namedLabels, counts := map[Position]captionLabel{}, map[string]captionLabel{}, map[string]int{}
+	walkNodes(d.Nodes, func(n Node) {
+		name := ""
+		if named, ok := n.(NodeWithName); ok {
+			name, n = named.Name, named.Node
+		}
+		m, ok := n.(NodeWithMeta)
+		if !ok {
+			return
+		}
+		if named, ok := m.Node.(NodeWithName); ok {
+			name = named.Name
+		}
+		if kind := captionKind(m); kind != "" {
+			if _, ok := labels[m.Pos]; ok {
+				return
+			}
+			counts[kind]++
+			label := captionLabel{fmt.Sprintf("%s-%d", strings.TrimSuffix(kind, "s"), counts[kind]), kind, counts[kind]}
+			labels[m.Pos] = label
+			if name != "" {
+				namedLabels[name] = label
+			}
+		}
+	})
+	return labels, namedLabels
+}
+
+// String returns the label of the caption, e.g. "Figure 3".
+func (l captionLabel) String() string {
+	return fmt.Sprintf("%s %d", captionKindLabels[l.kind], l.number)
 }
 
 func (w *HTMLWriter) WriteHeadline(h Headline) {
@@ -296,33 +1089,168 @@ func (w *HTMLWriter) WriteHeadline(h Headline) {
 		return
 	}
 
-	level := (h.Lvl - 1) + w.TopLevelHLevel
+	if limit := w.headlineLevelLimit(); limit != 0 && h.Lvl > limit {
+		if len(w.headlines) == 0 {
+			w.writeListHeadlines([]Headline{h})
+		} else {
+			w.writeListHeadline(h)
+		}
+		return
+	}
+
+	level, id := (h.Lvl-1)+w.TopLevelHLevel, w.headlineID(h)
+	// the content preceding the first subsection is part of the parent section, i.e. it ends with its footnotes
+	if w.footnoteMode == FootnoteModeSections && len(w.headlines) != 0 && len(w.headlines) < w.footnoteLevel {
+		w.WriteFootnotes(w.document)
+	}
 
-	w.WriteString(fmt.Sprintf(`<div id="outline-container-%s" class="outline-%d">`, h.ID(), level) + "\n")
-	w.WriteString(fmt.Sprintf(`<h%d id="%s">`, level, h.ID()) + "\n")
+	w.WriteString(fmt.Sprintf(`<div id="outline-container-%s"%s%s>`, id, w.class("Headline", fmt.Sprintf("outline-%d", level)), w.dataPos(h)) + "\n")
+	w.WriteString(fmt.Sprintf(`<h%d id="%s"%s>`, level, id, w.class("Headline/title")) + "\n")
+	if number, ok := w.sectionNumbers[h.Pos]; ok && w.numberHeadlines() {
+		w.WriteString(fmt.Sprintf(`<span%s>%s</span>`, w.class("Headline/number", "section-number-"+strconv.Itoa(level)), number) + "\n")
+	}
 	if w.document.GetOption("todo") != "nil" && h.Status != "" {
-		w.WriteString(fmt.Sprintf(`<span class="todo status-%s">%s</span>`, strings.ToLower(h.Status), h.Status) + "\n")
+		w.WriteString(fmt.Sprintf(`<span%s>%s</span>`, w.class("Headline/todo", "todo", "status-"+strings.ToLower(h.Status)), h.Status) + "\n")
 	}
+	w.headlines = append(w.headlines, h)
+	defer func() { w.headlines = w.headlines[:len(w.headlines)-1] }()
 	if w.document.GetOption("pri") != "nil" && h.Priority != "" {
-		w.WriteString(fmt.Sprintf(`<span class="priority priority-%s">[%s]</span>`, strings.ToLower(h.Priority), h.Priority) + "\n")
+		w.WriteString(fmt.Sprintf(`<span%s>[%s]</span>`, w.class("Headline/priority", "priority", "priority-"+strings.ToLower(h.Priority)), h.Priority) + "\n")
 	}
 
 	WriteNodes(w, h.Title...)
 	if w.document.GetOption("tags") != "nil" && len(h.Tags) != 0 {
 		tags := make([]string, len(h.Tags))
 		for i, tag := range h.Tags {
-			tags[i] = fmt.Sprintf(`<span class="tag-%s">%s</span>`, strings.ToLower(tag), tag)
+			tags[i] = fmt.Sprintf(`<span%s>%s</span>`, w.class("Headline/tag", "tag-"+strings.ToLower(tag)), tag)
 		}
 		w.WriteString("&#xa0;&#xa0;&#xa0;")
-		w.WriteString(fmt.Sprintf(`<span class="tags">%s</span>`, strings.Join(tags, "&#xa0;")))
+		w.WriteString(fmt.Sprintf(`<span%s>%s</span>`, w.class("Headline/tags", "tags"), strings.Join(tags, "&#xa0;")))
 	}
 	w.WriteString(fmt.Sprintf("\n</h%d>\n", level))
-	if content := w.WriteNodesAsString(h.Children...); content != "" {
-		w.WriteString(fmt.Sprintf(`<div id="outline-text-%s" class="outline-text-%d">`, h.ID(), level) + "\n" + content + "</div>\n")
+	if content := w.captureString(func() { w.writeHeadlineChildren(h.Children) }); content != "" {
+		w.WriteString(fmt.Sprintf(`<div id="outline-text-%s"%s>`, id, w.class("Headline/text", fmt.Sprintf("outline-text-%d", level))) + "\n" + content + "</div>\n")
+	}
+	if w.footnoteMode == FootnoteModeSections && len(w.headlines) <= w.footnoteLevel {
+		w.WriteFootnotes(w.document)
 	}
 	w.WriteString("</div>\n")
 }
 
+// writeHeadlineChildren writes the children of a headline - grouping consecutive headlines below the level given
+// via the H export option into a single list.
+func (w *HTMLWriter) writeHeadlineChildren(children []Node) {
+	limit := w.headlineLevelLimit()
+	for i := 0; i < len(children); i++ {
+		if h, ok := children[i].(Headline); ok && limit != 0 && h.Lvl > limit && !h.IsExcluded(w.document) {
+			hs := []Headline{h}
+			for ; i+1 < len(children); i++ {
+				next, ok := children[i+1].(Headline)
+				if !ok || next.Lvl <= limit || next.IsExcluded(w.document) {
+					break
+				}
+				hs = append(hs, next)
+			}
+			w.writeListHeadlines(hs)
+			continue
+		}
+		WriteNodes(w, children[i])
+	}
+}
+
+// writeListHeadlines writes headlines below the level given via the H export option as list - ordered if they are
+// numbered.
+func (w *HTMLWriter) writeListHeadlines(hs []Headline) {
+	tag := "ul"
+	if _, ok := w.sectionNumbers[hs[0].Pos]; ok && w.numberHeadlines() {
+		tag = "ol"
+	}
+	w.WriteString("<" + tag + w.class("Headline/list", "org-"+tag) + ">\n")
+	for _, h := range hs {
+		w.writeListHeadline(h)
+	}
+	w.WriteString("</" + tag + ">\n")
+}
+
+func (w *HTMLWriter) writeListHeadline(h Headline) {
+	w.headlines = append(w.headlines, h)
+	defer func() { w.headlines = w.headlines[:len(w.headlines)-1] }()
+	w.WriteString(fmt.Sprintf(`<li id="%s"%s%s>`, w.headlineID(h), w.class("Headline/item"), w.dataPos(h)))
+	if w.document.GetOption("todo") != "nil" && h.Status != "" {
+		w.WriteString(fmt.Sprintf(`<span%s>%s</span> `, w.class("Headline/todo", "todo", "status-"+strings.ToLower(h.Status)), h.Status))
+	}
+	WriteNodes(w, h.Title...)
+	if content := w.captureString(func() { w.writeHeadlineChildren(h.Children) }); content != "" {
+		w.WriteString("<br />\n" + content)
+	}
+	w.WriteString("</li>\n")
+}
+
+// headlineID returns the HTML id of the headline. See HTMLWriter.HeadlineSlug.
+// headlineID returns the HTML id of the headline - escaped, as CUSTOM_IDs are user input.
+func (w *HTMLWriter) headlineID(h Headline) string {
+	if id, ok := w.headlineIDs[h.key()]; ok {
+		return html.EscapeString(id)
+	}
+	if _, ok := h.Properties.Get("CUSTOM_ID"); ok || w.HeadlineSlug == nil {
+		return html.EscapeString(h.ID())
+	}
+	return html.EscapeString(w.HeadlineSlug(h))
+}
+
+func (w *HTMLWriter) generateHeadlineIDs(d *Document) map[headlineKey]string {
+	if w.HeadlineSlug == nil {
+		return nil
+	}
+	ids, counts := map[headlineKey]string{}, map[string]int{}
+	walkNodes(d.Nodes, func(n Node) {
+		if h, ok := n.(Headline); ok {
+			if customID, ok := h.Properties.Get("CUSTOM_ID"); ok {
+				counts[customID]++
+			}
+		}
+	})
+	walkNodes(d.Nodes, func(n Node) {
+		h, ok := n.(Headline)
+		if !ok {
+			return
+		} else if customID, ok := h.Properties.Get("CUSTOM_ID"); ok {
+			ids[h.key()] = customID
+			return
+		}
+		slug := w.HeadlineSlug(h)
+		id := slug
+		for i := counts[slug]; counts[id] != 0; i++ {
+			id = fmt.Sprintf("%s-%d", slug, i)
+		}
+		counts[slug]++
+		counts[id]++
+		ids[h.key()] = id
+	})
+	return ids
+}
+
+// SlugifyHeadline returns a slug of the plain text title of the headline, e.g. "Hello *World*" becomes "hello-world".
+// It can be used as HTMLWriter.HeadlineSlug.
+func SlugifyHeadline(h Headline) string {
+	slug, dash := strings.Builder{}, false
+	for _, r := range strings.ToLower(PlainText(h.Title...)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && slug.Len() != 0 {
+				slug.WriteRune('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if slug.Len() == 0 {
+		return "headline"
+	}
+	return slug.String()
+}
+
 func (w *HTMLWriter) WriteText(t Text) {
 	if !w.htmlEscape {
 		w.WriteString(t.Content)
@@ -334,27 +1262,140 @@ func (w *HTMLWriter) WriteText(t Text) {
 }
 
 func (w *HTMLWriter) WriteEmphasis(e Emphasis) {
-	tags, ok := emphasisTags[e.Kind]
-	if !ok {
-		panic(fmt.Sprintf("bad emphasis %#v", e))
+	tag, ok := w.EmphasisTags[e.Kind]
+	if !ok && w.defaultEmphasisTags != nil {
+		tag, ok = w.defaultEmphasisTags[e.Kind]
+	} else if !ok {
+		tag, ok = DefaultEmphasisTags[e.Kind]
+	}
+	if !ok && len(e.Kind) == 1 {
+		tag, ok = EmphasisTag{Element: "span"}, true // custom marker, see Configuration.EmphasisComponents
+	} else if !ok {
+		w.reportError(fmt.Errorf("bad emphasis %#v", e))
+		return
+	}
+	kind := emphasisKinds[e.Kind]
+	if kind == "" {
+		kind = e.Kind
+	}
+	if tag.Element != "" {
+		w.WriteString("<" + tag.Element + w.class("Emphasis/"+kind, tag.Attributes["class"]) + attributes(tag.Attributes) + ">")
+	}
+	if e.Kind == "=" || e.Kind == "~" {
+		w.writeRawText(e.Content...)
+	} else {
+		WriteNodes(w, e.Content...)
+	}
+	if tag.Element != "" {
+		w.WriteString("</" + tag.Element + ">")
 	}
-	w.WriteString(tags[0])
-	WriteNodes(w, e.Content...)
-	w.WriteString(tags[1])
+}
+
+// attributes returns the attributes except for class in alphabetical order, e.g. ` id="x" title="y"`.
+func attributes(attributes map[string]string) string {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		if k != "class" {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	out := ""
+	for _, k := range keys {
+		out += fmt.Sprintf(` %s="%s"`, k, html.EscapeString(attributes[k]))
+	}
+	return out
 }
 
 func (w *HTMLWriter) WriteLatexFragment(l LatexFragment) {
-	w.WriteString(l.OpeningPair)
-	WriteNodes(w, l.Content...)
-	w.WriteString(l.ClosingPair)
+	if w.MathMode == MathModeRaw || !w.isMathEnvironment(l.OpeningPair) {
+		w.WriteString(l.OpeningPair)
+		w.writeRawText(l.Content...)
+		w.WriteString(l.ClosingPair)
+		return
+	}
+	switch latex := String(l.Content...); l.OpeningPair {
+	case `\(`, "$":
+		w.writeMath(latex, false, false)
+	case `\[`, "$$":
+		w.writeMath(latex, true, false)
+	default:
+		w.writeMath(l.OpeningPair+latex+l.ClosingPair, true, true)
+	}
+}
+
+// isMathEnvironment returns false if latex starts with an environment that is not one of the MathEnvironments.
+func (w *HTMLWriter) isMathEnvironment(latex string) bool {
+	m := latexEnvironmentRegexp.FindStringSubmatch(strings.TrimSpace(latex))
+	return w.MathEnvironments == nil || m == nil || slices.Contains(w.MathEnvironments, m[1])
+}
+
+// writeMath writes latex according to the MathMode. isEnvironment is true for \begin{env}...\end{env} math.
+func (w *HTMLWriter) writeMath(latex string, display, isEnvironment bool) {
+	mode := w.MathMode
+	if mode == MathModeMathML || mode == MathModeImage {
+		if w.RenderMath == nil {
+			mode = MathModeMathJax
+		} else if out, err := w.RenderMath(latex, display); err != nil {
+			w.document.logf(slog.LevelWarn, nil, "Could not render math %q: %s", latex, err)
+			mode = MathModeMathJax
+		} else if mode == MathModeMathML {
+			w.WriteString(out)
+			return
+		} else {
+			class := w.class("Math/inline", "math", "math-inline")
+			if display {
+				class = w.class("Math/display", "math", "math-display")
+			}
+			w.WriteString(fmt.Sprintf(`<img%s src="%s" alt="%s" />`, class, html.EscapeString(out), html.EscapeString(latex)))
+			return
+		}
+	}
+	switch {
+	case mode == MathModeKaTeX && display:
+		w.WriteString(`<span` + w.class("Math/display", "math", "math-display") + ">" + html.EscapeString(latex) + "</span>")
+	case mode == MathModeKaTeX:
+		w.WriteString(`<span` + w.class("Math/inline", "math", "math-inline") + ">" + html.EscapeString(latex) + "</span>")
+	case isEnvironment:
+		w.WriteString(html.EscapeString(latex))
+	case display:
+		w.WriteString(`\[` + html.EscapeString(latex) + `\]`)
+	default:
+		w.WriteString(`\(` + html.EscapeString(latex) + `\)`)
+	}
 }
 
 func (w *HTMLWriter) WriteStatisticToken(s StatisticToken) {
-	w.WriteString(fmt.Sprintf(`<code class="statistic">[%s]</code>`, s.Content))
+	if value, total, ok := statisticRatio(s.Content); w.StatisticProgress && ok {
+		w.WriteString(fmt.Sprintf(`<progress%s value="%d" max="%d" aria-label="%s">[%s]</progress>`,
+			w.class("StatisticToken", "statistic"), value, total, s.Content, s.Content))
+		return
+	}
+	w.WriteString(fmt.Sprintf(`<code%s>[%s]</code>`, w.class("StatisticToken", "statistic"), s.Content))
+}
+
+// statisticRatio returns the value and maximum of the statistic cookie content ("3/7" or "42%").
+func statisticRatio(content string) (int, int, bool) {
+	if percent, ok := strings.CutSuffix(content, "%"); ok {
+		value, err := strconv.Atoi(percent)
+		return value, 100, err == nil && value <= 100
+	}
+	done, total, ok := strings.Cut(content, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	value, err1 := strconv.Atoi(done)
+	maximum, err2 := strconv.Atoi(total)
+	return value, maximum, err1 == nil && err2 == nil && maximum > 0 && value <= maximum
 }
 
 func (w *HTMLWriter) WriteLineBreak(l LineBreak) {
-	if w.document.GetOption("ealb") == "nil" || !l.BetweenMultibyteCharacters {
+	if w.document.GetOption("ealb") != "nil" && l.BetweenMultibyteCharacters {
+		return
+	}
+	if w.htmlEscape && !w.inRawText && w.document.PreserveLineBreaks() {
+		w.WriteString(strings.Repeat("<br>\n", l.Count))
+	} else {
 		w.WriteString(strings.Repeat("\n", l.Count))
 	}
 }
@@ -367,16 +1408,20 @@ func (w *HTMLWriter) WriteFootnoteLink(l FootnoteLink) {
 	if w.document.GetOption("f") == "nil" {
 		return
 	}
-	i := w.footnotes.add(l)
+	i, isNew := w.footnotes.add(l)
 	id := i + 1
-	w.WriteString(fmt.Sprintf(`<sup class="footnote-reference"><a id="footnote-reference-%d" href="#footnote-%d">%d</a></sup>`, id, id, id))
+	w.WriteString(fmt.Sprintf(`<sup%s><a id="footnote-reference-%d" href="#footnote-%d">%d</a></sup>`, w.class("FootnoteLink", "footnote-reference"), id, id, id))
+	if w.footnoteMode == FootnoteModeSidenotes && isNew {
+		w.writeSidenote(i)
+		w.footnotes.written = len(w.footnotes.list)
+	}
 }
 
 func (w *HTMLWriter) WriteTimestamp(t Timestamp) {
 	if w.document.GetOption("<") == "nil" {
 		return
 	}
-	w.WriteString(`<span class="timestamp">&lt;`)
+	w.WriteString(`<span` + w.class("Timestamp", "timestamp") + `>&lt;`)
 	if t.IsDate {
 		w.WriteString(t.Time.Format(datestampFormat))
 	} else {
@@ -385,6 +1430,9 @@ func (w *HTMLWriter) WriteTimestamp(t Timestamp) {
 	if t.Interval != "" {
 		w.WriteString(" " + t.Interval)
 	}
+	if t.Delay != "" {
+		w.WriteString(" " + t.Delay)
+	}
 	w.WriteString(`&gt;</span>`)
 }
 
@@ -412,61 +1460,86 @@ func (w *HTMLWriter) WriteRegularLink(l RegularLink) {
 	} else if prefix := w.document.Links[l.URL]; prefix != "" {
 		url = html.EscapeString(strings.ReplaceAll(strings.ReplaceAll(prefix, "%s", ""), "%h", ""))
 	}
+	if label, ok := w.namedCaptions[l.URL]; ok && w.NumberCaptions && w.document.Links[l.Protocol] == "" {
+		description := label.String()
+		if l.Description != nil {
+			description = w.WriteNodesAsString(l.Description...)
+		}
+		w.WriteString(fmt.Sprintf(`<a href="#%s"%s>%s</a>`, label.id, w.class("RegularLink"), description))
+		return
+	}
+	if w.SafeMode && !isSafeURL(url) {
+		w.document.logf(slog.LevelInfo, l, "SafeMode: dropping unsafe link %s", l.URL)
+		if l.Description != nil {
+			WriteNodes(w, l.Description...)
+		} else {
+			w.WriteString(html.EscapeString(l.URL))
+		}
+		return
+	}
 	switch l.Kind() {
 	case "image":
 		if l.Description == nil {
-			w.WriteString(fmt.Sprintf(`<img src="%s" alt="%s" title="%s" />`, url, url, url))
+			w.WriteString(fmt.Sprintf(`<img%s src="%s" alt="%s" title="%s" />`, w.class("Image"), url, url, url))
 		} else {
 			description := strings.TrimPrefix(String(l.Description...), "file:")
-			w.WriteString(fmt.Sprintf(`<a href="%s"><img src="%s" alt="%s" /></a>`, url, description, description))
+			w.WriteString(fmt.Sprintf(`<a href="%s"%s><img%s src="%s" alt="%s" /></a>`, url, w.class("RegularLink"), w.class("Image"), description, description))
 		}
 	case "video":
 		if l.Description == nil {
-			w.WriteString(fmt.Sprintf(`<video src="%s" title="%s">%s</video>`, url, url, url))
+			w.WriteString(fmt.Sprintf(`<video%s src="%s" title="%s">%s</video>`, w.class("Video"), url, url, url))
 		} else {
 			description := strings.TrimPrefix(String(l.Description...), "file:")
-			w.WriteString(fmt.Sprintf(`<a href="%s"><video src="%s" title="%s"></video></a>`, url, description, description))
+			w.WriteString(fmt.Sprintf(`<a href="%s"%s><video%s src="%s" title="%s"></video></a>`, url, w.class("RegularLink"), w.class("Video"), description, description))
 		}
 	default:
 		description := url
+		if l.Wiki.Page != "" {
+			description = html.EscapeString(l.Wiki.Page)
+		}
 		if l.Description != nil {
 			description = w.WriteNodesAsString(l.Description...)
 		}
-		w.WriteString(fmt.Sprintf(`<a href="%s">%s</a>`, url, description))
+		class := w.class("RegularLink")
+		if l.Wiki.Page != "" && l.Wiki.Exists {
+			class = w.class("RegularLink/wiki", "wiki-link")
+		} else if l.Wiki.Page != "" {
+			class = w.class("RegularLink/missing", "wiki-link", "missing")
+		}
+		w.WriteString(fmt.Sprintf(`<a href="%s"%s>%s</a>`, url, class, description))
 	}
 }
 
 func (w *HTMLWriter) WriteMacro(m Macro) {
-	if macro := w.document.Macros[m.Name]; macro != "" {
-		for i, param := range m.Parameters {
-			macro = strings.Replace(macro, fmt.Sprintf("$%d", i+1), param, -1)
-		}
-		macroDocument := w.document.Parse(strings.NewReader(macro), w.document.Path)
-		if macroDocument.HasErrors() {
-			w.log.Printf("bad macro: %s -> %s: %v", m.Name, macro, macroDocument.Errors[0])
-		}
-		WriteNodes(w, macroDocument.Nodes...)
-	}
+	WriteNodes(w, w.document.expandMacro(m)...)
 }
 
 func (w *HTMLWriter) WriteList(l List) {
 	tags, ok := listTags[l.Kind]
 	if !ok {
-		panic(fmt.Sprintf("bad list kind %#v", l))
+		w.reportError(fmt.Errorf("bad list kind %#v", l))
+		return
 	}
-	w.WriteString(tags[0] + "\n")
+	attributes := w.class("List/" + tags[1])
+	if l.Kind == OrderedList && l.BulletStyle != "" && l.BulletStyle != "1" {
+		attributes += fmt.Sprintf(` type="%s"`, l.BulletStyle)
+	}
+	w.WriteString("<" + tags[0] + attributes + w.dataPos(l) + ">\n")
 	WriteNodes(w, l.Items...)
-	w.WriteString(tags[1] + "\n")
+	w.WriteString("</" + tags[0] + ">\n")
 }
 
 func (w *HTMLWriter) WriteListItem(li ListItem) {
 	attributes := ""
 	if li.Value != "" {
-		attributes += fmt.Sprintf(` value="%s"`, li.Value)
+		attributes += fmt.Sprintf(` value="%s"`, ordinal(li.Value))
 	}
 	if li.Status != "" {
-		attributes += fmt.Sprintf(` class="%s"`, listItemStatuses[li.Status])
+		attributes += w.class("ListItem/"+listItemStatuses[li.Status], listItemStatuses[li.Status])
+	} else {
+		attributes += w.class("ListItem")
 	}
+	attributes += w.dataPos(li)
 	w.WriteString(fmt.Sprintf("<li%s>", attributes))
 	w.writeListItemContent(li.Children)
 	w.WriteString("</li>\n")
@@ -474,9 +1547,9 @@ func (w *HTMLWriter) WriteListItem(li ListItem) {
 
 func (w *HTMLWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
 	if di.Status != "" {
-		w.WriteString(fmt.Sprintf("<dt class=\"%s\">\n", listItemStatuses[di.Status]))
+		w.WriteString(fmt.Sprintf("<dt%s%s>\n", w.class("ListItem/"+listItemStatuses[di.Status], listItemStatuses[di.Status]), w.dataPos(di)))
 	} else {
-		w.WriteString("<dt>\n")
+		w.WriteString("<dt" + w.class("ListItem") + w.dataPos(di) + ">\n")
 	}
 
 	if len(di.Term) != 0 {
@@ -485,15 +1558,43 @@ func (w *HTMLWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
 		w.WriteString("?")
 	}
 	w.WriteString("\n</dt>\n")
-	w.WriteString("<dd>")
-	w.writeListItemContent(di.Details)
-	w.WriteString("</dd>\n")
+	for _, details := range splitDetails(di.Details) {
+		w.WriteString("<dd>")
+		w.writeListItemContent(details)
+		w.WriteString("</dd>\n")
+	}
+}
+
+// splitDetails splits the details of a descriptive list item into one group per paragraph - each written as
+// a separate <dd>. Other nodes (e.g. blocks or lists) and blank lines belong to the group of the preceding paragraph.
+func splitDetails(details []Node) [][]Node {
+	groups := [][]Node{}
+	for _, n := range details {
+		if p, ok := n.(Paragraph); len(groups) == 0 || ok && len(p.InlineNodes()) != 0 && !isBlankParagraph(groups[len(groups)-1]) {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], n)
+	}
+	if len(groups) == 0 {
+		return [][]Node{nil}
+	}
+	return groups
+}
+
+// isBlankParagraph returns true if nodes only contain paragraphs without content.
+func isBlankParagraph(nodes []Node) bool {
+	for _, n := range nodes {
+		if p, ok := n.(Paragraph); !ok || len(p.InlineNodes()) != 0 {
+			return false
+		}
+	}
+	return true
 }
 
 func (w *HTMLWriter) writeListItemContent(children []Node) {
 	if isParagraphNodeSlice(children) {
 		for i, c := range children {
-			out := w.WriteNodesAsString(c.(Paragraph).Children...)
+			out := w.WriteNodesAsString(c.(Paragraph).InlineNodes()...)
 			if i != 0 && out != "" {
 				w.WriteString("\n")
 			}
@@ -506,16 +1607,17 @@ func (w *HTMLWriter) writeListItemContent(children []Node) {
 }
 
 func (w *HTMLWriter) WriteParagraph(p Paragraph) {
-	if len(p.Children) == 0 {
+	children := p.InlineNodes()
+	if len(children) == 0 {
 		return
 	}
-	w.WriteString("<p>")
-	WriteNodes(w, p.Children...)
+	w.WriteString("<p" + w.class("Paragraph") + w.dataPos(p) + ">")
+	WriteNodes(w, children...)
 	w.WriteString("</p>\n")
 }
 
 func (w *HTMLWriter) WriteExample(e Example) {
-	w.WriteString(`<pre class="example">` + "\n")
+	w.WriteString(`<pre` + w.class("Example", "example") + w.dataPos(e) + ">\n")
 	if len(e.Children) != 0 {
 		for _, n := range e.Children {
 			WriteNodes(w, n)
@@ -526,28 +1628,50 @@ func (w *HTMLWriter) WriteExample(e Example) {
 }
 
 func (w *HTMLWriter) WriteHorizontalRule(h HorizontalRule) {
-	w.WriteString("<hr>\n")
+	w.WriteString("<hr" + w.class("HorizontalRule") + w.dataPos(h) + ">\n")
 }
 
 func (w *HTMLWriter) WriteNodeWithMeta(n NodeWithMeta) {
-	out := w.WriteNodesAsString(n.Node)
-	if p, ok := n.Node.(Paragraph); ok {
-		if len(p.Children) == 1 && isImageOrVideoLink(p.Children[0]) {
-			out = w.WriteNodesAsString(p.Children[0])
+	node := n.Node
+	if named, ok := node.(NodeWithName); ok {
+		node = named.Node
+	}
+	out, link := w.WriteNodesAsString(node), ""
+	if p, ok := node.(Paragraph); ok && len(p.InlineNodes()) == 1 {
+		if child := p.InlineNodes()[0]; isImageOrVideoLink(child) {
+			out = w.WriteNodesAsString(child)
+		} else if _, ok := child.(RegularLink); ok && len(n.Meta.HTMLAttributes) != 0 {
+			// attributes of a paragraph consisting of a single link apply to the link
+			link = w.WriteNodesAsString(child)
 		}
 	}
-	for _, attributes := range n.Meta.HTMLAttributes {
-		out = w.withHTMLAttributes(out, attributes...) + "\n"
+	label, hasLabel := w.captions[n.Pos]
+	caption := w.captionString(n)
+	if hasLabel && w.NumberCaptions {
+		caption = fmt.Sprintf(`<span%s>%s:</span> %s`, w.class("Caption/number", strings.TrimSuffix(label.kind, "s")+"-number"), label, caption)
 	}
-	if len(n.Meta.Caption) != 0 {
-		caption := ""
-		for i, ns := range n.Meta.Caption {
-			if i != 0 {
-				caption += " "
-			}
-			caption += w.WriteNodesAsString(ns...)
+	isTableWithCaption := hasLabel && w.NumberCaptions && label.kind == "tables"
+	if isTableWithCaption {
+		if i := strings.Index(out, ">"); i != -1 {
+			out = `<table id="` + label.id + `"` + out[len("<table"):i+1] + "\n<caption>\n" + caption + "\n</caption>" + out[i+1:]
 		}
-		out = fmt.Sprintf("<figure>\n%s<figcaption>\n%s\n</figcaption>\n</figure>\n", out, caption)
+	}
+	if link != "" {
+		for _, attributes := range n.Meta.HTMLAttributes {
+			link = w.withHTMLAttributes(link, attributes...)
+		}
+		out = "<p" + w.class("Paragraph") + ">" + link + "</p>\n"
+	} else {
+		for _, attributes := range n.Meta.HTMLAttributes {
+			out = w.withHTMLAttributes(out, attributes...) + "\n"
+		}
+	}
+	switch {
+	case len(n.Meta.Caption) == 0 || isTableWithCaption:
+	case hasLabel:
+		out = fmt.Sprintf("<figure id=\"%s\"%s>\n%s<figcaption>\n%s\n</figcaption>\n</figure>\n", label.id, w.class("Figure"), out, caption)
+	default:
+		out = fmt.Sprintf("<figure%s>\n%s<figcaption>\n%s\n</figcaption>\n</figure>\n", w.class("Figure"), out, caption)
 	}
 	w.WriteString(out)
 }
@@ -557,7 +1681,7 @@ func (w *HTMLWriter) WriteNodeWithName(n NodeWithName) {
 }
 
 func (w *HTMLWriter) WriteTable(t Table) {
-	w.WriteString("<table>\n")
+	w.WriteString("<table" + w.class("Table") + w.dataPos(t) + ">\n")
 	inHead := len(t.SeparatorIndices) > 0 &&
 		t.SeparatorIndices[0] != len(t.Rows)-1 &&
 		(t.SeparatorIndices[0] != 0 || len(t.SeparatorIndices) > 1 && t.SeparatorIndices[len(t.SeparatorIndices)-1] != len(t.Rows)-1)
@@ -591,9 +1715,9 @@ func (w *HTMLWriter) writeTableColumns(columns []Column, tag string) {
 	w.WriteString("<tr>\n")
 	for _, column := range columns {
 		if column.Align == "" {
-			w.WriteString(fmt.Sprintf("<%s>", tag))
+			w.WriteString(fmt.Sprintf("<%s%s>", tag, w.class("TableCell")))
 		} else {
-			w.WriteString(fmt.Sprintf(`<%s class="align-%s">`, tag, column.Align))
+			w.WriteString(fmt.Sprintf(`<%s%s>`, tag, w.class("TableCell", "align-"+column.Align)))
 		}
 		WriteNodes(w, column.Children...)
 		w.WriteString(fmt.Sprintf("</%s>\n", tag))
@@ -603,22 +1727,27 @@ func (w *HTMLWriter) writeTableColumns(columns []Column, tag string) {
 
 func (w *HTMLWriter) withHTMLAttributes(input string, kvs ...string) string {
 	if len(kvs)%2 != 0 {
-		w.log.Printf("withHTMLAttributes: Len of kvs must be even: %#v", kvs)
+		w.document.logf(slog.LevelWarn, nil, "withHTMLAttributes: Len of kvs must be even: %#v", kvs)
 		return input
 	}
 	context := &h.Node{Type: h.ElementNode, Data: "body", DataAtom: atom.Body}
 	nodes, err := h.ParseFragment(strings.NewReader(strings.TrimSpace(input)), context)
 	if err != nil || len(nodes) != 1 {
-		w.log.Printf("withHTMLAttributes: Could not extend attributes of %s: %v (%s)", input, nodes, err)
+		w.document.logf(slog.LevelWarn, nil, "withHTMLAttributes: Could not extend attributes of %s: %v (%s)", input, nodes, err)
 		return input
 	}
 	out, node := strings.Builder{}, nodes[0]
 	for i := 0; i < len(kvs)-1; i += 2 {
-		node.Attr = setHTMLAttribute(node.Attr, strings.TrimPrefix(kvs[i], ":"), kvs[i+1])
+		k, v := strings.TrimPrefix(kvs[i], ":"), kvs[i+1]
+		if w.SafeMode && !isSafeHTMLAttribute(k, v) {
+			w.document.logf(slog.LevelInfo, nil, "SafeMode: dropping unsafe attribute %s=%q", k, v)
+			continue
+		}
+		node.Attr = setHTMLAttribute(node.Attr, k, v)
 	}
 	err = h.Render(&out, nodes[0])
 	if err != nil {
-		w.log.Printf("withHTMLAttributes: Could not extend attributes of %s: %v (%s)", input, node, err)
+		w.document.logf(slog.LevelWarn, nil, "withHTMLAttributes: Could not extend attributes of %s: %v (%s)", input, node, err)
 		return input
 	}
 	return out.String()
@@ -638,15 +1767,46 @@ func (w *HTMLWriter) blockContent(name string, children []Node) string {
 	}
 }
 
+// writeRawText writes nodes that contain verbatim text, i.e. line breaks inside them are never exported as hard line breaks.
+func (w *HTMLWriter) writeRawText(nodes ...Node) {
+	inRawText := w.inRawText
+	w.inRawText = true
+	WriteNodes(w, nodes...)
+	w.inRawText = inRawText
+}
+
+var unsafeURLProtocols = []string{"javascript:", "vbscript:", "data:"}
+
+// isSafeURL reports whether the (html escaped) url does not use a protocol that allows executing code.
+func isSafeURL(url string) bool {
+	url = strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1 // browsers ignore whitespace and control characters in protocols, e.g. "java\tscript:"
+		}
+		return r
+	}, html.UnescapeString(url)))
+	for _, protocol := range unsafeURLProtocols {
+		if strings.HasPrefix(url, protocol) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSafeHTMLAttribute(k, v string) bool {
+	switch k = strings.ToLower(k); {
+	case strings.HasPrefix(k, "on"), k == "srcdoc":
+		return false
+	case k == "href" || k == "src" || k == "action" || k == "formaction" || k == "poster" || k == "xlink:href":
+		return isSafeURL(v)
+	}
+	return true
+}
+
 func setHTMLAttribute(attributes []h.Attribute, k, v string) []h.Attribute {
 	for i, a := range attributes {
 		if strings.ToLower(a.Key) == strings.ToLower(k) {
-			switch strings.ToLower(k) {
-			case "class", "style":
-				attributes[i].Val += " " + v
-			default:
-				attributes[i].Val = v
-			}
+			attributes[i].Val = joinHTMLAttribute(k, a.Val, v)
 			return attributes
 		}
 	}
@@ -662,9 +1822,10 @@ func isParagraphNodeSlice(ns []Node) bool {
 	return true
 }
 
-func (fs *footnotes) add(f FootnoteLink) int {
+// add registers the footnote reference f and returns the index of its footnote and whether it was newly added.
+func (fs *footnotes) add(f FootnoteLink) (int, bool) {
 	if i, ok := fs.mapping[f.Name]; ok && f.Name != "" {
-		return i
+		return i, false
 	}
 
 	if def, ok := fs.unused[f.Name]; ok && f.Name != "" && f.Definition == nil {
@@ -674,12 +1835,44 @@ func (fs *footnotes) add(f FootnoteLink) int {
 		delete(fs.unused, f.Name)
 	}
 
+	if f.Definition == nil && f.Name != "" && fs.index != nil {
+		if footnote, ok := fs.index.Get(f.Name); ok {
+			f.Definition = footnote.Definition
+		}
+	}
 	fs.list = append(fs.list, f.Definition)
 	i := len(fs.list) - 1
 	if f.Name != "" {
 		fs.mapping[f.Name] = i
 	}
-	return i
+	return i, true
+}
+
+// clone returns a copy of fs that can be modified independently.
+func (fs *footnotes) clone() *footnotes {
+	copied := *fs
+	copied.mapping, copied.list, copied.unused = maps.Clone(fs.mapping), slices.Clone(fs.list), maps.Clone(fs.unused)
+	return &copied
+}
+
+// equal returns true if fs and other number, define and write footnotes the same way.
+func (fs *footnotes) equal(other *footnotes) bool {
+	sameDefinition := func(a, b *FootnoteDefinition) bool {
+		return a == b || a != nil && b != nil && a.Name == b.Name && a.Pos == b.Pos
+	}
+	return fs.written == other.written && fs.index == other.index &&
+		maps.Equal(fs.mapping, other.mapping) &&
+		slices.EqualFunc(fs.list, other.list, sameDefinition) &&
+		maps.EqualFunc(fs.unused, other.unused, sameDefinition)
+}
+
+func (fs *footnotes) name(i int) string {
+	for k, v := range fs.mapping {
+		if v == i {
+			return k
+		}
+	}
+	return ""
 }
 
 func (fs *footnotes) updateDefinition(f FootnoteDefinition) {
@@ -1,6 +1,8 @@
 package org
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -103,3 +105,509 @@ func TestTopLevelHLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestTOCOptions(t *testing.T) {
+	input := "* A\n** A1\n*** A1a\n* B\n:PROPERTIES:\n:UNNUMBERED: t\n:END:\n"
+	entryRegexp := regexp.MustCompile(`(?:<span class="section-number">([^<]*)</span> )?<a href="#[^"]*">([^<]*)</a>`)
+	for _, test := range []struct {
+		options  string
+		depth    int
+		numbered bool
+		expected string
+	}{
+		{"toc:t", 0, false, "A A1 A1a B"},
+		{"toc:t", 2, false, "A A1 B"},
+		{"toc:3", 1, false, "A B"},
+		{"toc:1", 2, false, "A B"},
+		{"toc:t", 0, true, "1:A 1.1:A1 1.1.1:A1a B"},
+		{"toc:t", 2, true, "1:A 1.1:A1 B"},
+		{"toc:t num:t", 0, false, "1:A 1.1:A1 1.1.1:A1a B"},
+		{"toc:t num:1", 0, false, "1:A A1 A1a B"},
+	} {
+		writer := NewHTMLWriter()
+		writer.TOCDepth, writer.TOCNumbered = test.depth, test.numbered
+		out, err := New().Silent().Parse(strings.NewReader("#+OPTIONS: "+test.options+"\n"+input), "./toc.org").Write(writer)
+		if err != nil {
+			t.Errorf("%s: got error %s", test.options, err)
+			continue
+		}
+		toc, entries := out[:strings.Index(out, "</nav>")], []string{}
+		for _, m := range entryRegexp.FindAllStringSubmatch(toc, -1) {
+			entries = append(entries, strings.TrimPrefix(m[1]+":"+m[2], ":"))
+		}
+		if actual := strings.Join(entries, " "); actual != test.expected {
+			t.Errorf("%s (TOCDepth %d, TOCNumbered %v): got %q, expected %q", test.options, test.depth, test.numbered, actual, test.expected)
+		}
+	}
+}
+
+func TestRenderTOC(t *testing.T) {
+	input := "#+OPTIONS: toc:t\n#+TOC: tables\n* A\n** A1\n#+CAPTION: numbers\n| 1 |\n* B\n"
+	writer := NewHTMLWriter()
+	writer.TOCNumbered = true
+	var render func(entries []TOCEntry) string
+	render = func(entries []TOCEntry) string {
+		parts := []string{}
+		for _, e := range entries {
+			part := fmt.Sprintf("%s %s %s %d", e.ID, e.Number, e.Title, e.Level)
+			if len(e.Children) != 0 {
+				part += " (" + render(e.Children) + ")"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ", ")
+	}
+	writer.RenderTOC = func(kind string, entries []TOCEntry) string { return "[" + kind + ": " + render(entries) + "]\n" }
+	out, err := New().Silent().Parse(strings.NewReader(input), "./renderTOC.org").Write(writer)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	for _, expected := range []string{
+		"[headlines: headline-1 1 A 1 (headline-2 1.1 A1 2), headline-3 2 B 1]",
+		"[tables: table-1  numbers 0]",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "<nav") {
+		t.Errorf("expected RenderTOC to replace the default table of contents, got:\n%s", out)
+	}
+}
+
+var numberCaptionsTests = map[string]string{
+	"#+CAPTION: kitten\n#+NAME: fig:kitten\n[[kitten.png]]\n\nsee [[fig:kitten]]": `<figure id="figure-1">
+<img src="kitten.png" alt="kitten.png" title="kitten.png" /><figcaption>
+<span class="figure-number">Figure 1:</span> kitten
+</figcaption>
+</figure>
+<p>
+see <a href="#figure-1">Figure 1</a></p>`,
+	"#+NAME: numbers\n#+CAPTION: numbers\n| 1 |\n\nsee [[numbers][the table]]": `<table id="table-1">
+<caption>
+<span class="table-number">Table 1:</span> numbers
+</caption>
+<tbody>
+<tr>
+<td class="align-right">1</td>
+</tr>
+</tbody>
+</table>
+<p>
+see <a href="#table-1">the table</a></p>`,
+}
+
+func TestNumberCaptions(t *testing.T) {
+	for org, expected := range numberCaptionsTests {
+		t.Run(org, func(t *testing.T) {
+			writer := NewHTMLWriter()
+			writer.NumberCaptions = true
+			actual, err := New().Silent().Parse(strings.NewReader(org), "./numberCaptionsTests.org").Write(writer)
+			if err != nil {
+				t.Errorf("%s\n got error: %s", org, err)
+			} else if actual := strings.TrimSpace(actual); actual != expected {
+				t.Errorf("%s:\n%s'", org, diff(actual, expected))
+			}
+		})
+	}
+}
+
+var safeModeTests = map[string]string{
+	"#+BEGIN_EXPORT html\n<script>alert(1)</script>\n#+END_EXPORT": ``,
+	"#+HTML: <script>alert(1)</script>":                            ``,
+	"a @@html:<script>alert(1)</script>@@ b":                       "<p>a  b</p>",
+	"[[javascript:alert(1)][click me]]":                            "<p>click me</p>",
+	"[[JavaScript:alert(1)]]":                                      "<p>JavaScript:alert(1)</p>",
+	"[[data:text/html;base64,PHNjcmlwdD4=][data]]":                 "<p>data</p>",
+	"[[https://example.com][safe]]":                                `<p><a href="https://example.com">safe</a></p>`,
+	"#+ATTR_HTML: :onclick alert(1) :class safe\n| a |":            "<table class=\"safe\">\n<tbody>\n<tr>\n<td>a</td>\n</tr>\n</tbody>\n</table>",
+}
+
+func TestSafeMode(t *testing.T) {
+	for org, expected := range safeModeTests {
+		t.Run(org, func(t *testing.T) {
+			writer := NewHTMLWriter()
+			writer.SafeMode = true
+			actual, err := New().Silent().Parse(strings.NewReader(org), "./safeModeTests.org").Write(writer)
+			if err != nil {
+				t.Errorf("%s\n got error: %s", org, err)
+			} else if actual := strings.TrimSpace(actual); actual != expected {
+				t.Errorf("%s:\n%s'", org, diff(actual, expected))
+			}
+		})
+	}
+}
+
+func TestSafeModeCustomID(t *testing.T) {
+	input := "#+OPTIONS: toc:t\n* A\n:PROPERTIES:\n:CUSTOM_ID: \"><script>alert(1)</script>\n:END:\n"
+	writer := NewHTMLWriter()
+	writer.SafeMode = true
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./safeModeTests.org").Write(writer)
+	if err != nil {
+		t.Fatalf("%s\n got error: %s", input, err)
+	}
+	escaped := "&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;"
+	if strings.Contains(actual, "<script>") || !strings.Contains(actual, `href="#`+escaped+`"`) || !strings.Contains(actual, `<h2 id="`+escaped+`"`) {
+		t.Errorf("expected escaped CUSTOM_ID in headline and TOC, got:\n%s", actual)
+	}
+}
+
+func TestFootnoteModeSidenotes(t *testing.T) {
+	writer := NewHTMLWriter()
+	writer.FootnoteMode = FootnoteModeSidenotes
+	input := "a[fn:1] b[fn:1] c[fn:2]\n\n[fn:1] note\n\n[fn:2] one\n\ntwo"
+	expected := `<p>a<sup class="footnote-reference"><a id="footnote-reference-1" href="#footnote-1">1</a></sup>` +
+		`<span id="footnote-1" class="sidenote"><sup>1</sup> note</span>` +
+		` b<sup class="footnote-reference"><a id="footnote-reference-1" href="#footnote-1">1</a></sup>` +
+		` c<sup class="footnote-reference"><a id="footnote-reference-2" href="#footnote-2">2</a></sup>` +
+		`<span id="footnote-2" class="sidenote"><sup>2</sup> one<br>two</span></p>`
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./footnoteModeTests.org").Write(writer)
+	if err != nil {
+		t.Errorf("%s\n got error: %s", input, err)
+	} else if actual := strings.TrimSpace(actual); actual != expected {
+		t.Errorf("%s:\n%s'", input, diff(actual, expected))
+	}
+}
+
+func TestFootnoteModeSections(t *testing.T) {
+	input := strings.Join([]string{
+		"[fn:1] one",
+		"",
+		"[fn:2] two",
+		"",
+		"[fn:3] three",
+		"",
+		"* A",
+		"a[fn:1]",
+		"** A1",
+		"b[fn:2]",
+		"** A2",
+		"c[fn:3]",
+		"* B",
+		"d[fn:1]",
+	}, "\n")
+	idRegexp := regexp.MustCompile(`id="(footnote-\d|outline-container-[^"]+)"`)
+	for _, test := range []struct {
+		options  string
+		mode     FootnoteMode
+		level    int
+		expected string
+	}{
+		{"", FootnoteModeEndnotes, 0, "A A1 A2 B 1 2 3"},
+		{"", FootnoteModeSections, 0, "A A1 A2 1 2 3 B"},
+		{"", FootnoteModeSections, 2, "A 1 A1 2 A2 3 B"},
+		{"fnsec:t", FootnoteModeEndnotes, 2, "A 1 A1 2 A2 3 B"},
+		{"fnsec:1", FootnoteModeEndnotes, 2, "A A1 A2 1 2 3 B"},
+	} {
+		writer := NewHTMLWriter()
+		writer.HeadlineSlug, writer.FootnoteMode, writer.FootnoteSectionLevel = func(h Headline) string { return String(h.Title...) }, test.mode, test.level
+		d := New().Silent().Parse(strings.NewReader("#+OPTIONS: "+test.options+"\n"+input), "./footnoteModeTests.org")
+		actual, err := d.Write(writer)
+		if err != nil {
+			t.Errorf("%q: got error: %s", test.options, err)
+			continue
+		}
+		ids := []string{}
+		for _, m := range idRegexp.FindAllStringSubmatch(actual, -1) {
+			ids = append(ids, strings.TrimPrefix(strings.TrimPrefix(m[1], "footnote-"), "outline-container-"))
+		}
+		if strings.Join(ids, " ") != test.expected {
+			t.Errorf("%q %d %d: got %q, expected %q", test.options, test.mode, test.level, strings.Join(ids, " "), test.expected)
+		}
+		parallelWriter := NewHTMLWriter()
+		parallelWriter.HeadlineSlug, parallelWriter.FootnoteMode, parallelWriter.FootnoteSectionLevel = writer.HeadlineSlug, test.mode, test.level
+		if parallel, _ := d.WriteParallel(parallelWriter); parallel != actual {
+			t.Errorf("%q %d %d: parallel output differs:\n%s", test.options, test.mode, test.level, diff(parallel, actual))
+		}
+	}
+}
+
+func TestHeadlineSlug(t *testing.T) {
+	input := "* Hello World\n* Hello *World*\n:PROPERTIES:\n:CUSTOM_ID: hello-world-1\n:END:\n* Hello world\n** ???\n"
+	expected := []string{"hello-world", "hello-world-1", "hello-world-2", "headline"}
+	headlineIDRegexp := regexp.MustCompile(`<h\d id="([^"]*)"`)
+	var dropPositions func(n Node) Node // e.g. like for headlines built by hand
+	dropPositions = func(n Node) Node {
+		if h, ok := n.(Headline); ok {
+			h.Pos = Position{}
+			n = h
+		}
+		return mapChildren(n, dropPositions)
+	}
+	for _, drop := range []bool{false, true} {
+		d := New().Silent().Parse(strings.NewReader(input), "./headlineSlugTests.org")
+		if drop {
+			for i, n := range d.Nodes {
+				d.Nodes[i] = dropPositions(n)
+			}
+			d.rebuildOutline()
+		}
+		writer := NewHTMLWriter()
+		writer.HeadlineSlug = SlugifyHeadline
+		actual, err := d.Write(writer)
+		if err != nil {
+			t.Fatalf("%s\n got error: %s", input, err)
+		}
+		ids := []string{}
+		for _, m := range headlineIDRegexp.FindAllStringSubmatch(actual, -1) {
+			ids = append(ids, m[1])
+		}
+		if strings.Join(ids, " ") != strings.Join(expected, " ") {
+			t.Errorf("positions dropped %v: got ids %q, expected %q", drop, ids, expected)
+		}
+	}
+}
+
+func TestClassPrefixAndClassMap(t *testing.T) {
+	writer := NewHTMLWriter()
+	writer.ClassPrefix = "org-"
+	writer.ClassMap = map[string]string{"Emphasis/bold": "font-bold", "Paragraph": "my-2", "Timestamp": "text-sm"}
+	input := "*bold* =verbatim= <2024-01-01 Mon>"
+	expected := `<p class="my-2"><strong class="font-bold">bold</strong> <code class="org-verbatim">verbatim</code> ` +
+		`<span class="org-timestamp text-sm">&lt;2024-01-01 Mon&gt;</span></p>`
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./classMapTests.org").Write(writer)
+	if err != nil {
+		t.Errorf("%s\n got error: %s", input, err)
+	} else if actual := strings.TrimSpace(actual); actual != expected {
+		t.Errorf("%s:\n%s'", input, diff(actual, expected))
+	}
+}
+
+func TestEmphasisTags(t *testing.T) {
+	writer := NewHTMLWriter()
+	writer.EmphasisTags = map[string]EmphasisTag{
+		"_": {Element: "u"},
+		"+": {Element: "s", Attributes: map[string]string{"class": `strike "x"`, "data-kind": "+"}},
+		"*": {},
+	}
+	defaultItalic := DefaultEmphasisTags["/"]
+	DefaultEmphasisTags["/"] = EmphasisTag{Element: "i"} // writers copy DefaultEmphasisTags when they are created
+	defer func() { DefaultEmphasisTags["/"] = defaultItalic }()
+	input := "_underline_ +strike+ *bold* /italic/"
+	expected := `<p><u>underline</u> <s class="strike &#34;x&#34;" data-kind="+">strike</s> bold <em>italic</em></p>`
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./emphasisTagsTests.org").Write(writer)
+	if err != nil {
+		t.Errorf("%s\n got error: %s", input, err)
+	} else if actual := strings.TrimSpace(actual); actual != expected {
+		t.Errorf("%s:\n%s'", input, diff(actual, expected))
+	}
+}
+
+func TestEmphasisComponents(t *testing.T) {
+	components := DefaultEmphasisComponents
+	components.Pre += "（「"
+	components.Post += "）」。"
+	components.Markers += "%"
+	components.VerbatimMarkers = "="
+	input := "（*粗体*）「/斜体/」。 %custom% ~tilde~ =verbatim="
+	expected := `<p>（<strong>粗体</strong>）「<em>斜体</em>」。 <span class="Emphasis/%">custom</span> ~tilde~ <code class="verbatim">verbatim</code></p>`
+	writer := NewHTMLWriter()
+	writer.ClassMap = map[string]string{"Emphasis/%": "Emphasis/%"}
+	d := New(WithEmphasisComponents(components)).Silent().Parse(strings.NewReader(input), "./emphasisComponents.org")
+	if actual, err := d.Write(writer); err != nil {
+		t.Errorf("%s\n got error: %s", input, err)
+	} else if actual := strings.TrimSpace(actual); actual != expected {
+		t.Errorf("%s:\n%s'", input, diff(actual, expected))
+	}
+	if actual, err := d.Write(NewOrgWriter()); err != nil || strings.TrimSpace(actual) != input {
+		t.Errorf("got %q (%v), expected %q", actual, err, input)
+	}
+	components.Border = "="
+	d = New(WithEmphasisComponents(components)).Silent().Parse(strings.NewReader("*=a=* *b*"), "./emphasisComponents.org")
+	if actual := String(d.Nodes...); actual != "*=a=* *b*\n" || len(d.Nodes[0].(Paragraph).Children) != 2 {
+		t.Errorf("Border: got %q %#v", actual, d.Nodes)
+	}
+}
+
+func TestStatisticProgress(t *testing.T) {
+	writer := NewHTMLWriter()
+	writer.StatisticProgress = true
+	input := "[3/7] [42%] [0/0] [120%]"
+	expected := `<p><progress class="statistic" value="3" max="7" aria-label="3/7">[3/7]</progress> ` +
+		`<progress class="statistic" value="42" max="100" aria-label="42%">[42%]</progress> ` +
+		`<code class="statistic">[0/0]</code> <code class="statistic">[120%]</code></p>`
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./statisticProgressTests.org").Write(writer)
+	if err != nil {
+		t.Errorf("%s\n got error: %s", input, err)
+	} else if actual := strings.TrimSpace(actual); actual != expected {
+		t.Errorf("%s:\n%s'", input, diff(actual, expected))
+	}
+}
+
+func TestSpecialBlocks(t *testing.T) {
+	writer := NewHTMLWriter()
+	writer.SpecialBlocks = map[string]SpecialBlock{
+		"aside":   {Element: "aside"},
+		"details": {Element: "details", Summary: "summary"},
+		"warning": {Attributes: map[string]string{"class": "callout", "role": "note"}},
+	}
+	input := "#+BEGIN_aside\naside\n#+END_aside\n#+BEGIN_DETAILS Click <me>\ndetails\n#+END_DETAILS\n" +
+		"#+BEGIN_warning\nwarning\n#+END_warning\n#+BEGIN_other\nother\n#+END_other"
+	expected := "<aside class=\"aside-block\">\n<p>aside</p>\n</aside>\n" +
+		"<details class=\"details-block\">\n<summary>Click &lt;me&gt;</summary>\n<p>details</p>\n</details>\n" +
+		"<div class=\"callout\" role=\"note\">\n<p>warning</p>\n</div>\n" +
+		"<div class=\"other-block\">\n<p>other</p>\n</div>"
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./specialBlocksTests.org").Write(writer)
+	if err != nil {
+		t.Errorf("%s\n got error: %s", input, err)
+	} else if actual := strings.TrimSpace(actual); actual != expected {
+		t.Errorf("%s:\n%s'", input, diff(actual, expected))
+	}
+}
+
+func TestMathEnvironments(t *testing.T) {
+	writer := NewHTMLWriter()
+	writer.MathMode, writer.MathEnvironments = MathModeKaTeX, []string{"align*", "array"}
+	input := `\begin{align*}a\end{align*} \begin{array}\begin{array}x\end{array}\end{array} \begin{tikzpicture}<x>\end{tikzpicture}`
+	expected := `<p><span class="math math-display">\begin{align*}a\end{align*}</span> ` +
+		`<span class="math math-display">\begin{array}\begin{array}x\end{array}\end{array}</span> ` +
+		`\begin{tikzpicture}&lt;x&gt;\end{tikzpicture}</p>`
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./mathEnvironmentsTests.org").Write(writer)
+	if err != nil {
+		t.Errorf("%s\n got error: %s", input, err)
+	} else if actual := strings.TrimSpace(actual); actual != expected {
+		t.Errorf("%s:\n%s'", input, diff(actual, expected))
+	}
+}
+
+func TestMathMode(t *testing.T) {
+	input := "\\(a<b\\) $x$ $$y$$ \\[z\\]\n\n\\begin{equation}\ne\n\\end{equation}\n"
+	mathJax := "<p>\\(a&lt;b\\) \\(x\\) \\[y\\] \\[z\\]</p>\n\\begin{equation}\ne\n\\end{equation}\n"
+	render := func(latex string, display bool) (string, error) { return fmt.Sprintf("[%s %v]", latex, display), nil }
+	fail := func(string, bool) (string, error) { return "", fmt.Errorf("no renderer") }
+	for _, test := range []struct {
+		mode     MathMode
+		render   func(string, bool) (string, error)
+		expected string
+	}{
+		{MathModeRaw, render, "<p>\\(a&lt;b\\) $x$ $$y$$ \\[z\\]</p>\n\\begin{equation}\ne\n\\end{equation}\n"},
+		{MathModeMathJax, nil, mathJax},
+		{MathModeKaTeX, nil, `<p><span class="math math-inline">a&lt;b</span> <span class="math math-inline">x</span> ` +
+			`<span class="math math-display">y</span> <span class="math math-display">z</span></p>` + "\n" +
+			`<span class="math math-display">\begin{equation}` + "\ne\n" + `\end{equation}</span>` + "\n"},
+		{MathModeMathML, render, "<p>[a<b false] [x false] [y true] [z true]</p>\n[\\begin{equation}\ne\n\\end{equation} true]\n"},
+		{MathModeMathML, nil, mathJax},
+		{MathModeMathML, fail, mathJax},
+		{MathModeImage, render, `<p><img class="math math-inline" src="[a&lt;b false]" alt="a&lt;b" /> ` +
+			`<img class="math math-inline" src="[x false]" alt="x" /> <img class="math math-display" src="[y true]" alt="y" /> ` +
+			`<img class="math math-display" src="[z true]" alt="z" /></p>` + "\n" +
+			`<img class="math math-display" src="[\begin{equation}` + "\ne\n" + `\end{equation} true]" alt="\begin{equation}` + "\ne\n" + `\end{equation}" />` + "\n"},
+		{MathModeImage, fail, mathJax},
+	} {
+		writer := NewHTMLWriter()
+		writer.MathMode, writer.RenderMath = test.mode, test.render
+		actual, err := New().Silent().Parse(strings.NewReader(input), "./mathModeTests.org").Write(writer)
+		if err != nil {
+			t.Errorf("%d: got error: %s", test.mode, err)
+		} else if actual != test.expected {
+			t.Errorf("%d:\n%s'", test.mode, diff(actual, test.expected))
+		}
+	}
+}
+
+func TestDataPosAndSourceMap(t *testing.T) {
+	writer := NewHTMLWriter()
+	writer.DataPos, writer.SourceMap = true, true
+	input := "* Headline\nparagraph\n\n- item"
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./dataPosTests.org").Write(writer)
+	if err != nil {
+		t.Fatalf("%s\n got error: %s", input, err)
+	}
+	for _, expected := range []string{`<div id="outline-container-headline-1" class="outline-2" data-pos="0:0">`, `<p data-pos="1:0">`, `<ul data-pos="3:0">`} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("%s: expected output to contain %s:\n%s", input, expected, actual)
+		}
+	}
+	sourceMap, err := writer.SourceMapJSON()
+	if err != nil {
+		t.Fatalf("could not marshal source map: %s", err)
+	}
+	expected := `[{"dataPos":"0:0","kind":"Headline","startLine":0,"startColumn":0,"endLine":3,"endColumn":6},` +
+		`{"dataPos":"1:0","kind":"Paragraph","startLine":1,"startColumn":0,"endLine":1,"endColumn":9},` +
+		`{"dataPos":"3:0","kind":"List","startLine":3,"startColumn":0,"endLine":3,"endColumn":6},` +
+		`{"dataPos":"3:0","kind":"ListItem","startLine":3,"startColumn":0,"endLine":3,"endColumn":6}]`
+	if string(sourceMap) != expected {
+		t.Errorf("%s: source map:\n%s", input, diff(string(sourceMap), expected))
+	}
+}
+
+func TestEmoji(t *testing.T) {
+	shortcodes := map[string]string{"smile": "😄", "party_parrot": ""}
+	input := ":smile: and :party_parrot:, not x:smile: :unknown: or https://example.com/:smile:x"
+	writer := NewHTMLWriter()
+	expected := `<p>😄 and :party_parrot:, not x:smile: :unknown: or <a href="https://example.com/:smile:x">https://example.com/:smile:x</a></p>`
+	d := New(WithEmojiShortcodes(shortcodes)).Silent().Parse(strings.NewReader(input), "./emoji.org")
+	if actual, err := d.Write(writer); err != nil || strings.TrimSpace(actual) != expected {
+		t.Errorf("got %q (%v), expected %q", actual, err, expected)
+	}
+	writer = NewHTMLWriter()
+	writer.EmojiImageURL = func(shortcode string) string {
+		if shortcode == "party_parrot" {
+			return "/emoji/" + shortcode + ".gif"
+		}
+		return ""
+	}
+	expected = `<p>😄 and <img src="/emoji/party_parrot.gif" alt=":party_parrot:" title=":party_parrot:" />, not x:smile: :unknown: or <a href="https://example.com/:smile:x">https://example.com/:smile:x</a></p>`
+	if actual, err := d.Write(writer); err != nil || strings.TrimSpace(actual) != expected {
+		t.Errorf("got %q (%v), expected %q", actual, err, expected)
+	}
+	if actual := String(d.Nodes...); actual != input+"\n" {
+		t.Errorf("got %q, expected %q", actual, input+"\n")
+	}
+	if e, ok := d.Nodes[0].(Paragraph).Children[0].(Emoji); !ok || e.Pos.EndColumn != 7 {
+		t.Errorf("unexpected emoji %#v", d.Nodes[0].(Paragraph).Children[0])
+	}
+	if children := New().Silent().Parse(strings.NewReader("see :smile:"), "./emoji.org").Nodes[0].(Paragraph).Children; len(children) != 1 {
+		t.Errorf("emoji are opt-in, got %#v", children)
+	}
+}
+
+func TestHashtagsAndMentions(t *testing.T) {
+	input := "@alice- and (@bob_2) discussed #go-org, #1 and #über.\nmail alice@example.com, see @@html:<br>@@ and a#b\n"
+	d := New(WithHashtags(true), WithMentions(true)).Silent().Parse(strings.NewReader(input), "./social.org")
+	hashtags, mentions := []string{}, []string{}
+	for _, h := range d.Hashtags() {
+		hashtags = append(hashtags, fmt.Sprintf("%s %d:%d-%d", h.Name, h.Pos.StartLine, h.Pos.StartColumn, h.Pos.EndColumn))
+	}
+	for _, m := range d.Mentions() {
+		mentions = append(mentions, fmt.Sprintf("%s %d:%d-%d", m.Name, m.Pos.StartLine, m.Pos.StartColumn, m.Pos.EndColumn))
+	}
+	if expected := "go-org 0:31-38, über 0:47-53"; strings.Join(hashtags, ", ") != expected {
+		t.Errorf("got hashtags %v, expected %s", hashtags, expected)
+	}
+	if expected := "alice 0:0-6, bob_2 0:13-19"; strings.Join(mentions, ", ") != expected {
+		t.Errorf("got mentions %v, expected %s", mentions, expected)
+	}
+	expected := `<p><span class="mention">@alice</span>- and (<span class="mention">@bob_2</span>) discussed <span class="hashtag">#go-org</span>, #1 and <span class="hashtag">#über</span>.
+mail alice@example.com, see <br> and a#b</p>`
+	if actual, err := d.Write(NewHTMLWriter()); err != nil || strings.TrimSpace(actual) != expected {
+		t.Errorf("got %q (%v), expected %q", actual, err, expected)
+	}
+	if actual := String(d.Nodes...); actual != input {
+		t.Errorf("got %q, expected %q", actual, input)
+	}
+	if len(New().Silent().Parse(strings.NewReader(input), "./social.org").Hashtags()) != 0 {
+		t.Errorf("hashtags are opt-in")
+	}
+}
+
+func TestResolveWikiLink(t *testing.T) {
+	pages := map[string]bool{"Zettelkasten": true}
+	resolve := func(page string) (string, bool) {
+		return "notes/" + strings.ToLower(strings.ReplaceAll(page, " ", "-")) + ".org", pages[page]
+	}
+	input := "[[Zettelkasten]] [[Page Name][a new page]] [[#custom-id]] [[./other.org]] [[https://example.com]]"
+	d := New(WithResolveWikiLink(resolve)).Silent().Parse(strings.NewReader(input), "./wiki.org")
+	expected := `<p><a href="notes/zettelkasten.html" class="wiki-link">Zettelkasten</a> ` +
+		`<a href="notes/page-name.html" class="wiki-link missing">a new page</a> <a href="#custom-id">#custom-id</a> ` +
+		`<a href="./other.html">./other.html</a> <a href="https://example.com">https://example.com</a></p>`
+	if actual, err := d.Write(NewHTMLWriter()); err != nil || strings.TrimSpace(actual) != expected {
+		t.Errorf("got %q (%v), expected %q", actual, err, expected)
+	}
+	if actual := String(d.Nodes...); actual != input+"\n" {
+		t.Errorf("got %q, expected %q", actual, input+"\n")
+	}
+	if l := d.Nodes[0].(Paragraph).Children[2].(RegularLink); l.Wiki != (WikiLink{"Page Name", false}) || l.URL != "notes/page-name.org" {
+		t.Errorf("unexpected link %#v", l)
+	}
+}
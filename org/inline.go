@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 	"unicode/utf8"
@@ -33,7 +35,8 @@ type StatisticToken struct {
 type Timestamp struct {
 	Time     time.Time
 	IsDate   bool
-	Interval string
+	Interval string // Interval is the repeater of the timestamp, e.g. +1w. See Occurrences.
+	Delay    string // Delay is the warning period of a deadline or the delay of a scheduled timestamp, e.g. -3d.
 	Pos      Position
 }
 
@@ -68,9 +71,16 @@ type RegularLink struct {
 	Description []Node
 	URL         string
 	AutoLink    bool
+	Wiki        WikiLink // Wiki is set for links resolved by Configuration.ResolveWikiLink - URL is the target of the page then.
 	Pos         Position
 }
 
+// WikiLink is the resolution of a [[Page Name]] link - see Configuration.ResolveWikiLink.
+type WikiLink struct {
+	Page   string // Page is the page name of the link, e.g. "Page Name". Empty for other links.
+	Exists bool   // Exists is false if the page does not exist (yet) - e.g. to render the link as a "red link".
+}
+
 type Macro struct {
 	Name       string
 	Parameters []string
@@ -83,49 +93,197 @@ var imageExtensionRegexp = regexp.MustCompile(`(?i)^[.](png|gif|jpe?g|svg|tiff?|
 var videoExtensionRegexp = regexp.MustCompile(`(?i)^[.](webm|mp4)$`)
 
 var subScriptSuperScriptRegexp = regexp.MustCompile(`^([_^]){([^{}]+?)}`)
-var timestampRegexp = regexp.MustCompile(`^<(\d{4}-\d{2}-\d{2})( [A-Za-z]+)?( \d{2}:\d{2})?( \+\d+[dwmy])?>`)
+var timestampRegexp = regexp.MustCompile(`^<(\d{4}-\d{2}-\d{2})( [A-Za-z]+)?( \d{2}:\d{2})?( (?:\+\+|\.\+|\+)\d+[hdwmy])?( --?\d+[hdwmy])?>`)
 var footnoteRegexp = regexp.MustCompile(`^\[fn:([\w-]*?)(:(.*?))?\]`)
 var statisticsTokenRegexp = regexp.MustCompile(`^\[(\d+/\d+|\d+%)\]`)
-var latexFragmentRegexp = regexp.MustCompile(`(?s)^\\begin{(\w+)}(.*)\\end{(\w+)}`)
+var latexEnvironmentRegexp = regexp.MustCompile(`^\\begin{([A-Za-z0-9*]+)}`)
 var inlineBlockRegexp = regexp.MustCompile(`src_(\w+)(\[([^\]]*)\])?{([^}]*)}`)
-var inlineExportBlockRegexp = regexp.MustCompile(`@@(\w+):(.*?)@@`)
+var inlineExportBlockRegexp = regexp.MustCompile(`^@@(\w+):(.*?)@@`)
 var macroRegexp = regexp.MustCompile(`{{{(.*)\((.*)\)}}}`)
 
 var timestampFormat = "2006-01-02 Mon 15:04"
 var datestampFormat = "2006-01-02 Mon"
 
-// calculatePosition computes a Position from a base offset and character offset
-func calculatePosition(input string, startLine, startColumn int, charOffset int) Position {
-	line := startLine
-	col := startColumn
+// lineIndex contains the offsets of the newlines of the input of the inline parser - so that the positions of
+// nodes can be computed without rescanning the input from its start for every node.
+type lineIndex struct {
+	input    string
+	newlines []int
+}
 
-	for i := 0; i < charOffset && i < len(input); i++ {
-		if input[i] == '\n' {
-			line++
-			col = 0
-		} else {
-			col++
+func newLineIndex(input string) *lineIndex {
+	return &lineIndex{input: input, newlines: appendNewlines(nil, input)}
+}
+
+// appendNewlines appends the offsets of the newlines of input to newlines.
+func appendNewlines(newlines []int, input string) []int {
+	for i := 0; ; {
+		j := strings.IndexByte(input[i:], '\n')
+		if j == -1 {
+			return newlines
 		}
+		newlines = append(newlines, i+j)
+		i += j + 1
+	}
+}
+
+// position returns the line and column of input[offset] for an input starting at startLine and startColumn.
+func (l *lineIndex) position(startLine, startColumn, offset int) (int, int) {
+	offset = max(0, min(offset, len(l.input)))
+	i := sort.SearchInts(l.newlines, offset) // number of newlines before offset
+	if i == 0 {
+		return startLine, startColumn + offset
 	}
+	return startLine + i, offset - l.newlines[i-1] - 1
+}
 
-	return Position{
-		StartLine:   line,
-		StartColumn: col,
-		EndLine:     line,
-		EndColumn:   col,
+// lineIndex returns the line index of input. The index of the input of the innermost running inline parser is reused.
+func (d *Document) lineIndex(input string) *lineIndex {
+	if b := d.buffers; b != nil && len(b.lineIndexes) != 0 && b.lineIndexes[len(b.lineIndexes)-1].input == input {
+		return &b.lineIndexes[len(b.lineIndexes)-1]
 	}
+	return newLineIndex(input)
+}
+
+// InlineParser parses custom inline markup (e.g. wiki links or emoji shortcodes) starting at input[start], i.e. at its
+// trigger byte. It returns the number of consumed bytes and the node - or 0 if input does not contain its markup at
+// start. See Configuration.AddInlineParser.
+type InlineParser func(input string, start int) (int, Node)
+
+// AddInlineParser registers p for the trigger byte. Inline parsers take precedence over the built-in inline markup and
+// parsers of the same trigger are tried in the order they were added. Their nodes are written by writers implementing
+// CustomNodeWriter, e.g. via HTMLWriter.AddNodeWriter and OrgWriter.AddNodeWriter.
+func (c *Configuration) AddInlineParser(trigger byte, p InlineParser) *Configuration {
+	if c.InlineParsers == nil {
+		c.InlineParsers = map[byte][]InlineParser{}
+	}
+	c.InlineParsers[trigger] = append(c.InlineParsers[trigger], p)
+	return c
+}
+
+// parseCustomInline returns the result of the first InlineParser of input[start] that consumes input.
+func (d *Document) parseCustomInline(input string, start int) (int, Node) {
+	for _, p := range d.InlineParsers[input[start]] {
+		if consumed, node := p(input, start); consumed > 0 && start+consumed <= len(input) {
+			return consumed, node
+		}
+	}
+	return 0, nil
+}
+
+// parseBuffers contains the buffers of the inline parser. They are pooled (see parseBuffersPool) to reduce the
+// allocations of parsing large numbers of documents and paragraphs.
+type parseBuffers struct {
+	nodes       []Node      // nodes is a stack of the nodes of the running inline parsers. See popNodes.
+	lineIndexes []lineIndex // lineIndexes is a stack of the line indexes of the inputs of the running inline parsers.
+	markers     [256]byte   // markers maps the emphasis markers of the document to markerInline or markerVerbatim.
+	depth       int         // depth is the number of emphasis containing the running inline parser. See MaxEmphasisDepth.
+}
+
+const (
+	markerInline   = 1
+	markerVerbatim = 2
+)
+
+var parseBuffersPool = sync.Pool{New: func() any { return &parseBuffers{} }}
+
+// acquireBuffers sets the buffers of the document if they are not set already (i.e. by an outer inline parser) and
+// returns true if they have to be released (see releaseBuffers) afterwards.
+func (d *Document) acquireBuffers() bool {
+	if d.buffers != nil {
+		return false
+	}
+	d.buffers = parseBuffersPool.Get().(*parseBuffers)
+	d.buffers.markers = [256]byte{}
+	for _, m := range []byte(d.EmphasisComponents.Markers) {
+		d.buffers.markers[m] = markerInline
+	}
+	for _, m := range []byte(d.EmphasisComponents.VerbatimMarkers) {
+		d.buffers.markers[m] = markerVerbatim
+	}
+	return true
+}
+
+func (d *Document) releaseBuffers() {
+	b := d.buffers
+	clear(b.nodes)
+	for i := range b.lineIndexes {
+		b.lineIndexes[i].input = ""
+	}
+	b.nodes, b.lineIndexes, b.depth, d.buffers = b.nodes[:0], b.lineIndexes[:0], 0, nil
+	parseBuffersPool.Put(b)
+}
+
+// pushLineIndex makes the line index of input the index of the innermost running inline parser (see lineIndex).
+// The newlines slices of the line indexes are reused.
+func (d *Document) pushLineIndex(input string) {
+	b := d.buffers
+	if n := len(b.lineIndexes); n < cap(b.lineIndexes) {
+		b.lineIndexes = b.lineIndexes[:n+1]
+	} else {
+		b.lineIndexes = append(b.lineIndexes, lineIndex{})
+	}
+	l := &b.lineIndexes[len(b.lineIndexes)-1]
+	l.input, l.newlines = input, appendNewlines(l.newlines[:0], input)
+}
+
+func (d *Document) popLineIndex() {
+	b := d.buffers
+	b.lineIndexes[len(b.lineIndexes)-1].input = ""
+	b.lineIndexes = b.lineIndexes[:len(b.lineIndexes)-1]
+}
+
+// popNodes removes the nodes above start from the node stack and returns them - nil if there are none.
+func (d *Document) popNodes(start int) []Node {
+	b := d.buffers
+	if len(b.nodes) == start {
+		return nil
+	}
+	nodes := make([]Node, len(b.nodes)-start)
+	copy(nodes, b.nodes[start:])
+	clear(b.nodes[start:])
+	b.nodes = b.nodes[:start]
+	return nodes
+}
+
+// calculatePosition returns the (empty) Position of input[offset] for an input starting at startLine and startColumn.
+func (d *Document) calculatePosition(input string, startLine, startColumn int, offset int) Position {
+	line, col := d.lineIndex(input).position(startLine, startColumn, offset)
+	col = d.indentedColumn(startLine, line, col)
+	return Position{StartLine: line, StartColumn: col, EndLine: line, EndColumn: col}
 }
 
-// positionFromChars returns a Position spanning from startOffset to endOffset
-func positionFromChars(input string, startLine, startColumn int, startOffset, endOffset int) Position {
-	start := calculatePosition(input, startLine, startColumn, startOffset)
-	end := calculatePosition(input, startLine, startColumn, endOffset)
-	return Position{
-		StartLine:   start.StartLine,
-		StartColumn: start.StartColumn,
-		EndLine:     end.StartLine,
-		EndColumn:   end.StartColumn,
+// positionFromChars returns a Position spanning from startOffset to endOffset - including byte offsets.
+// Nodes parsed without position tracking (i.e. with startLine noPosition) have an empty Position.
+func (d *Document) positionFromChars(input string, startLine, startColumn int, startOffset, endOffset int) Position {
+	if startLine == noPosition {
+		return Position{}
 	}
+	l := d.lineIndex(input)
+	pos := Position{}
+	pos.StartLine, pos.StartColumn = l.position(startLine, startColumn, startOffset)
+	pos.EndLine, pos.EndColumn = l.position(startLine, startColumn, endOffset)
+	pos.StartColumn, pos.EndColumn = d.indentedColumn(startLine, pos.StartLine, pos.StartColumn), d.indentedColumn(startLine, pos.EndLine, pos.EndColumn)
+	pos.StartOffset, pos.EndOffset = d.offset(pos.StartLine, pos.StartColumn), d.offset(pos.EndLine, pos.EndColumn)
+	pos.StartColumn, pos.EndColumn = d.column(pos.StartLine, pos.StartColumn), d.column(pos.EndLine, pos.EndColumn)
+	return pos
+}
+
+// indentedColumn returns the byte column in the source of column col of line - for lines following startLine,
+// columns are counted from the indentation stripped from the line (see lineIndents).
+func (d *Document) indentedColumn(startLine, line, col int) int {
+	if line != startLine {
+		return col + d.lineIndents[line]
+	}
+	return col
+}
+
+// offset returns the byte offset of the given line and column in the parse input.
+func (d *Document) offset(line, column int) int {
+	if i := line - d.firstLine; i >= 0 && i < len(d.lineOffsets) {
+		return d.lineOffsets[i] + column
+	}
+	return 0
 }
 
 var latexFragmentPairs = map[string]string{
@@ -135,50 +293,80 @@ var latexFragmentPairs = map[string]string{
 	`$`:  `$`,
 }
 
+// noPosition is passed as startLine to parse inline content without position tracking.
+const noPosition = -1
+
+// parseNestedInline parses input[from:to] - e.g. the content of an emphasis - with the position of input[from].
+func (d *Document) parseNestedInline(input string, from, to int, startLine, startColumn int) []Node {
+	if startLine == noPosition {
+		return d.parseInlineWithPos(input[from:to], noPosition, 0)
+	}
+	pos := d.calculatePosition(input, startLine, startColumn, from)
+	return d.parseInlineWithPos(input[from:to], pos.StartLine, pos.StartColumn)
+}
+
 // parseInline parses inline content without position tracking (legacy)
 func (d *Document) parseInline(input string) (nodes []Node) {
-	return d.parseInlineWithPos(input, 0, 0)
+	return d.parseInlineWithPos(input, noPosition, 0)
 }
 
 // parseInlineWithPos parses inline content with position tracking
 func (d *Document) parseInlineWithPos(input string, startLine, startColumn int) (nodes []Node) {
-	previous, current := 0, 0
+	if d.acquireBuffers() {
+		defer d.releaseBuffers()
+	}
+	if startLine != noPosition {
+		d.pushLineIndex(input)
+		defer d.popLineIndex()
+	}
+	previous, current, start := 0, 0, len(d.buffers.nodes)
 	for current < len(input) {
 		rewind, consumed, node := 0, 0, (Node)(nil)
-		switch input[current] {
-		case '^':
-			consumed, node = d.parseSubOrSuperScriptWithPos(input, current, startLine, startColumn)
-		case '_':
-			rewind, consumed, node = d.parseSubScriptOrEmphasisOrInlineBlockWithPos(input, current, startLine, startColumn)
-		case '@':
-			consumed, node = d.parseInlineExportBlockWithPos(input, current, startLine, startColumn)
-		case '*', '/', '+':
-			consumed, node = d.parseEmphasisWithPos(input, current, false, startLine, startColumn)
-		case '=', '~':
-			consumed, node = d.parseEmphasisWithPos(input, current, true, startLine, startColumn)
-		case '[':
-			consumed, node = d.parseOpeningBracketWithPos(input, current, startLine, startColumn)
-		case '{':
-			consumed, node = d.parseMacroWithPos(input, current, startLine, startColumn)
-		case '<':
-			consumed, node = d.parseTimestampWithPos(input, current, startLine, startColumn)
-		case '\\':
-			consumed, node = d.parseExplicitLineBreakOrLatexFragmentWithPos(input, current, startLine, startColumn)
-		case '$':
-			consumed, node = d.parseLatexFragmentWithPos(input, current, 1, startLine, startColumn)
-		case '\n':
-			consumed, node = d.parseLineBreakWithPos(input, current, startLine, startColumn)
-		case ':':
-			rewind, consumed, node = d.parseAutoLink(input, current)
+		if len(d.InlineParsers) != 0 {
+			consumed, node = d.parseCustomInline(input, current)
+		}
+		if consumed == 0 {
+			switch input[current] {
+			case '^':
+				consumed, node = d.parseSubOrSuperScriptWithPos(input, current, startLine, startColumn)
+			case '_':
+				rewind, consumed, node = d.parseSubScriptOrEmphasisOrInlineBlockWithPos(input, current, startLine, startColumn)
+			case '@':
+				if consumed, node = d.parseInlineExportBlockWithPos(input, current, startLine, startColumn); consumed == 0 {
+					consumed, node = d.parseMentionWithPos(input, current, startLine, startColumn)
+				}
+			case '#':
+				consumed, node = d.parseHashtagWithPos(input, current, startLine, startColumn)
+			case '[':
+				consumed, node = d.parseOpeningBracketWithPos(input, current, startLine, startColumn)
+			case '{':
+				consumed, node = d.parseMacroWithPos(input, current, startLine, startColumn)
+			case '<':
+				consumed, node = d.parseTimestampWithPos(input, current, startLine, startColumn)
+			case '\\':
+				consumed, node = d.parseExplicitLineBreakOrLatexFragmentWithPos(input, current, startLine, startColumn)
+			case '$':
+				consumed, node = d.parseLatexFragmentWithPos(input, current, 1, startLine, startColumn)
+			case '\n':
+				consumed, node = d.parseLineBreakWithPos(input, current, startLine, startColumn)
+			case ':':
+				if consumed, node = d.parseEmojiWithPos(input, current, startLine, startColumn); consumed == 0 {
+					rewind, consumed, node = d.parseAutoLinkWithPos(input, current, startLine, startColumn)
+				}
+			default:
+				if m := d.buffers.markers[input[current]]; m != 0 {
+					consumed, node = d.parseEmphasisWithPos(input, current, m == markerVerbatim, startLine, startColumn)
+				}
+			}
 		}
 		current -= rewind
 		if consumed != 0 {
 			if current > previous {
-				textPos := positionFromChars(input, startLine, startColumn, previous, current)
-				nodes = append(nodes, Text{Content: input[previous:current], IsRaw: false, Pos: textPos})
+				textPos := d.positionFromChars(input, startLine, startColumn, previous, current)
+				d.buffers.nodes = append(d.buffers.nodes, Text{Content: input[previous:current], IsRaw: false, Pos: textPos})
 			}
 			if node != nil {
-				nodes = append(nodes, node)
+				d.buffers.nodes = append(d.buffers.nodes, node)
 			}
 			current += consumed
 			previous = current
@@ -188,26 +376,33 @@ func (d *Document) parseInlineWithPos(input string, startLine, startColumn int)
 	}
 
 	if previous < len(input) {
-		textPos := positionFromChars(input, startLine, startColumn, previous, len(input))
-		nodes = append(nodes, Text{Content: input[previous:], IsRaw: false, Pos: textPos})
+		textPos := d.positionFromChars(input, startLine, startColumn, previous, len(input))
+		d.buffers.nodes = append(d.buffers.nodes, Text{Content: input[previous:], IsRaw: false, Pos: textPos})
 	}
-	return nodes
+	return d.popNodes(start)
 }
 
 func (d *Document) parseRawInline(input string) (nodes []Node) {
-	return d.parseRawInlineWithPos(input, 0, 0)
+	return d.parseRawInlineWithPos(input, noPosition, 0)
 }
 
 func (d *Document) parseRawInlineWithPos(input string, startLine, startColumn int) (nodes []Node) {
-	previous, current := 0, 0
+	if d.acquireBuffers() {
+		defer d.releaseBuffers()
+	}
+	if startLine != noPosition {
+		d.pushLineIndex(input)
+		defer d.popLineIndex()
+	}
+	previous, current, start := 0, 0, len(d.buffers.nodes)
 	for current < len(input) {
 		if input[current] == '\n' {
 			consumed, node := d.parseLineBreakWithPos(input, current, startLine, startColumn)
 			if current > previous {
-				textPos := positionFromChars(input, startLine, startColumn, previous, current)
-				nodes = append(nodes, Text{Content: input[previous:current], IsRaw: true, Pos: textPos})
+				textPos := d.positionFromChars(input, startLine, startColumn, previous, current)
+				d.buffers.nodes = append(d.buffers.nodes, Text{Content: input[previous:current], IsRaw: true, Pos: textPos})
 			}
-			nodes = append(nodes, node)
+			d.buffers.nodes = append(d.buffers.nodes, node)
 			current += consumed
 			previous = current
 		} else {
@@ -215,10 +410,10 @@ func (d *Document) parseRawInlineWithPos(input string, startLine, startColumn in
 		}
 	}
 	if previous < len(input) {
-		textPos := positionFromChars(input, startLine, startColumn, previous, len(input))
-		nodes = append(nodes, Text{Content: input[previous:], IsRaw: true, Pos: textPos})
+		textPos := d.positionFromChars(input, startLine, startColumn, previous, len(input))
+		d.buffers.nodes = append(d.buffers.nodes, Text{Content: input[previous:], IsRaw: true, Pos: textPos})
 	}
-	return nodes
+	return d.popNodes(start)
 }
 
 func (d *Document) parseLineBreak(input string, start int) (int, Node) {
@@ -232,7 +427,7 @@ func (d *Document) parseLineBreakWithPos(input string, start int, startLine, sta
 	_, beforeLen := utf8.DecodeLastRuneInString(input[:start])
 	_, afterLen := utf8.DecodeRuneInString(input[i:])
 	consumed := i - start
-	pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
+	pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
 	return consumed, LineBreak{Count: consumed, BetweenMultibyteCharacters: beforeLen > 1 && afterLen > 1, Pos: pos}
 }
 
@@ -246,7 +441,7 @@ func (d *Document) parseInlineBlockWithPos(input string, start int, startLine, s
 	}
 	if m := inlineBlockRegexp.FindStringSubmatch(input[start-3:]); m != nil {
 		consumed := len(m[0])
-		pos := positionFromChars(input, startLine, startColumn, start-3, start+consumed)
+		pos := d.positionFromChars(input, startLine, startColumn, start-3, start+consumed)
 
 		return 3, consumed, InlineBlock{Name: "src", Parameters: strings.Fields(m[1] + " " + m[3]), Children: d.parseRawInline(m[4]), Pos: pos}
 	}
@@ -260,7 +455,7 @@ func (d *Document) parseInlineExportBlock(input string, start int) (int, Node) {
 func (d *Document) parseInlineExportBlockWithPos(input string, start int, startLine, startColumn int) (int, Node) {
 	if m := inlineExportBlockRegexp.FindStringSubmatch(input[start:]); m != nil {
 		consumed := len(m[0])
-		pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
+		pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
 		return consumed, InlineBlock{Name: "export", Parameters: m[1:2], Children: d.parseRawInline(m[2]), Pos: pos}
 	}
 	return 0, nil
@@ -277,26 +472,42 @@ func (d *Document) parseExplicitLineBreakOrLatexFragmentWithPos(input string, st
 		for i := start + 2; i <= len(input)-1 && unicode.IsSpace(rune(input[i])); i++ {
 			if input[i] == '\n' {
 				consumed := i + 1 - start
-				pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
+				pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
 				return consumed, ExplicitLineBreak{Pos: pos}
 			}
 		}
 	case input[start+1] == '(' || input[start+1] == '[':
 		return d.parseLatexFragmentWithPos(input, start, 2, startLine, startColumn)
 	case strings.Index(input[start:], `\begin{`) == 0:
-		if m := latexFragmentRegexp.FindStringSubmatch(input[start:]); m != nil {
-			if open, content, close := m[1], m[2], m[3]; open == close {
-				openingPair, closingPair := `\begin{`+open+`}`, `\end{`+close+`}`
-				i := strings.Index(input[start:], closingPair)
-				consumed := i + len(closingPair)
-				pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
-				return consumed, LatexFragment{OpeningPair: openingPair, ClosingPair: closingPair, Content: d.parseRawInline(content), Pos: pos}
+		if m := latexEnvironmentRegexp.FindStringSubmatch(input[start:]); m != nil {
+			openingPair, closingPair := `\begin{`+m[1]+`}`, `\end{`+m[1]+`}`
+			if end := latexEnvironmentEnd(input[start:], openingPair, closingPair); end != -1 {
+				content := input[start+len(openingPair) : start+end-len(closingPair)]
+				pos := d.positionFromChars(input, startLine, startColumn, start, start+end)
+				return end, LatexFragment{OpeningPair: openingPair, ClosingPair: closingPair, Content: d.parseRawInline(content), Pos: pos}
 			}
 		}
 	}
 	return 0, nil
 }
 
+// latexEnvironmentEnd returns the index after the closingPair matching the openingPair input starts with - skipping
+// nested environments of the same name. Returns -1 if the environment is not closed.
+func latexEnvironmentEnd(input, openingPair, closingPair string) int {
+	for i, depth := len(openingPair), 1; ; {
+		end := strings.Index(input[i:], closingPair)
+		if end == -1 {
+			return -1
+		} else if begin := strings.Index(input[i:], openingPair); begin != -1 && begin < end {
+			i, depth = i+begin+len(openingPair), depth+1
+			continue
+		}
+		if i, depth = i+end+len(closingPair), depth-1; depth == 0 {
+			return i
+		}
+	}
+}
+
 func (d *Document) parseLatexFragment(input string, start int, pairLength int) (int, Node) {
 	return d.parseLatexFragmentWithPos(input, start, pairLength, 0, 0)
 }
@@ -310,15 +521,45 @@ func (d *Document) parseLatexFragmentWithPos(input string, start int, pairLength
 	}
 	openingPair := input[start : start+pairLength]
 	closingPair := latexFragmentPairs[openingPair]
-	if i := strings.Index(input[start+pairLength:], closingPair); i != -1 {
+	i := strings.Index(input[start+pairLength:], closingPair)
+	if openingPair == "$" {
+		i = d.dollarMathEnd(input, start)
+	}
+	if i != -1 {
 		content := d.parseRawInline(input[start+pairLength : start+pairLength+i])
 		consumed := i + pairLength + pairLength
-		pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
+		pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
 		return consumed, LatexFragment{OpeningPair: openingPair, ClosingPair: closingPair, Content: content, Pos: pos}
 	}
 	return 0, nil
 }
 
+// dollarMathEnd returns the index of the closing $ of the $...$ fragment opened at start - relative to the start of
+// its content - or -1. Like Org, the opening $ must not follow a $ and must not be followed by whitespace or ",;.",
+// the closing $ must not follow whitespace or ",." and must be followed by whitespace, punctuation or the end of
+// the input, and the content may span at most 3 lines. This keeps e.g. "$5 and $10" from becoming a fragment.
+func (d *Document) dollarMathEnd(input string, start int) int {
+	if !d.DollarMath || (start > 0 && input[start-1] == '$') {
+		return -1
+	}
+	i := strings.IndexByte(input[start+1:], '$')
+	if i <= 0 {
+		return -1
+	}
+	content := input[start+1 : start+1+i]
+	if strings.ContainsAny(content[:1], " \t\r\n,;.") || strings.ContainsAny(content[len(content)-1:], " \t\r\n,.") ||
+		strings.Count(content, "\n") > 2 {
+		return -1
+	}
+	if end := start + 1 + i + 1; end < len(input) {
+		r, _ := utf8.DecodeRuneInString(input[end:])
+		if r == '$' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return -1
+		}
+	}
+	return i
+}
+
 func (d *Document) parseSubOrSuperScript(input string, start int) (int, Node) {
 	return d.parseSubOrSuperScriptWithPos(input, start, 0, 0)
 }
@@ -326,8 +567,8 @@ func (d *Document) parseSubOrSuperScript(input string, start int) (int, Node) {
 func (d *Document) parseSubOrSuperScriptWithPos(input string, start int, startLine, startColumn int) (int, Node) {
 	if m := subScriptSuperScriptRegexp.FindStringSubmatch(input[start:]); m != nil {
 		consumed := len(m[2]) + 3
-		pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
-		contentPos := positionFromChars(input, startLine, startColumn, start+2, start+2+len(m[2]))
+		pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
+		contentPos := d.positionFromChars(input, startLine, startColumn, start+2, start+2+len(m[2]))
 		content := []Node{Text{Content: m[2], IsRaw: false, Pos: contentPos}}
 		return consumed, Emphasis{Kind: m[1] + "{}", Content: content, Pos: pos}
 	}
@@ -344,8 +585,11 @@ func (d *Document) parseSubScriptOrEmphasisOrInlineBlockWithPos(input string, st
 	} else if consumed, node := d.parseSubOrSuperScriptWithPos(input, start, startLine, startColumn); consumed != 0 {
 		return 0, consumed, node
 	}
-	consumed, node := d.parseEmphasisWithPos(input, start, false, startLine, startColumn)
-	return 0, consumed, node
+	if m := d.buffers.markers['_']; m != 0 {
+		consumed, node := d.parseEmphasisWithPos(input, start, m == markerVerbatim, startLine, startColumn)
+		return 0, consumed, node
+	}
+	return 0, 0, nil
 }
 
 func (d *Document) parseOpeningBracket(input string, start int) (int, Node) {
@@ -370,7 +614,7 @@ func (d *Document) parseMacro(input string, start int) (int, Node) {
 func (d *Document) parseMacroWithPos(input string, start int, startLine, startColumn int) (int, Node) {
 	if m := macroRegexp.FindStringSubmatch(input[start:]); m != nil {
 		consumed := len(m[0])
-		pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
+		pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
 		return consumed, Macro{Name: m[1], Parameters: strings.Split(m[2], ","), Pos: pos}
 	}
 	return 0, nil
@@ -388,10 +632,10 @@ func (d *Document) parseFootnoteReferenceWithPos(input string, start int, startL
 		}
 		link := FootnoteLink{Name: name, Definition: nil}
 		if definition != "" {
-			link.Definition = &FootnoteDefinition{Name: name, Children: []Node{Paragraph{Children: d.parseInlineWithPos(definition, startLine, startColumn+start+len(name)+5), Pos: Position{}}}, Inline: true}
+			link.Definition = &FootnoteDefinition{Name: name, Children: []Node{Paragraph{Children: d.parseNestedInline(input, start+len(name)+5, start+len(name)+5+len(definition), startLine, startColumn), Pos: Position{}}}, Inline: true}
 		}
 		consumed := len(m[0])
-		pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
+		pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
 		link.Pos = pos
 		return consumed, link
 	}
@@ -405,7 +649,7 @@ func (d *Document) parseStatisticToken(input string, start int) (int, Node) {
 func (d *Document) parseStatisticTokenWithPos(input string, start int, startLine, startColumn int) (int, Node) {
 	if m := statisticsTokenRegexp.FindStringSubmatch(input[start:]); m != nil {
 		consumed := len(m[1]) + 2
-		pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
+		pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
 		return consumed, StatisticToken{Content: m[1], Pos: pos}
 	}
 	return 0, nil
@@ -438,7 +682,7 @@ func (d *Document) parseAutoLinkWithPos(input string, start int, startLine, star
 	if path == "://" {
 		return 0, 0, nil
 	}
-	pos := positionFromChars(input, startLine, startColumn, start-len(protocol), start+len(path))
+	pos := d.positionFromChars(input, startLine, startColumn, start-len(protocol), start+len(path))
 	// pos for autolink covers the entire URL including protocol
 	rl := RegularLink{Protocol: protocol, Description: nil, URL: protocol + path, AutoLink: true, Pos: pos}
 	return len(protocol), len(path + protocol), rl
@@ -461,7 +705,7 @@ func (d *Document) parseRegularLinkWithPos(input string, start int, startLine, s
 	rawLinkParts := strings.Split(input[start+2:absEnd], "][")
 	description, link := ([]Node)(nil), rawLinkParts[0]
 	if len(rawLinkParts) == 2 {
-		link, description = rawLinkParts[0], d.parseInlineWithPos(rawLinkParts[1], startLine, startColumn+start+2)
+		link, description = rawLinkParts[0], d.parseNestedInline(input, start+2+len(link)+2, absEnd, startLine, startColumn)
 	}
 	if strings.ContainsRune(link, '\n') {
 		return 0, nil
@@ -471,7 +715,15 @@ func (d *Document) parseRegularLinkWithPos(input string, start int, startLine, s
 	if len(linkParts) == 2 {
 		protocol = linkParts[0]
 	}
-	pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
+	pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
+	if protocol == "" && d.ResolveWikiLink != nil && isWikiPage(link) {
+		target, exists := d.ResolveWikiLink(link)
+		protocol, targetParts := "", strings.SplitN(target, ":", 2)
+		if len(targetParts) == 2 {
+			protocol = targetParts[0]
+		}
+		return consumed, RegularLink{Protocol: protocol, Description: description, URL: target, Wiki: WikiLink{link, exists}, Pos: pos}
+	}
 	linkNode := d.ResolveLink(protocol, description, link)
 	if rl, ok := linkNode.(RegularLink); ok {
 		rl.Pos = pos
@@ -480,13 +732,19 @@ func (d *Document) parseRegularLinkWithPos(input string, start int, startLine, s
 	return consumed, linkNode
 }
 
+// isWikiPage returns true if the link (without protocol) is a page name rather than an Org mode internal link (e.g.
+// [[#custom-id]], [[*headline]] or [[(coderef)]]) or a path (e.g. [[./file.org]] or [[file.org]]).
+func isWikiPage(link string) bool {
+	return link != "" && !strings.ContainsAny(link[:1], "#*(./~") && !strings.HasSuffix(link, ".org")
+}
+
 func (d *Document) parseTimestamp(input string, start int) (int, Node) {
 	return d.parseTimestampWithPos(input, start, 0, 0)
 }
 
 func (d *Document) parseTimestampWithPos(input string, start int, startLine, startColumn int) (int, Node) {
 	if m := timestampRegexp.FindStringSubmatch(input[start:]); m != nil {
-		ddmmyy, hhmm, interval, isDate := m[1], m[3], strings.TrimSpace(m[4]), false
+		ddmmyy, hhmm, interval, delay, isDate := m[1], m[3], strings.TrimSpace(m[4]), strings.TrimSpace(m[5]), false
 		if hhmm == "" {
 			hhmm, isDate = "00:00", true
 		}
@@ -495,8 +753,8 @@ func (d *Document) parseTimestampWithPos(input string, start int, startLine, sta
 			return 0, nil
 		}
 		consumed := len(m[0])
-		pos := positionFromChars(input, startLine, startColumn, start, start+consumed)
-		timestamp := Timestamp{Time: t, IsDate: isDate, Interval: interval, Pos: pos}
+		pos := d.positionFromChars(input, startLine, startColumn, start, start+consumed)
+		timestamp := Timestamp{Time: t, IsDate: isDate, Interval: interval, Delay: delay, Pos: pos}
 		return consumed, timestamp
 	}
 	return 0, nil
@@ -508,7 +766,7 @@ func (d *Document) parseEmphasis(input string, start int, isRaw bool) (int, Node
 
 func (d *Document) parseEmphasisWithPos(input string, start int, isRaw bool, startLine, startColumn int) (int, Node) {
 	marker, i := input[start], start
-	if !hasValidPreAndBorderChars(input, i) {
+	if d.MaxEmphasisDepth > 0 && d.buffers.depth >= d.MaxEmphasisDepth || !d.hasValidPreAndBorderChars(input, i) {
 		return 0, nil
 	}
 	for i, consumedNewLines := i+1, 0; i < len(input) && consumedNewLines <= d.MaxEmphasisNewLines; i++ {
@@ -516,28 +774,39 @@ func (d *Document) parseEmphasisWithPos(input string, start int, isRaw bool, sta
 			consumedNewLines++
 		}
 
-		if input[i] == marker && i != start+1 && hasValidPostAndBorderChars(input, i) {
+		if input[i] == marker && i != start+1 && d.hasValidPostAndBorderChars(input, i) {
 			var content []Node
 			if isRaw {
 				content = d.parseRawInline(input[start+1 : i])
 			} else {
-				content = d.parseInlineWithPos(input[start+1:i], startLine, startColumn+start+1)
+				d.buffers.depth++
+				content = d.parseNestedInline(input, start+1, i, startLine, startColumn)
+				d.buffers.depth--
 			}
-			pos := positionFromChars(input, startLine, startColumn, start, i+1)
+			pos := d.positionFromChars(input, startLine, startColumn, start, i+1)
 			return i + 1 - start, Emphasis{Kind: input[start : start+1], Content: content, Pos: pos}
 		}
 	}
 	return 0, nil
 }
 
-// see org-emphasis-regexp-components (emacs elisp variable)
+// see org-emphasis-regexp-components (emacs elisp variable) and EmphasisComponents.
+// emacs restricts whitespace to ASCII whitespace and the start and end of the input - see CompatibilityEmacs96.
 
-func hasValidPreAndBorderChars(input string, i int) bool {
-	return isValidBorderChar(nextRune(input, i)) && isValidPreChar(prevRune(input, i))
+func (d *Document) hasValidPreAndBorderChars(input string, i int) bool {
+	pre, emacs := prevRune(input, i), d.emacsCompatible()
+	if emacs && pre == utf8.RuneError && i != 0 {
+		return false
+	}
+	return d.isValidBorderChar(nextRune(input, i), emacs) && d.isValidPreChar(pre, emacs)
 }
 
-func hasValidPostAndBorderChars(input string, i int) bool {
-	return (isValidPostChar(nextRune(input, i))) && isValidBorderChar(prevRune(input, i))
+func (d *Document) hasValidPostAndBorderChars(input string, i int) bool {
+	post, emacs := nextRune(input, i), d.emacsCompatible()
+	if emacs && post == utf8.RuneError && i+1 != len(input) {
+		return false
+	}
+	return d.isValidPostChar(post, emacs) && d.isValidBorderChar(prevRune(input, i), emacs)
 }
 
 func prevRune(input string, i int) rune {
@@ -551,15 +820,24 @@ func nextRune(input string, i int) rune {
 	return r
 }
 
-func isValidPreChar(r rune) bool {
-	return r == utf8.RuneError || unicode.IsSpace(r) || strings.ContainsRune(`-({'"`, r)
+func (d *Document) isValidPreChar(r rune, emacs bool) bool {
+	return r == utf8.RuneError || isEmphasisSpace(r, emacs) || strings.ContainsRune(d.EmphasisComponents.Pre, r)
+}
+
+func (d *Document) isValidPostChar(r rune, emacs bool) bool {
+	return r == utf8.RuneError || isEmphasisSpace(r, emacs) || strings.ContainsRune(d.EmphasisComponents.Post, r)
 }
 
-func isValidPostChar(r rune) bool {
-	return r == utf8.RuneError || unicode.IsSpace(r) || strings.ContainsRune(`-.,:!?;'")}[\`, r)
+func (d *Document) isValidBorderChar(r rune, emacs bool) bool {
+	return !isEmphasisSpace(r, emacs) && !strings.ContainsRune(d.EmphasisComponents.Border, r)
 }
 
-func isValidBorderChar(r rune) bool { return !unicode.IsSpace(r) }
+func isEmphasisSpace(r rune, emacs bool) bool {
+	if emacs {
+		return r < utf8.RuneSelf && unicode.IsSpace(r)
+	}
+	return unicode.IsSpace(r)
+}
 
 func (l RegularLink) Kind() string {
 	description := String(l.Description...)
@@ -644,7 +922,7 @@ func (n LatexFragment) Copy() Node {
 	return LatexFragment{
 		OpeningPair: n.OpeningPair,
 		ClosingPair: n.ClosingPair,
-		Content:     n.Content,
+		Content:     CopyNodes(n.Content),
 		Pos:         n.Pos,
 	}
 }
@@ -663,6 +941,7 @@ func (n RegularLink) Copy() Node {
 		Description: CopyNodes(n.Description),
 		URL:         n.URL,
 		AutoLink:    n.AutoLink,
+		Wiki:        n.Wiki,
 		Pos:         n.Pos,
 	}
 }
@@ -680,6 +959,7 @@ func (n Timestamp) Copy() Node {
 		Time:     n.Time,
 		IsDate:   n.IsDate,
 		Interval: n.Interval,
+		Delay:    n.Delay,
 		Pos:      n.Pos,
 	}
 }
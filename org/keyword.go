@@ -2,6 +2,9 @@ package org
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -42,16 +45,6 @@ type Include struct {
 	Pos     Position
 }
 
-func (k Keyword) withPos(t token) Keyword {
-	k.Pos = Position{
-		StartLine:   t.line,
-		StartColumn: t.startCol,
-		EndLine:     t.line,
-		EndColumn:   t.endCol,
-	}
-	return k
-}
-
 var keywordRegexp = regexp.MustCompile(`^(\s*)#\+([^:]+):(\s+(.*)|$)`)
 var commentRegexp = regexp.MustCompile(`^(\s*)#\s(.*)`)
 
@@ -70,17 +63,41 @@ func lexKeywordOrComment(line string) (token, bool) {
 func (d *Document) parseComment(i int, stop stopFn) (int, Node) {
 	return 1, Comment{
 		Content: d.tokens[i].content,
-		Pos: Position{
-			StartLine:   d.tokens[i].line,
-			StartColumn: d.tokens[i].startCol,
-			EndLine:     d.tokens[i].line,
-			EndColumn:   d.tokens[i].endCol,
-		},
+		Pos:     d.getPositionFromToken(d.tokens[i]),
+	}
+}
+
+// KeywordHandler turns a keyword without a predefined meaning (e.g. #+HUGO_BASE_DIR: ...) into a custom node - see
+// Configuration.AddKeywordHandler. It returns nil to keep the keyword as is.
+type KeywordHandler func(d *Document, k Keyword) Node
+
+// AddKeywordHandler registers h for keywords with the given key. Keys ending with "_" (e.g. "REVEAL_") match all
+// keywords starting with them - the longest matching key wins. Handled keywords are still added to BufferSettings.
+// Custom nodes are written by writers implementing CustomNodeWriter, e.g. via HTMLWriter.AddNodeWriter.
+func (c *Configuration) AddKeywordHandler(key string, h KeywordHandler) *Configuration {
+	if c.KeywordHandlers == nil {
+		c.KeywordHandlers = map[string]KeywordHandler{}
 	}
+	c.KeywordHandlers[strings.ToUpper(key)] = h
+	return c
+}
+
+// keywordHandler returns the KeywordHandler for key - or nil.
+func (d *Document) keywordHandler(key string) KeywordHandler {
+	if h, ok := d.KeywordHandlers[key]; ok {
+		return h
+	}
+	handler, prefix := KeywordHandler(nil), ""
+	for k, h := range d.KeywordHandlers {
+		if strings.HasSuffix(k, "_") && strings.HasPrefix(key, k) && len(k) > len(prefix) {
+			handler, prefix = h, k
+		}
+	}
+	return handler
 }
 
 func (d *Document) parseKeyword(i int, stop stopFn) (int, Node) {
-	k := parseKeyword(d.tokens[i])
+	k := d.parseKeywordToken(d.tokens[i])
 	switch k.Key {
 	case "NAME":
 		return d.parseNodeWithName(k, i, stop)
@@ -110,6 +127,11 @@ func (d *Document) parseKeyword(i int, stop stopFn) (int, Node) {
 		} else {
 			d.BufferSettings[k.Key] = k.Value
 		}
+		if h := d.keywordHandler(k.Key); h != nil {
+			if n := h(d, k); n != nil {
+				return 1, n
+			}
+		}
 		return 1, k
 	}
 }
@@ -127,19 +149,14 @@ func (d *Document) parseNodeWithName(k Keyword, i int, stop stopFn) (int, Node)
 	return consumed + 1, NodeWithName{
 		Name: k.Value,
 		Node: node,
-		Pos: Position{
-			StartLine:   d.tokens[i].line,
-			StartColumn: d.tokens[i].startCol,
-			EndLine:     endToken.line,
-			EndColumn:   endToken.endCol,
-		},
+		Pos:  d.getPositionBetweenTokens(d.tokens[i], endToken),
 	}
 }
 
 func (d *Document) parseAffiliated(i int, stop stopFn) (int, Node) {
 	start, meta := i, Metadata{}
-	for ; !stop(d, i) && d.tokens[i].kind == "keyword"; i++ {
-		switch k := parseKeyword(d.tokens[i]); k.Key {
+	for ; !stop(d, i) && d.tokens[i].kind == "keyword" && d.parseKeywordToken(d.tokens[i]).Key != "NAME"; i++ {
+		switch k := d.parseKeywordToken(d.tokens[i]); k.Key {
 		case "CAPTION":
 			meta.Caption = append(meta.Caption, d.parseInlineWithPos(k.Value, d.tokens[i].line, d.tokens[i].startCol+len(k.Key)+1))
 		case "ATTR_HTML":
@@ -167,7 +184,14 @@ func (d *Document) parseAffiliated(i int, stop stopFn) (int, Node) {
 	if stop(d, i) {
 		return 0, nil
 	}
-	consumed, node := d.parseOne(i, stop)
+	var consumed int
+	var node Node
+	if d.tokens[i].kind == "keyword" {
+		// #+NAME following #+CAPTION / #+ATTR_HTML - the name is nested inside the metadata
+		consumed, node = d.parseNodeWithName(d.parseKeywordToken(d.tokens[i]), i, stop)
+	} else {
+		consumed, node = d.parseOne(i, stop)
+	}
 	if consumed == 0 || node == nil {
 		return 0, nil
 	}
@@ -175,76 +199,140 @@ func (d *Document) parseAffiliated(i int, stop stopFn) (int, Node) {
 	return i - start, NodeWithMeta{
 		Node: node,
 		Meta: meta,
-		Pos: Position{
-			StartLine:   d.tokens[start].line,
-			StartColumn: d.tokens[start].startCol,
-			EndLine:     d.tokens[i-1].line,
-			EndColumn:   d.tokens[i-1].endCol,
-		},
+		Pos:  d.getPositionBetweenTokens(d.tokens[start], d.tokens[i-1]),
 	}
 }
 
-func parseKeyword(t token) Keyword {
+func (d *Document) parseKeywordToken(t token) Keyword {
 	k, v := t.matches[2], t.matches[4]
 	return Keyword{
-		Key:   strings.ToUpper(k),
+		Key:   upper(k),
 		Value: strings.TrimSpace(v),
-		Pos: Position{
-			StartLine:   t.line,
-			StartColumn: t.startCol,
-			EndLine:     t.line,
-			EndColumn:   t.endCol,
-		},
+		Pos:   d.getPositionFromToken(t),
 	}
 }
 
 func (d *Document) parseInclude(k Keyword) (int, Node) {
+	if m := includeFileRegexp.FindStringSubmatch(k.Value); m != nil {
+		d.addDependency(IncludeDependency, d.resolvePath(m[1]), k.Pos)
+	}
+	return 1, Include{
+		Keyword: k,
+		Resolve: d.includeResolver(k),
+		Pos:     k.Pos,
+	}
+}
+
+// includeResolver returns the Resolve function of the include keyword k.
+func (d *Document) includeResolver(k Keyword) func() Node {
 	resolve := func() Node {
-		d.Log.Printf("Bad include %#v", k)
+		d.logf(slog.LevelWarn, k, "Bad include %#v", k)
 		return k
 	}
 	if m := includeFileRegexp.FindStringSubmatch(k.Value); m != nil {
-		path, kind, lang := m[1], m[2], m[3]
-		if !filepath.IsAbs(path) {
-			path = filepath.Join(filepath.Dir(d.Path), path)
-		}
+		path, kind, lang := d.resolvePath(m[1]), m[2], m[3]
 		resolve = func() Node {
-			bs, err := d.ReadFile(path)
+			bs, err := d.readFile(path)
 			if err != nil {
-				d.Log.Printf("Bad include %#v: %s", k, err)
+				d.logf(slog.LevelWarn, k, "Bad include %#v: %s", k, err)
 				return k
 			}
 			return Block{Name: strings.ToUpper(kind), Parameters: []string{lang}, Children: d.parseRawInline(string(bs)), Result: nil, Pos: k.Pos}
 		}
 	}
-	return 1, Include{
-		Keyword: k,
-		Resolve: resolve,
-		Pos:     k.Pos,
-	}
+	return resolve
 }
 
 func (d *Document) loadSetupFile(k Keyword) (int, Node) {
-	path := k.Value
-	if !filepath.IsAbs(path) {
-		path = filepath.Join(filepath.Dir(d.Path), path)
+	path := d.resolvePath(k.Value)
+	d.addDependency(SetupFileDependency, path, k.Pos)
+	if !d.checkInclude(path, k) {
+		return 1, k
 	}
-	bs, err := d.ReadFile(path)
+	bs, err := d.readFile(path)
 	if err != nil {
-		d.Log.Printf("Bad setup file: %#v: %s", k, err)
+		d.logf(slog.LevelWarn, k, "Bad setup file: %#v: %s", k, err)
 		return 1, k
 	}
-	setupDocument := d.Configuration.Parse(bytes.NewReader(bs), path)
-	if setupDocument.HasErrors() {
-		d.Log.Printf("Bad setup file: %#v: %s", k, setupDocument.Errors[0])
+	ctx := d.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	includeChain := append(d.includeChain[:len(d.includeChain):len(d.includeChain)], d.Path)
+	setupDocument := d.Configuration.parse(ctx, bytes.NewReader(bs), path, includeChain)
+	for _, err := range setupDocument.Errors {
+		if err.Type == ErrorTypeInclude {
+			d.Errors = append(d.Errors, err) // errors of nested setup files point at the keyword in the nested file
+		}
+	}
+	if errs := setupDocument.errorsWithoutFootnotes(); len(errs) != 0 {
+		d.logf(slog.LevelWarn, k, "Bad setup file: %#v: %s", k, errs[0])
 		return 1, k
 	}
 	for k, v := range setupDocument.BufferSettings {
 		d.BufferSettings[k] = v
 	}
+	d.dependencies = append(d.dependencies, setupDocument.dependencies...)
 	return 1, k
 }
 
+// checkInclude returns true if the file at path can be parsed as part of the document. Otherwise, i.e. if the file
+// is already being parsed (an include cycle) or nesting it would exceed MaxIncludeDepth, an error pointing at the
+// including keyword k is added to the document.
+func (d *Document) checkInclude(path string, k Keyword) bool {
+	chain := append(d.includeChain[:len(d.includeChain):len(d.includeChain)], d.Path)
+	for i, p := range chain {
+		if p != "" && filepath.Clean(p) == filepath.Clean(path) {
+			cycle := strings.Join(append(chain[i:], path), " -> ")
+			d.AddError(ErrorTypeInclude, "include cycle", k.Pos, token{}, fmt.Errorf("%s includes itself: %s", path, cycle))
+			return false
+		}
+	}
+	if d.MaxIncludeDepth > 0 && len(chain) > d.MaxIncludeDepth {
+		d.AddError(ErrorTypeInclude, "include depth exceeded", k.Pos, token{}, fmt.Errorf("could not include %s: more than %d nested includes", path, d.MaxIncludeDepth))
+		return false
+	}
+	return true
+}
+
+// HTMLAttributesMap returns the attributes declared via #+ATTR_HTML keywords. Later keywords take precedence
+// over earlier ones - except for the class and style attributes, which are concatenated (see joinHTMLAttribute).
+func (m Metadata) HTMLAttributesMap() map[string]string {
+	attributes := map[string]string{}
+	for _, kvs := range m.HTMLAttributes {
+		for i := 0; i < len(kvs)-1; i += 2 {
+			k, v := strings.TrimPrefix(kvs[i], ":"), kvs[i+1]
+			if old, ok := attributes[k]; ok {
+				v = joinHTMLAttribute(k, old, v)
+			}
+			attributes[k] = v
+		}
+	}
+	return attributes
+}
+
+// joinHTMLAttribute returns the value of attribute k when it is set to v after having been set to old.
+// Class names are separated by spaces, style declarations by semicolons - other attributes are overwritten.
+func joinHTMLAttribute(k, old, v string) string {
+	switch strings.ToLower(k) {
+	case "class":
+		return old + " " + v
+	case "style":
+		if old = strings.TrimSpace(old); old == "" || strings.HasSuffix(old, ";") {
+			return strings.TrimSpace(old + " " + v)
+		}
+		return old + "; " + v
+	default:
+		return v
+	}
+}
+
+// HTMLAttribute returns the value of the #+ATTR_HTML attribute key (without the leading colon), e.g. "width".
+func (m Metadata) HTMLAttribute(key string) (string, bool) {
+	v, ok := m.HTMLAttributesMap()[strings.TrimPrefix(key, ":")]
+	return v, ok
+}
+
 func (n Comment) String() string      { return String(n) }
 func (n Keyword) String() string      { return String(n) }
 func (n NodeWithMeta) String() string { return String(n) }
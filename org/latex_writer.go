@@ -0,0 +1,591 @@
+package org
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// LaTeXWriter exports an org document into LaTeX. #+LATEX keywords, latex blocks and latex fragments are written
+// verbatim.
+// A LaTeXWriter must not be used by multiple goroutines at once.
+type LaTeXWriter struct {
+	ExtendingWriter Writer
+	// DocumentClass is the class of the complete LaTeX document (preamble, \begin{document} ... \end{document})
+	// written by the writer. If it is empty, only the body of the document is written. Defaults to "article".
+	DocumentClass string
+	// Packages are the packages loaded in the preamble of complete documents, e.g. "amsmath" or "[normalem]ulem".
+	// Defaults to DefaultLaTeXPackages.
+	Packages []string
+	// NodeWriters write custom nodes (e.g. the nodes of InlineParsers), keyed by their type. See AddNodeWriter.
+	NodeWriters map[reflect.Type]func(*LaTeXWriter, Node)
+
+	strings.Builder
+	document  *Document
+	escape    bool
+	footnotes *footnotes
+	// enumerateDepth is the nesting depth of the ordered list being written - its items use the counter enumi, enumii, ...
+	enumerateDepth int
+	err            error // err is the first error of writing the document - see errorReporter.
+}
+
+// DefaultLaTeXPackages are the packages required by the output of the LaTeXWriter. See LaTeXWriter.Packages.
+var DefaultLaTeXPackages = []string{"[utf8]inputenc", "[T1]fontenc", "graphicx", "[normalem]ulem", "amsmath", "amssymb", "hyperref"}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "$", `\$`, "&", `\&`, "#", `\#`, "%", `\%`, "_", `\_`,
+	"~", `\textasciitilde{}`, "^", `\textasciicircum{}`, "<", `\textless{}`, ">", `\textgreater{}`)
+
+var latexURLEscaper = strings.NewReplacer(`\`, `\\`, "#", `\#`, "%", `\%`, "{", `\{`, "}", `\}`)
+
+var latexSectionCommands = []string{"section", "subsection", "subsubsection", "paragraph", "subparagraph"}
+
+var latexListItemStatuses = map[string]string{" ": `[$\square$]`, "-": `[$\boxminus$]`, "X": `[$\boxtimes$]`}
+
+var latexEmphasisCommands = map[string]string{
+	"*":   `\textbf`,
+	"/":   `\emph`,
+	"_":   `\uline`,
+	"+":   `\sout`,
+	"=":   `\texttt`,
+	"~":   `\texttt`,
+	"_{}": `\textsubscript`,
+	"^{}": `\textsuperscript`,
+}
+
+func NewLaTeXWriter() *LaTeXWriter {
+	return &LaTeXWriter{
+		document:      &Document{Configuration: New()},
+		escape:        true,
+		DocumentClass: "article",
+		Packages:      DefaultLaTeXPackages,
+		footnotes:     &footnotes{mapping: map[string]int{}, unused: map[string]*FootnoteDefinition{}},
+	}
+}
+
+// Backend implements Writer. Only export blocks and snippets for latex are written.
+func (w *LaTeXWriter) Backend() string { return "latex" }
+
+func (w *LaTeXWriter) WriterWithExtensions() Writer {
+	if w.ExtendingWriter != nil {
+		return w.ExtendingWriter
+	}
+	return w
+}
+
+// AddNodeWriter registers f to write all nodes with the same type as node, e.g. the nodes of an InlineParser.
+func (w *LaTeXWriter) AddNodeWriter(node Node, f func(*LaTeXWriter, Node)) *LaTeXWriter {
+	if w.NodeWriters == nil {
+		w.NodeWriters = map[reflect.Type]func(*LaTeXWriter, Node){}
+	}
+	w.NodeWriters[reflect.TypeOf(node)] = f
+	return w
+}
+
+// WriteCustomNode implements CustomNodeWriter using the NodeWriters. Emoji, hashtags and mentions are written unless
+// overridden.
+func (w *LaTeXWriter) WriteCustomNode(n Node) bool {
+	if f, ok := w.NodeWriters[reflect.TypeOf(n)]; ok {
+		f(w, n)
+		return true
+	}
+	switch n := n.(type) {
+	case Emoji:
+		if n.Unicode == "" {
+			w.WriteString(latexEscaper.Replace(":" + n.Shortcode + ":"))
+		} else {
+			w.WriteString(n.Unicode)
+		}
+	case Hashtag:
+		w.WriteString(latexEscaper.Replace("#" + n.Name))
+	case Mention:
+		w.WriteString(latexEscaper.Replace("@" + n.Name))
+	default:
+		return false
+	}
+	return true
+}
+
+func (w *LaTeXWriter) Before(d *Document) {
+	w.document, w.err = d, nil
+	w.footnotes = &footnotes{mapping: map[string]int{}, unused: map[string]*FootnoteDefinition{}}
+	index := d.Footnotes()
+	w.footnotes.index = &index
+	title := ""
+	if rawTitle := d.Get("TITLE"); rawTitle != "" && d.GetOption("title") != "nil" {
+		title = strings.TrimSpace(w.inlineString(d, rawTitle))
+	}
+	if w.DocumentClass != "" {
+		w.WriteString(`\documentclass{` + w.DocumentClass + "}\n")
+		for _, p := range w.Packages {
+			if options, name, ok := strings.Cut(p, "]"); ok {
+				w.WriteString(`\usepackage` + options + "]{" + name + "}\n")
+			} else {
+				w.WriteString(`\usepackage{` + p + "}\n")
+			}
+		}
+		if author := d.Get("AUTHOR"); author != "" {
+			w.WriteString(`\author{` + w.inlineString(d, author) + "}\n")
+		}
+		if date := d.Get("DATE"); date != "" {
+			w.WriteString(`\date{` + w.inlineString(d, date) + "}\n")
+		}
+		if title != "" {
+			w.WriteString(`\title{` + title + "}\n")
+		}
+		w.WriteString(`\begin{document}` + "\n\n")
+		if title != "" {
+			w.WriteString(`\maketitle` + "\n\n")
+		}
+	} else if title != "" {
+		w.WriteString(`\section*{` + title + "}\n\n")
+	}
+	if toc := d.GetOption("toc"); toc != "nil" {
+		if maxLvl, err := strconv.Atoi(toc); err == nil {
+			w.WriteString(fmt.Sprintf(`\setcounter{tocdepth}{%d}`+"\n", maxLvl))
+		}
+		w.WriteString(`\tableofcontents` + "\n\n")
+	}
+}
+
+// inlineString returns the inline markup s (e.g. the #+TITLE) written as LaTeX - or escaped if it is not a single
+// paragraph.
+func (w *LaTeXWriter) inlineString(d *Document, s string) string {
+	document := d.Parse(strings.NewReader(s), d.Path)
+	if len(document.errorsWithoutFootnotes()) == 0 && len(document.Nodes) == 1 {
+		if p, ok := document.Nodes[0].(Paragraph); ok {
+			return w.WriteNodesAsString(p.InlineNodes()...)
+		}
+	}
+	return latexEscaper.Replace(s)
+}
+
+func (w *LaTeXWriter) After(d *Document) {
+	out, held := strings.TrimRight(w.Builder.String(), "\n"), w.Builder.Len() != 0
+	w.Builder.Reset()
+	if w.DocumentClass != "" && out == "" && held {
+		out = "\n" + `\end{document}` // the output written by WriteNodesTo already ends with a newline
+	} else if w.DocumentClass != "" {
+		out += "\n\n" + `\end{document}`
+	}
+	if out != "" {
+		w.WriteString(out + "\n")
+	}
+}
+
+// writeError implements errorReporter.
+func (w *LaTeXWriter) writeError() error { return w.err }
+
+// reportError implements errorReporter.
+func (w *LaTeXWriter) reportError(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *LaTeXWriter) WriteNodesAsString(nodes ...Node) string {
+	original := w.Builder
+	w.Builder = strings.Builder{}
+	WriteNodes(w, nodes...)
+	out := w.String()
+	w.Builder = original
+	return out
+}
+
+// WriteNodesTo implements Writer. All but one of the trailing newlines are held back as After removes the blank
+// lines at the end of the output.
+func (w *LaTeXWriter) WriteNodesTo(out io.Writer, nodes ...Node) error {
+	return writeNodesTo(w, &w.Builder, out, holdBlankLines, nodes)
+}
+
+func (w *LaTeXWriter) WriteComment(Comment)               {}
+func (w *LaTeXWriter) WritePropertyDrawer(PropertyDrawer) {}
+
+func (w *LaTeXWriter) WriteHeadline(h Headline) {
+	if h.IsExcluded(w.document) {
+		return
+	}
+	command := latexSectionCommands[min(h.Lvl, len(latexSectionCommands))-1]
+	if num := w.document.GetOption("num"); num == "" || num == "nil" {
+		command += "*"
+	} else if n, err := strconv.Atoi(num); err == nil && h.Lvl > n {
+		command += "*"
+	}
+	title := ""
+	if w.document.GetOption("todo") != "nil" && h.Status != "" {
+		title += `\textbf{` + h.Status + "} "
+	}
+	if w.document.GetOption("pri") != "nil" && h.Priority != "" {
+		title += `\textbf{[` + h.Priority + "]} "
+	}
+	title += strings.ReplaceAll(w.WriteNodesAsString(h.Title...), "\n", " ")
+	if w.document.GetOption("tags") != "nil" && len(h.Tags) != 0 {
+		title += `\hfill{}\textsc{` + latexEscaper.Replace(strings.Join(h.Tags, ":")) + "}"
+	}
+	w.WriteString(`\` + command + "{" + title + "}\n")
+	w.WriteString(`\label{` + latexLabel(h.ID()) + "}\n\n")
+	WriteNodes(w, h.Children...)
+}
+
+// latexLabel returns s without the characters that are not allowed in \label and \ref.
+func latexLabel(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\{}$&#%^~`, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func (w *LaTeXWriter) WriteBlock(b Block) {
+	content, params := w.blockContent(b.Name, b.Children), b.ParameterMap()
+	switch b.Name {
+	case "SRC":
+		if params[":exports"] == "results" || params[":exports"] == "none" {
+			break
+		}
+		w.writeVerbatim(b.Switches().removeLabels(content))
+	case "EXAMPLE":
+		w.writeVerbatim(b.Switches().removeLabels(content))
+	case "EXPORT":
+		if exportsTo(w, b.Parameters) {
+			w.WriteString(content + "\n\n")
+		}
+	case "QUOTE":
+		if quote, cite := b.Citation(); cite != nil {
+			content = w.WriteNodesAsString(quote...) + `\hfill{}--- ` + strings.TrimSpace(w.WriteNodesAsString(cite...)) + "\n"
+		}
+		w.writeEnvironment("quote", "", content)
+	case "CENTER":
+		w.writeEnvironment("center", "", content)
+	case "VERSE":
+		w.writeEnvironment("verse", "", w.verseString(b.Children))
+	default:
+		w.writeEnvironment(strings.ToLower(b.Name), "", content)
+	}
+	if b.Result != nil && params[":exports"] != "code" && params[":exports"] != "none" {
+		WriteNodes(w, b.Result)
+	}
+}
+
+// writeEnvironment writes content inside of the environment name - arguments are appended to \begin{name}, e.g. "[htbp]".
+func (w *LaTeXWriter) writeEnvironment(name, arguments, content string) {
+	w.WriteString(`\begin{` + name + "}" + arguments + "\n" + strings.TrimRight(content, "\n") + "\n" + `\end{` + name + "}\n\n")
+}
+
+func (w *LaTeXWriter) writeVerbatim(content string) {
+	// the verbatim environment ends at the first \end{verbatim} - even inside its content
+	content = strings.ReplaceAll(content, `\end{verbatim}`, `\end {verbatim}`)
+	w.writeEnvironment("verbatim", "", content)
+}
+
+// verseString returns the lines of a verse block - separated by \\ and with the leading whitespace of lines preserved.
+func (w *LaTeXWriter) verseString(nodes []Node) string {
+	out, lineStart := "", true
+	for _, n := range nodes {
+		if l, ok := n.(LineBreak); ok {
+			out += strings.Repeat(`\\`+"\n", l.Count)
+			lineStart = true
+			continue
+		} else if t, ok := n.(Text); ok && lineStart {
+			content := strings.TrimLeft(t.Content, " \t")
+			if indent := len(t.Content) - len(content); indent != 0 {
+				out += fmt.Sprintf(`\hspace*{%dem}`, indent)
+			}
+			t.Content = content
+			n = t
+		}
+		out += w.WriteNodesAsString(n)
+		lineStart = false
+	}
+	return strings.TrimSuffix(strings.TrimRight(out, "\n"), `\\`)
+}
+
+// blockContent returns the content of a block - the verbatim text of src, example and export blocks.
+func (w *LaTeXWriter) blockContent(name string, children []Node) string {
+	if !isRawTextBlock(name) {
+		return w.WriteNodesAsString(children...)
+	}
+	escape := w.escape
+	w.escape = false
+	out := w.WriteNodesAsString(children...)
+	w.escape = escape
+	return strings.TrimRightFunc(strings.TrimLeftFunc(out, IsNewLineChar), unicode.IsSpace)
+}
+
+func (w *LaTeXWriter) WriteResult(r Result) { WriteNodes(w, r.Node) }
+
+func (w *LaTeXWriter) WriteLatexBlock(b LatexBlock) {
+	w.WriteString(w.blockContent("EXPORT", b.Content) + "\n\n")
+}
+
+func (w *LaTeXWriter) WriteInlineBlock(b InlineBlock) {
+	content := w.blockContent(strings.ToUpper(b.Name), b.Children)
+	switch b.Name {
+	case "src":
+		w.WriteString(`\texttt{` + latexEscaper.Replace(content) + "}")
+	case "export":
+		if exportsTo(w, b.Parameters) {
+			w.WriteString(content)
+		}
+	}
+}
+
+func (w *LaTeXWriter) WriteExample(e Example) {
+	lines := make([]string, len(e.Children))
+	for i, n := range e.Children {
+		lines[i] = w.blockContent("EXAMPLE", []Node{n})
+	}
+	w.writeVerbatim(strings.Join(lines, "\n"))
+}
+
+func (w *LaTeXWriter) WriteDrawer(d Drawer) {
+	if w.document.ExportsDrawer(d.Name) {
+		WriteNodes(w, d.Children...)
+	}
+}
+
+func (w *LaTeXWriter) WriteKeyword(k Keyword) {
+	switch {
+	case k.Key == "LATEX":
+		w.WriteString(k.Value + "\n\n")
+	case k.Key == "TOC" && strings.HasPrefix(k.Value, "headlines"):
+		w.WriteString(`\tableofcontents` + "\n\n")
+	case k.Key == "TOC" && strings.HasPrefix(k.Value, "tables"):
+		w.WriteString(`\listoftables` + "\n\n")
+	case k.Key == "TOC" && strings.HasPrefix(k.Value, "listings"):
+		w.WriteString(`\listoffigures` + "\n\n")
+	}
+}
+
+func (w *LaTeXWriter) WriteInclude(i Include) {
+	WriteNodes(w, i.Resolve())
+}
+
+func (w *LaTeXWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
+	w.footnotes.updateDefinition(f)
+}
+
+// WriteNodeWithMeta writes captioned tables as table and other captioned nodes (e.g. images) as figure.
+func (w *LaTeXWriter) WriteNodeWithMeta(n NodeWithMeta) {
+	node, label := n.Node, ""
+	if named, ok := node.(NodeWithName); ok {
+		node, label = named.Node, `\label{`+latexLabel(named.Name)+"}\n"
+	}
+	if len(n.Meta.Caption) == 0 {
+		WriteNodes(w, n.Node)
+		return
+	}
+	captions := make([]string, len(n.Meta.Caption))
+	for i, caption := range n.Meta.Caption {
+		captions[i] = strings.TrimSpace(w.WriteNodesAsString(caption...))
+	}
+	environment := "figure"
+	if _, ok := node.(Table); ok {
+		environment = "table"
+	}
+	content := `\centering` + "\n" + strings.TrimRight(w.WriteNodesAsString(node), "\n") + "\n"
+	w.writeEnvironment(environment, "[htbp]", content+`\caption{`+strings.Join(captions, " ")+"}\n"+label)
+}
+
+func (w *LaTeXWriter) WriteNodeWithName(n NodeWithName) {
+	WriteNodes(w, n.Node)
+}
+
+func (w *LaTeXWriter) WriteList(l List) {
+	environment, ok := map[ListKind]string{UnorderedList: "itemize", OrderedList: "enumerate", DescriptiveList: "description"}[l.Kind]
+	if !ok {
+		w.reportError(fmt.Errorf("bad list kind %#v", l))
+		return
+	}
+	if l.Kind == OrderedList {
+		w.enumerateDepth++
+		defer func() { w.enumerateDepth-- }()
+	}
+	w.writeEnvironment(environment, "", w.WriteNodesAsString(l.Items...))
+}
+
+func (w *LaTeXWriter) WriteListItem(li ListItem) {
+	if li.Value != "" {
+		if n, err := strconv.Atoi(ordinal(li.Value)); err == nil {
+			counter := "enum" + strings.Repeat("i", min(w.enumerateDepth, 3))
+			if w.enumerateDepth >= 4 {
+				counter = "enumiv"
+			}
+			w.WriteString(fmt.Sprintf(`\setcounter{%s}{%d}`+"\n", counter, n-1))
+		}
+	}
+	w.WriteString(`\item` + latexListItemStatuses[li.Status] + " ")
+	w.writeListItemContent(li.Children)
+}
+
+func (w *LaTeXWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
+	term := "?"
+	if len(di.Term) != 0 {
+		term = strings.TrimSpace(w.WriteNodesAsString(di.Term...))
+	}
+	w.WriteString(`\item[{` + latexListItemStatuses[di.Status] + term + "}] ")
+	w.writeListItemContent(di.Details)
+}
+
+func (w *LaTeXWriter) writeListItemContent(children []Node) {
+	parts := []string{}
+	for _, c := range children {
+		if p, ok := c.(Paragraph); ok {
+			if out := w.WriteNodesAsString(p.InlineNodes()...); out != "" {
+				parts = append(parts, out)
+			}
+		} else if out := strings.TrimRight(w.WriteNodesAsString(c), "\n"); out != "" {
+			parts = append(parts, out)
+		}
+	}
+	w.WriteString(strings.TrimLeft(strings.Join(parts, "\n"), "\n") + "\n")
+}
+
+func (w *LaTeXWriter) WriteTable(t Table) {
+	alignments := make([]string, len(t.ColumnInfos))
+	for i, info := range t.ColumnInfos {
+		if alignments[i] = map[string]string{"center": "c", "right": "r"}[info.Align]; alignments[i] == "" {
+			alignments[i] = "l"
+		}
+	}
+	content := ""
+	for i, row := range t.Rows {
+		if len(row.Columns) == 0 {
+			if i != 0 && i != len(t.Rows)-1 {
+				content += `\hline` + "\n"
+			}
+			continue
+		} else if row.IsSpecial {
+			continue
+		}
+		columns := make([]string, len(row.Columns))
+		for j, column := range row.Columns {
+			columns[j] = strings.TrimSpace(w.WriteNodesAsString(column.Children...))
+		}
+		content += strings.Join(columns, " & ") + ` \\` + "\n"
+	}
+	w.writeEnvironment("tabular", "{"+strings.Join(alignments, "")+"}", content)
+}
+
+func (w *LaTeXWriter) WriteHorizontalRule(h HorizontalRule) {
+	w.WriteString(`\noindent\rule{\textwidth}{0.5pt}` + "\n\n")
+}
+
+func (w *LaTeXWriter) WriteParagraph(p Paragraph) {
+	if out := w.WriteNodesAsString(p.InlineNodes()...); strings.TrimSpace(out) != "" {
+		w.WriteString(strings.TrimRight(strings.TrimLeftFunc(out, unicode.IsSpace), "\n") + "\n\n")
+	}
+}
+
+func (w *LaTeXWriter) WriteText(t Text) {
+	if !w.escape {
+		w.WriteString(t.Content)
+	} else if w.document.GetOption("e") == "nil" || t.IsRaw {
+		w.WriteString(latexEscaper.Replace(t.Content))
+	} else {
+		w.WriteString(latexEscaper.Replace(htmlEntityReplacer.Replace(t.Content)))
+	}
+}
+
+func (w *LaTeXWriter) WriteEmphasis(e Emphasis) {
+	command, ok := latexEmphasisCommands[e.Kind]
+	if !ok && len(e.Kind) == 1 {
+		WriteNodes(w, e.Content...) // custom marker, see Configuration.EmphasisComponents
+		return
+	} else if !ok {
+		w.reportError(fmt.Errorf("bad emphasis %#v", e))
+		return
+	}
+	w.WriteString(command + "{")
+	WriteNodes(w, e.Content...)
+	w.WriteString("}")
+}
+
+func (w *LaTeXWriter) WriteLatexFragment(l LatexFragment) {
+	w.WriteString(l.OpeningPair + w.blockContent("EXPORT", l.Content) + l.ClosingPair)
+}
+
+func (w *LaTeXWriter) WriteStatisticToken(s StatisticToken) {
+	w.WriteString(`\texttt{[` + latexEscaper.Replace(s.Content) + "]}")
+}
+
+func (w *LaTeXWriter) WriteLineBreak(l LineBreak) {
+	if w.document.GetOption("ealb") != "nil" && l.BetweenMultibyteCharacters {
+		return
+	}
+	if w.escape && w.document.PreserveLineBreaks() {
+		w.WriteString(strings.Repeat(`\\`+"\n", l.Count))
+	} else {
+		w.WriteString(strings.Repeat("\n", l.Count))
+	}
+}
+
+func (w *LaTeXWriter) WriteExplicitLineBreak(l ExplicitLineBreak) {
+	w.WriteString(`\\` + "\n")
+}
+
+// WriteFootnoteLink writes the definition of the footnote as \footnote at its first reference and \footnotemark at
+// later references.
+func (w *LaTeXWriter) WriteFootnoteLink(l FootnoteLink) {
+	if w.document.GetOption("f") == "nil" {
+		return
+	}
+	i, isNew := w.footnotes.add(l)
+	if !isNew {
+		w.WriteString(fmt.Sprintf(`\footnotemark[%d]`, i+1))
+		return
+	}
+	definition := w.footnotes.list[i]
+	if definition == nil {
+		w.document.logf(slog.LevelWarn, l, "Missing footnote definition for [fn:%s] (#%d)", l.Name, i+1)
+		w.WriteString(fmt.Sprintf(`\footnotemark[%d]`, i+1))
+		return
+	}
+	content := strings.TrimSpace(w.WriteNodesAsString(definition.Children...))
+	w.WriteString(fmt.Sprintf(`\footnote[%d]{%s}`, i+1, content))
+}
+
+func (w *LaTeXWriter) WriteTimestamp(t Timestamp) {
+	if w.document.GetOption("<") == "nil" {
+		return
+	}
+	w.WriteString(`\textit{\textless{}` + latexEscaper.Replace(timestampString(t)) + `\textgreater{}}`)
+}
+
+func (w *LaTeXWriter) WriteRegularLink(l RegularLink) {
+	url := latexURLEscaper.Replace(w.document.exportURL(l, ".tex"))
+	switch l.Kind() {
+	case "image":
+		if l.Description == nil {
+			w.WriteString(`\includegraphics[width=.9\linewidth]{` + url + "}")
+		} else {
+			description := latexURLEscaper.Replace(strings.TrimPrefix(String(l.Description...), "file:"))
+			w.WriteString(`\href{` + url + `}{\includegraphics[width=.9\linewidth]{` + description + "}}")
+		}
+	default:
+		if id, ok := strings.CutPrefix(l.URL, "#"); ok && l.Protocol == "" && l.Description != nil {
+			w.WriteString(`\hyperref[` + latexLabel(id) + "]{" + w.WriteNodesAsString(l.Description...) + "}")
+			return
+		} else if ok && l.Protocol == "" {
+			w.WriteString(`\ref{` + latexLabel(id) + "}")
+			return
+		} else if l.Description == nil && l.Wiki.Page == "" {
+			w.WriteString(`\url{` + url + "}")
+			return
+		}
+		description := latexEscaper.Replace(l.Wiki.Page)
+		if l.Description != nil {
+			description = w.WriteNodesAsString(l.Description...)
+		}
+		w.WriteString(`\href{` + url + "}{" + description + "}")
+	}
+}
+
+func (w *LaTeXWriter) WriteMacro(m Macro) {
+	WriteNodes(w, inlineContent(w.document.expandMacro(m))...)
+}
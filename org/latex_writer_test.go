@@ -0,0 +1,91 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+var latexWriterTests = []struct {
+	name     string
+	input    string
+	expected string
+}{
+	{"headlines",
+		"#+OPTIONS: num:1\n* TODO [#A] Headline :tag:\n:PROPERTIES:\n:CUSTOM_ID: h\n:END:\ntext\n** Sub\n",
+		"\\section{\\textbf{TODO} \\textbf{[A]} Headline\\hfill{}\\textsc{tag}}\n\\label{h}\n\n" +
+			"text\n\n\\subsection*{Sub}\n\\label{headline-2}\n"},
+	{"emphasis and escaping",
+		"*bold* /italic/ _underline_ +strike+ =a_b= a^{sup} 50% $5 & #1 {x} ~\n",
+		"\\textbf{bold} \\emph{italic} \\uline{underline} \\sout{strike} \\texttt{a\\_b} a\\textsuperscript{sup} " +
+			"50\\% \\$5 \\& \\#1 \\{x\\} \\textasciitilde{}\n"},
+	{"latex",
+		"$a_1$ and \\(b^2\\)\n\\begin{equation}\nx = 1\n\\end{equation}\n#+LATEX: \\newpage\n",
+		"$a_1$ and \\(b^2\\)\n\n\\begin{equation}\nx = 1\n\\end{equation}\n\n\\newpage\n"},
+	{"lists",
+		"- [X] a\n  1. b\n  2. [@5] c\n- term :: details\n",
+		"\\begin{itemize}\n\\item[$\\boxtimes$] a\n\\begin{enumerate}\n\\item b\n\\setcounter{enumi}{4}\n\\item c\n\\end{enumerate}\n" +
+			"\\item term :: details\n\\end{itemize}\n"},
+	{"blocks",
+		"#+BEGIN_SRC go\nx := `\\end{verbatim}`\n#+END_SRC\n#+BEGIN_QUOTE\nquoted\n#+END_QUOTE\n#+BEGIN_EXPORT latex\n\\raw\n#+END_EXPORT\n#+BEGIN_EXPORT html\n<b>html</b>\n#+END_EXPORT\n",
+		"\\begin{verbatim}\nx := `\\end {verbatim}`\n\\end{verbatim}\n\n\\begin{quote}\nquoted\n\\end{quote}\n\n\\raw\n"},
+	{"captioned table",
+		"#+CAPTION: numbers\n#+NAME: tbl\n| a | b |\n|---+---|\n| 1 | 2 |\n",
+		"\\begin{table}[htbp]\n\\centering\n\\begin{tabular}{rr}\na & b \\\\\n\\hline\n1 & 2 \\\\\n\\end{tabular}\n\\caption{numbers}\n\\label{tbl}\n\\end{table}\n"},
+	{"links",
+		"[[https://example.com/#a%20b][example]] [[#h][section]] [[./img.png]] https://example.com\n",
+		"\\href{https://example.com/\\#a\\%20b}{example} \\hyperref[h]{section} \\includegraphics[width=.9\\linewidth]{./img.png} \\url{https://example.com}\n"},
+	{"footnotes",
+		"a[fn:1] b[fn:: inline] c[fn:1]\n\n[fn:1] the definition\n",
+		"a\\footnote[1]{the definition} b\\footnote[2]{inline} c\\footnotemark[1]\n"},
+}
+
+func TestLaTeXWriter(t *testing.T) {
+	for _, test := range latexWriterTests {
+		t.Run(test.name, func(t *testing.T) {
+			d := New().Silent().Parse(strings.NewReader(test.input), "./test.org")
+			w := NewLaTeXWriter()
+			w.DocumentClass = ""
+			d.BufferSettings["OPTIONS"] += " toc:nil"
+			if out, err := d.Write(w); err != nil {
+				t.Errorf("got error: %s", err)
+			} else if out != test.expected {
+				t.Errorf("%s", diff(out, test.expected))
+			}
+		})
+	}
+}
+
+func TestLaTeXWriterDocument(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("#+TITLE: A _title_\n#+AUTHOR: me\n#+OPTIONS: toc:nil\ntext\n"), "./test.org")
+	newWriter := func() *LaTeXWriter {
+		w := NewLaTeXWriter()
+		w.Packages = []string{"[normalem]ulem", "hyperref"}
+		return w
+	}
+	expected := "\\documentclass{article}\n\\usepackage[normalem]{ulem}\n\\usepackage{hyperref}\n\\author{me}\n" +
+		"\\title{A \\uline{title}}\n\\begin{document}\n\n\\maketitle\n\ntext\n\n\\end{document}\n"
+	if out, err := d.Write(newWriter()); err != nil || out != expected {
+		t.Errorf("got error %v or\n%s", err, diff(out, expected))
+	}
+	var streamed strings.Builder
+	if err := d.WriteStream(&streamed, newWriter()); err != nil || streamed.String() != expected {
+		t.Errorf("WriteStream: got error %v or\n%s", err, diff(streamed.String(), expected))
+	}
+}
+
+func TestLaTeXWriterTestdata(t *testing.T) {
+	for _, path := range orgTestFiles() {
+		d := New().Silent().Parse(strings.NewReader(fileString(t, path)), path)
+		out, err := d.Write(NewLaTeXWriter())
+		if err != nil {
+			t.Errorf("%s: got error: %s", path, err)
+			continue
+		} else if !strings.HasPrefix(out, `\documentclass{article}`) || !strings.HasSuffix(out, "\n\\end{document}\n") {
+			t.Errorf("%s: incomplete document:\n%s", path, out)
+		}
+		var streamed strings.Builder
+		if err := d.WriteStream(&streamed, NewLaTeXWriter()); err != nil || streamed.String() != out {
+			t.Errorf("%s: WriteStream (%v):\n%s", path, err, diff(streamed.String(), out))
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package org
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// useRegexpLexer makes tokenize use the regexp based lexers (lexFns) rather than lexLine. The regexps are the
+// reference implementation of the syntax - lexLine must produce the same tokens (see TestLexLine).
+var useRegexpLexer = false
+
+func tokenize(line string) (token, bool) {
+	if useRegexpLexer {
+		return tokenizeRegexp(line)
+	}
+	return lexLine(line)
+}
+
+func tokenizeRegexp(line string) (token, bool) {
+	for _, lexFn := range lexFns {
+		if token, ok := lexFn(line); ok {
+			return token, true
+		}
+	}
+	return nilToken, false
+}
+
+// lexLine returns the token of the line. It dispatches on the first non-whitespace byte of the line and only checks
+// the syntax that can start with that byte - in the order of lexFns. The matches of the token are those of the
+// regexp the line would have been matched by.
+func lexLine(line string) (token, bool) {
+	ws, rest := splitIndent(line)
+	if rest == "" {
+		return token{kind: "text", lvl: len(ws), content: "", matches: []string{line, ws, ""}}, true
+	}
+	switch rest[0] {
+	case '*':
+		if ws == "" {
+			if t, ok := lexHeadlineLine(line); ok {
+				return t, true
+			}
+		}
+		if t, ok := lexUnorderedListLine(line, ws, rest); ok {
+			return t, true
+		}
+	case ':':
+		if t, ok := lexDrawerLine(line, ws, rest); ok {
+			return t, true
+		} else if t, ok := lexExampleLine(line, ws, rest); ok {
+			return t, true
+		}
+	case '#':
+		if t, ok := lexHashLine(line, ws, rest); ok {
+			return t, true
+		}
+	case '+', '-':
+		if t, ok := lexUnorderedListLine(line, ws, rest); ok {
+			return t, true
+		} else if t, ok := lexHorizontalRuleLine(line, ws, rest); ok {
+			return t, true
+		}
+	case '|':
+		return lexTableLine(line, ws, rest), true
+	case '[':
+		if ws == "" {
+			if t, ok := lexFootnoteDefinitionLine(line); ok {
+				return t, true
+			}
+		}
+	case '\\':
+		if t, ok := lexLatexBlockLine(line, ws, rest); ok {
+			return t, true
+		}
+	default:
+		if isAlphanumeric(rest[0]) {
+			if t, ok := lexOrderedListLine(line, ws, rest); ok {
+				return t, true
+			}
+		}
+	}
+	return token{kind: "text", lvl: len(ws), content: rest, matches: []string{line, ws, rest}}, true
+}
+
+// lexHeadlineLine matches headlineRegexp.
+func lexHeadlineLine(line string) (token, bool) {
+	stars := len(line) - len(strings.TrimLeft(line, "*"))
+	if stars == len(line) || !isSpace(line[stars]) {
+		return nilToken, false
+	}
+	content := trimLeftSpace(line[stars:])
+	return token{kind: "headline", lvl: 0, content: content, matches: []string{line, line[:stars], content}}, true
+}
+
+// lexDrawerLine matches endDrawerRegexp and beginDrawerRegexp.
+func lexDrawerLine(line, ws, rest string) (token, bool) {
+	if n, ok := foldPrefix(rest, ":END:"); ok && trimLeftSpace(rest[n:]) == "" {
+		return token{kind: "endDrawer", lvl: len(ws), content: "", matches: []string{line, ws}}, true
+	}
+	trimmed := trimRightSpace(rest)
+	if len(trimmed) < 3 || trimmed[len(trimmed)-1] != ':' || strings.IndexFunc(trimmed, isSpaceRune) != -1 {
+		return nilToken, false
+	}
+	name := trimmed[1 : len(trimmed)-1]
+	return token{kind: "beginDrawer", lvl: len(ws), content: upper(name), matches: []string{line, ws, name}}, true
+}
+
+// lexExampleLine matches exampleLineRegexp.
+func lexExampleLine(line, ws, rest string) (token, bool) {
+	if r := rest[1:]; r == "" {
+		return token{kind: "example", lvl: len(ws), content: "", matches: []string{line, ws, "", ""}}, true
+	} else if isSpace(r[0]) {
+		return token{kind: "example", lvl: len(ws), content: r[1:], matches: []string{line, ws, r, r[1:]}}, true
+	}
+	return nilToken, false
+}
+
+// lexHashLine matches beginBlockRegexp, endBlockRegexp, resultRegexp, keywordRegexp and commentRegexp.
+func lexHashLine(line, ws, rest string) (token, bool) {
+	if len(rest) < 2 {
+		return nilToken, false
+	} else if rest[1] != '+' {
+		if isSpace(rest[1]) {
+			return token{kind: "comment", lvl: len(ws), content: rest[2:], matches: []string{line, ws, rest[2:]}}, true
+		}
+		return nilToken, false
+	}
+	if n, ok := foldPrefix(rest, "#+BEGIN_"); ok {
+		if name := foldedWordPrefix(rest[n:]); name != "" {
+			parameters := rest[n+len(name):]
+			return token{kind: "beginBlock", lvl: len(ws), content: upper(name), matches: []string{line, ws, name, parameters}}, true
+		}
+	} else if n, ok := foldPrefix(rest, "#+END_"); ok {
+		if name := foldedWordPrefix(rest[n:]); name != "" {
+			match := ws + rest[:n+len(name)]
+			return token{kind: "endBlock", lvl: len(ws), content: upper(name), matches: []string{match, ws, name}}, true
+		}
+	}
+	if n, ok := foldPrefix(rest, "#+RESULTS:"); ok {
+		return token{kind: "result", lvl: len(ws), content: "", matches: []string{ws + rest[:n], ws}}, true
+	}
+	colon := strings.IndexByte(rest[2:], ':') + 2
+	if colon <= 2 {
+		return nilToken, false
+	}
+	key, value := rest[2:colon], rest[colon+1:]
+	if value == "" {
+		return token{kind: "keyword", lvl: len(ws), content: key, matches: []string{line, ws, key, "", ""}}, true
+	} else if isSpace(value[0]) {
+		return token{kind: "keyword", lvl: len(ws), content: key, matches: []string{line, ws, key, value, trimLeftSpace(value)}}, true
+	}
+	return nilToken, false
+}
+
+// lexUnorderedListLine matches unorderedListRegexp.
+func lexUnorderedListLine(line, ws, rest string) (token, bool) {
+	if r := rest[1:]; r == "" {
+		return token{kind: "unorderedList", lvl: len(ws), content: "", matches: []string{line, ws, rest[:1], "", ""}}, true
+	} else if isSpace(r[0]) {
+		content := trimLeftSpace(r)
+		return token{kind: "unorderedList", lvl: len(ws), content: content, matches: []string{line, ws, rest[:1], r, content}}, true
+	}
+	return nilToken, false
+}
+
+// lexOrderedListLine matches orderedListRegexp.
+func lexOrderedListLine(line, ws, rest string) (token, bool) {
+	n := 1
+	if isDigit(rest[0]) {
+		for n < len(rest) && isDigit(rest[n]) {
+			n++
+		}
+	}
+	if n >= len(rest) || rest[n] != '.' && rest[n] != ')' {
+		return nilToken, false
+	}
+	bullet, value, r := rest[:n+1], rest[:n], rest[n+1:]
+	if r == "" {
+		return token{kind: "orderedList", lvl: len(ws), content: "", matches: []string{line, ws, bullet, value, "", ""}}, true
+	} else if isSpace(r[0]) {
+		content := trimLeftSpace(r)
+		return token{kind: "orderedList", lvl: len(ws), content: content, matches: []string{line, ws, bullet, value, r, content}}, true
+	}
+	return nilToken, false
+}
+
+// lexHorizontalRuleLine matches horizontalRuleRegexp.
+func lexHorizontalRuleLine(line, ws, rest string) (token, bool) {
+	dashes := len(rest) - len(strings.TrimLeft(rest, "-"))
+	if dashes < 5 || trimLeftSpace(rest[dashes:]) != "" {
+		return nilToken, false
+	}
+	return token{kind: "horizontalRule", lvl: len(ws), content: "", matches: []string{line, ws}}, true
+}
+
+// lexTableLine matches tableSeparatorRegexp and tableRowRegexp.
+func lexTableLine(line, ws, rest string) token {
+	n := 1
+	for n < len(rest) && rest[n] >= '+' && rest[n] <= '|' { // [+-|] is the character range from + to |
+		n++
+	}
+	if trimLeftSpace(rest[n:]) == "" {
+		return token{kind: "tableSeparator", lvl: len(ws), content: rest[:n], matches: []string{line, ws, rest[:n]}}
+	}
+	return token{kind: "tableRow", lvl: len(ws), content: rest, matches: []string{line, ws, rest}}
+}
+
+// lexFootnoteDefinitionLine matches footnoteDefinitionRegexp.
+func lexFootnoteDefinitionLine(line string) (token, bool) {
+	if !strings.HasPrefix(line, "[fn:") {
+		return nilToken, false
+	}
+	n := 4
+	for n < len(line) && (isWordChar(line[n]) || line[n] == '-') {
+		n++
+	}
+	if n == 4 || n == len(line) || line[n] != ']' {
+		return nilToken, false
+	}
+	name, r := line[4:n], line[n+1:]
+	if content := trimLeftSpace(r); r != "" && isSpace(r[0]) && content != "" {
+		return token{kind: "footnoteDefinition", lvl: 0, content: name, matches: []string{line, name, r, content}}, true
+	} else if content == "" && len(r) >= 2 {
+		return token{kind: "footnoteDefinition", lvl: 0, content: name, matches: []string{line, name, r, r[len(r)-1:]}}, true
+	} else if content == "" {
+		return token{kind: "footnoteDefinition", lvl: 0, content: name, matches: []string{line, name, r, ""}}, true
+	}
+	return nilToken, false
+}
+
+// lexLatexBlockLine matches beginLatexBlockRegexp and endLatexBlockRegexp.
+func lexLatexBlockLine(line, ws, rest string) (token, bool) {
+	kind, n, ok := "beginLatexBlock", 0, false
+	if n, ok = foldPrefix(rest, `\begin{`); !ok {
+		kind = "endLatexBlock"
+		if n, ok = foldPrefix(rest, `\end{`); !ok {
+			return nilToken, false
+		}
+	}
+	r := rest[n:]
+	end := strings.IndexByte(r, '}')
+	if end <= 0 || trimLeftSpace(r[end+1:]) != "" {
+		return nilToken, false
+	}
+	name := r[:end]
+	return token{kind: kind, lvl: len(ws), content: upper(name), matches: []string{line, ws, name, r[end+1:]}}, true
+}
+
+// commonNames maps the lower and upper case spellings of common block, drawer, keyword and property names to their
+// upper case spelling. See upper.
+var commonNames = map[string]string{}
+
+func init() {
+	for _, name := range []string{"SRC", "EXAMPLE", "QUOTE", "EXPORT", "CENTER", "VERSE", "COMMENT", "PROPERTIES",
+		"LOGBOOK", "TITLE", "AUTHOR", "DATE", "EMAIL", "DESCRIPTION", "KEYWORDS", "LANGUAGE", "OPTIONS", "STARTUP",
+		"SETUPFILE", "INCLUDE", "NAME", "CAPTION", "ATTR_HTML", "RESULTS", "CALL", "HEADER", "LINK", "MACRO", "FILETAGS",
+		"TODO", "TAGS", "ID", "CUSTOM_ID", "CATEGORY", "EFFORT", "EQUATION", "ALIGN"} {
+		commonNames[name], commonNames[strings.ToLower(name)] = name, name
+	}
+}
+
+// upper is like strings.ToUpper but returns the interned upper case spelling of common names (see commonNames) rather
+// than allocating a new string for each of their lower case occurrences.
+func upper(s string) string {
+	if name, ok := commonNames[s]; ok {
+		return name
+	}
+	return strings.ToUpper(s)
+}
+
+// splitIndent splits the line into its leading whitespace and the rest.
+func splitIndent(line string) (string, string) {
+	rest := trimLeftSpace(line)
+	return line[:len(line)-len(rest)], rest
+}
+
+// isSpace returns true if c is matched by \s in regexps.
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r' }
+
+func isSpaceRune(r rune) bool { return r < 0x80 && isSpace(byte(r)) }
+
+func trimLeftSpace(s string) string { return strings.TrimLeft(s, " \t\n\f\r") }
+
+func trimRightSpace(s string) string { return strings.TrimRight(s, " \t\n\f\r") }
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isAlphanumeric(c byte) bool { return isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+// isWordChar returns true if c is matched by \w in regexps.
+func isWordChar(c byte) bool { return isAlphanumeric(c) || c == '_' }
+
+// foldedWordPrefix returns the longest prefix of s consisting of characters matched by \w in case insensitive
+// regexps - i.e. including the characters whose case folds to a \w character: ſ (s) and K (Kelvin sign, k).
+func foldedWordPrefix(s string) string {
+	n := 0
+	for n < len(s) {
+		if isWordChar(s[n]) {
+			n++
+		} else if r, size := utf8.DecodeRuneInString(s[n:]); r == '\u017F' || r == '\u212A' {
+			n += size
+		} else {
+			break
+		}
+	}
+	return s[:n]
+}
+
+// foldPrefix returns the length of the prefix of s matching prefix under Unicode case folding (like (?i) in regexps).
+func foldPrefix(s, prefix string) (int, bool) {
+	n := 0
+	for _, p := range prefix {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if size == 0 || !equalFold(r, p) {
+			return 0, false
+		}
+		n += size
+	}
+	return n, true
+}
+
+// equalFold returns true if r and p are equal under simple Unicode case folding.
+func equalFold(r, p rune) bool {
+	for f := p; ; {
+		if f == r {
+			return true
+		} else if f = unicode.SimpleFold(f); f == p {
+			return false
+		}
+	}
+}
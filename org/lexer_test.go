@@ -0,0 +1,71 @@
+package org
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var lexerTestLines = []string{
+	"", "   ", "\t", "text", "  indented text",
+	"* headline", "*** TODO [#A] headline :tag:", "*bold*", "*", "**", "* ", "*\t",
+	":PROPERTIES:", "  :END:  ", ":end:", ":LOGBOOK:", ":a b:", "::", ":::", ": example", ":", ":example", ":  ",
+	"#+BEGIN_SRC go :results output", "#+begin_quote", "  #+END_SRC", "#+END_SRC trailing", "#+BEGIN_", "#+END_: x",
+	"#+RESULTS:", "#+results: foo", "#+TITLE: title", "#+TITLE:", "#+TITLE:x", "#+:", "#+", "#", "# comment", "#comment",
+	"#+BEGIN_ſrc", "#+reſults:", "#+END_\u212A",
+	"- item", "-", "-item", "+ item", "  * item", "-----", "  ----- ", "----", "------x", "- [ ] task",
+	"1. item", "12) item", "a. item", "Z)", "1.", "1.item", "ab. item", "1a. item",
+	"| a | b |", "|---+---|", "|-", "|", "|abc", "| a |  ", "  |+-|  x",
+	"[fn:1] definition", "[fn:name]", "[fn:a-b]  ", "[fn:x] ", "[fn:x]y", "[fn:]", "[fn:1", " [fn:1] indented",
+	`\begin{equation}`, `\end{equation}  `, `\BEGIN{x}`, `\begin{}`, `\begin{a} b`, `\end{a}}`,
+	"\u00a0text", "\xff\xfe", "\f- item", "\r#+TITLE: x",
+}
+
+func TestLexLine(t *testing.T) {
+	lines := append([]string{}, lexerTestLines...)
+	for _, path := range orgTestFiles() {
+		lines = append(lines, strings.Split(fileString(t, path), "\n")...)
+	}
+	for _, line := range lines {
+		expected, expectedOk := tokenizeRegexp(line)
+		if actual, ok := lexLine(line); ok != expectedOk || !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%q:\n got %#v\n expected %#v", line, actual, expected)
+		}
+	}
+}
+
+func FuzzLexLine(f *testing.F) {
+	for _, line := range lexerTestLines {
+		f.Add(line)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		if strings.Contains(line, "\n") {
+			return
+		}
+		expected, expectedOk := tokenizeRegexp(line)
+		if actual, ok := lexLine(line); ok != expectedOk || !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%q:\n got %#v\n expected %#v", line, actual, expected)
+		}
+	})
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	lines := []string{}
+	for _, path := range orgTestFiles() {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		lines = append(lines, strings.Split(string(bs), "\n")...)
+	}
+	for name, lex := range map[string]func(string) (token, bool){"lexLine": lexLine, "regexp": tokenizeRegexp} {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				for _, line := range lines {
+					lex(line)
+				}
+			}
+		})
+	}
+}
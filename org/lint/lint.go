@@ -0,0 +1,96 @@
+// Package lint checks parsed Org mode documents for common mistakes.
+//
+//	d := org.New().Silent().Parse(input, "./notes.org")
+//	for _, diagnostic := range lint.Lint(d) {
+//	    log.Print(diagnostic)
+//	}
+package lint
+
+import (
+	"sort"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+// Rule checks a document and returns its findings as diagnostics.
+type Rule interface {
+	Name() string                            // Name returns the name of the rule, e.g. "broken-link".
+	Check(d *org.Document) []*org.ParseError // Check returns the diagnostics for the document.
+}
+
+type rule struct {
+	name  string
+	check func(d *org.Document) []*org.ParseError
+}
+
+// DefaultRules are the rules used by Lint if no rules are given.
+var DefaultRules = []Rule{BrokenLinks, DuplicateNames, MalformedTimestamps, UnknownTodoKeywords, HeadlineTrailingWhitespace}
+
+// NewRule returns a Rule with the given name that uses check to check documents.
+func NewRule(name string, check func(d *org.Document) []*org.ParseError) Rule {
+	return rule{name, check}
+}
+
+func (r rule) Name() string                            { return r.name }
+func (r rule) Check(d *org.Document) []*org.ParseError { return r.check(d) }
+
+// Lint checks the document using the given rules (DefaultRules if no rules are given) and returns the
+// diagnostics of all rules sorted by position.
+func Lint(d *org.Document, rules ...Rule) []*org.ParseError {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	diagnostics := []*org.ParseError{}
+	for _, r := range rules {
+		diagnostics = append(diagnostics, r.Check(d)...)
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.StartCol < b.StartCol
+	})
+	return diagnostics
+}
+
+// diagnostic returns a ParseError for a finding at the given position.
+func diagnostic(d *org.Document, typ org.ErrorType, severity org.Severity, pos org.Position, message, hint string) *org.ParseError {
+	return &org.ParseError{
+		Type:      typ,
+		Severity:  severity,
+		Message:   message,
+		File:      d.Path,
+		StartLine: pos.StartLine,
+		EndLine:   pos.EndLine,
+		StartCol:  pos.StartColumn,
+		EndCol:    pos.EndColumn,
+		Context:   hint,
+	}
+}
+
+// start returns the start of pos - e.g. to report diagnostics for headlines at the headline rather than the whole section.
+func start(pos org.Position) org.Position {
+	return org.Position{StartLine: pos.StartLine, StartColumn: pos.StartColumn, EndLine: pos.StartLine, EndColumn: pos.StartColumn}
+}
+
+// walk calls f for each node and its descendants in document order - including headline titles and
+// link descriptions.
+func walk(nodes []org.Node, f func(org.Node)) {
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+		f(n)
+		switch n := n.(type) {
+		case org.Headline:
+			walk(n.Title, f)
+		case org.RegularLink:
+			walk(n.Description, f)
+		}
+		n.Range(func(child org.Node) bool {
+			walk([]org.Node{child}, f)
+			return true
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+func TestLint(t *testing.T) {
+	input := strings.Join([]string{
+		"* WAIT headline ",
+		":PROPERTIES:",
+		":CUSTOM_ID: id",
+		":END:",
+		"[[#id]] [[#missing]] [[*WAIT headline]] [[*missing]] [[table]] [[file.org]]",
+		"<2024-02-30 Fri> <2024-02-29 Thu>",
+		"#+NAME: table",
+		"| a |",
+		"#+NAME: table",
+		"| b |",
+	}, "\n")
+	d := org.New().Silent().Parse(strings.NewReader(input), "lint.org")
+	expected := []string{
+		"lint.org:0:0: unknown TODO keyword WAIT (hint: add it to #+TODO)",
+		"lint.org:0:15-16: trailing whitespace in headline",
+		"lint.org:4:8-20: broken internal link [[#missing]]",
+		"lint.org:4:40-52: broken internal link [[*missing]]",
+		"lint.org:5:0-16: malformed timestamp <2024-02-30 Fri> (hint: expected <YYYY-MM-DD Day HH:MM>)",
+		"lint.org:8:0: duplicate #+NAME: table (hint: names must be unique)",
+	}
+	diagnostics := Lint(d)
+	actual := make([]string, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		actual[i] = diagnostic.Error()
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got diagnostics:\n%s\nexpected:\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	input := strings.Join([]string{
+		"#+SEQ_TODO: NEXT | DONE",
+		"#+TYP_TODO: WAIT",
+		"* API design",
+		"* FAQ",
+		"* NEXT planned",
+		"* WAIT typed",
+		"* HOLD unknown",
+		"* tagged :tag:\t",
+		"* untagged",
+		"<2024-02-29 Thu 10:00-11:00> [2024-02-29 Thu] <2024-01-01 Mon +1w -2d> ünï <2024-01-01 Mon 25:00> [2024-13-01]",
+	}, "\n")
+	d := org.New().Silent().Parse(strings.NewReader(input), "rules.org")
+	expected := []string{
+		"rules.org:6:0: unknown TODO keyword HOLD (hint: add it to #+TODO)",
+		"rules.org:7:14-15: trailing whitespace in headline",
+		"rules.org:9:77-99: malformed timestamp <2024-01-01 Mon 25:00> (hint: expected <YYYY-MM-DD Day HH:MM>)",
+		"rules.org:9:100-112: malformed timestamp [2024-13-01] (hint: expected <YYYY-MM-DD Day HH:MM>)",
+	}
+	diagnostics := Lint(d, MalformedTimestamps, UnknownTodoKeywords, HeadlineTrailingWhitespace)
+	actual := make([]string, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		actual[i] = diagnostic.Error()
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got diagnostics:\n%s\nexpected:\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+
+	c := org.New().Silent()
+	c.ColumnMode = org.ColumnRunes
+	d = c.Parse(strings.NewReader(input), "rules.org")
+	if diagnostics := MalformedTimestamps.Check(d); len(diagnostics) != 2 || diagnostics[0].StartCol != 75 || diagnostics[0].EndCol != 97 {
+		t.Errorf("expected the malformed timestamp at rune columns 75-97, got %v", diagnostics)
+	}
+}
+
+func TestBrokenLinksTargets(t *testing.T) {
+	input := "[[target]] [[radio]] [[missing]] <<target>> <<<radio>>>"
+	d := org.New().Silent().Parse(strings.NewReader(input), "targets.org")
+	diagnostics := BrokenLinks.Check(d)
+	if len(diagnostics) != 1 || diagnostics[0].Error() != "targets.org:0:21-32: broken internal link [[missing]]" {
+		t.Errorf("expected only [[missing]] to be broken, got %v", diagnostics)
+	}
+}
+
+type shortcode struct{ org.Text }
+
+func (s shortcode) String() string { return ":" + s.Content + ":" }
+
+func TestLintCustomNodes(t *testing.T) {
+	c := org.New().Silent().AddInlineParser(':', func(input string, start int) (int, org.Node) {
+		if end := strings.IndexByte(input[start+1:], ':'); end > 0 {
+			return end + 2, shortcode{org.Text{Content: input[start+1 : start+1+end]}}
+		}
+		return 0, nil
+	}).AddBlockHandler("notes", func(d *org.Document, b org.Block) org.Node {
+		return struct{ org.Block }{b}
+	})
+	d := c.Parse(strings.NewReader("* FAQ :smile: \n#+BEGIN_NOTES\n[[*missing]] :wave:\n#+END_NOTES\n"), "custom.org")
+	expected := []string{
+		"custom.org:0:13-14: trailing whitespace in headline",
+		"custom.org:2:0-12: broken internal link [[*missing]]",
+	}
+	diagnostics := Lint(d)
+	actual := make([]string, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		actual[i] = diagnostic.Error()
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got diagnostics:\n%s\nexpected:\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+}
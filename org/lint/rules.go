@@ -0,0 +1,182 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+// BrokenLinks reports internal links (e.g. [[#custom-id]], [[*Headline]] or [[name]]) that do not resolve to a
+// CUSTOM_ID, headline, #+NAME or <<target>> of the document.
+var BrokenLinks = NewRule("broken-link", func(d *org.Document) []*org.ParseError {
+	customIDs, titles := map[string]bool{}, map[string]bool{}
+	walk(d.Nodes, func(n org.Node) {
+		if h, ok := n.(org.Headline); ok {
+			titles[strings.TrimSpace(org.String(h.Title...))] = true
+			if id, ok := h.Properties.Get("CUSTOM_ID"); ok {
+				customIDs[id] = true
+			}
+		}
+	})
+	diagnostics := []*org.ParseError{}
+	walk(d.Nodes, func(n org.Node) {
+		l, ok := n.(org.RegularLink)
+		if !ok || l.Protocol != "" || l.AutoLink {
+			return
+		}
+		broken := false
+		switch {
+		case strings.HasPrefix(l.URL, "#"):
+			broken = !customIDs[l.URL[1:]]
+		case strings.HasPrefix(l.URL, "*"):
+			broken = !titles[strings.TrimSpace(l.URL[1:])]
+		case !strings.ContainsAny(l.URL, "./"):
+			_, named := d.NamedNodes[l.URL]
+			broken = !named && !titles[l.URL] && len(d.Lookup(org.TargetSymbol, l.URL)) == 0 && len(d.Lookup(org.RadioTargetSymbol, l.URL)) == 0
+		}
+		if broken {
+			message := fmt.Sprintf("broken internal link [[%s]]", l.URL)
+			diagnostics = append(diagnostics, diagnostic(d, org.ErrorTypeMissingNode, org.SeverityError, l.Pos, message, ""))
+		}
+	})
+	return diagnostics
+})
+
+// DuplicateNames reports #+NAME keywords that reuse the name of a previous #+NAME.
+var DuplicateNames = NewRule("duplicate-name", func(d *org.Document) []*org.ParseError {
+	names, diagnostics := map[string]bool{}, []*org.ParseError{}
+	walk(d.Nodes, func(n org.Node) {
+		if n, ok := n.(org.NodeWithName); ok {
+			if names[n.Name] {
+				message := fmt.Sprintf("duplicate #+NAME: %s", n.Name)
+				diagnostics = append(diagnostics, diagnostic(d, org.ErrorTypeDuplicateNode, org.SeverityError, start(n.Pos), message, "names must be unique"))
+			}
+			names[n.Name] = true
+		}
+	})
+	return diagnostics
+})
+
+var timestampLikeRegexp = regexp.MustCompile(`[<\[]\d{4}-\d{1,2}-\d{1,2}[^<>\[\]\n]*[>\]]`)
+var timestampRegexp = regexp.MustCompile(`^<(\d{4}-\d{2}-\d{2})(?: [^\s\d<>\[\]+-]+)?(?: (\d{1,2}:\d{2})(?:-(\d{1,2}:\d{2}))?)?(?: (?:\+\+|\.\+|\+)\d+[hdwmy])?(?: --?\d+[hdwmy])?>$`)
+
+// MalformedTimestamps reports text that looks like a timestamp but could not be parsed as one, e.g. <2024-02-30 Fri>.
+var MalformedTimestamps = NewRule("malformed-timestamp", func(d *org.Document) []*org.ParseError {
+	diagnostics := []*org.ParseError{}
+	walk(d.Nodes, func(n org.Node) {
+		t, ok := n.(org.Text)
+		if !ok || t.IsRaw {
+			return
+		}
+		for _, m := range timestampLikeRegexp.FindAllStringIndex(t.Content, -1) {
+			if s := t.Content[m[0]:m[1]]; isMalformedTimestamp(s) {
+				message := fmt.Sprintf("malformed timestamp %s", s)
+				pos := span(d, t, m[0], m[1])
+				diagnostics = append(diagnostics, diagnostic(d, org.ErrorTypeInvalidSyntax, org.SeverityWarning, pos, message, "expected <YYYY-MM-DD Day HH:MM>"))
+			}
+		}
+	})
+	return diagnostics
+})
+
+// isMalformedTimestamp returns true if s is not a valid timestamp.
+func isMalformedTimestamp(s string) bool {
+	if s[0] == '[' { // inactive timestamps are not parsed - only report invalid dates
+		_, err := time.Parse("2006-01-02", s[1:strings.IndexAny(s, " >]")])
+		return err != nil
+	}
+	m := timestampRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return true
+	}
+	for i, layout := range []string{"2006-01-02", "15:04", "15:04"} {
+		if _, err := time.Parse(layout, m[i+1]); m[i+1] != "" && err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// span returns the position of the bytes [start, end) of the content of t.
+func span(d *org.Document, t org.Text, start, end int) org.Position {
+	line, column, prefix := t.Pos.StartLine, t.Pos.StartColumn, t.Content[:start]
+	if i := strings.LastIndexByte(prefix, '\n'); i != -1 {
+		line, column, prefix = line+strings.Count(prefix, "\n"), 0, prefix[i+1:]
+	}
+	column += width(d, prefix)
+	return org.Position{StartLine: line, StartColumn: column, EndLine: line, EndColumn: column + width(d, t.Content[start:end])}
+}
+
+// width returns the width of s in the ColumnMode of the document.
+func width(d *org.Document, s string) int {
+	switch d.ColumnMode {
+	case org.ColumnRunes:
+		return utf8.RuneCountInString(s)
+	case org.ColumnUTF16:
+		return len(utf16.Encode([]rune(s)))
+	}
+	return len(s)
+}
+
+var todoKeywordLikeRegexp = regexp.MustCompile(`^([A-Z][A-Z_-]+)\s`)
+
+// commonTodoKeywords are the TODO keywords of common Org mode configurations. Other uppercase words, e.g. the
+// "API" of "* API design", are not reported by UnknownTodoKeywords.
+var commonTodoKeywords = map[string]bool{
+	"TODO": true, "DONE": true, "NEXT": true, "WAIT": true, "WAITING": true, "HOLD": true, "STARTED": true,
+	"DOING": true, "IN-PROGRESS": true, "INPROGRESS": true, "CANCELED": true, "CANCELLED": true, "DELEGATED": true,
+	"SOMEDAY": true, "MAYBE": true,
+}
+
+// UnknownTodoKeywords reports headlines starting with a common TODO keyword (e.g. WAIT or NEXT) that is not
+// configured via #+TODO, #+SEQ_TODO or #+TYP_TODO.
+var UnknownTodoKeywords = NewRule("unknown-todo-keyword", func(d *org.Document) []*org.ParseError {
+	keywords := map[string]bool{}
+	for _, key := range []string{"TODO", "SEQ_TODO", "TYP_TODO"} {
+		for _, k := range strings.FieldsFunc(d.Get(key), func(r rune) bool { return unicode.IsSpace(r) || r == '|' }) {
+			if i := strings.IndexByte(k, '('); i != -1 {
+				k = k[:i]
+			}
+			keywords[k] = true
+		}
+	}
+	diagnostics := []*org.ParseError{}
+	walk(d.Nodes, func(n org.Node) {
+		h, ok := n.(org.Headline)
+		if !ok || h.Status != "" {
+			return
+		}
+		if m := todoKeywordLikeRegexp.FindStringSubmatch(org.String(h.Title...) + " "); m != nil && commonTodoKeywords[m[1]] && !keywords[m[1]] {
+			message := fmt.Sprintf("unknown TODO keyword %s", m[1])
+			diagnostics = append(diagnostics, diagnostic(d, org.ErrorTypeValidation, org.SeverityWarning, start(h.Pos), message, "add it to #+TODO"))
+		}
+	})
+	return diagnostics
+})
+
+// HeadlineTrailingWhitespace reports headlines with trailing whitespace - at the whitespace.
+var HeadlineTrailingWhitespace = NewRule("headline-trailing-whitespace", func(d *org.Document) []*org.ParseError {
+	diagnostics := []*org.ParseError{}
+	walk(d.Nodes, func(n org.Node) {
+		h, ok := n.(org.Headline)
+		if !ok {
+			return
+		}
+		line, _, _ := strings.Cut(d.Source(h), "\n")
+		if line = strings.TrimSuffix(line, "\r"); line == "" {
+			return
+		}
+		if trimmed := strings.TrimRightFunc(line, unicode.IsSpace); trimmed != line {
+			column := h.Pos.StartColumn + width(d, trimmed)
+			pos := org.Position{StartLine: h.Pos.StartLine, StartColumn: column, EndLine: h.Pos.StartLine, EndColumn: column + width(d, line[len(trimmed):])}
+			diagnostics = append(diagnostics, diagnostic(d, org.ErrorTypeValidation, org.SeverityInfo, pos, "trailing whitespace in headline", ""))
+		}
+	})
+	return diagnostics
+})
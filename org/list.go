@@ -3,6 +3,7 @@ package org
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -35,6 +36,9 @@ type List struct {
 	Kind ListKind
 	// Items contains the individual list items that belong to this list
 	Items []Node
+	// BulletStyle is the numbering style of ordered lists, taken from the bullet of the first item:
+	//   "1" for numbers, "a" / "A" for lower / upper case letters. Empty for other lists
+	BulletStyle string
 	// Pos tracks the source document position where this list begins
 	Pos Position
 }
@@ -50,7 +54,7 @@ type ListItem struct {
 	// Appears as "[ ]", "[X]", or "[-]" in source text
 	Status string
 	// Value is for ordered lists only: overrides automatic numbering with [@num]
-	// Allows explicit numbering like "[@5]" to force a specific number
+	// Allows explicit numbering like "[@5]" or - in alphabetical lists - "[@e]" to force a specific number
 	Value string
 	// Children contains the actual content nodes after the marker/status/value prefixes
 	// Includes text, nested lists, code blocks, or other Org elements
@@ -70,7 +74,7 @@ type DescriptiveListItem struct {
 var unorderedListRegexp = regexp.MustCompile(`^(\s*)([+*-])(\s+(.*)|$)`)
 var orderedListRegexp = regexp.MustCompile(`^(\s*)(([0-9]+|[a-zA-Z])[.)])(\s+(.*)|$)`)
 var descriptiveListItemRegexp = regexp.MustCompile(`\s::(\s|$)`)
-var listItemValueRegexp = regexp.MustCompile(`\[@(\d+)\]\s`)
+var listItemValueRegexp = regexp.MustCompile(`\[@(\d+|[a-zA-Z])\]\s`)
 var listItemStatusRegexp = regexp.MustCompile(`\[( |X|-)\]\s`)
 
 func lexList(line string) (token, bool) {
@@ -106,6 +110,9 @@ func (d *Document) parseList(i int, parentStop stopFn) (int, Node) {
 	start, lvl := i, d.tokens[i].lvl
 	listMainKind, kind := listKind(d.tokens[i])
 	list := List{Kind: kind}
+	if kind == OrderedList {
+		list.BulletStyle = bulletStyle(d.tokens[i].matches[2])
+	}
 	stop := func(d *Document, i int) bool {
 		if parentStop(d, i) || d.tokens[i].lvl != lvl || !isListToken(d.tokens[i]) {
 			return true
@@ -119,18 +126,34 @@ func (d *Document) parseList(i int, parentStop stopFn) (int, Node) {
 		list.Items = append(list.Items, node)
 	}
 	if i > start {
-		list.Pos = Position{
-			StartLine:   d.tokens[start].line,
-			StartColumn: d.tokens[start].startCol,
-			EndLine:     d.tokens[i-1].line,
-			EndColumn:   d.tokens[i-1].endCol,
-		}
+		list.Pos = d.getPositionBetweenTokens(d.tokens[start], d.tokens[i-1])
 	}
 	return i - start, list
 }
 
+// bulletStyle returns the numbering style of the ordered list bullet, see List.BulletStyle.
+func bulletStyle(bullet string) string {
+	switch c := bullet[0]; {
+	case c >= 'a' && c <= 'z':
+		return "a"
+	case c >= 'A' && c <= 'Z':
+		return "A"
+	default:
+		return "1"
+	}
+}
+
+// ordinal returns the number of the list item value v - letters count from 1, i.e. "c" is 3.
+// Returns v if it is not a single letter.
+func ordinal(v string) string {
+	if len(v) == 1 && unicode.IsLetter(rune(v[0])) {
+		return strconv.Itoa(int(unicode.ToLower(rune(v[0]))-'a') + 1)
+	}
+	return v
+}
+
 func (d *Document) parseListItem(l List, i int, parentStop stopFn) (int, Node) {
-	start, nodes, bullet := i, []Node{}, d.tokens[i].matches[2]
+	start, nodes, bullet, bulletIndent := i, []Node{}, d.tokens[i].matches[2], d.tokens[i].lvl
 	minIndent, dterm, content, status, value := d.tokens[i].lvl+len(bullet), "", d.tokens[i].content, "", ""
 	originalBaseLvl := d.baseLvl
 	d.baseLvl = minIndent + 1
@@ -141,9 +164,13 @@ func (d *Document) parseListItem(l List, i int, parentStop stopFn) (int, Node) {
 		status, content = m[1], content[len("[ ] "):]
 	}
 	if l.Kind == DescriptiveList {
-		if m := descriptiveListItemRegexp.FindStringIndex(content); m != nil {
+		if m := d.descriptiveListSeparator(content); m != nil {
+			if line := d.tokens[i].matches[0]; strings.TrimSpace(content[m[1]:]) != "" {
+				d.baseLvl = len(line) - len(content) + m[1]
+			} else if lvl := d.detailsIndent(i + 1); lvl > bulletIndent {
+				d.baseLvl = lvl
+			}
 			dterm, content = content[:m[0]], content[m[1]:]
-			d.baseLvl = strings.Index(d.tokens[i].matches[0], " ::") + 4
 		}
 	}
 
@@ -151,22 +178,27 @@ func (d *Document) parseListItem(l List, i int, parentStop stopFn) (int, Node) {
 	originalLine := d.tokens[i].line
 	originalStartCol := d.tokens[i].startCol
 	originalEndCol := d.tokens[i].endCol
+	originalOffset := d.tokens[i].offset
 	d.tokens[i], ok = tokenize(strings.Repeat(" ", minIndent) + content)
 	if !ok {
 		line := d.tokens[i].line
-		d.AddError(ErrorTypeTokenization, "could not lex line", getPositionFromToken(d.tokens[i]), d.tokens[i], fmt.Errorf("no lexer matched: %q", line))
+		d.AddError(ErrorTypeTokenization, "could not lex line", d.getPositionFromToken(d.tokens[i]), d.tokens[i], fmt.Errorf("no lexer matched: %q", line))
 	}
 	d.tokens[i].line = originalLine
 	d.tokens[i].startCol = originalStartCol
 	d.tokens[i].endCol = originalEndCol
+	d.tokens[i].offset = originalOffset
 	stop := func(d *Document, i int) bool {
 		if parentStop(d, i) {
 			return true
 		}
 		t := d.tokens[i]
+		if d.emacsCompatible() {
+			return t.lvl <= bulletIndent && !(t.kind == "text" && t.content == "")
+		}
 		return t.lvl < minIndent && !(t.kind == "text" && t.content == "")
 	}
-	for !stop(d, i) && (i <= start+1 || !isSecondBlankLine(d, i)) {
+	for !stop(d, i) && !(i > start && d.endsList(i)) {
 		consumed, node := d.parseOne(i, stop)
 		i += consumed
 		nodes = append(nodes, node)
@@ -174,33 +206,74 @@ func (d *Document) parseListItem(l List, i int, parentStop stopFn) (int, Node) {
 	d.baseLvl = originalBaseLvl
 	if l.Kind == DescriptiveList {
 		item := DescriptiveListItem{Bullet: bullet, Status: status, Term: d.parseInline(dterm), Details: nodes}
-		item.Pos = Position{
-			StartLine:   d.tokens[start].line,
-			StartColumn: d.tokens[start].startCol,
-			EndLine:     d.tokens[i-1].line,
-			EndColumn:   d.tokens[i-1].endCol,
-		}
+		item.Pos = d.getPositionBetweenTokens(d.tokens[start], d.tokens[i-1])
 		return i - start, item
 	}
 	item := ListItem{Bullet: bullet, Status: status, Value: value, Children: nodes}
-	item.Pos = Position{
-		StartLine:   d.tokens[start].line,
-		StartColumn: d.tokens[start].startCol,
-		EndLine:     d.tokens[i-1].line,
-		EndColumn:   d.tokens[i-1].endCol,
-	}
+	item.Pos = d.getPositionBetweenTokens(d.tokens[start], d.tokens[i-1])
 	return i - start, item
 }
 
+// descriptiveListSeparator returns the start and end index of the first " :: " in content that separates the term
+// from the details of a descriptive list item. Separators inside of links and emphasis are part of the term. Returns
+// nil if content does not contain a separator.
+func (d *Document) descriptiveListSeparator(content string) []int {
+	if d.acquireBuffers() {
+		defer d.releaseBuffers()
+	}
+	for i := 0; i < len(content); {
+		consumed := 0
+		switch m := d.buffers.markers[content[i]]; {
+		case content[i] == '[':
+			consumed, _ = d.parseRegularLink(content, i)
+		case m != 0:
+			consumed, _ = d.parseEmphasis(content, i, m == markerVerbatim)
+		case content[i] == ' ' || content[i] == '\t':
+			if m := descriptiveListItemRegexp.FindStringIndex(content[i:]); m != nil && m[0] == 0 {
+				return []int{i, i + m[1]}
+			}
+		}
+		i += max(consumed, 1)
+	}
+	return nil
+}
+
+// detailsIndent returns the indentation of the first non-blank line starting at i, i.e. of the details of a
+// descriptive list item that start on the line after the term.
+func (d *Document) detailsIndent(i int) int {
+	for ; i < len(d.tokens); i++ {
+		if t := d.tokens[i]; t.kind != "text" || t.content != "" {
+			return t.lvl
+		}
+	}
+	return 0
+}
+
+// endsList returns true if the tokens starting at i are ListEndBlankLines blank lines, i.e. end all lists containing
+// them. The blank lines are not part of the lists.
+func (d *Document) endsList(i int) bool {
+	n := d.ListEndBlankLines
+	if n <= 0 || i+n > len(d.tokens) {
+		return false
+	}
+	for _, t := range d.tokens[i : i+n] {
+		if t.kind != "text" || t.content != "" {
+			return false
+		}
+	}
+	return true
+}
+
 func (n List) String() string                { return String(n) }
 func (n ListItem) String() string            { return String(n) }
 func (n DescriptiveListItem) String() string { return String(n) }
 
 func (n List) Copy() Node {
 	return List{
-		Kind:  n.Kind,
-		Items: CopyNodes(n.Items),
-		Pos:   n.Pos,
+		Kind:        n.Kind,
+		Items:       CopyNodes(n.Items),
+		BulletStyle: n.BulletStyle,
+		Pos:         n.Pos,
 	}
 }
 
@@ -0,0 +1,391 @@
+// Package lsp implements a language server (see https://microsoft.github.io/language-server-protocol/) for Org mode
+// documents. It provides document symbols (the outline), folding ranges, diagnostics (parse errors and linter
+// findings), hovers for links and footnotes, go-to-definition for internal links and footnotes and semantic tokens.
+//
+//	err := lsp.NewServer(org.New()).Serve(os.Stdin, os.Stdout)
+//
+// The features are also available as functions of parsed documents, e.g. to implement them in other servers.
+// Documents have to be parsed with ColumnMode ColumnUTF16 for the columns to match the positions of the protocol.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/alexispurslane/go-org/org"
+	"github.com/alexispurslane/go-org/org/lint"
+)
+
+// Server is a language server for Org mode documents. Documents are synchronized in full (TextDocumentSyncKind.Full)
+// and parsed on each change.
+type Server struct {
+	Configuration *org.Configuration // Configuration is used to parse documents - with ColumnMode ColumnUTF16.
+	Rules         []lint.Rule        // Rules are the linter rules of the diagnostics - lint.DefaultRules if empty.
+
+	documents map[string]*org.Document
+	out       io.Writer
+}
+
+// NewServer returns a server that parses documents using c.
+func NewServer(c *org.Configuration) *Server {
+	return &Server{Configuration: c, documents: map[string]*org.Document{}}
+}
+
+// Serve reads requests from r and writes responses and notifications to w until the exit notification is received
+// or r is exhausted.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	in := bufio.NewReader(r)
+	s.out = w
+	for {
+		content, err := readMessage(in)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("could not read message: %w", err)
+		}
+		m := message{}
+		if err := json.Unmarshal(content, &m); err != nil {
+			if err := writeMessage(w, message{ID: json.RawMessage("null"), Error: &responseError{codeParseError, err.Error()}}); err != nil {
+				return fmt.Errorf("could not write response: %w", err)
+			}
+			continue
+		}
+		if m.Method == "exit" {
+			return nil
+		}
+		result, rErr := s.handle(m)
+		if m.ID == nil {
+			continue // notifications are not answered
+		}
+		response := message{ID: m.ID, Result: result, Error: rErr}
+		if result == nil && rErr == nil {
+			response.Result = json.RawMessage("null")
+		}
+		if err := writeMessage(w, response); err != nil {
+			return fmt.Errorf("could not write response: %w", err)
+		}
+	}
+}
+
+func (s *Server) handle(m message) (any, *responseError) {
+	switch m.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":       1,
+				"documentSymbolProvider": true,
+				"foldingRangeProvider":   true,
+				"hoverProvider":          true,
+				"definitionProvider":     true,
+				"semanticTokensProvider": map[string]any{
+					"legend": map[string][]string{"tokenTypes": TokenTypes, "tokenModifiers": {}},
+					"full":   true,
+				},
+			},
+			"serverInfo": map[string]string{"name": "go-org"},
+		}, nil
+	case "initialized", "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		p := didOpenParams{}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &responseError{codeInvalidParams, err.Error()}
+		}
+		s.update(p.TextDocument.URI, p.TextDocument.Text)
+	case "textDocument/didChange":
+		p := didChangeParams{}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &responseError{codeInvalidParams, err.Error()}
+		}
+		if n := len(p.ContentChanges); n != 0 {
+			s.update(p.TextDocument.URI, p.ContentChanges[n-1].Text)
+		}
+	case "textDocument/didClose":
+		p := textDocumentParams{}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &responseError{codeInvalidParams, err.Error()}
+		}
+		delete(s.documents, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{p.TextDocument.URI, []Diagnostic{}})
+	case "textDocument/documentSymbol", "textDocument/foldingRange", "textDocument/semanticTokens/full":
+		p := textDocumentParams{}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &responseError{codeInvalidParams, err.Error()}
+		}
+		d, ok := s.documents[p.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		} else if m.Method == "textDocument/documentSymbol" {
+			return DocumentSymbols(d), nil
+		} else if m.Method == "textDocument/semanticTokens/full" {
+			return map[string][]int{"data": SemanticTokens(d)}, nil
+		}
+		return FoldingRanges(d), nil
+	case "textDocument/hover", "textDocument/definition":
+		p := textDocumentPositionParams{}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &responseError{codeInvalidParams, err.Error()}
+		}
+		d, ok := s.documents[p.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		} else if m.Method == "textDocument/hover" {
+			if h := HoverAt(d, p.Position); h != nil {
+				return h, nil
+			}
+			return nil, nil
+		}
+		return Definition(d, p.TextDocument.URI, p.Position), nil
+	default:
+		if m.ID != nil && !strings.HasPrefix(m.Method, "$/") {
+			return nil, &responseError{codeMethodNotFound, "method not found: " + m.Method}
+		}
+	}
+	return nil, nil
+}
+
+// update parses the text of the document and publishes its diagnostics.
+func (s *Server) update(uri, text string) {
+	c := *s.Configuration
+	c.ColumnMode = org.ColumnUTF16
+	d := c.Parse(strings.NewReader(text), uriPath(uri))
+	s.documents[uri] = d
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{uri, Diagnostics(d, s.Rules...)})
+}
+
+func (s *Server) notify(method string, params any) {
+	content, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	if err := writeMessage(s.out, message{Method: method, Params: content}); err != nil && s.Configuration.Log != nil {
+		s.Configuration.Log.Printf("could not write notification: %s", err)
+	}
+}
+
+// uriPath returns the file path of a file:// URI - or the URI itself for other URIs.
+func uriPath(uri string) string {
+	if u, err := url.Parse(uri); err == nil && u.Scheme == "file" {
+		return filepath.FromSlash(u.Path)
+	}
+	return uri
+}
+
+// DocumentSymbols returns the outline of the document as nested symbols.
+func DocumentSymbols(d *org.Document) []DocumentSymbol {
+	var symbols func(sections []*org.Section) []DocumentSymbol
+	symbols = func(sections []*org.Section) []DocumentSymbol {
+		result := []DocumentSymbol{}
+		for _, s := range sections {
+			h := *s.Headline
+			name := strings.TrimSpace(org.String(h.Title...))
+			if name == "" {
+				name = "(untitled)"
+			}
+			detail := h.Status
+			if len(h.Tags) != 0 {
+				detail = strings.TrimSpace(h.Status + " :" + strings.Join(h.Tags, ":") + ":")
+			}
+			line, _, _ := strings.Cut(d.Source(h), "\n")
+			start := Position{h.Pos.StartLine, h.Pos.StartColumn}
+			result = append(result, DocumentSymbol{
+				Name:           name,
+				Detail:         detail,
+				Kind:           SymbolKindString,
+				Range:          toRange(h.Pos),
+				SelectionRange: Range{start, Position{start.Line, start.Character + len(utf16.Encode([]rune(line)))}},
+				Children:       symbols(s.Children),
+			})
+		}
+		return result
+	}
+	return symbols(d.Outline.Children)
+}
+
+// FoldingRanges returns the foldable ranges of the document: sections, blocks, drawers, lists and tables.
+func FoldingRanges(d *org.Document) []FoldingRange {
+	ranges := []FoldingRange{}
+	walk(d.Nodes, func(n org.Node) {
+		positions := []org.Position{}
+		switch n := n.(type) {
+		case org.Headline:
+			if positions = append(positions, n.Pos); n.Properties != nil {
+				positions = append(positions, n.Properties.Pos)
+			}
+		case org.Block, org.LatexBlock, org.Drawer, org.PropertyDrawer, org.List, org.Table:
+			positions = append(positions, n.Position())
+		}
+		for _, pos := range positions {
+			if pos.EndLine > pos.StartLine {
+				ranges = append(ranges, FoldingRange{StartLine: pos.StartLine, EndLine: pos.EndLine, Kind: "region"})
+			}
+		}
+	})
+	return ranges
+}
+
+// TokenTypes is the legend of the semantic tokens, i.e. the names of the org.SemanticTokenTypes by value.
+var TokenTypes = tokenTypes()
+
+func tokenTypes() []string {
+	types := []string{}
+	for t := org.SemanticTokenType(0); t.String() != "unknown"; t++ {
+		types = append(types, t.String())
+	}
+	return types
+}
+
+// SemanticTokens returns the semantic tokens of the document in the relative encoding of the protocol (see TokenTypes).
+// Tokens spanning multiple lines are not included.
+func SemanticTokens(d *org.Document) []int {
+	data, line, character := []int{}, 0, 0
+	for _, t := range d.SemanticTokens() {
+		if t.Pos.StartLine != t.Pos.EndLine {
+			continue
+		}
+		if t.Pos.StartLine != line {
+			character = 0
+		}
+		data = append(data, t.Pos.StartLine-line, t.Pos.StartColumn-character, t.Pos.EndColumn-t.Pos.StartColumn, int(t.Type), 0)
+		line, character = t.Pos.StartLine, t.Pos.StartColumn
+	}
+	return data
+}
+
+// Diagnostics returns the errors of the document and the findings of the linter rules (lint.DefaultRules if no rules are given).
+func Diagnostics(d *org.Document, rules ...lint.Rule) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, e := range append(append([]*org.ParseError{}, d.Errors...), lint.Lint(d, rules...)...) {
+		severity := SeverityError
+		switch e.Severity {
+		case org.SeverityWarning:
+			severity = SeverityWarning
+		case org.SeverityInfo:
+			severity = SeverityInformation
+		}
+		message := e.Message
+		if e.Context != "" {
+			message += " (hint: " + e.Context + ")"
+		}
+		start, end := Position{e.StartLine, e.StartCol}, Position{e.EndLine, e.EndCol}
+		if end.Line < start.Line || end.Line == start.Line && end.Character < start.Character {
+			end = start
+		}
+		diagnostics = append(diagnostics, Diagnostic{Range{start, end}, severity, string(e.Type), "go-org", message})
+	}
+	return diagnostics
+}
+
+// HoverAt returns the hover for the link or footnote reference at the position - or nil if there is none.
+func HoverAt(d *org.Document, p Position) *Hover {
+	for _, n := range reversed(d.NodesAt(p.Line, p.Character)) {
+		value := ""
+		switch n := n.(type) {
+		case org.RegularLink:
+			value = "`" + strings.ReplaceAll(n.URL, "`", "'") + "`"
+			if symbols := resolve(d, n); len(symbols) != 0 {
+				value += fmt.Sprintf("\n\n%s %s (line %d)", symbols[0].Kind, symbols[0].Name, symbols[0].Pos.StartLine+1)
+			}
+		case org.FootnoteLink:
+			value = "footnote " + n.Name
+			if definition := footnoteDefinition(d, n); definition != nil {
+				value += "\n\n" + strings.TrimSpace(org.String(definition.Children...))
+			}
+		default:
+			continue
+		}
+		r := toRange(n.Position())
+		return &Hover{MarkupContent{"markdown", value}, &r}
+	}
+	return nil
+}
+
+// Definition returns the locations of the targets of the internal link or footnote reference at the position of the
+// document with the given URI. Links to other files resolve to the start of the file.
+func Definition(d *org.Document, uri string, p Position) []Location {
+	locations := []Location{}
+	for _, n := range reversed(d.NodesAt(p.Line, p.Character)) {
+		switch n := n.(type) {
+		case org.RegularLink:
+			if n.Protocol == "file" || n.Protocol == "" && (strings.HasPrefix(n.URL, "./") || strings.HasPrefix(n.URL, "/")) {
+				path, _, _ := strings.Cut(strings.TrimPrefix(n.URL, "file:"), "::")
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(filepath.Dir(uriPath(uri)), path)
+				}
+				return append(locations, Location{URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()})
+			}
+			for _, s := range resolve(d, n) {
+				locations = append(locations, Location{uri, toRange(s.Pos)})
+			}
+			return locations
+		case org.FootnoteLink:
+			if definition := footnoteDefinition(d, n); definition != nil {
+				locations = append(locations, Location{uri, toRange(definition.Pos)})
+			}
+			return locations
+		}
+	}
+	return locations
+}
+
+// resolve returns the symbols an internal link refers to: CUSTOM_IDs for [[#id]], headlines for [[*title]] and
+// - like Org mode - targets, #+NAMEs or headlines (the first kind with matches) for other links without protocol.
+func resolve(d *org.Document, l org.RegularLink) []org.Symbol {
+	switch {
+	case l.Protocol != "" || l.AutoLink:
+		return nil
+	case strings.HasPrefix(l.URL, "#"):
+		return d.Lookup(org.CustomIDSymbol, l.URL[1:])
+	case strings.HasPrefix(l.URL, "*"):
+		return d.Lookup(org.HeadlineSymbol, strings.TrimSpace(l.URL[1:]))
+	}
+	for _, kind := range []org.SymbolKind{org.TargetSymbol, org.RadioTargetSymbol, org.NameSymbol, org.HeadlineSymbol} {
+		if symbols := d.Lookup(kind, l.URL); len(symbols) != 0 {
+			return symbols
+		}
+	}
+	return nil
+}
+
+func footnoteDefinition(d *org.Document, l org.FootnoteLink) *org.FootnoteDefinition {
+	if l.Definition != nil {
+		return l.Definition
+	}
+	for _, s := range d.Lookup(org.FootnoteSymbol, l.Name) {
+		if definition, ok := s.Node.(org.FootnoteDefinition); ok {
+			return &definition
+		}
+	}
+	return nil
+}
+
+func toRange(pos org.Position) Range {
+	return Range{Position{pos.StartLine, pos.StartColumn}, Position{pos.EndLine, pos.EndColumn}}
+}
+
+func reversed(nodes []org.Node) []org.Node {
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+	return nodes
+}
+
+// walk calls f for each node and its descendants in document order.
+func walk(nodes []org.Node, f func(org.Node)) {
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+		f(n)
+		n.Range(func(child org.Node) bool {
+			walk([]org.Node{child}, f)
+			return true
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+const input = `#+TITLE: Notes
+* TODO Über :work:
+See [[#tasks][tasks]], [[target]] and a footnote[fn:1].
+#+BEGIN_SRC go
+x
+#+END_SRC
+** Tasks
+:PROPERTIES:
+:CUSTOM_ID: tasks
+:END:
+<<target>> and a [[#missing]] link
+* Footnotes
+[fn:1] The definition.
+`
+
+func parse(input string) *org.Document {
+	c := org.New().Silent()
+	c.ColumnMode = org.ColumnUTF16
+	return c.Parse(strings.NewReader(input), "/notes/index.org")
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	symbols := DocumentSymbols(parse(input))
+	if len(symbols) != 2 || len(symbols[0].Children) != 1 || symbols[1].Name != "Footnotes" {
+		t.Fatalf("unexpected symbols %#v", symbols)
+	}
+	if s := symbols[0]; s.Name != "Über" || s.Detail != "TODO :work:" || s.Range.Start != (Position{1, 0}) || s.Range.End.Line != 10 ||
+		s.SelectionRange.End != (Position{1, 18}) {
+		t.Errorf("unexpected symbol %#v", s)
+	}
+	if s := symbols[0].Children[0]; s.Name != "Tasks" || s.Range.Start.Line != 6 {
+		t.Errorf("unexpected child symbol %#v", s)
+	}
+}
+
+func TestFoldingRanges(t *testing.T) {
+	actual := fmt.Sprint(FoldingRanges(parse(input)))
+	if expected := "[{1 10 region} {3 5 region} {6 10 region} {7 9 region} {11 12 region}]"; actual != expected {
+		t.Errorf("got %s, expected %s", actual, expected)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	diagnostics := Diagnostics(parse(input))
+	if len(diagnostics) != 1 {
+		t.Fatalf("unexpected diagnostics %#v", diagnostics)
+	}
+	if d := diagnostics[0]; d.Range.Start != (Position{10, 17}) || d.Severity != SeverityError || d.Code != "missing_node" || d.Message != "broken internal link [[#missing]]" {
+		t.Errorf("unexpected diagnostic %#v", d)
+	}
+}
+
+func TestHoverAndDefinition(t *testing.T) {
+	d := parse(input)
+	if h := HoverAt(d, Position{2, 8}); h == nil || h.Contents.Value != "`#tasks`\n\ncustom-id tasks (line 8)" || h.Range.Start != (Position{2, 4}) {
+		t.Errorf("unexpected link hover %#v", h)
+	}
+	if h := HoverAt(d, Position{2, 50}); h == nil || h.Contents.Value != "footnote 1\n\nThe definition." {
+		t.Errorf("unexpected footnote hover %#v", h)
+	}
+	if h := HoverAt(d, Position{0, 3}); h != nil {
+		t.Errorf("unexpected hover %#v", h)
+	}
+	uri := "file:///notes/index.org"
+	for _, test := range []struct {
+		position Position
+		expected string
+	}{
+		{Position{2, 8}, "[{file:///notes/index.org {{7 0} {9 5}}}]"},
+		{Position{2, 27}, "[{file:///notes/index.org {{10 0} {10 10}}}]"},
+		{Position{2, 50}, "[{file:///notes/index.org {{12 0} {12 22}}}]"},
+		{Position{10, 20}, "[]"},
+	} {
+		if actual := fmt.Sprint(Definition(d, uri, test.position)); actual != test.expected {
+			t.Errorf("%v: got %s, expected %s", test.position, actual, test.expected)
+		}
+	}
+	d = parse("[[file:other.org::*A][other]]\n")
+	if actual, expected := fmt.Sprint(Definition(d, uri, Position{0, 3})), "[{file:///notes/other.org {{0 0} {0 0}}}]"; actual != expected {
+		t.Errorf("got %s, expected %s", actual, expected)
+	}
+}
+
+func TestSemanticTokens(t *testing.T) {
+	actual := fmt.Sprint(SemanticTokens(parse("* TODO Über *a* :x:\n- [ ] b\n")))
+	if expected := "[0 0 1 0 0 0 2 4 1 0 0 10 1 7 0 0 2 1 7 0 0 3 1 3 0 1 0 1 12 0 0 2 3 13 0]"; actual != expected {
+		t.Errorf("got %s, expected %s", actual, expected)
+	}
+	if TokenTypes[0] != "headline-stars" || TokenTypes[len(TokenTypes)-1] != "statistic-cookie" {
+		t.Errorf("unexpected token types %v", TokenTypes)
+	}
+}
+
+func TestServe(t *testing.T) {
+	in := &bytes.Buffer{}
+	for _, m := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///notes/index.org","text":"* A\n[[#b]]\n"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":"file:///notes/index.org"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///notes/index.org"},"position":{"line":0,"character":0}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"unknown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"shutdown"}`,
+	} {
+		fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	out := &bytes.Buffer{}
+	if err := NewServer(org.New().Silent()).Serve(in, out); err != nil {
+		t.Fatalf("could not serve: %s", err)
+	}
+	messages, r := []string{}, bufio.NewReader(out)
+	for {
+		content, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		m := map[string]any{}
+		if err := json.Unmarshal(content, &m); err != nil {
+			t.Fatalf("invalid message %s", content)
+		}
+		messages = append(messages, fmt.Sprint(m["id"], " ", m["method"], " ", m["result"] != nil, " ", m["error"] != nil))
+	}
+	expected := []string{
+		"1 <nil> true false",
+		"<nil> textDocument/publishDiagnostics false false",
+		"2 <nil> true false",
+		"3 <nil> false false",
+		"4 <nil> false true",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got\n%s\nexpected\n%s", strings.Join(messages, "\n"), strings.Join(expected, "\n"))
+	}
+}
@@ -0,0 +1,622 @@
+package org
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MarkdownWriter exports an org document into markdown - CommonMark with the GitHub extensions for tables,
+// strikethrough, task lists and footnotes. Markup without a markdown equivalent (e.g. underlines) is written as inline
+// HTML, #+HTML keywords are passed through as well. Headlines are not numbered and tables of contents are not written.
+// A MarkdownWriter must not be used by multiple goroutines at once.
+type MarkdownWriter struct {
+	ExtendingWriter Writer
+	// TopLevelHLevel is the markdown heading level of level-1 Org headlines - see HTMLWriter.TopLevelHLevel.
+	// The #+TITLE is written as level 1 heading. Defaults to 2.
+	TopLevelHLevel int
+	// NodeWriters write custom nodes (e.g. the nodes of InlineParsers), keyed by their type. See AddNodeWriter.
+	NodeWriters map[reflect.Type]func(*MarkdownWriter, Node)
+
+	strings.Builder
+	document  *Document
+	escape    bool
+	footnotes *footnotes
+	bullet    string // bullet is the bullet of the list item being written, see WriteList.
+	err       error  // err is the first error of writing the document - see errorReporter.
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`)
+
+var markdownListItemStatuses = map[string]string{" ": "[ ] ", "-": "[ ] ", "X": "[x] "}
+
+func NewMarkdownWriter() *MarkdownWriter {
+	return &MarkdownWriter{
+		document:       &Document{Configuration: New()},
+		escape:         true,
+		TopLevelHLevel: 2,
+		footnotes:      &footnotes{mapping: map[string]int{}, unused: map[string]*FootnoteDefinition{}},
+	}
+}
+
+// Backend implements Writer. Only export blocks and snippets for md are written.
+func (w *MarkdownWriter) Backend() string { return "md" }
+
+func (w *MarkdownWriter) WriterWithExtensions() Writer {
+	if w.ExtendingWriter != nil {
+		return w.ExtendingWriter
+	}
+	return w
+}
+
+// AddNodeWriter registers f to write all nodes with the same type as node, e.g. the nodes of an InlineParser.
+func (w *MarkdownWriter) AddNodeWriter(node Node, f func(*MarkdownWriter, Node)) *MarkdownWriter {
+	if w.NodeWriters == nil {
+		w.NodeWriters = map[reflect.Type]func(*MarkdownWriter, Node){}
+	}
+	w.NodeWriters[reflect.TypeOf(node)] = f
+	return w
+}
+
+// WriteCustomNode implements CustomNodeWriter using the NodeWriters. Emoji, hashtags and mentions are written unless
+// overridden.
+func (w *MarkdownWriter) WriteCustomNode(n Node) bool {
+	if f, ok := w.NodeWriters[reflect.TypeOf(n)]; ok {
+		f(w, n)
+		return true
+	}
+	switch n := n.(type) {
+	case Emoji:
+		if n.Unicode == "" {
+			w.WriteString(":" + n.Shortcode + ":")
+		} else {
+			w.WriteString(n.Unicode)
+		}
+	case Hashtag:
+		w.WriteString("#" + markdownEscaper.Replace(n.Name))
+	case Mention:
+		w.WriteString("@" + markdownEscaper.Replace(n.Name))
+	default:
+		return false
+	}
+	return true
+}
+
+func (w *MarkdownWriter) Before(d *Document) {
+	w.document, w.err = d, nil
+	w.footnotes = &footnotes{mapping: map[string]int{}, unused: map[string]*FootnoteDefinition{}}
+	index := d.Footnotes()
+	w.footnotes.index = &index
+	if title := d.Get("TITLE"); title != "" && d.GetOption("title") != "nil" {
+		w.WriteString("# " + strings.TrimSpace(w.inlineString(d, title)) + "\n\n")
+	}
+}
+
+// inlineString returns the inline markup s (e.g. the #+TITLE) written as markdown - or escaped if it is not a
+// single paragraph.
+func (w *MarkdownWriter) inlineString(d *Document, s string) string {
+	document := d.Parse(strings.NewReader(s), d.Path)
+	if len(document.errorsWithoutFootnotes()) == 0 && len(document.Nodes) == 1 {
+		if p, ok := document.Nodes[0].(Paragraph); ok {
+			return w.WriteNodesAsString(p.InlineNodes()...)
+		}
+	}
+	return markdownEscaper.Replace(s)
+}
+
+func (w *MarkdownWriter) After(d *Document) {
+	w.WriteFootnotes(d)
+	out := strings.TrimRight(w.Builder.String(), "\n")
+	w.Builder.Reset()
+	if out != "" {
+		w.WriteString(out + "\n")
+	}
+}
+
+// writeError implements errorReporter.
+func (w *MarkdownWriter) writeError() error { return w.err }
+
+// reportError implements errorReporter.
+func (w *MarkdownWriter) reportError(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *MarkdownWriter) WriteNodesAsString(nodes ...Node) string {
+	original := w.Builder
+	w.Builder = strings.Builder{}
+	WriteNodes(w, nodes...)
+	out := w.String()
+	w.Builder = original
+	return out
+}
+
+// WriteNodesTo implements Writer. All but one of the trailing newlines are held back as After removes the blank
+// lines at the end of the output.
+func (w *MarkdownWriter) WriteNodesTo(out io.Writer, nodes ...Node) error {
+	return writeNodesTo(w, &w.Builder, out, holdBlankLines, nodes)
+}
+
+// WriteFootnotes writes the definitions of the footnotes referenced so far.
+func (w *MarkdownWriter) WriteFootnotes(d *Document) {
+	if w.document.GetOption("f") == "nil" {
+		return
+	}
+	// iterate by index instead of ranging, since new footnotes can be added when writing the definitions
+	for ; w.footnotes.written < len(w.footnotes.list); w.footnotes.written++ {
+		i := w.footnotes.written
+		definition := w.footnotes.list[i]
+		if definition == nil {
+			w.document.logf(slog.LevelWarn, nil, "Missing footnote definition for [fn:%s] (#%d)", w.footnotes.name(i), i+1)
+			continue
+		}
+		content := strings.TrimRight(w.WriteNodesAsString(definition.Children...), "\n")
+		w.WriteString(indentLines(fmt.Sprintf("[^%d]: ", i+1), content, "    ") + "\n\n")
+	}
+}
+
+// holdBlankLines returns the number of trailing newlines of s that are held back by writers that remove the blank
+// lines at the end of their output in After - all but the one ending the last line.
+func holdBlankLines(s string, last bool) int {
+	trailing := len(s) - len(strings.TrimRight(s, "\n"))
+	if trailing == len(s) {
+		return trailing
+	}
+	return max(trailing-1, 0)
+}
+
+// indentLines returns s with the prefix prepended to its first line and indent prepended to all following non-blank
+// lines.
+func indentLines(prefix, s, indent string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if i != 0 && strings.TrimSpace(line) != "" {
+			lines[i] = indent + line
+		}
+	}
+	return prefix + strings.Join(lines, "\n")
+}
+
+func (w *MarkdownWriter) WriteComment(Comment)               {}
+func (w *MarkdownWriter) WritePropertyDrawer(PropertyDrawer) {}
+
+func (w *MarkdownWriter) WriteHeadline(h Headline) {
+	if h.IsExcluded(w.document) {
+		return
+	}
+	level := min((h.Lvl-1)+w.TopLevelHLevel, 6)
+	w.WriteString(strings.Repeat("#", level) + " ")
+	if w.document.GetOption("todo") != "nil" && h.Status != "" {
+		w.WriteString(h.Status + " ")
+	}
+	if w.document.GetOption("pri") != "nil" && h.Priority != "" {
+		w.WriteString(`\[#` + h.Priority + `\] `)
+	}
+	w.WriteString(strings.ReplaceAll(w.WriteNodesAsString(h.Title...), "\n", " "))
+	if w.document.GetOption("tags") != "nil" && len(h.Tags) != 0 {
+		w.WriteString(" `:" + strings.Join(h.Tags, ":") + ":`")
+	}
+	w.WriteString("\n\n")
+	WriteNodes(w, h.Children...)
+}
+
+func (w *MarkdownWriter) WriteBlock(b Block) {
+	content, params := w.blockContent(b.Name, b.Children), b.ParameterMap()
+	switch b.Name {
+	case "SRC":
+		if params[":exports"] == "results" || params[":exports"] == "none" {
+			break
+		}
+		lang := ""
+		if len(b.Parameters) >= 1 {
+			lang = strings.ToLower(b.Parameters[0])
+		}
+		w.writeCodeBlock(b.Switches().removeLabels(content), lang)
+	case "EXAMPLE":
+		w.writeCodeBlock(b.Switches().removeLabels(content), "")
+	case "EXPORT":
+		if exportsTo(w, b.Parameters) {
+			w.WriteString(content + "\n\n")
+		}
+	case "QUOTE":
+		if quote, cite := b.Citation(); cite != nil {
+			content = w.WriteNodesAsString(quote...) + "— " + strings.TrimSpace(w.WriteNodesAsString(cite...))
+		}
+		w.writeQuote(content)
+	case "VERSE":
+		w.WriteString(strings.TrimRight(w.verseString(b.Children), "\n") + "\n\n")
+	default:
+		w.WriteString(content)
+	}
+	if b.Result != nil && params[":exports"] != "code" && params[":exports"] != "none" {
+		WriteNodes(w, b.Result)
+	}
+}
+
+// writeCodeBlock writes a fenced code block - using a fence that is longer than the backtick runs in content.
+func (w *MarkdownWriter) writeCodeBlock(content, lang string) {
+	fence := strings.Repeat("`", max(3, longestRun(content, '`')+1))
+	w.WriteString(fence + lang + "\n" + content + "\n" + fence + "\n\n")
+}
+
+// longestRun returns the length of the longest run of c in s.
+func longestRun(s string, c rune) int {
+	longest, current := 0, 0
+	for _, r := range s {
+		if r == c {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return longest
+}
+
+func (w *MarkdownWriter) writeQuote(content string) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	w.WriteString(strings.Join(lines, "\n") + "\n\n")
+}
+
+// verseString returns the lines of a verse block - with hard line breaks and the leading whitespace of lines preserved.
+func (w *MarkdownWriter) verseString(nodes []Node) string {
+	out, lineStart := "", true
+	for _, n := range nodes {
+		if l, ok := n.(LineBreak); ok {
+			out += strings.Repeat("\\\n", l.Count)
+			lineStart = true
+			continue
+		} else if t, ok := n.(Text); ok && lineStart {
+			content := strings.TrimLeft(t.Content, " \t")
+			out += strings.Repeat("&nbsp;", len(t.Content)-len(content))
+			t.Content = content
+			n = t
+		}
+		out += w.WriteNodesAsString(n)
+		lineStart = false
+	}
+	return strings.TrimSuffix(strings.TrimRight(out, "\n"), `\`)
+}
+
+// blockContent returns the content of a block - the verbatim text of src, example and export blocks.
+func (w *MarkdownWriter) blockContent(name string, children []Node) string {
+	if !isRawTextBlock(name) {
+		return w.WriteNodesAsString(children...)
+	}
+	escape := w.escape
+	w.escape = false
+	out := w.WriteNodesAsString(children...)
+	w.escape = escape
+	return strings.TrimRightFunc(strings.TrimLeftFunc(out, IsNewLineChar), unicode.IsSpace)
+}
+
+func (w *MarkdownWriter) WriteResult(r Result) { WriteNodes(w, r.Node) }
+
+func (w *MarkdownWriter) WriteLatexBlock(b LatexBlock) {
+	w.WriteString(w.blockContent("EXPORT", b.Content) + "\n\n")
+}
+
+func (w *MarkdownWriter) WriteInlineBlock(b InlineBlock) {
+	content := w.blockContent(strings.ToUpper(b.Name), b.Children)
+	switch b.Name {
+	case "src":
+		w.writeCode(content)
+	case "export":
+		if exportsTo(w, b.Parameters) {
+			w.WriteString(content)
+		}
+	}
+}
+
+// writeCode writes a code span - using delimiters that are longer than the backtick runs in content.
+func (w *MarkdownWriter) writeCode(content string) {
+	delimiter := strings.Repeat("`", longestRun(content, '`')+1)
+	if strings.HasPrefix(content, "`") || strings.HasSuffix(content, "`") {
+		content = " " + content + " "
+	}
+	w.WriteString(delimiter + content + delimiter)
+}
+
+func (w *MarkdownWriter) WriteExample(e Example) {
+	lines := make([]string, len(e.Children))
+	for i, n := range e.Children {
+		lines[i] = w.blockContent("EXAMPLE", []Node{n})
+	}
+	w.writeCodeBlock(strings.Join(lines, "\n"), "")
+}
+
+func (w *MarkdownWriter) WriteDrawer(d Drawer) {
+	if w.document.ExportsDrawer(d.Name) {
+		WriteNodes(w, d.Children...)
+	}
+}
+
+func (w *MarkdownWriter) WriteKeyword(k Keyword) {
+	if k.Key == "HTML" {
+		w.WriteString(k.Value + "\n\n")
+	}
+}
+
+func (w *MarkdownWriter) WriteInclude(i Include) {
+	WriteNodes(w, i.Resolve())
+}
+
+func (w *MarkdownWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
+	w.footnotes.updateDefinition(f)
+}
+
+func (w *MarkdownWriter) WriteNodeWithMeta(n NodeWithMeta) {
+	WriteNodes(w, n.Node)
+	for _, caption := range n.Meta.Caption {
+		w.WriteString("*" + strings.TrimSpace(w.WriteNodesAsString(caption...)) + "*\n\n")
+	}
+}
+
+func (w *MarkdownWriter) WriteNodeWithName(n NodeWithName) {
+	WriteNodes(w, n.Node)
+}
+
+func (w *MarkdownWriter) WriteList(l List) {
+	number := 0
+	for _, item := range l.Items {
+		switch l.Kind {
+		case UnorderedList, DescriptiveList:
+			w.bullet = "- "
+		case OrderedList:
+			number++
+			if li, ok := item.(ListItem); ok && li.Value != "" {
+				number, _ = strconv.Atoi(ordinal(li.Value))
+			}
+			w.bullet = strconv.Itoa(number) + ". "
+		default:
+			w.reportError(fmt.Errorf("bad list kind %#v", l))
+			return
+		}
+		WriteNodes(w, item)
+	}
+	w.WriteString("\n")
+}
+
+func (w *MarkdownWriter) WriteListItem(li ListItem) {
+	bullet := w.listItemBullet()
+	w.writeListItem(bullet+markdownListItemStatuses[li.Status], w.listItemContent(li.Children), len(bullet))
+}
+
+func (w *MarkdownWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
+	bullet, term := w.listItemBullet(), "?"
+	if len(di.Term) != 0 {
+		term = w.WriteNodesAsString(di.Term...)
+	}
+	prefix := bullet + markdownListItemStatuses[di.Status] + "**" + strings.TrimSpace(term) + "**"
+	if details := w.listItemContent(di.Details); details != "" {
+		prefix += ": "
+		w.writeListItem(prefix, details, len(bullet))
+	} else {
+		w.writeListItem(prefix, "", len(bullet))
+	}
+}
+
+func (w *MarkdownWriter) listItemBullet() string {
+	if w.bullet == "" {
+		return "- "
+	}
+	return w.bullet
+}
+
+// writeListItem writes a list item - indenting the lines following the first by the width of its bullet.
+func (w *MarkdownWriter) writeListItem(prefix, content string, indent int) {
+	w.bullet = ""
+	w.WriteString(indentLines(prefix, content, strings.Repeat(" ", indent)) + "\n")
+}
+
+// listItemContent returns the content of a list item. Paragraphs are not separated by blank lines to keep lists tight.
+func (w *MarkdownWriter) listItemContent(children []Node) string {
+	parts := []string{}
+	for _, c := range children {
+		if p, ok := c.(Paragraph); ok {
+			if out := w.WriteNodesAsString(p.InlineNodes()...); out != "" {
+				parts = append(parts, out)
+			}
+		} else if out := strings.TrimRight(w.WriteNodesAsString(c), "\n"); out != "" {
+			parts = append(parts, out)
+		}
+	}
+	return strings.TrimLeft(strings.Join(parts, "\n"), "\n")
+}
+
+func (w *MarkdownWriter) WriteTable(t Table) {
+	rows := [][]string{}
+	headerRows := 0
+	for i, row := range t.Rows {
+		if len(row.Columns) == 0 {
+			if headerRows == 0 && i != 0 && len(rows) != 0 {
+				headerRows = len(rows)
+			}
+			continue
+		} else if row.IsSpecial {
+			continue
+		}
+		columns := make([]string, len(row.Columns))
+		for j, column := range row.Columns {
+			columns[j] = strings.ReplaceAll(strings.TrimSpace(w.WriteNodesAsString(column.Children...)), "|", `\|`)
+		}
+		rows = append(rows, columns)
+	}
+	if len(rows) == 0 {
+		return
+	}
+	// markdown tables require a header - the first row is used if the table does not have one
+	delimiters := make([]string, len(t.ColumnInfos))
+	for i, info := range t.ColumnInfos {
+		delimiters[i] = map[string]string{"left": ":---", "center": ":---:", "right": "---:"}[info.Align]
+		if delimiters[i] == "" {
+			delimiters[i] = "---"
+		}
+	}
+	if headerRows > 1 && headerRows < len(rows) {
+		// markdown only supports a single header row - the other rows are merged into it
+		for _, row := range rows[1:headerRows] {
+			for j := range min(len(row), len(rows[0])) {
+				rows[0][j] += " " + row[j]
+			}
+		}
+		rows = append(rows[:1], rows[headerRows:]...)
+	}
+	w.writeTableRow(rows[0], len(delimiters))
+	w.writeTableRow(delimiters, len(delimiters))
+	for _, row := range rows[1:] {
+		w.writeTableRow(row, len(delimiters))
+	}
+	w.WriteString("\n")
+}
+
+func (w *MarkdownWriter) writeTableRow(columns []string, count int) {
+	for len(columns) < count {
+		columns = append(columns, "")
+	}
+	w.WriteString("| " + strings.Join(columns, " | ") + " |\n")
+}
+
+func (w *MarkdownWriter) WriteHorizontalRule(h HorizontalRule) {
+	w.WriteString("---\n\n")
+}
+
+func (w *MarkdownWriter) WriteParagraph(p Paragraph) {
+	if out := w.WriteNodesAsString(p.InlineNodes()...); strings.TrimSpace(out) != "" {
+		w.WriteString(strings.TrimRight(strings.TrimLeftFunc(out, unicode.IsSpace), "\n") + "\n\n")
+	}
+}
+
+func (w *MarkdownWriter) WriteText(t Text) {
+	if !w.escape {
+		w.WriteString(t.Content)
+	} else if w.document.GetOption("e") == "nil" || t.IsRaw {
+		w.WriteString(markdownEscaper.Replace(t.Content))
+	} else {
+		w.WriteString(markdownEscaper.Replace(htmlEntityReplacer.Replace(t.Content)))
+	}
+}
+
+var markdownEmphasisTags = map[string][]string{
+	"*":   {"**", "**"},
+	"/":   {"*", "*"},
+	"+":   {"~~", "~~"},
+	"_":   {"<u>", "</u>"},
+	"_{}": {"<sub>", "</sub>"},
+	"^{}": {"<sup>", "</sup>"},
+}
+
+func (w *MarkdownWriter) WriteEmphasis(e Emphasis) {
+	if e.Kind == "=" || e.Kind == "~" {
+		escape := w.escape
+		w.escape = false
+		w.writeCode(w.WriteNodesAsString(e.Content...))
+		w.escape = escape
+		return
+	}
+	tags, ok := markdownEmphasisTags[e.Kind]
+	if !ok && len(e.Kind) == 1 {
+		tags = []string{"", ""} // custom marker, see Configuration.EmphasisComponents
+	} else if !ok {
+		w.reportError(fmt.Errorf("bad emphasis %#v", e))
+		return
+	}
+	w.WriteString(tags[0])
+	WriteNodes(w, e.Content...)
+	w.WriteString(tags[1])
+}
+
+func (w *MarkdownWriter) WriteLatexFragment(l LatexFragment) {
+	w.WriteString(l.OpeningPair + w.blockContent("EXPORT", l.Content) + l.ClosingPair)
+}
+
+func (w *MarkdownWriter) WriteStatisticToken(s StatisticToken) {
+	w.writeCode("[" + s.Content + "]")
+}
+
+func (w *MarkdownWriter) WriteLineBreak(l LineBreak) {
+	if w.document.GetOption("ealb") != "nil" && l.BetweenMultibyteCharacters {
+		return
+	}
+	if w.escape && w.document.PreserveLineBreaks() {
+		w.WriteString(strings.Repeat("\\\n", l.Count))
+	} else {
+		w.WriteString(strings.Repeat("\n", l.Count))
+	}
+}
+
+func (w *MarkdownWriter) WriteExplicitLineBreak(l ExplicitLineBreak) {
+	w.WriteString("\\\n")
+}
+
+func (w *MarkdownWriter) WriteFootnoteLink(l FootnoteLink) {
+	if w.document.GetOption("f") == "nil" {
+		return
+	}
+	i, _ := w.footnotes.add(l)
+	w.WriteString(fmt.Sprintf("[^%d]", i+1))
+}
+
+func (w *MarkdownWriter) WriteTimestamp(t Timestamp) {
+	if w.document.GetOption("<") == "nil" {
+		return
+	}
+	w.WriteString(`\<` + timestampString(t) + `\>`)
+}
+
+// timestampString returns the timestamp t without its angle brackets, e.g. "2024-01-01 Mon 10:00 +1w".
+func timestampString(t Timestamp) string {
+	out := t.Time.Format(timestampFormat)
+	if t.IsDate {
+		out = t.Time.Format(datestampFormat)
+	}
+	if t.Interval != "" {
+		out += " " + t.Interval
+	}
+	if t.Delay != "" {
+		out += " " + t.Delay
+	}
+	return out
+}
+
+func (w *MarkdownWriter) WriteRegularLink(l RegularLink) {
+	url := markdownURL(w.document.exportURL(l, ".md"))
+	switch l.Kind() {
+	case "image":
+		if l.Description == nil {
+			w.WriteString("![" + markdownEscaper.Replace(w.document.exportURL(l, ".md")) + "](" + url + ")")
+		} else {
+			description := markdownURL(strings.TrimPrefix(String(l.Description...), "file:"))
+			w.WriteString("[![" + description + "](" + description + ")](" + url + ")")
+		}
+	default:
+		description := markdownEscaper.Replace(url)
+		if l.Wiki.Page != "" {
+			description = markdownEscaper.Replace(l.Wiki.Page)
+		}
+		if l.Description != nil {
+			description = w.WriteNodesAsString(l.Description...)
+		}
+		w.WriteString("[" + description + "](" + url + ")")
+	}
+}
+
+// markdownURL returns url as markdown link destination - enclosed in angle brackets if it contains spaces or parens.
+func markdownURL(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
+
+func (w *MarkdownWriter) WriteMacro(m Macro) {
+	WriteNodes(w, inlineContent(w.document.expandMacro(m))...)
+}
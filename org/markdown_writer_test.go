@@ -0,0 +1,65 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+var markdownWriterTests = []struct {
+	name     string
+	input    string
+	expected string
+}{
+	{"title and headlines",
+		"#+TITLE: A *title*\n* TODO [#A] Headline :tag:\ntext\n** Sub\n",
+		"# A **title**\n\n## TODO \\[#A\\] Headline `:tag:`\n\ntext\n\n### Sub\n"},
+	{"emphasis and escaping",
+		"*bold* /italic/ _underline_ +strike+ =ver`b= ~code~ a_{sub} 2*3 [x] <y>\n",
+		"**bold** *italic* <u>underline</u> ~~strike~~ ``ver`b`` `code` a<sub>sub</sub> 2\\*3 \\[x\\] \\<y\\>\n"},
+	{"lists",
+		"- a\n  - [X] b\n- c\n\n1. one\n2. [@5] five\n3. six\n\n- term :: details\n",
+		"- a\n  - [x] b\n- c\n\n1. one\n5. five\n6. six\n\n- **term**: details\n"},
+	{"blocks",
+		"#+BEGIN_SRC go\nfmt.Println(\"```\")\n#+END_SRC\n#+BEGIN_QUOTE\nquoted\n#+END_QUOTE\n#+BEGIN_EXPORT md\n<kbd>raw</kbd>\n#+END_EXPORT\n#+BEGIN_EXPORT html\n<b>html</b>\n#+END_EXPORT\n: example\n",
+		"````go\nfmt.Println(\"```\")\n````\n\n> quoted\n\n<kbd>raw</kbd>\n\n```\nexample\n```\n"},
+	{"table",
+		"| a | b |\n|---+---|\n| 1 | x\\vert{}y |\n",
+		"| a | b |\n| ---: | --- |\n| 1 | x\\|y |\n"},
+	{"links",
+		"[[https://example.com][example]] [[file:notes.org]] [[./img.png]] https://example.com\n",
+		"[example](https://example.com) [notes.md](notes.md) ![./img.png](./img.png) [https://example.com](https://example.com)\n"},
+	{"footnotes",
+		"a[fn:1] b[fn:: inline] c[fn:1]\n\n[fn:1] the definition\n",
+		"a[^1] b[^2] c[^1]\n\n[^1]: the definition\n\n[^2]: inline\n"},
+	{"options",
+		"#+OPTIONS: todo:nil tags:nil <:nil\n* TODO Headline :tag:\n<2024-01-01 Mon> text\n",
+		"## Headline\n\ntext\n"},
+}
+
+func TestMarkdownWriter(t *testing.T) {
+	for _, test := range markdownWriterTests {
+		t.Run(test.name, func(t *testing.T) {
+			d := New().Silent().Parse(strings.NewReader(test.input), "./test.org")
+			if out, err := d.Write(NewMarkdownWriter()); err != nil {
+				t.Errorf("got error: %s", err)
+			} else if out != test.expected {
+				t.Errorf("%s", diff(out, test.expected))
+			}
+		})
+	}
+}
+
+func TestMarkdownWriterTestdata(t *testing.T) {
+	for _, path := range orgTestFiles() {
+		d := New().Silent().Parse(strings.NewReader(fileString(t, path)), path)
+		out, err := d.Write(NewMarkdownWriter())
+		if err != nil {
+			t.Errorf("%s: got error: %s", path, err)
+			continue
+		}
+		var streamed strings.Builder
+		if err := d.WriteStream(&streamed, NewMarkdownWriter()); err != nil || streamed.String() != out {
+			t.Errorf("%s: WriteStream (%v):\n%s", path, err, diff(streamed.String(), out))
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package org
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// mergedSettings are the buffer settings whose values are combined by Merge - the values of all other settings are
+// taken from the first document that defines them.
+var mergedSettings = map[string]bool{"TODO": true, "SEQ_TODO": true, "TYP_TODO": true, "OPTIONS": true}
+
+// Merge concatenates the parse inputs of the documents and parses the result into a new document with a unified
+// Outline - e.g. to build a book from one file per chapter. The Configuration and Path of the first document are used.
+//
+// Footnotes of later documents that use a name already used by an earlier document are renamed by appending the
+// (1-based) index of their document, e.g. [fn:1] becomes [fn:1-2]. Buffer settings defined by multiple documents
+// take the value of the first document - except for the TODO keywords and OPTIONS of all documents, which are combined.
+// The #+TITLE of the first document is the title of the merged document - the #+TITLE keywords of later documents
+// are dropped, as are the #+MACRO and #+LINK keywords of later documents that define a macro or link abbreviation
+// already defined by an earlier document. Documents without a parse input (see Document.Source) are pretty printed (see String).
+func Merge(docs ...*Document) *Document {
+	if len(docs) == 0 {
+		return New().Parse(strings.NewReader(""), "")
+	}
+	text, used, defined, previous := &strings.Builder{}, map[string]bool{}, map[string]bool{}, ""
+	for i, d := range docs {
+		input := d.mergeInput(used, defined, fmt.Sprintf("-%d", i+1), i == 0)
+		for name := range d.Macros {
+			defined["MACRO "+name] = true
+		}
+		for name := range d.Links {
+			defined["LINK "+name] = true
+		}
+		if input != "" && !strings.HasSuffix(input, "\n") {
+			input += "\n"
+		}
+		if last := strings.TrimSuffix(previous, "\n"); strings.TrimSpace(last[strings.LastIndexByte(last, '\n')+1:]) != "" {
+			text.WriteString("\n") // keep the last paragraph of the previous document from continuing into this one
+		}
+		text.WriteString(input)
+		previous = input
+	}
+	merged := docs[0].Configuration.Parse(strings.NewReader(text.String()), docs[0].Path)
+	for key := range merged.BufferSettings {
+		if mergedSettings[key] {
+			continue
+		}
+		for _, d := range docs {
+			if v, ok := d.BufferSettings[key]; ok {
+				merged.BufferSettings[key] = v
+				break
+			}
+		}
+	}
+	return merged
+}
+
+// mergeInput returns the parse input of the document with all footnote names in used renamed by appending suffix
+// and without #+TITLE keywords unless keepTitle is true. #+MACRO and #+LINK keywords redefining a name in defined
+// (see redefines) are dropped. The (new) footnote names of the document are added to used.
+func (d *Document) mergeInput(used, defined map[string]bool, suffix string, keepTitle bool) string {
+	drop := func(k Keyword) bool { return k.Key == "TITLE" && !keepTitle || redefines(k, defined) }
+	type edit struct {
+		start, end int
+		old, new   string
+	}
+	edits, names := []edit{}, map[string]bool{}
+	addEdit := func(edits []edit, n Node, name string) []edit {
+		if d.Source(n) == "" {
+			return edits
+		}
+		start := n.Position().StartOffset + len("[fn:")
+		return append(edits, edit{start, start + len(name), name, ""})
+	}
+	walkNodes(d.Nodes, func(n Node) {
+		switch n := n.(type) {
+		case FootnoteLink:
+			if n.Name != "" {
+				edits, names[n.Name] = addEdit(edits, n, n.Name), true
+			}
+		case FootnoteDefinition:
+			if !n.Inline {
+				edits, names[n.Name] = addEdit(edits, n, n.Name), true
+			}
+		}
+	})
+	renamed := map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		newName := name
+		for i := 1; used[newName]; i++ {
+			newName = name + strings.Repeat(suffix, i)
+		}
+		renamed[name], used[newName] = newName, true
+	}
+	if d.input == "" {
+		return String(Walk(d, Visitor{Exit: func(c *Cursor) {
+			switch n := c.Node().(type) {
+			case Keyword:
+				if drop(n) {
+					c.Delete()
+				}
+			case FootnoteLink:
+				if n.Name != "" {
+					n.Name = renamed[n.Name]
+					c.Replace(n)
+				}
+			case FootnoteDefinition:
+				if newName, ok := renamed[n.Name]; ok {
+					n.Name = newName
+					c.Replace(n)
+				}
+			}
+		}})...)
+	}
+	for i, e := range edits {
+		edits[i].new = renamed[e.old]
+	}
+	walkNodes(d.Nodes, func(n Node) {
+		if k, ok := n.(Keyword); ok && drop(k) && d.Source(k) != "" {
+			start, end := strings.LastIndexByte(d.input[:k.Pos.StartOffset], '\n')+1, k.Pos.EndOffset
+			if i := strings.IndexByte(d.input[end:], '\n'); i != -1 {
+				end += i + 1
+			} else {
+				end = len(d.input)
+			}
+			edits = append(edits, edit{start, end, d.input[start:end], ""})
+		}
+	})
+	slices.SortFunc(edits, func(a, b edit) int { return b.start - a.start })
+	input := d.input
+	for _, e := range edits {
+		if e.end <= len(input) && input[e.start:e.end] == e.old && e.new != e.old {
+			input = input[:e.start] + e.new + input[e.end:]
+		}
+	}
+	return input
+}
+
+// redefines returns true if k is a #+MACRO or #+LINK keyword defining a name in defined, e.g. "MACRO name".
+func redefines(k Keyword, defined map[string]bool) bool {
+	if k.Key != "MACRO" && k.Key != "LINK" {
+		return false
+	}
+	name, _, _ := strings.Cut(k.Value, " ")
+	return defined[k.Key+" "+name]
+}
@@ -0,0 +1,46 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	parse := func(input, path string) *Document { return New().Silent().Parse(strings.NewReader(input), path) }
+	a := parse("#+TITLE: Book\n#+TODO: TODO | DONE\n* Chapter 1\nText[fn:1]\n\n[fn:1] First\n", "./a.org")
+	b := parse("#+TITLE: Chapter\n#+TODO: WAIT | CANCELED\n* WAIT Chapter 2\nMore[fn:1] and[fn:note]\n\n[fn:1] Second\n[fn:note] Note", "./b.org")
+	c := parse("trailing paragraph", "./c.org")
+	stream := New().Silent().ParseStream(strings.NewReader("#+TITLE: Stream\n* Streamed[fn:1]\n\n[fn:1] Streamed note\n"), func(Node) bool { return true })
+	merged := Merge(a, b, c, parse("continued", "./d.org"), stream)
+	expected := "#+TITLE: Book\n#+TODO: TODO | DONE\n* Chapter 1\nText[fn:1]\n\n[fn:1] First\n\n" +
+		"#+TODO: WAIT | CANCELED\n* WAIT Chapter 2\nMore[fn:1-2] and[fn:note]\n\n[fn:1-2] Second\n[fn:note] Note\n\n" +
+		"trailing paragraph\n\ncontinued\n\n* Streamed[fn:1-5]\n\n[fn:1-5] Streamed note\n"
+	if merged.input != expected {
+		t.Errorf("got:\n%s", diff(merged.input, expected))
+	}
+	if title, todo := merged.Get("TITLE"), merged.Get("TODO"); title != "Book" || todo != "TODO | DONE\nWAIT | CANCELED" {
+		t.Errorf("bad buffer settings: title %q, todo %q", title, todo)
+	}
+	if len(merged.Outline.Children) != 3 || merged.Outline.Children[1].Headline.Status != "WAIT" || merged.Path != "./a.org" {
+		t.Errorf("bad outline %#v", merged.Outline.Children)
+	}
+	if out, err := merged.Write(NewHTMLWriter()); err != nil || !strings.Contains(out, "Second") || strings.Count(out, `class="footnote-definition"`) != 4 {
+		t.Errorf("expected four footnote definitions, got %v:\n%s", err, out)
+	}
+}
+
+func TestMergeDefinitions(t *testing.T) {
+	parse := func(input, path string) *Document { return New().Silent().Parse(strings.NewReader(input), path) }
+	a := parse("#+MACRO: m a$1\n#+LINK: gh https://github.com/%s\n{{{m(1)}}} [[gh:foo]]\n", "./a.org")
+	b := parse("#+MACRO: m b$1\n#+MACRO: n b$1\n#+LINK: gh https://example.com/%s\n{{{m(2)}}} [[gh:bar]]\n{{{n(3)}}}\n", "./b.org")
+	merged := Merge(a, b)
+	expected := "#+MACRO: m a$1\n#+LINK: gh https://github.com/%s\n{{{m(1)}}} [[gh:foo]]\n\n#+MACRO: n b$1\n{{{m(2)}}} [[gh:bar]]\n{{{n(3)}}}\n"
+	if merged.input != expected {
+		t.Errorf("got:\n%s", diff(merged.input, expected))
+	}
+	out, err := merged.Write(NewHTMLWriter())
+	if err != nil || !strings.Contains(out, "a1") || !strings.Contains(out, "a2") || !strings.Contains(out, "b3") ||
+		!strings.Contains(out, "https://github.com/foo") || !strings.Contains(out, "https://github.com/bar") {
+		t.Errorf("expected the definitions of the first document, got %v:\n%s", err, out)
+	}
+}
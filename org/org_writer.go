@@ -1,7 +1,13 @@
 package org
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"maps"
+	"reflect"
 	"regexp"
 	"strings"
 	"unicode"
@@ -9,15 +15,65 @@ import (
 )
 
 // OrgWriter export an org document into pretty printed org document.
+// An OrgWriter must not be used by multiple goroutines at once - String can be used concurrently.
 type OrgWriter struct {
 	ExtendingWriter Writer
-	TagsColumn      int
+	// TagsColumn is the column headline tags are aligned to. Tags are separated from the title by a single space
+	// if the title is too long.
+	TagsColumn int
+	// ListIndentWidth is the number of spaces the content and sublists of list items are indented by. Defaults to 0,
+	// i.e. the content is aligned with the text following the bullet.
+	ListIndentWidth int
+	// PadTables pads the cells of tables to the width of their column. Defaults to true.
+	PadTables bool
+	// AlignTables realigns tables via AlignTable before writing them, i.e. column widths are recomputed from the
+	// (possibly modified) content of the cells and East Asian wide characters are counted as two columns.
+	AlignTables bool
+	// BlankLinesBetweenSections is the number of blank lines written before each headline (except at the start
+	// of the document). Defaults to -1, i.e. blank lines are written as they were parsed.
+	BlankLinesBetweenSections int
+	// LowercaseKeywords writes keywords (#+title:), blocks (#+begin_src) and related keywords (#+caption:, #+name:,
+	// #+results:) in lowercase.
+	LowercaseKeywords bool
+	// Lossless reproduces the original source of headlines and top level section elements (paragraphs, lists, tables,
+	// blocks, ...) that were not modified after parsing - preserving indentation, blank lines, tag alignment and table
+	// spacing. Only modified nodes (i.e. nodes whose pretty printed form changed) are pretty printed.
+	Lossless bool
+	// PreserveLineEndings writes documents with the line ending of their parse input (see Document.LineEnding) and
+	// only ends them with a newline if the parse input did (see Document.FinalNewline).
+	PreserveLineEndings bool
+	// NodeWriters write custom nodes (e.g. the nodes of InlineParsers), keyed by their type. See AddNodeWriter.
+	NodeWriters map[reflect.Type]func(*OrgWriter, Node)
 
 	strings.Builder
-	indent string
-}
-
-var exampleBlockUnescapeRegexp = regexp.MustCompile(`(^|\n)([ \t]*)(\*|,\*|#\+|,#\+)`)
+	// newlines is the number of trailing newlines written but not yet moved to the Builder - they are held back so
+	// writeSectionSeparator can replace the blank lines preceding a headline without rewriting the output.
+	newlines int
+	indent   string
+	original *Document
+	// originals maps the positions of the unmodified headlines and section elements of the original document
+	// to their pretty printed form and source lines. See Lossless.
+	originals map[Position]originalNode
+	// lineEnding is the line ending of the document being written ("" for "\n") and noFinalNewline is true if it
+	// must not end with a newline. See PreserveLineEndings.
+	lineEnding     string
+	noFinalNewline bool
+	err            error // err is the first error of writing the document - see errorReporter.
+	// paragraphBreakMode is the ParagraphBreakMode of the document being written and inParagraph is true while the
+	// inline nodes of a paragraph are written - see WriteLineBreak.
+	paragraphBreakMode ParagraphBreakMode
+	inParagraph        bool
+	// forked is true for the writers returned by Fork and separated is true if a fork wrote a section separator
+	// at the start of its output - i.e. the separator must be written by Join.
+	forked, separated bool
+}
+
+type originalNode struct {
+	fingerprint string
+	start, end  int
+}
+
+var escapableBlockLineRegexp = regexp.MustCompile(`(?m)^([ \t]*)(,*(\*|#\+))`)
 
 var emphasisOrgBorders = map[string][]string{
 	"_":   {"_", "_"},
@@ -32,10 +88,15 @@ var emphasisOrgBorders = map[string][]string{
 
 func NewOrgWriter() *OrgWriter {
 	return &OrgWriter{
-		TagsColumn: 77,
+		TagsColumn:                77,
+		PadTables:                 true,
+		BlankLinesBetweenSections: -1,
 	}
 }
 
+// Backend implements Writer. The OrgWriter writes the export blocks and snippets of all backends back as Org source.
+func (w *OrgWriter) Backend() string { return "" }
+
 func (w *OrgWriter) WriterWithExtensions() Writer {
 	if w.ExtendingWriter != nil {
 		return w.ExtendingWriter
@@ -43,19 +104,210 @@ func (w *OrgWriter) WriterWithExtensions() Writer {
 	return w
 }
 
-func (w *OrgWriter) Before(d *Document) {}
-func (w *OrgWriter) After(d *Document)  {}
+// AddNodeWriter registers f to write all nodes with the same type as node, e.g. the nodes of an InlineParser.
+func (w *OrgWriter) AddNodeWriter(node Node, f func(*OrgWriter, Node)) *OrgWriter {
+	if w.NodeWriters == nil {
+		w.NodeWriters = map[reflect.Type]func(*OrgWriter, Node){}
+	}
+	w.NodeWriters[reflect.TypeOf(node)] = f
+	return w
+}
+
+// WriteCustomNode implements CustomNodeWriter using the NodeWriters. Emoji, hashtags and mentions are written unless
+// overridden. Other nodes are written as their Org mode source text, i.e. using their String method - e.g. nodes of
+// a KeywordHandler that embed the Keyword are written as the keyword. This allows String and the packages built on it
+// to handle documents with custom nodes - which must therefore not implement their String method using String.
+func (w *OrgWriter) WriteCustomNode(n Node) bool {
+	if f, ok := w.NodeWriters[reflect.TypeOf(n)]; ok {
+		f(w, n)
+		return true
+	}
+	switch n := n.(type) {
+	case Emoji:
+		w.WriteString(":" + n.Shortcode + ":")
+	case Hashtag:
+		w.WriteString("#" + n.Name)
+	case Mention:
+		w.WriteString("@" + n.Name)
+	default:
+		w.WriteString(n.String())
+	}
+	return true
+}
+
+func (w *OrgWriter) Before(d *Document) {
+	w.original, w.originals, w.err = nil, nil, nil
+	w.lineEnding, w.noFinalNewline = "", false
+	if w.PreserveLineEndings && d.LineEnding() != "\n" {
+		w.lineEnding = d.LineEnding()
+	}
+	w.noFinalNewline = w.PreserveLineEndings && !d.FinalNewline()
+	w.paragraphBreakMode = d.ParagraphBreakMode
+	if !w.Lossless || d.source == nil || d.sourceLines == nil {
+		return
+	}
+	original := d.parseOriginal()
+	if original == nil {
+		return
+	}
+	w.original, w.originals = d, map[Position]originalNode{}
+	w.addOriginals(original.Nodes)
+}
+
+// parseOriginal parses the source of the document again to find the nodes modified after parsing - see
+// OrgWriter.Lossless. The lines of the document are reused and keywords have no side effects, i.e. setup files are
+// not loaded (the buffer settings of d are used instead) and nothing is logged. It returns nil if parsing failed.
+func (d *Document) parseOriginal() (original *Document) {
+	c := *d.Configuration
+	c.Log, c.StructuredLog, c.Hooks, c.FS = log.New(io.Discard, "", 0), nil, Hooks{}, nil
+	c.ReadFile = func(string) ([]byte, error) { return nil, errors.New("setup files are not loaded") }
+	c.DefaultSettings = maps.Clone(d.DefaultSettings)
+	maps.Copy(c.DefaultSettings, d.BufferSettings)
+	original = c.newDocument(d.Path)
+	original.input, original.source, original.lineOffsets, original.firstLine = d.input, d.source, d.lineOffsets, d.firstLine
+	defer func() {
+		if recover() != nil {
+			original = nil
+		}
+	}()
+	original.tokens = original.tokenizeLines(0, len(original.source))
+	_, original.Nodes, _ = original.parseTopLevel(len(original.tokens))
+	return original
+}
+
+func (w *OrgWriter) After(d *Document) {
+	s := w.String()
+	if w.noFinalNewline {
+		s = strings.TrimSuffix(s, "\n")
+	}
+	if w.lineEnding != "" {
+		s = strings.ReplaceAll(s, "\n", w.lineEnding)
+	}
+	w.Reset()
+	w.WriteString(s)
+}
+
+// writeError implements errorReporter.
+func (w *OrgWriter) writeError() error { return w.err }
+
+// reportError implements errorReporter.
+func (w *OrgWriter) reportError(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// Fork implements ParallelWriter. Writers with an ExtendingWriter cannot be forked.
+func (w *OrgWriter) Fork(sections [][]Node) []Writer {
+	if w.ExtendingWriter != nil {
+		return nil
+	}
+	forks := make([]Writer, len(sections))
+	for i := range sections {
+		fork := *w
+		fork.Builder, fork.newlines, fork.indent, fork.forked = strings.Builder{}, 0, "", true
+		forks[i] = &fork
+	}
+	return forks
+}
+
+// Join implements ParallelWriter.
+func (w *OrgWriter) Join(fork Writer) bool {
+	f := fork.(*OrgWriter)
+	if f.separated {
+		w.writeSectionSeparator()
+	}
+	w.WriteString(f.String())
+	return true
+}
+
+// addOriginals adds the given section elements (and the elements of the sections of headlines) to originals.
+// The positions of the nodes of the original parse equal those of the document - see parseOriginal.
+func (w *OrgWriter) addOriginals(nodes []Node) {
+	for _, n := range nodes {
+		lines, ok := w.original.sourceLines[n.Position()]
+		if !ok {
+			continue
+		}
+		if h, ok := n.(Headline); ok {
+			if len(h.Children) != 0 {
+				w.addOriginals(h.Children)
+				lines[1] = w.original.sourceLines[h.Children[0].Position()][0] - 1
+			}
+		}
+		w.originals[n.Position()] = originalNode{fingerprint(n), lines[0], lines[1]}
+	}
+}
+
+// fingerprint returns the pretty printed form of the node - excluding the children of headlines.
+func fingerprint(n Node) string {
+	if h, ok := n.(Headline); ok {
+		h.Children = nil
+		n = h
+	}
+	return String(n)
+}
+
+// writeOriginal writes the original source of the node and returns true if the node was not modified after
+// parsing. See Lossless.
+func (w *OrgWriter) writeOriginal(n Node) bool {
+	if w.originals == nil || w.indent != "" {
+		return false
+	}
+	original, ok := w.originals[n.Position()]
+	if !ok || original.fingerprint != fingerprint(n) {
+		return false
+	}
+	for _, line := range w.original.source[original.start : original.end+1] {
+		w.WriteString(line + "\n")
+	}
+	return true
+}
+
+// WriteNodesTo implements Writer. The last line of the output is only moved to out once all nodes have been
+// written - the blank lines between sections are adjusted when the next headline is written.
+func (w *OrgWriter) WriteNodesTo(out io.Writer, nodes ...Node) error {
+	if w.lineEnding != "" {
+		out = lineEndingWriter{out, w.lineEnding}
+	}
+	return writeNodesTo(w, w, out, func(s string, last bool) int {
+		if last && w.noFinalNewline && strings.HasSuffix(s, "\n") {
+			return 1 // the final newline is removed by After
+		} else if last {
+			return 0
+		}
+		return len(s) - (strings.LastIndexByte(strings.TrimRight(s, "\n"), '\n') + 1)
+	}, nodes)
+}
+
+// lineEndingWriter replaces the newlines written to it with lineEnding.
+type lineEndingWriter struct {
+	io.Writer
+	lineEnding string
+}
+
+func (w lineEndingWriter) Write(p []byte) (int, error) {
+	if _, err := w.Writer.Write(bytes.ReplaceAll(p, []byte("\n"), []byte(w.lineEnding))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
 
 func (w *OrgWriter) WriteNodesAsString(nodes ...Node) string {
-	builder := w.Builder
-	w.Builder = strings.Builder{}
+	builder, newlines := w.Builder, w.newlines
+	w.Builder, w.newlines = strings.Builder{}, 0
 	WriteNodes(w, nodes...)
 	out := w.String()
-	w.Builder = builder
+	w.Builder, w.newlines = builder, newlines
 	return out
 }
 
 func (w *OrgWriter) WriteHeadline(h Headline) {
+	w.writeSectionSeparator()
+	if w.writeOriginal(h) {
+		WriteNodes(w, h.Children...)
+		return
+	}
 	start := w.Len()
 	w.WriteString(strings.Repeat("*", h.Lvl))
 	if h.Status != "" {
@@ -84,24 +336,95 @@ func (w *OrgWriter) WriteHeadline(h Headline) {
 	WriteNodes(w, h.Children...)
 }
 
+// writeSectionSeparator replaces the blank lines preceding a headline with BlankLinesBetweenSections blank lines.
+func (w *OrgWriter) writeSectionSeparator() {
+	if w.BlankLinesBetweenSections < 0 {
+		return
+	} else if w.Len() == 0 {
+		w.separated = w.separated || w.forked
+		return
+	}
+	w.newlines = 1 + w.BlankLinesBetweenSections
+}
+
+// WriteString writes s to the Builder. Trailing newlines are held back until the next non-newline output - see
+// writeSectionSeparator.
+func (w *OrgWriter) WriteString(s string) (int, error) {
+	if content := strings.TrimRight(s, "\n"); content != "" {
+		for ; w.newlines > 0; w.newlines-- {
+			w.Builder.WriteByte('\n')
+		}
+		w.Builder.WriteString(content)
+		w.newlines = len(s) - len(content)
+	} else {
+		w.newlines += len(s)
+	}
+	return len(s), nil
+}
+
+// Write implements io.Writer - see WriteString.
+func (w *OrgWriter) Write(p []byte) (int, error) { return w.WriteString(string(p)) }
+
+// String returns the output written so far.
+func (w *OrgWriter) String() string {
+	if w.newlines == 0 {
+		return w.Builder.String()
+	}
+	return w.Builder.String() + strings.Repeat("\n", w.newlines)
+}
+
+// Len returns the length of the output written so far.
+func (w *OrgWriter) Len() int { return w.Builder.Len() + w.newlines }
+
+// Reset discards the output written so far.
+func (w *OrgWriter) Reset() { w.Builder.Reset(); w.newlines = 0 }
+
+// keyword returns the keyword (e.g. "BEGIN_SRC") in the case configured via LowercaseKeywords.
+func (w *OrgWriter) keyword(k string) string {
+	if w.LowercaseKeywords {
+		return strings.ToLower(k)
+	}
+	return k
+}
+
+// listItemIndent returns the indentation of the content of a list item with the given bullet. See ListIndentWidth.
+func (w *OrgWriter) listItemIndent(bullet string) string {
+	if w.ListIndentWidth > 0 {
+		return w.indent + strings.Repeat(" ", w.ListIndentWidth)
+	}
+	return w.indent + strings.Repeat(" ", len(bullet)+1)
+}
+
 func (w *OrgWriter) WriteBlock(b Block) {
-	w.WriteString(w.indent + "#+BEGIN_" + b.Name)
-	if len(b.Parameters) != 0 {
-		w.WriteString(" " + strings.Join(b.Parameters, " "))
+	if w.writeOriginal(b) {
+		return
+	}
+	w.WriteString(w.indent + "#+" + w.keyword("BEGIN_"+b.Name))
+	for _, p := range b.Parameters {
+		if p != "" { // switches without arguments (e.g. -r) have an empty value
+			w.WriteString(" " + p)
+		}
 	}
 	w.WriteString("\n")
-	if isRawTextBlock(b.Name) {
+	preserveIndentation := b.Switches().PreserveIndentation // the content contains the indentation of the block (-i)
+	lines := isRawTextBlock(b.Name) || b.Name == "VERSE"    // the content consists of lines rather than of nodes
+	if lines && !preserveIndentation {
 		w.WriteString(w.indent)
 	}
+	originalIndent := w.indent
+	if preserveIndentation {
+		w.indent = ""
+	}
 	content := w.WriteNodesAsString(b.Children...)
-	if b.Name == "EXAMPLE" || (b.Name == "SRC" && len(b.Parameters) >= 1 && b.Parameters[0] == "org") {
-		content = exampleBlockUnescapeRegexp.ReplaceAllString(content, "$1$2,$3")
+	w.indent = originalIndent
+	if isRawTextBlock(b.Name) {
+		content = escapableBlockLineRegexp.ReplaceAllString(content, "$1,$2")
 	}
 	w.WriteString(content)
-	if !isRawTextBlock(b.Name) {
+	if !lines || preserveIndentation {
 		w.WriteString(w.indent)
 	}
-	w.WriteString("#+END_" + b.Name + "\n")
+	w.WriteString("#+" + w.keyword("END_"+b.Name) + "\n")
 
 	if b.Result != nil {
 		w.WriteString("\n")
@@ -110,13 +433,19 @@ func (w *OrgWriter) WriteBlock(b Block) {
 }
 
 func (w *OrgWriter) WriteLatexBlock(b LatexBlock) {
+	if w.writeOriginal(b) {
+		return
+	}
 	w.WriteString(w.indent)
 	WriteNodes(w, b.Content...)
 	w.WriteString("\n")
 }
 
 func (w *OrgWriter) WriteResult(r Result) {
-	w.WriteString("#+RESULTS:\n")
+	if w.writeOriginal(r) {
+		return
+	}
+	w.WriteString("#+" + w.keyword("RESULTS") + ":\n")
 	WriteNodes(w, r.Node)
 }
 
@@ -138,6 +467,9 @@ func (w *OrgWriter) WriteInlineBlock(b InlineBlock) {
 }
 
 func (w *OrgWriter) WriteDrawer(d Drawer) {
+	if w.writeOriginal(d) {
+		return
+	}
 	w.WriteString(w.indent + ":" + d.Name + ":\n")
 	WriteNodes(w, d.Children...)
 	w.WriteString(w.indent + ":END:\n")
@@ -156,6 +488,9 @@ func (w *OrgWriter) WritePropertyDrawer(d PropertyDrawer) {
 }
 
 func (w *OrgWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
+	if w.writeOriginal(f) {
+		return
+	}
 	w.WriteString(fmt.Sprintf("[fn:%s]", f.Name))
 	content := w.WriteNodesAsString(f.Children...)
 	if content != "" && !unicode.IsSpace(rune(content[0])) {
@@ -165,7 +500,13 @@ func (w *OrgWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
 }
 
 func (w *OrgWriter) WriteParagraph(p Paragraph) {
-	content := w.WriteNodesAsString(p.Children...)
+	if w.writeOriginal(p) {
+		return
+	}
+	inParagraph := w.inParagraph
+	w.inParagraph = true
+	content := w.WriteNodesAsString(p.InlineNodes()...)
+	w.inParagraph = inParagraph
 	if len(content) > 0 && content[0] != '\n' {
 		w.WriteString(w.indent)
 	}
@@ -173,6 +514,9 @@ func (w *OrgWriter) WriteParagraph(p Paragraph) {
 }
 
 func (w *OrgWriter) WriteExample(e Example) {
+	if w.writeOriginal(e) {
+		return
+	}
 	for _, n := range e.Children {
 		w.WriteString(w.indent + ":")
 		if content := w.WriteNodesAsString(n); content != "" {
@@ -183,7 +527,10 @@ func (w *OrgWriter) WriteExample(e Example) {
 }
 
 func (w *OrgWriter) WriteKeyword(k Keyword) {
-	w.WriteString(w.indent + "#+" + k.Key + ":")
+	if w.writeOriginal(k) {
+		return
+	}
+	w.WriteString(w.indent + "#+" + w.keyword(k.Key) + ":")
 	if k.Value != "" {
 		w.WriteString(" " + k.Value)
 	}
@@ -191,39 +538,56 @@ func (w *OrgWriter) WriteKeyword(k Keyword) {
 }
 
 func (w *OrgWriter) WriteInclude(i Include) {
+	if w.writeOriginal(i) {
+		return
+	}
 	w.WriteKeyword(i.Keyword)
 }
 
 func (w *OrgWriter) WriteNodeWithMeta(n NodeWithMeta) {
+	if w.writeOriginal(n) {
+		return
+	}
 	for _, ns := range n.Meta.Caption {
-		w.WriteString("#+CAPTION: ")
+		w.WriteString("#+" + w.keyword("CAPTION") + ": ")
 		WriteNodes(w, ns...)
 		w.WriteString("\n")
 	}
 	for _, attributes := range n.Meta.HTMLAttributes {
-		w.WriteString("#+ATTR_HTML: ")
+		w.WriteString("#+" + w.keyword("ATTR_HTML") + ": ")
 		w.WriteString(strings.Join(attributes, " ") + "\n")
 	}
 	WriteNodes(w, n.Node)
 }
 
 func (w *OrgWriter) WriteNodeWithName(n NodeWithName) {
-	w.WriteString(fmt.Sprintf("#+NAME: %s\n", n.Name))
+	if w.writeOriginal(n) {
+		return
+	}
+	w.WriteString(fmt.Sprintf("#+%s: %s\n", w.keyword("NAME"), n.Name))
 	WriteNodes(w, n.Node)
 }
 
 func (w *OrgWriter) WriteComment(c Comment) {
+	if w.writeOriginal(c) {
+		return
+	}
 	w.WriteString(w.indent + "# " + c.Content + "\n")
 }
 
-func (w *OrgWriter) WriteList(l List) { WriteNodes(w, l.Items...) }
+func (w *OrgWriter) WriteList(l List) {
+	if w.writeOriginal(l) {
+		return
+	}
+	WriteNodes(w, l.Items...)
+}
 
 func (w *OrgWriter) WriteListItem(li ListItem) {
-	originalBuilder, originalIndent := w.Builder, w.indent
-	w.Builder, w.indent = strings.Builder{}, w.indent+strings.Repeat(" ", len(li.Bullet)+1)
+	originalBuilder, originalNewlines, originalIndent := w.Builder, w.newlines, w.indent
+	w.Builder, w.newlines, w.indent = strings.Builder{}, 0, w.listItemIndent(li.Bullet)
 	WriteNodes(w, li.Children...)
 	content := strings.TrimPrefix(w.String(), w.indent)
-	w.Builder, w.indent = originalBuilder, originalIndent
+	w.Builder, w.newlines, w.indent = originalBuilder, originalNewlines, originalIndent
 	w.WriteString(w.indent + li.Bullet)
 	if li.Value != "" {
 		w.WriteString(fmt.Sprintf(" [@%s]", li.Value))
@@ -239,22 +603,25 @@ func (w *OrgWriter) WriteListItem(li ListItem) {
 }
 
 func (w *OrgWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
-	indent := w.indent + strings.Repeat(" ", len(di.Bullet)+1)
+	indent := w.listItemIndent(di.Bullet)
 	w.WriteString(w.indent + di.Bullet)
 	if di.Status != "" {
 		w.WriteString(fmt.Sprintf(" [%s]", di.Status))
-		indent = indent + strings.Repeat(" ", len(di.Status)+3)
+		if w.ListIndentWidth <= 0 {
+			indent = indent + strings.Repeat(" ", len(di.Status)+3)
+		}
 	}
 	if len(di.Term) != 0 {
 		term := w.WriteNodesAsString(di.Term...)
 		w.WriteString(" " + term + " ::")
-		indent = indent + strings.Repeat(" ", len(term)+4)
+		if w.ListIndentWidth <= 0 {
+			indent = indent + strings.Repeat(" ", len(term)+4)
+		}
+	}
+	details := w.writeDetails(di.Details, indent)
+	if plainIndent := w.listItemIndent(di.Bullet); len(details) > 0 && details[0] == '\n' && indent != plainIndent {
+		details = w.writeDetails(di.Details, plainIndent) // details starting on the next line are not aligned to the term
 	}
-	originalBuilder, originalIndent := w.Builder, w.indent
-	w.Builder, w.indent = strings.Builder{}, indent
-	WriteNodes(w, di.Details...)
-	details := strings.TrimPrefix(w.String(), w.indent)
-	w.Builder, w.indent = originalBuilder, originalIndent
 	if len(details) > 0 && details[0] == '\n' {
 		w.WriteString(details)
 	} else {
@@ -262,13 +629,33 @@ func (w *OrgWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
 	}
 }
 
+func (w *OrgWriter) writeDetails(details []Node, indent string) string {
+	originalBuilder, originalNewlines, originalIndent := w.Builder, w.newlines, w.indent
+	w.Builder, w.newlines, w.indent = strings.Builder{}, 0, indent
+	WriteNodes(w, details...)
+	out := strings.TrimPrefix(w.String(), w.indent)
+	w.Builder, w.newlines, w.indent = originalBuilder, originalNewlines, originalIndent
+	return out
+}
+
 func (w *OrgWriter) WriteTable(t Table) {
+	if w.writeOriginal(t) {
+		return
+	}
+	width := utf8.RuneCountInString
+	if w.AlignTables {
+		t, width = AlignTable(t), stringWidth
+	}
 	for _, row := range t.Rows {
 		w.WriteString(w.indent)
 		if len(row.Columns) == 0 {
 			w.WriteString(`|`)
 			for i := 0; i < len(t.ColumnInfos); i++ {
-				w.WriteString(strings.Repeat("-", t.ColumnInfos[i].Len+2))
+				if w.PadTables {
+					w.WriteString(strings.Repeat("-", t.ColumnInfos[i].Len+2))
+				} else {
+					w.WriteString("--")
+				}
 				if i < len(t.ColumnInfos)-1 {
 					w.WriteString("+")
 				}
@@ -283,8 +670,8 @@ func (w *OrgWriter) WriteTable(t Table) {
 				if content == "" {
 					content = " "
 				}
-				n := column.Len - utf8.RuneCountInString(content)
-				if n < 0 {
+				n := column.Len - width(content)
+				if n < 0 || !w.PadTables {
 					n = 0
 				}
 				if column.Align == "center" {
@@ -305,6 +692,9 @@ func (w *OrgWriter) WriteTable(t Table) {
 }
 
 func (w *OrgWriter) WriteHorizontalRule(hr HorizontalRule) {
+	if w.writeOriginal(hr) {
+		return
+	}
 	w.WriteString(w.indent + "-----\n")
 }
 
@@ -312,8 +702,12 @@ func (w *OrgWriter) WriteText(t Text) { w.WriteString(t.Content) }
 
 func (w *OrgWriter) WriteEmphasis(e Emphasis) {
 	borders, ok := emphasisOrgBorders[e.Kind]
+	if !ok && len(e.Kind) == 1 {
+		borders, ok = []string{e.Kind, e.Kind}, true // custom marker, see Configuration.EmphasisComponents
+	}
 	if !ok {
-		panic(fmt.Sprintf("bad emphasis %#v", e))
+		w.reportError(fmt.Errorf("bad emphasis %#v", e))
+		return
 	}
 	w.WriteString(borders[0])
 	WriteNodes(w, e.Content...)
@@ -330,8 +724,19 @@ func (w *OrgWriter) WriteStatisticToken(s StatisticToken) {
 	w.WriteString(fmt.Sprintf("[%s]", s.Content))
 }
 
+// WriteLineBreak writes the line breaks of paragraphs according to the ParagraphBreakMode of the document:
+// ParagraphBreakPreserve turns them into explicit line breaks (\\) and ParagraphBreakJoin joins the lines. The \n
+// export option does not need to be applied - it is part of the written #+OPTIONS.
 func (w *OrgWriter) WriteLineBreak(l LineBreak) {
-	w.WriteString(strings.Repeat("\n"+w.indent, l.Count))
+	if w.inParagraph && w.paragraphBreakMode == ParagraphBreakPreserve {
+		w.WriteString(`\\`)
+	} else if w.inParagraph && w.paragraphBreakMode == ParagraphBreakJoin && l.Count == 1 {
+		if !l.BetweenMultibyteCharacters {
+			w.WriteString(" ")
+		}
+		return
+	}
+	w.WriteString(strings.Repeat("\n", l.Count) + w.indent) // blank lines are not indented
 }
 
 func (w *OrgWriter) WriteExplicitLineBreak(l ExplicitLineBreak) {
@@ -348,6 +753,9 @@ func (w *OrgWriter) WriteTimestamp(t Timestamp) {
 	if t.Interval != "" {
 		w.WriteString(" " + t.Interval)
 	}
+	if t.Delay != "" {
+		w.WriteString(" " + t.Delay)
+	}
 	w.WriteString(">")
 }
 
@@ -355,12 +763,15 @@ func (w *OrgWriter) WriteFootnoteLink(l FootnoteLink) {
 	w.WriteString("[fn:" + l.Name)
 	if l.Definition != nil {
 		w.WriteString(":")
-		WriteNodes(w, l.Definition.Children[0].(Paragraph).Children...)
+		WriteNodes(w, l.Definition.Children[0].(Paragraph).InlineNodes()...)
 	}
 	w.WriteString("]")
 }
 
 func (w *OrgWriter) WriteRegularLink(l RegularLink) {
+	if l.Wiki.Page != "" {
+		l.URL = l.Wiki.Page
+	}
 	if l.AutoLink {
 		w.WriteString(l.URL)
 	} else if l.Description == nil {
@@ -2,9 +2,11 @@ package org
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/pmezard/go-difflib/difflib"
@@ -87,3 +89,208 @@ func diff(actual, expected string) string {
 	text, _ := difflib.GetUnifiedDiffString(diff)
 	return text
 }
+
+func TestStringConcurrent(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader(fileString(t, "./testdata/lists.org")), "./testdata/lists.org")
+	expected, wg := String(d.Nodes...), sync.WaitGroup{}
+	for range 8 {
+		wg.Go(func() {
+			for range 20 {
+				if actual := String(d.Nodes...); actual != expected {
+					t.Errorf("concurrent String differs:\n%s", diff(actual, expected))
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func BenchmarkString(b *testing.B) {
+	d := New().Silent().Parse(strings.NewReader("* headline\nsome *bold* text\n- item\n"), "./bench.org")
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			String(d.Nodes...)
+		}
+	})
+}
+
+func TestOrgWriterFormattingOptions(t *testing.T) {
+	input := "#+TITLE: title\n* A :tag:\n- item\n  - sub item\n| a | bb |\n|---+----|\n#+BEGIN_SRC go\nx\n#+END_SRC\n\n\n* B\ntext\n"
+	writer := NewOrgWriter()
+	writer.TagsColumn = 20
+	writer.ListIndentWidth = 4
+	writer.PadTables = false
+	writer.BlankLinesBetweenSections = 1
+	writer.LowercaseKeywords = true
+	expected := "#+title: title\n\n* A            :tag:\n- item\n    - sub item\n| a | bb |\n|--+--|\n#+begin_src go\nx\n#+end_src\n\n* B\ntext\n"
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./formattingTests.org").Write(writer)
+	if err != nil {
+		t.Fatalf("%s\n got error: %s", input, err)
+	} else if actual != expected {
+		t.Fatalf("%s:\n%s'", input, diff(actual, expected))
+	}
+}
+
+func TestBlankLinesBetweenSections(t *testing.T) {
+	input := "* A\ntext\n\n\n\n** B\n- item\n\n\n* C\n#+BEGIN_SRC\nx\n\n#+END_SRC\n\n* D\n"
+	d := New().Silent().Parse(strings.NewReader(strings.Repeat(input, 100)), "./blankLinesTests.org")
+	for blankLines, section := range []string{
+		"* A\ntext\n** B\n- item\n* C\n#+BEGIN_SRC\nx\n\n#+END_SRC\n* D\n",
+		"* A\ntext\n\n** B\n- item\n\n* C\n#+BEGIN_SRC\nx\n\n#+END_SRC\n\n* D\n",
+		"* A\ntext\n\n\n** B\n- item\n\n\n* C\n#+BEGIN_SRC\nx\n\n#+END_SRC\n\n\n* D\n",
+	} {
+		writer := NewOrgWriter()
+		writer.BlankLinesBetweenSections = blankLines
+		expected := strings.TrimSuffix(strings.Repeat(section+strings.Repeat("\n", blankLines), 100), strings.Repeat("\n", blankLines))
+		if actual, err := d.Write(writer); err != nil || actual != expected {
+			t.Errorf("%d blank lines: %v\n%s", blankLines, err, diff(actual, expected))
+		}
+	}
+}
+
+func TestParagraphBreakMode(t *testing.T) {
+	input := "a\nb\n\n#+BEGIN_VERSE\nc\nd\n#+END_VERSE\n"
+	for _, test := range []struct {
+		mode ParagraphBreakMode
+		org  string
+		html string
+	}{
+		{ParagraphBreakDefault, "a\nb\n\n#+BEGIN_VERSE\nc\nd\n#+END_VERSE\n", "<p>a\nb</p>"},
+		{ParagraphBreakPreserve, "a\\\\\nb\n\n#+BEGIN_VERSE\nc\nd\n#+END_VERSE\n", "<p>a<br>\nb</p>"},
+		{ParagraphBreakJoin, "a b\n\n#+BEGIN_VERSE\nc\nd\n#+END_VERSE\n", "<p>a\nb</p>"},
+	} {
+		c := New().Silent()
+		c.ParagraphBreakMode = test.mode
+		d := c.Parse(strings.NewReader(input), "./paragraphBreakModeTests.org")
+		if actual, err := d.Write(NewOrgWriter()); err != nil || actual != test.org {
+			t.Errorf("%d: got org %q (error: %v), expected %q", test.mode, actual, err, test.org)
+		}
+		if actual, err := d.Write(NewHTMLWriter()); err != nil || !strings.HasPrefix(actual, test.html) {
+			t.Errorf("%d: got html %q (error: %v), expected it to start with %q", test.mode, actual, err, test.html)
+		}
+	}
+}
+
+func TestLosslessOrgWriter(t *testing.T) {
+	for _, path := range orgTestFiles() {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			expected := fileString(t, path)
+			writer := NewOrgWriter()
+			writer.Lossless = true
+			actual, err := New().Silent().Parse(strings.NewReader(expected), path).Write(writer)
+			if err != nil {
+				t.Fatalf("%s\n got error: %s", path, err)
+			} else if actual != expected {
+				t.Fatalf("%s:\n%s'", path, diff(actual, expected))
+			}
+		})
+	}
+}
+
+func TestLosslessOrgWriterModifiedNodes(t *testing.T) {
+	input := "#+TITLE:   title\n* headline     :tag:\n  indented paragraph\n\n| a |   b |\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./losslessTests.org")
+	h := d.Nodes[1].(Headline)
+	h.Children[0] = Paragraph{Children: []Node{Text{Content: "modified paragraph"}}}
+	d.Nodes[1] = h
+	writer := NewOrgWriter()
+	writer.Lossless = true
+	expected := "#+TITLE:   title\n* headline     :tag:\nmodified paragraph\n\n| a |   b |\n"
+	actual, err := d.Write(writer)
+	if err != nil {
+		t.Fatalf("%s\n got error: %s", input, err)
+	} else if actual != expected {
+		t.Fatalf("%s:\n%s'", input, diff(actual, expected))
+	}
+}
+
+func TestLosslessOrgWriterSetupFile(t *testing.T) {
+	reads, logs := 0, &strings.Builder{}
+	c := New(WithLogger(log.New(logs, "", 0)), WithReadFile(func(filename string) ([]byte, error) {
+		reads++
+		return []byte("#+TODO: WAIT | DONE\n"), nil
+	}))
+	input := "#+SETUPFILE: setup.org\n* WAIT   headline     :tag:\n  indented paragraph\n"
+	d := c.Parse(strings.NewReader(input), "./losslessTests.org")
+	writer := NewOrgWriter()
+	writer.Lossless = true
+	if actual, err := d.Write(writer); err != nil || actual != input {
+		t.Errorf("%s: got %q (error: %v)", input, actual, err)
+	}
+	if reads != 1 || logs.Len() != 0 {
+		t.Errorf("expected the setup file to be read once while parsing, got %d reads and logs %q", reads, logs)
+	}
+}
+
+func TestPreserveLineEndings(t *testing.T) {
+	for _, input := range []string{"* headline\r\ntext\r\n\n* second\r\n", "* headline\ntext", "#+TITLE: crlf\r\n\n* a\r\nb"} {
+		d := New().Silent().Parse(strings.NewReader(input), "./lineEndingTests.org")
+		if crlf := strings.Contains(input, "\r\n"); (d.LineEnding() == "\r\n") != crlf || d.FinalNewline() != strings.HasSuffix(input, "\n") {
+			t.Errorf("%q: unexpected line ending %q / final newline %v", input, d.LineEnding(), d.FinalNewline())
+		}
+		newWriter := func() *OrgWriter {
+			writer := NewOrgWriter()
+			writer.PreserveLineEndings, writer.Lossless = true, true
+			return writer
+		}
+		actual, err := d.Write(newWriter())
+		if err != nil {
+			t.Fatalf("%q: got error: %s", input, err)
+		} else if expected := strings.ReplaceAll(input, "\r\n\n", "\r\n\r\n"); actual != expected {
+			t.Errorf("%q: got %q", input, actual)
+		}
+		out := &strings.Builder{}
+		if err := d.WriteStream(out, newWriter()); err != nil || out.String() != actual {
+			t.Errorf("%q: expected streamed output %q, got %q (%v)", input, actual, out.String(), err)
+		}
+	}
+}
+
+func TestTabWidth(t *testing.T) {
+	input := "- a\n\t- b\n\t  more b\n  \t- c\n\n\t#+BEGIN_SRC go\n\t\tfmt.Println()\n\t#+END_SRC\n"
+	configuration := New().Silent()
+	d := configuration.Parse(strings.NewReader(input), "./tabTests.org")
+	expected := "- a\n  - b\n    more b\n  - c\n\n  #+BEGIN_SRC go\n  \tfmt.Println()\n  #+END_SRC\n"
+	if actual := String(d.Nodes...); actual != expected {
+		t.Errorf("%q:\n%s", input, diff(actual, expected))
+	}
+	if warnings := d.GetErrorsBySeverity(SeverityWarning); len(warnings) != 2 || warnings[1].StartLine != 3 || warnings[1].EndCol != 3 {
+		t.Errorf("expected mixed indentation warnings for lines 2 and 3, got %v", warnings)
+	}
+	configuration.TabWidth = 2
+	d = configuration.Parse(strings.NewReader("- a\n\t- b\n\t- c\n"), "./tabTests.org")
+	if expected := "- a\n  - b\n  - c\n"; String(d.Nodes...) != expected {
+		t.Errorf("expected tabs to be 2 columns wide, got %q", String(d.Nodes...))
+	}
+	input = "- a\n\tb *c*"
+	d = New().Silent().Parse(strings.NewReader(input), "./tabTests.org")
+	children := d.Nodes[0].(List).Items[0].(ListItem).Children[0].(Paragraph).Children
+	for i, expected := range []struct {
+		source                 string
+		startOffset, endOffset int
+		startColumn            int
+	}{{"a", 2, 3, 2}, {"\n\t", 3, 5, 3}, {"b ", 5, 7, 1}, {"*c*", 7, 10, 3}} {
+		pos := children[i].Position()
+		if source := d.Source(children[i]); source != expected.source || pos.StartOffset != expected.startOffset || pos.EndOffset != expected.endOffset || pos.StartColumn != expected.startColumn {
+			t.Errorf("%q: got %q at %d-%d (column %d), expected %q at %d-%d (column %d)", input, source, pos.StartOffset, pos.EndOffset, pos.StartColumn,
+				expected.source, expected.startOffset, expected.endOffset, expected.startColumn)
+		}
+	}
+}
+
+func TestAlignTables(t *testing.T) {
+	input := "| 名前 | a |\n|-+-|\n| x | 12 |\n| abcde | 3 |\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./alignTableTests.org")
+	table := d.Nodes[0].(Table)
+	table.Rows[2].Columns[0].Children = []Node{Text{Content: "漢字"}}
+	d.Nodes[0] = table
+	writer := NewOrgWriter()
+	writer.AlignTables = true
+	expected := "| 名前  |  a |\n|-------+----|\n| 漢字  | 12 |\n| abcde |  3 |\n"
+	actual, err := d.Write(writer)
+	if err != nil {
+		t.Fatalf("%s\n got error: %s", input, err)
+	} else if actual != expected {
+		t.Fatalf("%s:\n%s'", input, diff(actual, expected))
+	}
+}
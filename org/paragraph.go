@@ -1,20 +1,43 @@
 package org
 
 import (
-	"math"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 type Paragraph struct {
 	Children []Node
 	Pos      Position
+	lazy     *lazyInline // lazy contains the content of the paragraph until it is parsed. See Configuration.LazyInline.
+}
+
+// lazyInline contains the unparsed content of a paragraph. It is parsed on the first call of nodes.
+type lazyInline struct {
+	document     *Document
+	content      string
+	line, column int
+	indents      map[int]int // indents are the lineIndents of the content.
+	once         sync.Once
+	children     []Node
 }
 
 type HorizontalRule struct {
 	Pos Position
 }
 
+// ParagraphBreakMode determines how the line breaks between the lines of a paragraph are exported.
+type ParagraphBreakMode int
+
+const (
+	// ParagraphBreakDefault follows the \n export option - line breaks are preserved for \n:t and joined otherwise.
+	ParagraphBreakDefault ParagraphBreakMode = iota
+	// ParagraphBreakJoin joins the lines of a paragraph (soft line breaks) regardless of the \n export option.
+	ParagraphBreakJoin
+	// ParagraphBreakPreserve exports each line break of a paragraph as a hard line break regardless of the \n export option.
+	ParagraphBreakPreserve
+)
+
 var horizontalRuleRegexp = regexp.MustCompile(`^(\s*)-{5,}\s*$`)
 var plainTextRegexp = regexp.MustCompile(`^(\s*)(.*)`)
 
@@ -33,47 +56,124 @@ func lexHorizontalRule(line string) (token, bool) {
 }
 
 func (d *Document) parseParagraph(i int, parentStop stopFn) (int, Node) {
-	lines, start := []string{d.tokens[i].content}, i
+	lines, start, indents := []string{d.tokens[i].content}, i, map[int]int(nil)
 	stop := func(d *Document, i int) bool {
 		return parentStop(d, i) || d.tokens[i].kind != "text" || d.tokens[i].content == ""
 	}
 	for i += 1; !stop(d, i); i++ {
-		lvl := math.Max(float64(d.tokens[i].lvl-d.baseLvl), 0)
-		lines = append(lines, strings.Repeat(" ", int(lvl))+d.tokens[i].content)
+		t := d.tokens[i]
+		indent, stripped := d.continuationIndent(t)
+		if stripped != 0 {
+			if indents == nil {
+				indents = map[int]int{}
+			}
+			indents[t.line] = stripped
+		}
+		lines = append(lines, indent+t.content)
 	}
 	consumed := i - start
 	endToken := d.tokens[i-1]
 	startToken := d.tokens[start]
+	content, line, column := strings.Join(lines, "\n"), startToken.line, d.contentColumn(startToken)
+	if d.LazyInline {
+		lazy := &lazyInline{document: d, content: content, line: line, column: column, indents: indents}
+		return consumed, Paragraph{Pos: d.getPositionBetweenTokens(startToken, endToken), lazy: lazy}
+	}
+	d.lineIndents = indents
+	defer func() { d.lineIndents = nil }()
 	paragraph := Paragraph{
-		Children: d.parseInlineWithPos(strings.Join(lines, "\n"), d.tokens[start].line, d.tokens[start].startCol),
-		Pos: Position{
-			StartLine:   startToken.line,
-			StartColumn: startToken.startCol,
-			EndLine:     endToken.line,
-			EndColumn:   endToken.endCol,
-		},
+		Children: d.parseInlineWithPos(content, line, column),
+		Pos:      d.getPositionBetweenTokens(startToken, endToken),
 	}
 	return consumed, paragraph
 }
 
+// continuationIndent returns the indentation of the continuation line t of a paragraph relative to baseLvl and
+// the number of bytes of indentation stripped from the source line. Indentation is stripped byte-wise (see
+// trimIndentUpTo) - so that the inline content of the line keeps its byte columns.
+func (d *Document) continuationIndent(t token) (string, int) {
+	line, ok := d.sourceLine(t.line)
+	if !ok || !strings.HasSuffix(line, t.content) {
+		return strings.Repeat(" ", max(t.lvl-d.baseLvl, 0)), 0
+	}
+	indent := line[:len(line)-len(t.content)]
+	trimmed := trimIndentUpTo(d.baseLvl, d.TabWidth)(indent)
+	return trimmed, len(indent) - len(trimmed)
+}
+
+// contentColumn returns the byte column at which the content of the token starts in its source line.
+func (d *Document) contentColumn(t token) int {
+	if line, ok := d.sourceLine(t.line); ok && strings.HasSuffix(line, t.content) {
+		return len(line) - len(t.content)
+	}
+	return t.startCol + t.lvl
+}
+
 func (d *Document) parseHorizontalRule(i int, parentStop stopFn) (int, Node) {
 	t := d.tokens[i]
 	hr := HorizontalRule{}
-	hr.Pos = Position{
-		StartLine:   t.line,
-		StartColumn: t.startCol,
-		EndLine:     t.line,
-		EndColumn:   t.endCol,
-	}
+	hr.Pos = d.getPositionFromToken(t)
 	return 1, hr
 }
 
+// PreserveLineBreaks returns true if line breaks inside of paragraphs should be exported as hard line breaks.
+func (d *Document) PreserveLineBreaks() bool {
+	switch d.ParagraphBreakMode {
+	case ParagraphBreakJoin:
+		return false
+	case ParagraphBreakPreserve:
+		return true
+	default:
+		return d.GetOption(`\n`) != "nil"
+	}
+}
+
 func (n Paragraph) String() string      { return String(n) }
 func (n HorizontalRule) String() string { return String(n) }
 
+// InlineNodes returns the children of the paragraph. Paragraphs of documents parsed with Configuration.LazyInline
+// do not have Children until their content is parsed - on the first call of InlineNodes.
+func (n Paragraph) InlineNodes() []Node {
+	if n.lazy == nil {
+		return n.Children
+	}
+	return n.lazy.nodes()
+}
+
+func (l *lazyInline) nodes() []Node {
+	l.once.Do(func() {
+		parser := *l.document // paragraphs may be accessed concurrently - the parser must not share the buffers of the document
+		parser.buffers, parser.lineIndents = nil, l.indents
+		l.children = parser.parseInlineWithPos(l.content, l.line, l.column)
+	})
+	return l.children
+}
+
+// shifted returns the unparsed content moved by lines - see Reparse.
+func (l *lazyInline) shifted(lines int) *lazyInline {
+	indents := map[int]int(nil)
+	for line, indent := range l.indents {
+		if indents == nil {
+			indents = map[int]int{}
+		}
+		indents[line+lines] = indent
+	}
+	return &lazyInline{document: l.document, content: l.content, line: l.line + lines, column: l.column, indents: indents}
+}
+
+// ParseInline parses the content of all paragraphs that have not been parsed yet - see Configuration.LazyInline.
+func (d *Document) ParseInline() {
+	var parse func(n Node) Node
+	parse = func(n Node) Node { return mapChildren(n, parse) }
+	for i, n := range d.Nodes {
+		d.Nodes[i] = parse(n)
+	}
+	d.rebuildOutline()
+}
+
 func (n Paragraph) Copy() Node {
 	return Paragraph{
-		Children: CopyNodes(n.Children),
+		Children: CopyNodes(n.InlineNodes()),
 		Pos:      n.Pos,
 	}
 }
@@ -85,7 +185,7 @@ func (n HorizontalRule) Copy() Node {
 }
 
 func (n Paragraph) Range(f func(Node) bool) {
-	for _, child := range n.Children {
+	for _, child := range n.InlineNodes() {
 		if !f(child) {
 			return
 		}
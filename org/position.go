@@ -0,0 +1,153 @@
+package org
+
+import (
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ColumnMode determines the unit of the StartColumn and EndColumn of positions.
+type ColumnMode int
+
+const (
+	// ColumnBytes counts columns in bytes.
+	ColumnBytes ColumnMode = iota
+	// ColumnRunes counts columns in runes (unicode code points).
+	ColumnRunes
+	// ColumnUTF16 counts columns in UTF-16 code units - as expected by e.g. LSP clients.
+	ColumnUTF16
+)
+
+// column converts the byte column of the given line into a column of the configured ColumnMode.
+func (d *Document) column(line, byteColumn int) int {
+	s, ok := d.sourceLine(line)
+	if d.ColumnMode == ColumnBytes || !ok {
+		return byteColumn
+	}
+	if byteColumn > len(s) {
+		return byteColumn - len(s) + d.column(line, len(s))
+	}
+	s = s[:byteColumn]
+	if d.ColumnMode == ColumnRunes {
+		return utf8.RuneCountInString(s)
+	}
+	column := 0
+	for _, r := range s {
+		column += len(utf16.Encode([]rune{r}))
+	}
+	return column
+}
+
+// byteColumn converts a column of the configured ColumnMode of the given line into a byte column.
+func (d *Document) byteColumn(line, column int) int {
+	s, ok := d.sourceLine(line)
+	if d.ColumnMode == ColumnBytes || !ok {
+		return column
+	}
+	units := 0
+	for i, r := range s {
+		if units >= column {
+			return i
+		} else if d.ColumnMode == ColumnUTF16 {
+			units += len(utf16.Encode([]rune{r}))
+		} else {
+			units++
+		}
+	}
+	return len(s) + column - units
+}
+
+// positionFromOffsets returns the Position spanning from the byte offset start to the byte offset end of the parse input.
+func (d *Document) positionFromOffsets(start, end int) Position {
+	startLine, endLine := d.lineAt(start), d.lineAt(end)
+	return Position{
+		StartLine:   startLine,
+		StartColumn: d.column(startLine, start-d.offset(startLine, 0)),
+		EndLine:     endLine,
+		EndColumn:   d.column(endLine, end-d.offset(endLine, 0)),
+		StartOffset: start,
+		EndOffset:   end,
+	}
+}
+
+// lineAt returns the line containing the given byte offset of the parse input.
+func (d *Document) lineAt(offset int) int {
+	line := sort.Search(len(d.lineOffsets), func(i int) bool { return d.lineOffsets[i] > offset }) - 1
+	if line < 0 {
+		return d.firstLine
+	}
+	return d.firstLine + line
+}
+
+// sourceLine returns the given line of the parse input - if it is available.
+func (d *Document) sourceLine(line int) (string, bool) {
+	if i := line - d.firstLine; i >= 0 && i < len(d.source) {
+		return d.source[i], true
+	}
+	return "", false
+}
+
+// NodeAt returns the innermost node covering the given line and column - or nil if no node covers it.
+// Lines and columns are 0-based, columns are counted in the configured ColumnMode.
+func (d *Document) NodeAt(line, column int) Node {
+	if nodes := d.NodesAt(line, column); len(nodes) != 0 {
+		return nodes[len(nodes)-1]
+	}
+	return nil
+}
+
+// NodesAt returns the chain of nodes covering the given line and column - from the outermost (top level)
+// node to the innermost node. See NodeAt.
+func (d *Document) NodesAt(line, column int) []Node {
+	chain := []Node{}
+	for nodes := d.Nodes; len(nodes) != 0; {
+		var next []Node
+		for _, n := range nodes {
+			if n != nil && n.Position().covers(line, column) {
+				chain, next = append(chain, n), children(n)
+				break
+			}
+		}
+		nodes = next
+	}
+	return chain
+}
+
+// covers returns true if the given line and column lie within the position - the end is exclusive.
+func (p Position) covers(line, column int) bool {
+	if p == (Position{}) {
+		return false
+	}
+	afterStart := line > p.StartLine || (line == p.StartLine && column >= p.StartColumn)
+	beforeEnd := line < p.EndLine || (line == p.EndLine && column < p.EndColumn)
+	return afterStart && beforeEnd
+}
+
+// children returns the child nodes of n - including headline titles and link descriptions.
+func children(n Node) []Node {
+	nodes := []Node{}
+	switch n := n.(type) {
+	case Headline:
+		nodes = append(nodes, n.Title...)
+	case RegularLink:
+		nodes = append(nodes, n.Description...)
+	}
+	n.Range(func(child Node) bool {
+		nodes = append(nodes, child)
+		return true
+	})
+	return nodes
+}
+
+// Source returns the original text of the node, i.e. the span of the parse input between the StartOffset and EndOffset
+// of its position. An empty string is returned if the node does not belong to the parse input of the document, i.e.
+// if the lines and columns of its position do not match its offsets (e.g. for nodes of other documents).
+func (d *Document) Source(n Node) string {
+	pos := n.Position()
+	if pos.StartOffset < 0 || pos.StartOffset > pos.EndOffset || pos.EndOffset > len(d.input) {
+		return ""
+	} else if p := d.positionFromOffsets(pos.StartOffset, pos.EndOffset); p != pos {
+		return ""
+	}
+	return d.input[pos.StartOffset:pos.EndOffset]
+}
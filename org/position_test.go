@@ -0,0 +1,187 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPositionOffsets(t *testing.T) {
+	for _, newline := range []string{"\n", "\r\n"} {
+		input := strings.Join([]string{
+			"#+TITLE: offsets",
+			"* Headline",
+			"A paragraph with *bold* text",
+			"and a [[https://example.com][link]].",
+			"",
+			"- item",
+			"",
+		}, newline)
+		d := New().Silent().Parse(strings.NewReader(input), "./offsets.org")
+		expected := map[string]bool{
+			"#+TITLE: offsets":              false,
+			"*bold*":                        false,
+			"[[https://example.com][link]]": false,
+			"A paragraph with *bold* text" + newline + "and a [[https://example.com][link]].": false,
+			"- item": false,
+		}
+		var check func(nodes []Node)
+		check = func(nodes []Node) {
+			for _, n := range nodes {
+				pos := n.Position()
+				if pos.StartOffset < 0 || pos.EndOffset > len(input) || pos.StartOffset > pos.EndOffset {
+					t.Errorf("%q: bad offsets %#v for %T", newline, pos, n)
+					continue
+				}
+				if _, ok := expected[input[pos.StartOffset:pos.EndOffset]]; ok {
+					expected[input[pos.StartOffset:pos.EndOffset]] = true
+				}
+				n.Range(func(child Node) bool {
+					check([]Node{child})
+					return true
+				})
+			}
+		}
+		check(d.Nodes)
+		for source, found := range expected {
+			if !found {
+				t.Errorf("%q: no node with offsets spanning %q", newline, source)
+			}
+		}
+	}
+}
+
+func TestColumnModes(t *testing.T) {
+	input := "Grüße 😀 *bold*\n"
+	expected := map[ColumnMode][2]int{
+		ColumnBytes: {13, 19},
+		ColumnRunes: {8, 14},
+		ColumnUTF16: {9, 15},
+	}
+	for mode, columns := range expected {
+		c := New().Silent()
+		c.ColumnMode = mode
+		d := c.Parse(strings.NewReader(input), "./columns.org")
+		emphasis := d.Nodes[0].(Paragraph).Children[1].(Emphasis)
+		if pos := emphasis.Pos; pos.StartColumn != columns[0] || pos.EndColumn != columns[1] {
+			t.Errorf("mode %d: got columns %d-%d, expected %d-%d", mode, pos.StartColumn, pos.EndColumn, columns[0], columns[1])
+		}
+		if pos := emphasis.Pos; input[pos.StartOffset:pos.EndOffset] != "*bold*" {
+			t.Errorf("mode %d: bad offsets %d-%d", mode, pos.StartOffset, pos.EndOffset)
+		}
+	}
+}
+
+func TestNodeAt(t *testing.T) {
+	input := "* Headline\nSome *bold* text\n** Child\n- item with [[https://example.com][a link]]\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./node_at.org")
+	kinds := func(nodes []Node) string {
+		s := []string{}
+		for _, n := range nodes {
+			s = append(s, strings.TrimPrefix(fmt.Sprintf("%T", n), "org."))
+		}
+		return strings.Join(s, " ")
+	}
+	for _, c := range []struct {
+		line, column int
+		expected     string
+	}{
+		{0, 3, "Headline Text"},
+		{1, 6, "Headline Paragraph Emphasis Text"},
+		{1, 13, "Headline Paragraph Text"},
+		{2, 4, "Headline Headline Text"},
+		{3, 22, "Headline Headline List ListItem Paragraph RegularLink"},
+		{3, 40, "Headline Headline List ListItem Paragraph RegularLink Text"},
+		{10, 0, ""},
+	} {
+		if actual := kinds(d.NodesAt(c.line, c.column)); actual != c.expected {
+			t.Errorf("%d:%d: got %q, expected %q", c.line, c.column, actual, c.expected)
+		}
+	}
+	if n := d.NodeAt(1, 6); n == nil || n.(Text).Content != "bold" {
+		t.Errorf("NodeAt(1, 6): got %#v", n)
+	}
+}
+
+func TestSource(t *testing.T) {
+	input := "* TODO Headline :tag:\r\n#+NAME: table\r\n| a | b |\r\n|---+---|\r\n\r\nSome *bold* text\r\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./source.org")
+	section := d.Nodes[0].(Headline)
+	if actual := d.Source(section); actual != strings.TrimSuffix(input, "\r\n") {
+		t.Errorf("headline: got %q", actual)
+	}
+	if actual, expected := d.Source(section.Children[0]), "#+NAME: table\r\n| a | b |\r\n|---+---|"; actual != expected {
+		t.Errorf("named table: got %q, expected %q", actual, expected)
+	}
+	if actual := d.Source(d.NodeAt(5, 6)); actual != "bold" {
+		t.Errorf("emphasis text: got %q", actual)
+	}
+	if actual := d.Source(Text{Content: "not in input", Pos: Position{StartOffset: 100, EndOffset: 112}}); actual != "" {
+		t.Errorf("foreign node: got %q", actual)
+	}
+	other := New().Silent().Parse(strings.NewReader("#+TITLE: other\r\n* Other\r\n"), "./other.org")
+	if actual := d.Source(other.Nodes[1]); actual != "" {
+		t.Errorf("node of another document: got %q", actual)
+	}
+}
+
+func BenchmarkParseLargeParagraph(b *testing.B) {
+	for _, lines := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("%d lines", lines), func(b *testing.B) {
+			input := strings.Repeat("some *bold* and /italic/ text with a [[https://example.com][link]] and ~code~\n", lines)
+			b.ReportAllocs()
+			for b.Loop() {
+				New().Silent().Parse(strings.NewReader(input), "")
+			}
+		})
+	}
+}
+
+func BenchmarkParseManyParagraphs(b *testing.B) {
+	paragraph := "#+name: p\nsome *bold* and /italic/ text\nwith a [[https://example.com][link]] and ~code~\n\n#+begin_src go\nx := 1\n#+end_src\n\n"
+	input := strings.Repeat(paragraph, 10000)
+	b.ReportAllocs()
+	for b.Loop() {
+		New().Silent().Parse(strings.NewReader(input), "")
+	}
+}
+
+func TestParseBuffers(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("a *b /c/* [[d][e *f*]]\n"), "")
+	p := d.Nodes[0].(Paragraph)
+	if d.buffers != nil || len(p.Children) != 4 || cap(p.Children) != len(p.Children) {
+		t.Errorf("expected released buffers and exactly sized nodes, got %#v", p.Children)
+	}
+	if nodes := d.parseInline("a\nb"); len(nodes) != 3 || d.buffers != nil {
+		t.Errorf("expected buffers to be released after parseInline, got %#v", nodes)
+	}
+	if upper("src") != "SRC" || upper("Src") != "SRC" || upper("foo") != "FOO" {
+		t.Errorf("unexpected upper case names")
+	}
+}
+
+func TestListEndBlankLines(t *testing.T) {
+	input := "- a\n  - b\n\n\n- c\n\n- d\n"
+	cases := []struct {
+		n        int
+		expected []string // Node type and line range of each top-level node
+	}{
+		{2, []string{"List 0-1", "Paragraph 2-2", "Paragraph 3-3", "List 4-6"}},
+		{1, []string{"List 0-1", "Paragraph 2-2", "Paragraph 3-3", "List 4-4", "Paragraph 5-5", "List 6-6"}},
+		{0, []string{"List 0-6"}},
+	}
+	for _, c := range cases {
+		d := New(WithListEndBlankLines(c.n)).Silent().Parse(strings.NewReader(input), "./list.org")
+		actual := []string{}
+		for _, n := range d.Nodes {
+			pos := n.Position()
+			actual = append(actual, fmt.Sprintf("%s %d-%d", strings.TrimPrefix(fmt.Sprintf("%T", n), "org."), pos.StartLine, pos.EndLine))
+		}
+		if strings.Join(actual, ", ") != strings.Join(c.expected, ", ") {
+			t.Errorf("ListEndBlankLines %d: got %v, expected %v", c.n, actual, c.expected)
+		}
+		if out, err := d.Write(NewOrgWriter()); err != nil || out != input {
+			t.Errorf("ListEndBlankLines %d: got %q (%v), expected %q", c.n, out, err, input)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package org
+
+import (
+	"fmt"
+	"io"
+)
+
+// Reader is the interface that is used to import a document from a format - the counterpart of Writer. See
+// Configuration.Read, OrgReader and e.g. the htmlimport package.
+type Reader interface {
+	// Read returns the document for the input. The document uses the configuration c and path is the path of the input
+	// (used e.g. to resolve relative links). Readers of other formats typically convert the input into Org mode and
+	// parse it with c.
+	Read(c *Configuration, input io.Reader, path string) (*Document, error)
+}
+
+// OrgReader is the Reader for Org mode, i.e. the parser of this package.
+type OrgReader struct{}
+
+// Read implements Reader using Configuration.Parse. The FatalError of the document is returned as error - other parse
+// errors are only added to Document.Errors.
+func (OrgReader) Read(c *Configuration, input io.Reader, path string) (*Document, error) {
+	d := c.Parse(input, path)
+	if d.FatalError != nil {
+		return d, d.FatalError
+	}
+	return d, nil
+}
+
+// Read imports the input using r, e.g. c.Read(OrgReader{}, input, path) parses Org mode like c.Parse.
+func (c *Configuration) Read(r Reader, input io.Reader, path string) (*Document, error) {
+	d, err := r.Read(c, input, path)
+	if err != nil {
+		return d, fmt.Errorf("could not read document: %w", err)
+	}
+	return d, nil
+}
@@ -0,0 +1,163 @@
+package org
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Promote decreases the level of the headline h and its descendants by one.
+//
+// Like all headline manipulation methods, Promote edits the parse input of the document and reparses it (see Reparse),
+// i.e. the formatting of the input is preserved and Nodes, Outline and positions are updated. Headlines are identified
+// by their position and content - h must be a headline of the document as of the last parse.
+func (d *Document) Promote(h Headline) error {
+	return d.shiftSubtree(h, -1)
+}
+
+// Demote increases the level of the headline h and its descendants by one. See Promote.
+func (d *Document) Demote(h Headline) error {
+	return d.shiftSubtree(h, 1)
+}
+
+// MoveBefore moves the subtree of the headline h before its sibling headline. See Promote.
+func (d *Document) MoveBefore(h, sibling Headline) error {
+	if err := d.checkSiblings(h, sibling); err != nil {
+		return err
+	}
+	from, to := subtreeLines(h)
+	d.moveLines(from, to, sibling.Pos.StartLine, d.linesText(from, to))
+	return nil
+}
+
+// MoveAfter moves the subtree of the headline h after the subtree of its sibling headline. See Promote.
+func (d *Document) MoveAfter(h, sibling Headline) error {
+	if err := d.checkSiblings(h, sibling); err != nil {
+		return err
+	}
+	from, to := subtreeLines(h)
+	_, end := subtreeLines(sibling)
+	d.moveLines(from, to, end, d.linesText(from, to))
+	return nil
+}
+
+// Refile moves the subtree of the headline h to the end of the subtree of parent in the target document - which may be
+// d itself. The levels of the moved headlines are adjusted to make h a child of parent. If parent is nil, h is moved
+// to the end of target as a top level headline. See Promote.
+func (d *Document) Refile(h Headline, target *Document, parent *Headline) error {
+	if d.sectionOf(h) == nil {
+		return fmt.Errorf("could not refile headline: headline %q does not belong to the document", String(h.Title...))
+	}
+	lvl, at := 1, len(target.source)
+	if parent != nil {
+		if target.sectionOf(*parent) == nil {
+			return fmt.Errorf("could not refile headline: parent %q does not belong to the target document", String(parent.Title...))
+		} else if target == d && parent.Pos.StartOffset >= h.Pos.StartOffset && parent.Pos.EndOffset <= h.Pos.EndOffset {
+			return fmt.Errorf("could not refile headline: parent %q is part of the refiled subtree", String(parent.Title...))
+		}
+		lvl, at = parent.Lvl+1, parent.Pos.EndLine+1
+	}
+	from, to := subtreeLines(h)
+	text, err := d.shiftedSubtreeText(h, lvl-h.Lvl)
+	if err != nil {
+		return err
+	}
+	if target == d {
+		d.moveLines(from, to, at, text)
+		return nil
+	}
+	if at == len(target.source) && at != 0 && !strings.HasSuffix(target.input, "\n") {
+		text = "\n" + text
+	}
+	target.Reparse(TextEdit{StartLine: at, EndLine: at, NewText: text})
+	d.Reparse(TextEdit{StartLine: from, EndLine: to})
+	return nil
+}
+
+// shiftSubtree changes the level of the headline h and its descendants by delta.
+func (d *Document) shiftSubtree(h Headline, delta int) error {
+	if d.sectionOf(h) == nil {
+		return fmt.Errorf("could not change headline level: headline %q does not belong to the document", String(h.Title...))
+	}
+	text, err := d.shiftedSubtreeText(h, delta)
+	if err != nil {
+		return err
+	}
+	from, to := subtreeLines(h)
+	d.Reparse(TextEdit{StartLine: from, EndLine: to, NewText: text})
+	return nil
+}
+
+// shiftedSubtreeText returns the source lines of the subtree of h with the levels of all headlines changed by delta.
+func (d *Document) shiftedSubtreeText(h Headline, delta int) (string, error) {
+	from, to := subtreeLines(h)
+	start, _ := d.editOffset(from, 0)
+	headlines := []Headline{}
+	walkNodes([]Node{h}, func(n Node) {
+		if h, ok := n.(Headline); ok {
+			headlines = append(headlines, h)
+		}
+	})
+	slices.SortFunc(headlines, func(a, b Headline) int { return b.Pos.StartOffset - a.Pos.StartOffset })
+	text := d.linesText(from, to)
+	for _, h := range headlines {
+		if h.Lvl+delta < 1 {
+			return "", fmt.Errorf("could not change headline level: headline %q would be above the top level", String(h.Title...))
+		}
+		offset := h.Pos.StartOffset - start
+		text = text[:offset] + strings.Repeat("*", h.Lvl+delta) + text[offset+h.Lvl:]
+	}
+	return text, nil
+}
+
+// moveLines replaces the source lines from (inclusive) to to (exclusive) with text inserted before the line at.
+func (d *Document) moveLines(from, to, at int, text string) {
+	if at >= from && at <= to {
+		d.Reparse(TextEdit{StartLine: from, EndLine: to, NewText: text})
+	} else if at < from {
+		d.Reparse(TextEdit{StartLine: at, EndLine: to, NewText: text + d.linesText(at, from)})
+	} else {
+		d.Reparse(TextEdit{StartLine: from, EndLine: at, NewText: d.linesText(to, at) + text})
+	}
+}
+
+// linesText returns the source lines from (inclusive) to to (exclusive) - terminated by a newline.
+func (d *Document) linesText(from, to int) string {
+	start, _ := d.editOffset(from, 0)
+	end, _ := d.editOffset(to, 0)
+	text := d.input[start:end]
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text
+}
+
+// checkSiblings returns an error unless h and sibling are different headlines of the document with the same parent.
+func (d *Document) checkSiblings(h, sibling Headline) error {
+	s, t := d.sectionOf(h), d.sectionOf(sibling)
+	if s == nil || t == nil {
+		return fmt.Errorf("could not move headline: headline does not belong to the document")
+	} else if s == t || s.Parent != t.Parent {
+		return fmt.Errorf("could not move headline: %q is not a sibling of %q", String(sibling.Title...), String(h.Title...))
+	}
+	return nil
+}
+
+// sectionOf returns the section of the headline h - or nil if h is not a headline of the document as of the last
+// parse. Unlike Outline.SectionOf, it also compares the content of the headlines, i.e. a stale copy of a headline
+// that was modified since (e.g. by changing its TODO keyword) does not belong to the document.
+func (d *Document) sectionOf(h Headline) *Section {
+	s := d.Outline.SectionOf(h)
+	if s == nil {
+		return nil
+	} else if o := s.Headline; o.Pos != h.Pos || o.Status != h.Status || o.Priority != h.Priority || o.IsComment != h.IsComment ||
+		!slices.Equal(o.Tags, h.Tags) || String(o.Title...) != String(h.Title...) {
+		return nil
+	}
+	return s
+}
+
+// subtreeLines returns the source lines of the subtree of h, i.e. from (inclusive) to to (exclusive).
+func subtreeLines(h Headline) (from, to int) {
+	return h.Pos.StartLine, h.Pos.EndLine + 1
+}
@@ -0,0 +1,97 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeadlineManipulation(t *testing.T) {
+	input := "* A\n** A1\n* B\nbody\n** B1\n*** B2\n* C"
+	for _, c := range []struct {
+		name     string
+		edit     func(d *Document, h func(title string) Headline) error
+		expected string
+	}{
+		{"demote", func(d *Document, h func(string) Headline) error { return d.Demote(h("B")) },
+			"* A\n** A1\n** B\nbody\n*** B1\n**** B2\n* C"},
+		{"promote", func(d *Document, h func(string) Headline) error { return d.Promote(h("B1")) },
+			"* A\n** A1\n* B\nbody\n* B1\n** B2\n* C"},
+		{"move before", func(d *Document, h func(string) Headline) error { return d.MoveBefore(h("C"), h("A")) },
+			"* C\n* A\n** A1\n* B\nbody\n** B1\n*** B2\n"},
+		{"move after", func(d *Document, h func(string) Headline) error { return d.MoveAfter(h("A"), h("B")) },
+			"* B\nbody\n** B1\n*** B2\n* A\n** A1\n* C"},
+		{"refile", func(d *Document, h func(string) Headline) error { return d.Refile(h("B"), d, ptr(h("A1"))) },
+			"* A\n** A1\n*** B\nbody\n**** B1\n***** B2\n* C"},
+		{"refile to top level", func(d *Document, h func(string) Headline) error { return d.Refile(h("B1"), d, nil) },
+			"* A\n** A1\n* B\nbody\n* C\n* B1\n** B2\n"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			d := New().Silent().Parse(strings.NewReader(input), "./manipulation.org")
+			if err := c.edit(d, func(title string) Headline { return findHeadline(t, d, title) }); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if d.input != c.expected {
+				t.Errorf("got:\n%s", diff(d.input, c.expected))
+			}
+			fresh := New().Silent().Parse(strings.NewReader(c.expected), "./manipulation.org")
+			if actual, expected := dumpDocument(d), dumpDocument(fresh); actual != expected {
+				t.Errorf("expected document to match a fresh parse:\n%s", diff(actual, expected))
+			}
+		})
+	}
+}
+
+func TestHeadlineManipulationErrors(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("* A\n** A1\n* B\n"), "./manipulation.org")
+	a, a1, b := findHeadline(t, d, "A"), findHeadline(t, d, "A1"), findHeadline(t, d, "B")
+	if err := d.Promote(a); err == nil {
+		t.Errorf("expected error promoting top level headline")
+	}
+	if err := d.MoveBefore(a1, b); err == nil {
+		t.Errorf("expected error moving headline before non-sibling")
+	}
+	if err := d.Refile(a, d, &a1); err == nil {
+		t.Errorf("expected error refiling headline into its own subtree")
+	}
+	other := New().Silent().Parse(strings.NewReader("* Inbox"), "./other.org")
+	if err := d.Demote(findHeadline(t, other, "Inbox")); err == nil {
+		t.Errorf("expected error for headline of another document")
+	}
+	if err := d.Refile(a, other, ptr(findHeadline(t, other, "Inbox"))); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.input != "* B\n" || other.input != "* Inbox\n** A\n*** A1\n" {
+		t.Errorf("got %q and %q", d.input, other.input)
+	}
+	if len(other.Outline.Children[0].Children) != 1 || len(d.Outline.Children) != 1 {
+		t.Errorf("expected outlines to be updated")
+	}
+
+	d = New().Silent().Parse(strings.NewReader("* TODO A\n* B :x:\n"), "./manipulation.org")
+	a, b = findHeadline(t, d, "A"), findHeadline(t, d, "B")
+	d.Reparse(TextEdit{StartLine: 0, EndLine: 2, NewText: "* DONE A\n* C :x:\n"})
+	if err := d.Demote(a); err == nil {
+		t.Errorf("expected error for stale headline with a different keyword")
+	}
+	if err := d.Refile(findHeadline(t, d, "A"), d, &b); err == nil {
+		t.Errorf("expected error for stale parent with a different title")
+	}
+	if err := d.Demote(findHeadline(t, d, "C")); err != nil || d.input != "* DONE A\n** C :x:\n" {
+		t.Errorf("got %q (%v)", d.input, err)
+	}
+}
+
+func findHeadline(t *testing.T, d *Document, title string) Headline {
+	var found *Headline
+	walkNodes(d.Nodes, func(n Node) {
+		if h, ok := n.(Headline); ok && String(h.Title...) == title {
+			found = &h
+		}
+	})
+	if found == nil {
+		t.Fatalf("headline %q not found", title)
+	}
+	return *found
+}
+
+func ptr[T any](v T) *T { return &v }
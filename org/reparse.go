@@ -0,0 +1,368 @@
+package org
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TextEdit describes the replacement of a range of the parse input with NewText - e.g. a change reported by an editor.
+// Lines and columns are 0-based and columns are counted in the configured ColumnMode. The end of the range is exclusive.
+type TextEdit struct {
+	StartLine   int
+	StartColumn int
+	EndLine     int
+	EndColumn   int
+	NewText     string
+}
+
+// maxReparseExtensions is the maximum number of times the reparsed range is extended by the following top level node
+// before Reparse falls back to parsing the complete input.
+const maxReparseExtensions = 8
+
+// reparseSafeKeywords are the keywords that do not affect the parsing of the rest of the document.
+var reparseSafeKeywords = map[string]bool{"NAME": true, "CAPTION": true}
+
+// Reparse applies the edit to the parse input of the document and updates the document in place.
+//
+// Only the lines of the top level nodes affected by the edit are tokenized and parsed again - the positions of
+// all other nodes are shifted. Edits that may change how the rest of the document is parsed (e.g. buffer settings
+// like #+TODO or unbalanced #+BEGIN / #+END lines) fall back to parsing the complete input.
+// Reparse expects Nodes to be unmodified since the document was parsed.
+func (d *Document) Reparse(edit TextEdit) *Document {
+	start, startOk := d.editOffset(edit.StartLine, edit.StartColumn)
+	end, endOk := d.editOffset(edit.EndLine, edit.EndColumn)
+	if !startOk || !endOk || start > end {
+		pos := Position{StartLine: edit.StartLine, StartColumn: edit.StartColumn, EndLine: edit.EndLine, EndColumn: edit.EndColumn}
+		d.AddError(ErrorTypeValidation, "invalid text edit", pos, token{line: edit.StartLine}, fmt.Errorf("range %d:%d-%d:%d is out of bounds", edit.StartLine, edit.StartColumn, edit.EndLine, edit.EndColumn))
+		return d
+	}
+	input := d.input[:start] + edit.NewText + d.input[end:]
+	if !d.reparseRange(edit, input) {
+		*d = *d.Configuration.Parse(strings.NewReader(input), d.Path)
+		d.Outline.adopt(d)
+	}
+	return d
+}
+
+// editOffset returns the byte offset of the given line and column of a TextEdit.
+func (d *Document) editOffset(line, column int) (int, bool) {
+	if line == len(d.source) && column == 0 {
+		return len(d.input), true
+	} else if line < 0 || line >= len(d.source) || column < 0 {
+		return 0, false
+	}
+	byteColumn := d.byteColumn(line, column)
+	if byteColumn > len(d.source[line]) {
+		return 0, false
+	}
+	return d.lineOffsets[line] + byteColumn, true
+}
+
+// reparseRange replaces the top level nodes affected by the edit with the result of parsing their (edited) lines.
+// It returns false if the complete input has to be parsed instead.
+func (d *Document) reparseRange(edit TextEdit, input string) bool {
+	if d.FatalError != nil || d.tokens == nil || len(d.topLevelLines) != len(d.Nodes) {
+		return false
+	}
+	oldSource, oldInput, oldLines := d.source, d.input, d.topLevelLines
+	errors, sourceLines := d.errorsWithoutFootnotes(), d.sourceLines
+	if err := d.readLines(strings.NewReader(input)); err != nil {
+		return false
+	}
+	lineDelta, byteDelta := len(d.source)-len(oldSource), len(d.input)-len(oldInput)
+	// lists and footnote definitions look ahead two lines - the preceding node must end before that
+	lo := sort.Search(len(oldLines), func(i int) bool { return oldLines[i][1] >= edit.StartLine-2 })
+	hi := sort.Search(len(oldLines), func(i int) bool { return oldLines[i][0] > edit.EndLine+1 }) - 1
+	for extensions := 0; ; extensions++ {
+		from, to, lookaheadEnd := 0, len(oldSource), len(oldSource)
+		if lo > 0 {
+			from = oldLines[lo-1][1] + 1
+		}
+		if hi+1 < len(oldLines) {
+			to, lookaheadEnd = oldLines[hi+1][0], oldLines[hi+1][1]+1
+		}
+		if !isSelfContained(oldSource[from:to]) || !isSelfContained(d.source[from:to+lineDelta]) {
+			return false
+		}
+		d.Errors, d.sourceLines = keepErrors(errors, from), map[Position][2]int{}
+		for pos, lines := range sourceLines {
+			if lines[1] < from {
+				d.sourceLines[pos] = lines
+			} else if lines[0] >= to {
+				d.sourceLines[shiftPosition(pos, lineDelta, byteDelta)] = [2]int{lines[0] + lineDelta, lines[1] + lineDelta}
+			}
+		}
+		consumed, count, nodes, lines, ok := d.parseLines(from, to+lineDelta, lookaheadEnd+lineDelta)
+		if !ok {
+			return false
+		} else if consumed > count && hi+1 < len(oldLines) && extensions < maxReparseExtensions {
+			hi++ // the last node continues into the following node
+			continue
+		} else if consumed > count {
+			return false
+		}
+		d.Errors = append(d.Errors, shiftErrors(errors, to, lineDelta)...)
+		d.Nodes = append(append(d.Nodes[:lo:lo], nodes...), shiftNodes(d.Nodes[hi+1:], lineDelta, byteDelta)...)
+		d.topLevelLines = append(append(oldLines[:lo:lo], lines...), oldLines[hi+1:]...)
+		for i := lo + len(lines); i < len(d.topLevelLines); i++ {
+			d.topLevelLines[i] = [2]int{d.topLevelLines[i][0] + lineDelta, d.topLevelLines[i][1] + lineDelta}
+		}
+		d.NamedNodes = map[string]Node{}
+		addNamedNodes(d.NamedNodes, d.Nodes)
+		d.rebuildOutline()
+		d.addFootnoteWarnings(d.Nodes)
+		return true
+	}
+}
+
+// parseLines parses the top level nodes starting in the source lines from (inclusive) to end (exclusive). The lines
+// up to lookaheadEnd are available as lookahead. It returns the number of consumed tokens and the number of tokens
+// in the range - ok is false if parsing was aborted.
+func (d *Document) parseLines(from, end, lookaheadEnd int) (consumed, count int, nodes []Node, lines [][2]int, ok bool) {
+	defer func() {
+		d.parsing = false
+		if recovered := recover(); recovered != nil {
+			ok = false
+		}
+	}()
+	d.parsing = true
+	tokens := d.tokenizeLines(from, end)
+	errorCount := len(d.Errors)
+	d.tokens, count = append(tokens, d.tokenizeLines(end, lookaheadEnd)...), len(tokens)
+	d.Errors = d.Errors[:errorCount]
+	consumed, nodes, lines = d.parseTopLevel(count)
+	return consumed, count, nodes, lines, true
+}
+
+// isSelfContained returns false if the lines contain tokens that affect how the rest of the document is parsed.
+func isSelfContained(lines []string) bool {
+	open := []string{}
+	for _, line := range lines {
+		t, _ := tokenize(line)
+		switch t.kind {
+		case "keyword":
+			if key := upper(t.matches[2]); !reparseSafeKeywords[key] && !strings.HasPrefix(key, "ATTR_") {
+				return false
+			}
+		case "beginBlock", "beginLatexBlock":
+			open = append(open, strings.ToUpper(openBlockName(t)))
+		case "beginDrawer":
+			open = append(open, t.kind)
+		case "endBlock", "endLatexBlock", "endDrawer":
+			name := "beginDrawer"
+			if t.kind != "endDrawer" {
+				name = strings.ToUpper(openBlockName(t))
+			}
+			if len(open) == 0 || open[len(open)-1] != name {
+				return false
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	return len(open) == 0
+}
+
+// keepErrors returns the errors starting before line from.
+func keepErrors(errors []*ParseError, from int) []*ParseError {
+	kept := []*ParseError{}
+	for _, err := range errors {
+		if err.StartLine < from {
+			kept = append(kept, err)
+		}
+	}
+	return kept
+}
+
+// shiftErrors returns copies of the errors starting at or after line to - shifted by lineDelta lines.
+func shiftErrors(errors []*ParseError, to, lineDelta int) []*ParseError {
+	shifted := []*ParseError{}
+	for _, err := range errors {
+		if err.StartLine >= to {
+			err := *err
+			err.StartLine, err.EndLine = err.StartLine+lineDelta, err.EndLine+lineDelta
+			shifted = append(shifted, &err)
+		}
+	}
+	return shifted
+}
+
+// addNamedNodes adds the (nested) named nodes to namedNodes.
+func addNamedNodes(namedNodes map[string]Node, nodes []Node) {
+	walkNodes(nodes, func(n Node) {
+		if n, ok := n.(NodeWithName); ok {
+			namedNodes[n.Name] = n.Node
+		}
+	})
+}
+
+// rebuildOutline rebuilds the Outline and the headline indices from Nodes - headlines are added in document order
+// like during parsing.
+func (d *Document) rebuildOutline() {
+	root := &Section{document: d}
+	d.Outline = Outline{root, root, 0}
+	for i, n := range d.Nodes {
+		d.Nodes[i] = d.addToOutline(n)
+	}
+}
+
+// addToOutline adds the (nested) headlines of n to the Outline and the headline indices and returns n with their
+// updated Index.
+func (d *Document) addToOutline(n Node) Node {
+	if h, ok := n.(Headline); ok {
+		h.Index = d.addHeadline(&h)
+		h = mapChildren(h, d.addToOutline).(Headline) // the outline references h - update it in place
+		return h
+	}
+	return mapChildren(n, d.addToOutline)
+}
+
+// adopt makes d the document of the section and all its descendants.
+func (s *Section) adopt(d *Document) {
+	s.document = d
+	for _, child := range s.Children {
+		child.adopt(d)
+	}
+}
+
+// shiftPosition moves pos by the given number of lines and bytes. Empty positions are not moved.
+func shiftPosition(pos Position, lines, bytes int) Position {
+	if pos == (Position{}) {
+		return pos
+	}
+	pos.StartLine, pos.EndLine = pos.StartLine+lines, pos.EndLine+lines
+	pos.StartOffset, pos.EndOffset = pos.StartOffset+bytes, pos.EndOffset+bytes
+	return pos
+}
+
+// shiftNodes moves the positions of the (nested) nodes by the given number of lines and bytes.
+func shiftNodes(nodes []Node, lines, bytes int) []Node {
+	if lines == 0 && bytes == 0 {
+		return nodes
+	}
+	for i, n := range nodes {
+		nodes[i] = shiftNode(n, lines, bytes)
+	}
+	return nodes
+}
+
+func shiftNode(n Node, lines, bytes int) Node {
+	if p, ok := n.(Paragraph); ok && p.lazy != nil {
+		p.lazy = p.lazy.shifted(lines) // the positions of lazily parsed content are computed from its line
+		return withPosition(p, shiftPosition(p.Pos, lines, bytes))
+	}
+	n = mapChildren(n, func(child Node) Node { return shiftNode(child, lines, bytes) })
+	switch n := n.(type) {
+	case Table:
+		for i := range n.ColumnInfos {
+			n.ColumnInfos[i].Pos = shiftPosition(n.ColumnInfos[i].Pos, lines, bytes)
+		}
+		for i := range n.Rows {
+			n.Rows[i].Pos = shiftPosition(n.Rows[i].Pos, lines, bytes)
+			for j := range n.Rows[i].Columns {
+				n.Rows[i].Columns[j].Pos = shiftPosition(n.Rows[i].Columns[j].Pos, lines, bytes)
+			}
+		}
+	case NodeWithMeta:
+		n.Meta.Pos = shiftPosition(n.Meta.Pos, lines, bytes)
+		return withPosition(n, shiftPosition(n.Pos, lines, bytes))
+	}
+	return withPosition(n, shiftPosition(n.Position(), lines, bytes))
+}
+
+// withPosition returns a copy of n with the given position.
+func withPosition(n Node, pos Position) Node {
+	switch n := n.(type) {
+	case Headline:
+		n.Pos = pos
+		return n
+	case Block:
+		n.Pos = pos
+		return n
+	case Result:
+		n.Pos = pos
+		return n
+	case Example:
+		n.Pos = pos
+		return n
+	case LatexBlock:
+		n.Pos = pos
+		return n
+	case Drawer:
+		n.Pos = pos
+		return n
+	case PropertyDrawer:
+		n.Pos = pos
+		return n
+	case FootnoteDefinition:
+		n.Pos = pos
+		return n
+	case List:
+		n.Pos = pos
+		return n
+	case ListItem:
+		n.Pos = pos
+		return n
+	case DescriptiveListItem:
+		n.Pos = pos
+		return n
+	case Table:
+		n.Pos = pos
+		return n
+	case Paragraph:
+		n.Pos = pos
+		return n
+	case HorizontalRule:
+		n.Pos = pos
+		return n
+	case Comment:
+		n.Pos = pos
+		return n
+	case Keyword:
+		n.Pos = pos
+		return n
+	case Include:
+		n.Pos = pos
+		return n
+	case NodeWithName:
+		n.Pos = pos
+		return n
+	case NodeWithMeta:
+		n.Pos = pos
+		return n
+	case Text:
+		n.Pos = pos
+		return n
+	case LineBreak:
+		n.Pos = pos
+		return n
+	case ExplicitLineBreak:
+		n.Pos = pos
+		return n
+	case StatisticToken:
+		n.Pos = pos
+		return n
+	case Timestamp:
+		n.Pos = pos
+		return n
+	case Emphasis:
+		n.Pos = pos
+		return n
+	case InlineBlock:
+		n.Pos = pos
+		return n
+	case LatexFragment:
+		n.Pos = pos
+		return n
+	case FootnoteLink:
+		n.Pos = pos
+		return n
+	case RegularLink:
+		n.Pos = pos
+		return n
+	case Macro:
+		n.Pos = pos
+		return n
+	}
+	return n
+}
@@ -0,0 +1,106 @@
+package org
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestReparse(t *testing.T) {
+	edits := []func(lines []string) TextEdit{
+		func(lines []string) TextEdit { return TextEdit{len(lines) / 2, 0, len(lines) / 2, 0, "x"} },
+		func(lines []string) TextEdit { return TextEdit{len(lines) / 3, 0, len(lines)/3 + 1, 0, ""} },
+		func(lines []string) TextEdit { return TextEdit{len(lines) / 4, 0, len(lines) / 4, 0, "- new item\n\n"} },
+		func(lines []string) TextEdit {
+			return TextEdit{2 * len(lines) / 3, 0, 2 * len(lines) / 3, 0, "* new headline\nwith *text*\n"}
+		},
+		func(lines []string) TextEdit { return TextEdit{len(lines) - 1, 0, len(lines), 0, "#+BEGIN_SRC\n"} },
+	}
+	for _, path := range orgTestFiles() {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			input := fileString(t, path)
+			for i, edit := range edits {
+				d := New().Silent().Parse(strings.NewReader(input), path)
+				edit := edit(d.source)
+				d.Reparse(edit)
+				expected := New().Silent().Parse(strings.NewReader(d.input), path)
+				if actual, expected := dumpDocument(d), dumpDocument(expected); actual != expected {
+					t.Errorf("edit %d %#v:\n%s", i, edit, diff(actual, expected))
+				}
+			}
+		})
+	}
+}
+
+func TestReparseBlockPairing(t *testing.T) {
+	input := "* a\n#+begin_src go\nx\n#+end_src\n* b\n#+begin_quote\nq\n#+end_quote\n"
+	for _, edit := range []TextEdit{
+		{StartLine: 1, StartColumn: 10, EndLine: 1, EndColumn: 11, NewText: ""},
+		{StartLine: 3, StartColumn: 6, EndLine: 3, EndColumn: 9, NewText: "quote"},
+	} {
+		d := New().Silent().Parse(strings.NewReader(input), "./blocks.org")
+		d.Reparse(edit)
+		expected := New().Silent().Parse(strings.NewReader(d.input), "./blocks.org")
+		if actual, expected := dumpDocument(d), dumpDocument(expected); actual != expected {
+			t.Errorf("edit %#v:\n%s", edit, diff(actual, expected))
+		}
+	}
+}
+
+func TestReparseIsIncremental(t *testing.T) {
+	sections := []string{}
+	for i := 0; i < 100; i++ {
+		sections = append(sections, fmt.Sprintf("* Section %d\nSome *text*\n- a list\n- with [[#id][a link]]\n", i))
+	}
+	d := New().Silent().Parse(strings.NewReader(strings.Join(sections, "")), "./incremental.org")
+	d.Reparse(TextEdit{StartLine: 201, StartColumn: 5, EndLine: 201, EndColumn: 5, NewText: "more "})
+	if len(d.tokens) > 20 {
+		t.Errorf("expected only the sections around the edit to be tokenized, got %d tokens", len(d.tokens))
+	}
+	if actual := d.Source(d.NodeAt(201, 10)); actual != "*text*" {
+		t.Errorf("got %q", actual)
+	}
+	if actual, expected := dumpDocument(d), dumpDocument(New().Silent().Parse(strings.NewReader(d.input), "./incremental.org")); actual != expected {
+		t.Errorf("%s", diff(actual, expected))
+	}
+	d.Reparse(TextEdit{StartLine: 0, StartColumn: 0, EndLine: 0, EndColumn: 0, NewText: "#+TODO: TODO DONE\n"})
+	if len(d.tokens) != len(d.source) {
+		t.Errorf("expected buffer settings to be parsed completely, got %d tokens for %d lines", len(d.tokens), len(d.source))
+	}
+	d.Reparse(TextEdit{StartLine: 1000, StartColumn: 0, EndLine: 1000, EndColumn: 0, NewText: "x"})
+	if errors := d.GetErrorByType(ErrorTypeValidation); len(errors) != 1 {
+		t.Errorf("expected invalid text edit error, got %v", errors)
+	}
+}
+
+// dumpDocument returns a string containing the (nested) nodes of the document with their positions and
+// everything else Reparse updates.
+func dumpDocument(d *Document) string {
+	b := &strings.Builder{}
+	var dump func(nodes []Node, indent string)
+	dump = func(nodes []Node, indent string) {
+		for _, n := range nodes {
+			fmt.Fprintf(b, "%s%T %+v\n", indent, n, n.Position())
+			dump(children(n), indent+"  ")
+		}
+	}
+	dump(d.Nodes, "")
+	fmt.Fprintf(b, "top level lines: %v\n", d.topLevelLines)
+	for _, err := range d.Errors {
+		fmt.Fprintf(b, "error: %s\n", err)
+	}
+	names := []string{}
+	for name, n := range d.NamedNodes {
+		names = append(names, fmt.Sprintf("name %s: %+v\n", name, n.Position()))
+	}
+	sort.Strings(names)
+	b.WriteString(strings.Join(names, ""))
+	for _, s := range d.Outline.Children {
+		fmt.Fprintf(b, "section: %d %s\n", s.Headline.Index, String(s.Headline.Title...))
+	}
+	out, err := d.Write(NewOrgWriter())
+	fmt.Fprintf(b, "%s%v\n", out, err)
+	return b.String()
+}
@@ -0,0 +1,183 @@
+package org
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SemanticTokenType identifies the syntax element a SemanticToken highlights.
+type SemanticTokenType int
+
+const (
+	HeadlineStarsToken SemanticTokenType = iota
+	TodoKeywordToken
+	PriorityToken
+	TagToken
+	TimestampToken
+	LinkToken
+	FootnoteToken
+	EmphasisMarkerToken
+	BlockDelimiterToken
+	DrawerDelimiterToken
+	KeywordToken
+	CommentToken
+	ListBulletToken
+	CheckboxToken
+	MacroToken
+	LatexToken
+	StatisticCookieToken
+)
+
+func (t SemanticTokenType) String() string {
+	switch t {
+	case HeadlineStarsToken:
+		return "headline-stars"
+	case TodoKeywordToken:
+		return "todo-keyword"
+	case PriorityToken:
+		return "priority"
+	case TagToken:
+		return "tag"
+	case TimestampToken:
+		return "timestamp"
+	case LinkToken:
+		return "link"
+	case FootnoteToken:
+		return "footnote"
+	case EmphasisMarkerToken:
+		return "emphasis-marker"
+	case BlockDelimiterToken:
+		return "block-delimiter"
+	case DrawerDelimiterToken:
+		return "drawer-delimiter"
+	case KeywordToken:
+		return "keyword"
+	case CommentToken:
+		return "comment"
+	case ListBulletToken:
+		return "list-bullet"
+	case CheckboxToken:
+		return "checkbox"
+	case MacroToken:
+		return "macro"
+	case LatexToken:
+		return "latex"
+	case StatisticCookieToken:
+		return "statistic-cookie"
+	default:
+		return "unknown"
+	}
+}
+
+// SemanticToken is a highlighted span of the source text, e.g. the stars of a headline.
+type SemanticToken struct {
+	Type SemanticTokenType
+	Pos  Position
+}
+
+var listItemPrefixRegexp = regexp.MustCompile(`^(\s*)(\S+)\s+(\[@\w+\]\s+)?(\[[ X-]\])?`)
+
+// SemanticTokens returns the tokens of the document for syntax highlighting (e.g. LSP semantic tokens) ordered by
+// position. Tokens do not overlap - tokens inside links and footnotes (e.g. emphasis markers of a link description)
+// are dropped. Only nodes of the parse input are tokenized, i.e. not those of included files.
+func (d *Document) SemanticTokens() []SemanticToken {
+	tokens := []SemanticToken{}
+	add := func(t SemanticTokenType, start, end int) {
+		if start < end && end <= len(d.input) {
+			tokens = append(tokens, SemanticToken{t, d.positionFromOffsets(start, end)})
+		}
+	}
+	delimiters := func(t SemanticTokenType, pos Position) {
+		src := d.input[pos.StartOffset:pos.EndOffset]
+		first, last := strings.Index(src, "\n"), strings.LastIndex(src, "\n")
+		if first == -1 {
+			return
+		}
+		add(t, pos.StartOffset, pos.StartOffset+first)
+		add(t, pos.StartOffset+last+1, pos.EndOffset)
+	}
+	walkNodes(d.Nodes, func(n Node) {
+		pos := n.Position()
+		if pos == (Position{}) || pos.StartOffset >= pos.EndOffset || pos.EndOffset > len(d.input) {
+			return
+		}
+		start, end, src := pos.StartOffset, pos.EndOffset, d.input[pos.StartOffset:pos.EndOffset]
+		switch n := n.(type) {
+		case Headline:
+			d.headlineTokens(n, add)
+			if n.Properties != nil && n.Properties.Pos.EndOffset <= len(d.input) && n.Properties.Pos.StartOffset < n.Properties.Pos.EndOffset {
+				delimiters(DrawerDelimiterToken, n.Properties.Pos)
+			}
+		case Emphasis:
+			if len(n.Kind) == 1 && src[0] == n.Kind[0] && src[len(src)-1] == n.Kind[0] {
+				add(EmphasisMarkerToken, start, start+1)
+				add(EmphasisMarkerToken, end-1, end)
+			}
+		case Timestamp:
+			add(TimestampToken, start, end)
+		case RegularLink:
+			add(LinkToken, start, end)
+		case FootnoteLink:
+			add(FootnoteToken, start, end)
+		case Macro:
+			add(MacroToken, start, end)
+		case LatexFragment:
+			add(LatexToken, start, end)
+		case StatisticToken:
+			add(StatisticCookieToken, start, end)
+		case Comment:
+			add(CommentToken, start, end)
+		case Keyword:
+			if i := strings.Index(src, ":"); strings.HasPrefix(src, "#+") && i != -1 {
+				add(KeywordToken, start, start+i+1)
+			}
+		case Block:
+			delimiters(BlockDelimiterToken, pos)
+		case Drawer, PropertyDrawer:
+			delimiters(DrawerDelimiterToken, pos)
+		case ListItem, DescriptiveListItem:
+			if m := listItemPrefixRegexp.FindStringSubmatchIndex(src); m != nil {
+				add(ListBulletToken, start+m[4], start+m[5])
+				if m[8] != -1 {
+					add(CheckboxToken, start+m[8], start+m[9])
+				}
+			}
+		}
+	})
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].Pos.StartOffset < tokens[j].Pos.StartOffset })
+	nonOverlapping := tokens[:0]
+	for _, t := range tokens {
+		if n := len(nonOverlapping); n == 0 || t.Pos.StartOffset >= nonOverlapping[n-1].Pos.EndOffset {
+			nonOverlapping = append(nonOverlapping, t)
+		}
+	}
+	return nonOverlapping
+}
+
+// headlineTokens adds the tokens of the headline line of h: stars, todo keyword, priority and tags.
+func (d *Document) headlineTokens(h Headline, add func(SemanticTokenType, int, int)) {
+	start := h.Pos.StartOffset
+	line, _, _ := strings.Cut(d.input[start:h.Pos.EndOffset], "\n")
+	if !strings.HasPrefix(line, strings.Repeat("*", h.Lvl)) {
+		return
+	}
+	add(HeadlineStarsToken, start, start+h.Lvl)
+	i := h.Lvl + len(line[h.Lvl:]) - len(strings.TrimLeft(line[h.Lvl:], " \t"))
+	if h.Status != "" && strings.HasPrefix(line[i:], h.Status) {
+		add(TodoKeywordToken, start+i, start+i+len(h.Status))
+		i += len(h.Status) + len(line[i+len(h.Status):]) - len(strings.TrimLeft(line[i+len(h.Status):], " \t"))
+	}
+	if priority := "[#" + h.Priority + "]"; h.Priority != "" && strings.HasPrefix(line[i:], priority) {
+		add(PriorityToken, start+i, start+i+len(priority))
+	}
+	if m := tagRegexp.FindStringSubmatchIndex(line); m != nil && len(h.Tags) != 0 {
+		offset := m[4]
+		for _, tag := range strings.Split(strings.TrimSpace(line[m[4]:m[5]]), ":") {
+			if tag != "" {
+				add(TagToken, start+offset, start+offset+len(tag))
+			}
+			offset += len(tag) + 1
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	input := strings.Join([]string{
+		"#+MACRO: m x",
+		"* TODO [#A] Title *bold* =v= :a:b:",
+		":PROPERTIES:",
+		":X: 1",
+		":END:",
+		"  - [X] item <2024-01-01 Mon> [1/2]",
+		"#+BEGIN_SRC go",
+		"x",
+		"#+END_SRC",
+		"# comment",
+		"[[https://x.org][*x*]] [fn:1] {{{m()}}} \\(x\\)",
+	}, "\n")
+	d := New().Silent().Parse(strings.NewReader(input), "./tokens.org")
+	actual := []string{}
+	for _, t := range d.SemanticTokens() {
+		actual = append(actual, fmt.Sprintf("%s %d:%d-%d %s", t.Type, t.Pos.StartLine, t.Pos.StartColumn, t.Pos.EndColumn, input[t.Pos.StartOffset:t.Pos.EndOffset]))
+	}
+	expected := []string{
+		"keyword 0:0-8 #+MACRO:",
+		"headline-stars 1:0-1 *",
+		"todo-keyword 1:2-6 TODO",
+		"priority 1:7-11 [#A]",
+		"emphasis-marker 1:18-19 *",
+		"emphasis-marker 1:23-24 *",
+		"emphasis-marker 1:25-26 =",
+		"emphasis-marker 1:27-28 =",
+		"tag 1:30-31 a",
+		"tag 1:32-33 b",
+		"drawer-delimiter 2:0-12 :PROPERTIES:",
+		"drawer-delimiter 4:0-5 :END:",
+		"list-bullet 5:2-3 -",
+		"checkbox 5:4-7 [X]",
+		"timestamp 5:13-29 <2024-01-01 Mon>",
+		"statistic-cookie 5:30-35 [1/2]",
+		"block-delimiter 6:0-14 #+BEGIN_SRC go",
+		"block-delimiter 8:0-9 #+END_SRC",
+		"comment 9:0-9 # comment",
+		"link 10:0-22 [[https://x.org][*x*]]",
+		"footnote 10:23-29 [fn:1]",
+		"macro 10:30-39 {{{m()}}}",
+		"latex 10:40-45 \\(x\\)",
+	}
+	if actual, expected := strings.Join(actual, "\n"), strings.Join(expected, "\n"); actual != expected {
+		t.Errorf("got:\n%s", diff(actual, expected))
+	}
+}
@@ -0,0 +1,121 @@
+package org
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseStream parses the input and calls f for each top level node as soon as it is complete, i.e. once the next
+// top level node starts. Parsing stops early if f returns false.
+//
+// Unlike Parse, ParseStream only keeps the lines, tokens and nodes of the current top level node (e.g. a top
+// level headline and its subtree) in memory - this allows processing huge files with bounded memory.
+// The returned document contains the buffer settings and errors of the input. Its Nodes, Outline and NamedNodes
+// only contain the last top level node and Source is not available.
+func (c *Configuration) ParseStream(input io.Reader, f func(Node) bool) (d *Document) {
+	d = c.newDocument("")
+	defer func() {
+		d.parsing = false
+		if recovered := recover(); recovered != nil {
+			if abort, ok := recovered.(abortParse); ok {
+				d.Nodes, d.FatalError = nil, abort.err
+				return
+			}
+			d.AddFatalError(ErrorTypeInvalidStructure, "parse panic", d.Pos, token{}, fmt.Errorf("recovered from panic: %v", recovered))
+		}
+	}()
+	d.parsing, d.tokens, d.source, d.lineOffsets = true, []token{}, []string{}, []int{}
+	lineNum, lineOffset, offset, footnotes := 0, 0, 0, []Node{}
+	scanner := d.lineScanner(input, func(line, _ []byte) { lineOffset, offset = offset, offset+len(line) })
+	emit := func() bool {
+		_, d.Nodes, _ = d.parseTopLevel(len(d.tokens))
+		footnotes = appendFootnoteNodes(footnotes, d.Nodes)
+		for _, n := range d.Nodes {
+			if !f(n) {
+				return false
+			}
+		}
+		return true
+	}
+	openBlocks, headlineLvl := []string{}, 0
+	for ; scanner.Scan(); lineNum++ {
+		d.source, d.lineOffsets = append(d.source, scanner.Text()), append(d.lineOffsets, lineOffset)
+		tokens := d.tokenizeLines(lineNum, lineNum+1)
+		if len(tokens) == 0 {
+			continue
+		}
+		t := tokens[0]
+		// a headline ends the current top level node unless it is nested in it or part of a block
+		if t.kind == "headline" && len(openBlocks) == 0 && (headlineLvl == 0 || len(t.matches[1]) <= headlineLvl) {
+			if len(d.tokens) != 0 && !emit() {
+				d.addFootnoteWarnings(footnotes)
+				return d
+			}
+			root := &Section{document: d}
+			d.Outline, d.NamedNodes = Outline{root, root, d.Outline.count}, map[string]Node{}
+			d.tokens, d.source, d.lineOffsets, d.firstLine = []token{}, d.source[len(d.source)-1:], d.lineOffsets[len(d.lineOffsets)-1:], lineNum
+			headlineLvl = len(t.matches[1])
+		}
+		openBlocks = updateOpenBlocks(openBlocks, t)
+		d.tokens = append(d.tokens, t)
+	}
+	if err := scanner.Err(); err != nil {
+		d.addReadError(err, lineNum)
+		return d
+	}
+	if len(d.tokens) != 0 {
+		emit()
+	}
+	d.addFootnoteWarnings(footnotes)
+	return d
+}
+
+// appendFootnoteNodes appends the footnote links and definitions of nodes to footnotes - without the content of
+// the definitions, i.e. just what is needed to report their warnings (see addFootnoteWarnings).
+func appendFootnoteNodes(footnotes []Node, nodes []Node) []Node {
+	walkNodes(nodes, func(n Node) {
+		switch n := n.(type) {
+		case FootnoteLink:
+			if n.Definition != nil {
+				definition := *n.Definition
+				definition.Children, n.Definition = nil, &definition
+			}
+			footnotes = append(footnotes, n)
+		case FootnoteDefinition:
+			n.Children = nil
+			footnotes = append(footnotes, n)
+		}
+	})
+	return footnotes
+}
+
+// updateOpenBlocks returns the blocks that are still open after the token. Blocks are matched by name like
+// during parsing and the content of raw text blocks (e.g. SRC) is not parsed.
+func updateOpenBlocks(openBlocks []string, t token) []string {
+	switch t.kind {
+	case "beginBlock", "beginLatexBlock":
+		if len(openBlocks) == 0 || !isRawOpenBlock(openBlocks[len(openBlocks)-1]) {
+			return append(openBlocks, openBlockName(t))
+		}
+	case "endBlock", "endLatexBlock":
+		for i := len(openBlocks) - 1; i >= 0; i-- {
+			if openBlocks[i] == openBlockName(t) {
+				return openBlocks[:i]
+			} else if isRawOpenBlock(openBlocks[i]) {
+				break
+			}
+		}
+	}
+	return openBlocks
+}
+
+// openBlockName returns the name of the block of a begin or end token. Latex environments are prefixed with \.
+func openBlockName(t token) string {
+	if t.kind == "beginLatexBlock" || t.kind == "endLatexBlock" {
+		return `\` + t.content
+	}
+	return t.content
+}
+
+func isRawOpenBlock(name string) bool { return isRawTextBlock(name) || strings.HasPrefix(name, `\`) }
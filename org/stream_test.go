@@ -0,0 +1,80 @@
+package org
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseStream(t *testing.T) {
+	for _, path := range orgTestFiles() {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			input := fileString(t, path)
+			d := New().Silent().Parse(strings.NewReader(input), "")
+			nodes := []Node{}
+			streamed := New().Silent().ParseStream(strings.NewReader(input), func(n Node) bool {
+				nodes = append(nodes, n)
+				return true
+			})
+			if actual, expected := dumpNodes(nodes), dumpNodes(d.Nodes); actual != expected {
+				t.Errorf("%s", diff(actual, expected))
+			}
+			if actual, expected := fmt.Sprint(streamed.BufferSettings), fmt.Sprint(d.BufferSettings); actual != expected {
+				t.Errorf("got buffer settings %s, expected %s", actual, expected)
+			}
+			if actual, expected := fmt.Sprint(streamed.Errors), fmt.Sprint(d.Errors); actual != expected {
+				t.Errorf("got errors %s, expected %s", actual, expected)
+			}
+		})
+	}
+}
+
+func TestParseStreamBoundedMemory(t *testing.T) {
+	input := "#+TODO: TODO NEXT | DONE\n" + strings.Repeat("* NEXT headline\n** child\n#+BEGIN_SRC\n* not a headline\n#+END_SRC\ntext\n", 1000)
+	count := 0
+	d := New().Silent().ParseStream(strings.NewReader(input), func(n Node) bool {
+		if h, ok := n.(Headline); ok && (h.Status != "NEXT" || len(h.Children) != 1) {
+			t.Errorf("bad headline %#v", h)
+		}
+		count++
+		return count < 500
+	})
+	if count != 500 {
+		t.Errorf("expected ParseStream to stop after 500 nodes, got %d", count)
+	}
+	if len(d.source) > 10 {
+		t.Errorf("expected only the lines of the last top level node to be kept, got %d", len(d.source))
+	}
+}
+
+func TestParseStreamLongLines(t *testing.T) {
+	input := "* a\n" + strings.Repeat("x", 100000) + "\n* b\n"
+	count := 0
+	d := New().Silent().ParseStream(strings.NewReader(input), func(Node) bool { count++; return true })
+	if d.FatalError != nil || count != 2 {
+		t.Errorf("expected lines longer than the default scanner buffer to be parsed, got %d nodes and %v", count, d.FatalError)
+	}
+	configuration := New().Silent()
+	configuration.MaxLineLength = 1024
+	d = configuration.ParseStream(strings.NewReader(input), func(Node) bool { return true })
+	if d.FatalError == nil || d.FatalError.Message != "line too long" || d.FatalError.StartLine != 1 {
+		t.Errorf("expected a line too long error for line 1, got %v", d.FatalError)
+	}
+}
+
+func dumpNodes(nodes []Node) string {
+	b := &strings.Builder{}
+	var dump func(nodes []Node, indent string)
+	dump = func(nodes []Node, indent string) {
+		for _, n := range nodes {
+			if h, ok := n.(Headline); ok {
+				fmt.Fprintf(b, "%sheadline %d\n", indent, h.Index)
+			}
+			fmt.Fprintf(b, "%s%T %+v\n", indent, n, n.Position())
+			dump(children(n), indent+"  ")
+		}
+	}
+	dump(nodes, "")
+	return b.String() + String(nodes...)
+}
@@ -0,0 +1,144 @@
+package org
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SymbolKind identifies the kind of entity a Symbol refers to.
+type SymbolKind int
+
+const (
+	MacroSymbol SymbolKind = iota
+	NameSymbol
+	TargetSymbol
+	RadioTargetSymbol
+	FootnoteSymbol
+	CustomIDSymbol
+	HeadlineSymbol
+)
+
+func (k SymbolKind) String() string {
+	switch k {
+	case MacroSymbol:
+		return "macro"
+	case NameSymbol:
+		return "name"
+	case TargetSymbol:
+		return "target"
+	case RadioTargetSymbol:
+		return "radio-target"
+	case FootnoteSymbol:
+		return "footnote"
+	case CustomIDSymbol:
+		return "custom-id"
+	case HeadlineSymbol:
+		return "headline"
+	default:
+		return "unknown"
+	}
+}
+
+// Symbol is a definable / referencable entity of a document, e.g. a macro or a footnote definition.
+type Symbol struct {
+	Kind SymbolKind
+	Name string
+	Node Node     // Node is the node that defines the symbol.
+	Pos  Position // Pos is the position of the definition in the source text.
+}
+
+var radioTargetRegexp = regexp.MustCompile(`<<<([^<>\n]+)>>>`)
+var targetRegexp = regexp.MustCompile(`(^|[^<])<<([^<>\n]+)>>`)
+
+// Symbols returns an index of all macros, #+NAME nodes, targets, radio targets,
+// footnote definitions, CUSTOM_IDs and headlines of the document in document order.
+func (d *Document) Symbols() []Symbol {
+	symbols := []Symbol{}
+	walkNodes(d.Nodes, func(n Node) {
+		switch n := n.(type) {
+		case Keyword:
+			if n.Key == "MACRO" {
+				if name := strings.Fields(n.Value); len(name) != 0 {
+					symbols = append(symbols, Symbol{MacroSymbol, name[0], n, n.Pos})
+				}
+			}
+		case NodeWithName:
+			symbols = append(symbols, Symbol{NameSymbol, n.Name, n, n.Pos})
+		case FootnoteDefinition:
+			symbols = append(symbols, Symbol{FootnoteSymbol, n.Name, n, n.Pos})
+		case FootnoteLink:
+			if n.Name != "" && n.Definition != nil {
+				symbols = append(symbols, Symbol{FootnoteSymbol, n.Name, *n.Definition, n.Pos})
+			}
+		case Text:
+			if !n.IsRaw {
+				symbols = append(symbols, d.textTargetSymbols(n)...)
+			}
+		case Headline:
+			symbols = append(symbols, Symbol{HeadlineSymbol, strings.TrimSpace(String(n.Title...)), n, n.Pos})
+			if customID, ok := n.Properties.Get("CUSTOM_ID"); ok {
+				symbols = append(symbols, Symbol{CustomIDSymbol, customID, n, n.Properties.Pos})
+			}
+		}
+	})
+	return symbols
+}
+
+// Lookup returns all symbols of the given kind and name in document order.
+// The symbols are indexed once per parse (and Reparse) - like Reparse, Lookup expects Nodes to be unmodified since.
+func (d *Document) Lookup(kind SymbolKind, name string) []Symbol {
+	return append([]Symbol{}, d.symbolIndex().symbols[symbolKey{kind, name}]...)
+}
+
+// symbolIndex maps the kinds and names of the symbols of a document to the symbols - see Lookup.
+type symbolIndex struct {
+	root    *Section
+	nodes   []Node // nodes are the Nodes the index was built for.
+	symbols map[symbolKey][]Symbol
+}
+
+type symbolKey struct {
+	kind SymbolKind
+	name string
+}
+
+// symbolIndex returns the symbolIndex of the document - it is rebuilt if the Outline or the Nodes changed.
+func (d *Document) symbolIndex() *symbolIndex {
+	if d.index == nil {
+		return d.buildSymbolIndex()
+	}
+	d.index.Lock()
+	defer d.index.Unlock()
+	if index := d.index.symbols; index != nil && index.root == d.Outline.Section && sameNodes(index.nodes, d.Nodes) {
+		return index
+	}
+	d.index.symbols = d.buildSymbolIndex()
+	return d.index.symbols
+}
+
+func (d *Document) buildSymbolIndex() *symbolIndex {
+	index := &symbolIndex{d.Outline.Section, d.Nodes, map[symbolKey][]Symbol{}}
+	for _, s := range d.Symbols() {
+		key := symbolKey{s.Kind, s.Name}
+		index.symbols[key] = append(index.symbols[key], s)
+	}
+	return index
+}
+
+func sameNodes(a, b []Node) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+func (d *Document) textTargetSymbols(t Text) []Symbol {
+	symbols := []Symbol{}
+	for _, m := range radioTargetRegexp.FindAllStringSubmatchIndex(t.Content, -1) {
+		pos := d.positionFromOffsets(t.Pos.StartOffset+m[0], t.Pos.StartOffset+m[1])
+		symbols = append(symbols, Symbol{RadioTargetSymbol, t.Content[m[2]:m[3]], t, pos})
+	}
+	for _, m := range targetRegexp.FindAllStringSubmatchIndex(t.Content, -1) {
+		start := m[3] // skip the character preceding the target (if any)
+		pos := d.positionFromOffsets(t.Pos.StartOffset+start, t.Pos.StartOffset+m[1])
+		symbols = append(symbols, Symbol{TargetSymbol, t.Content[m[4]:m[5]], t, pos})
+	}
+	return symbols
+}
@@ -0,0 +1,47 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSymbols(t *testing.T) {
+	input := `#+MACRO: greet Hello $1
+* Headline
+:PROPERTIES:
+:CUSTOM_ID: custom
+:END:
+A <<target>> and a <<<radio>>> with a footnote [fn:1] and [fn:inline:definition].
+
+#+NAME: named
+| a | b |
+
+[fn:1] the definition
+`
+	d := New().Silent().Parse(strings.NewReader(input), "./symbols.org")
+	expected := map[SymbolKind][]string{
+		MacroSymbol:       {"greet"},
+		HeadlineSymbol:    {"Headline"},
+		CustomIDSymbol:    {"custom"},
+		TargetSymbol:      {"target"},
+		RadioTargetSymbol: {"radio"},
+		FootnoteSymbol:    {"inline", "1"},
+		NameSymbol:        {"named"},
+	}
+	actual := map[SymbolKind][]string{}
+	for _, s := range d.Symbols() {
+		actual[s.Kind] = append(actual[s.Kind], s.Name)
+	}
+	for kind, names := range expected {
+		if strings.Join(actual[kind], " ") != strings.Join(names, " ") {
+			t.Errorf("%s: got %v, expected %v", kind, actual[kind], names)
+		}
+	}
+	if s := d.Lookup(TargetSymbol, "target"); len(s) != 1 || s[0].Pos.StartLine != 5 || s[0].Pos.StartColumn != 2 {
+		t.Errorf("bad target position: %#v", s)
+	}
+	d.Reparse(TextEdit{StartLine: 5, StartColumn: 4, EndLine: 5, EndColumn: 10, NewText: "edited"})
+	if len(d.Lookup(TargetSymbol, "target")) != 0 || len(d.Lookup(TargetSymbol, "edited")) != 1 {
+		t.Errorf("expected Lookup to reflect Reparse")
+	}
+}
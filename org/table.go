@@ -64,7 +64,7 @@ func (d *Document) parseTable(i int, parentStop stopFn) (int, Node) {
 		}
 	}
 
-	table := Table{Rows: nil, ColumnInfos: getColumnInfos(rawRows), SeparatorIndices: separatorIndices}
+	table := Table{Rows: nil, ColumnInfos: getColumnInfos(rawRows, utf8.RuneCountInString), SeparatorIndices: separatorIndices}
 	for j, rawColumns := range rawRows {
 		row := Row{Columns: nil, IsSpecial: isSpecialRow(rawColumns)}
 		if len(rawColumns) != 0 {
@@ -76,24 +76,77 @@ func (d *Document) parseTable(i int, parentStop stopFn) (int, Node) {
 				row.Columns = append(row.Columns, column)
 			}
 		}
-		row.Pos = Position{
-			StartLine:   d.tokens[start+j].line,
-			StartColumn: d.tokens[start+j].startCol,
-			EndLine:     d.tokens[start+j].line,
-			EndColumn:   d.tokens[start+j].endCol,
-		}
+		row.Pos = d.getPositionFromToken(d.tokens[start+j])
 		table.Rows = append(table.Rows, row)
 	}
-	table.Pos = Position{
-		StartLine:   d.tokens[start].line,
-		StartColumn: d.tokens[start].startCol,
-		EndLine:     d.tokens[i-1].line,
-		EndColumn:   d.tokens[i-1].endCol,
-	}
+	table.Pos = d.getPositionBetweenTokens(d.tokens[start], d.tokens[i-1])
 	return i - start, table
 }
 
-func getColumnInfos(rows [][]string) []ColumnInfo {
+// AlignTable returns a copy of the table with column widths and alignments recomputed from the current content of its
+// cells - like org-table-align. Widths are measured in display columns, i.e. East Asian wide characters count as two
+// columns. Rows with missing cells are padded with empty cells. See OrgWriter.AlignTables.
+func AlignTable(table Table) Table {
+	aligned, writer, rawRows := table.Copy().(Table), NewOrgWriter(), [][]string{}
+	for _, row := range aligned.Rows {
+		if len(row.Columns) == 0 {
+			rawRows = append(rawRows, nil)
+			continue
+		}
+		rawRow := make([]string, len(row.Columns))
+		for i, column := range row.Columns {
+			rawRow[i] = strings.TrimSpace(writer.WriteNodesAsString(column.Children...))
+		}
+		rawRows = append(rawRows, rawRow)
+	}
+	aligned.ColumnInfos = getColumnInfos(rawRows, stringWidth)
+	for i, row := range aligned.Rows {
+		if len(row.Columns) == 0 {
+			continue
+		}
+		for j := range aligned.ColumnInfos {
+			if j < len(row.Columns) {
+				row.Columns[j].ColumnInfo = &aligned.ColumnInfos[j]
+			} else {
+				row.Columns = append(row.Columns, Column{ColumnInfo: &aligned.ColumnInfos[j]})
+			}
+		}
+		aligned.Rows[i] = row
+	}
+	return aligned
+}
+
+// stringWidth returns the number of display columns of s - East Asian wide and fullwidth characters count as two.
+func stringWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if isWideRune(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+func isWideRune(r rune) bool {
+	return r >= 0x1100 && (r <= 0x115f || // Hangul Jamo
+		r >= 0x2e80 && r <= 0x303e || // CJK Radicals, Kangxi Radicals, CJK Symbols and Punctuation
+		r >= 0x3041 && r <= 0x33ff || // Hiragana, Katakana, Bopomofo, CJK Compatibility
+		r >= 0x3400 && r <= 0x4dbf || // CJK Unified Ideographs Extension A
+		r >= 0x4e00 && r <= 0x9fff || // CJK Unified Ideographs
+		r >= 0xa000 && r <= 0xa4cf || // Yi
+		r >= 0xac00 && r <= 0xd7a3 || // Hangul Syllables
+		r >= 0xf900 && r <= 0xfaff || // CJK Compatibility Ideographs
+		r >= 0xfe30 && r <= 0xfe4f || // CJK Compatibility Forms
+		r >= 0xff00 && r <= 0xff60 || // Fullwidth Forms
+		r >= 0xffe0 && r <= 0xffe6 ||
+		r >= 0x1f300 && r <= 0x1f64f || // Emoji
+		r >= 0x20000 && r <= 0x3fffd) // CJK Unified Ideographs Extension B and beyond
+}
+
+// getColumnInfos returns the column infos for the raw rows of a table - width returns the length of a cell.
+func getColumnInfos(rows [][]string, width func(string) int) []ColumnInfo {
 	columnCount := 0
 	for _, columns := range rows {
 		if n := len(columns); n > columnCount {
@@ -109,7 +162,7 @@ func getColumnInfos(rows [][]string) []ColumnInfo {
 				continue
 			}
 
-			if n := utf8.RuneCountInString(columns[i]); n > columnInfos[i].Len {
+			if n := width(columns[i]); n > columnInfos[i].Len {
 				columnInfos[i].Len = n
 			}
 
@@ -0,0 +1,161 @@
+package org
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// TagDefinition is a single tag declared via #+TAGS, e.g. "@work(w)".
+type TagDefinition struct {
+	Name    string
+	FastKey rune // FastKey is the optional selection key given in parentheses - 0 if there is none.
+}
+
+// TagGroup is a group of tags declared via #+TAGS.
+// "{ a b }" declares a group of mutually exclusive tags, "[ g : a b ]" declares a group tag g for the tags a and b.
+type TagGroup struct {
+	Name      string // Name is the group tag (the tag before the ":" separator) - empty if there is none.
+	Tags      []TagDefinition
+	Exclusive bool
+}
+
+// TagConfiguration is the structured form of all #+TAGS keywords of a document.
+type TagConfiguration struct {
+	Tags   []TagDefinition // Tags contains all declared tags - including the ones inside of groups.
+	Groups []TagGroup
+}
+
+// TagConfiguration parses the #+TAGS keywords of the document.
+func (d *Document) TagConfiguration() TagConfiguration {
+	return parseTagConfiguration(d.Get("TAGS"))
+}
+
+// FileTags returns the tags declared via #+FILETAGS. They are inherited by every headline of the document.
+func (d *Document) FileTags() []string {
+	return strings.FieldsFunc(d.Get("FILETAGS"), func(r rune) bool { return r == ':' || r == ' ' || r == '\t' || r == '\n' })
+}
+
+// AllTags returns the tags of the headline including the ones inherited from #+FILETAGS and its ancestors.
+func (h Headline) AllTags(d *Document) []string {
+	tags := append([]string{}, d.FileTags()...)
+	if section := d.Outline.SectionOf(h); section != nil {
+		ancestors := []*Section{}
+		for s := section.Parent; s != nil && s.Headline != nil; s = s.Parent {
+			ancestors = append(ancestors, s)
+		}
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			tags = append(tags, ancestors[i].Headline.Tags...)
+		}
+	}
+	tags = append(tags, h.Tags...)
+	uniqueTags := []string{}
+	for _, tag := range tags {
+		if !slices.Contains(uniqueTags, tag) {
+			uniqueTags = append(uniqueTags, tag)
+		}
+	}
+	return uniqueTags
+}
+
+// Get returns the definition of the tag with the given name.
+func (c TagConfiguration) Get(name string) (TagDefinition, bool) {
+	for _, t := range c.Tags {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return TagDefinition{}, false
+}
+
+// ExclusiveWith returns the tags that are mutually exclusive with the tag with the given name.
+func (c TagConfiguration) ExclusiveWith(name string) []string {
+	exclusive := []string{}
+	for _, g := range c.Groups {
+		if !g.Exclusive || !slices.ContainsFunc(g.Tags, func(t TagDefinition) bool { return t.Name == name }) {
+			continue
+		}
+		for _, t := range g.Tags {
+			if t.Name != name {
+				exclusive = append(exclusive, t.Name)
+			}
+		}
+	}
+	return exclusive
+}
+
+// GroupMembers returns the tags of the group tag with the given name.
+func (c TagConfiguration) GroupMembers(name string) []string {
+	members := []string{}
+	for _, g := range c.Groups {
+		if g.Name != name {
+			continue
+		}
+		for _, t := range g.Tags {
+			members = append(members, t.Name)
+		}
+	}
+	return members
+}
+
+func parseTagConfiguration(s string) TagConfiguration {
+	c, group := TagConfiguration{}, (*TagGroup)(nil)
+	replacer := strings.NewReplacer("{", " { ", "}", " } ", "[", " [ ", "]", " ] ")
+	for _, field := range strings.Fields(replacer.Replace(s)) {
+		switch {
+		case field == "{" || field == "[":
+			group = &TagGroup{Exclusive: field == "{"}
+		case field == "}" || field == "]":
+			if group != nil {
+				c.Groups = append(c.Groups, *group)
+				group = nil
+			}
+		case field == ":" && group != nil && len(group.Tags) == 1 && group.Name == "":
+			group.Name, group.Tags = group.Tags[0].Name, nil
+		case field == `\n`:
+		default:
+			t := parseTagDefinition(field)
+			if _, ok := c.Get(t.Name); !ok {
+				c.Tags = append(c.Tags, t)
+			}
+			if group != nil {
+				group.Tags = append(group.Tags, t)
+			}
+		}
+	}
+	return c
+}
+
+func parseTagDefinition(s string) TagDefinition {
+	if lParen := strings.LastIndex(s, "("); lParen > 0 && strings.HasSuffix(s, ")") {
+		if key := s[lParen+1 : len(s)-1]; utf8.RuneCountInString(key) == 1 {
+			r, _ := utf8.DecodeRuneInString(key)
+			return TagDefinition{Name: s[:lParen], FastKey: r}
+		}
+	}
+	return TagDefinition{Name: s}
+}
+
+// SectionOf returns the section of the headline h - or nil if h is not part of the outline. Headlines are matched by
+// their Index, level and position, i.e. headlines without positions (e.g. built by hand) are told apart by their Index.
+func (o Outline) SectionOf(h Headline) *Section {
+	if o.Section != nil && o.document != nil && o.document.Outline.Section == o.Section {
+		return o.document.outlineIndex().sections[h.key()]
+	}
+	var find func(*Section) *Section
+	find = func(s *Section) *Section {
+		if s.Headline != nil && s.Headline.Index == h.Index && s.Headline.Lvl == h.Lvl && s.Headline.Pos == h.Pos {
+			return s
+		}
+		for _, child := range s.Children {
+			if found := find(child); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	if o.Section == nil {
+		return nil
+	}
+	return find(o.Section)
+}
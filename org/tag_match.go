@@ -0,0 +1,355 @@
+package org
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TagMatch is a compiled tag / property match expression (see CompileTagMatch).
+// It reports whether the headline h of document d is matched by the expression.
+type TagMatch func(d *Document, h Headline) bool
+
+type tagMatchTerm struct {
+	negated bool
+	match   TagMatch
+}
+
+var tagMatchFactorRegexp = regexp.MustCompile(`^([-+&]?)\s*(?:\{([^}]*)\}|([\p{L}\p{N}_]+)(<>|<=|>=|==|=|<|>)(\{[^}]*\}|"[^"]*"|-?[.0-9]+(?:[eE][-+]?[0-9]+)?)|([\p{L}\p{N}_@#%]+))`)
+var planningRegexp = regexp.MustCompile(`(DEADLINE|SCHEDULED|CLOSED):\s*$`)
+
+// CompileTagMatch compiles an Org mode tag / property match expression like `+work-urgent|laptop+LEVEL>1`
+// into a TagMatch. See https://orgmode.org/manual/Matching-tags-and-properties.html
+//
+// Supported are tags (`+tag`, `-tag`, `tag`), tag regexps (`{^@}`), property comparisons (`PROP="value"`,
+// `PROP<>{regexp}`, `PROP>=2`, `PROP<"<2024-06-01>"`), the special properties LEVEL, TODO, PRIORITY, ITEM, TAGS,
+// ALLTAGS, CATEGORY, DEADLINE, SCHEDULED and CLOSED, alternatives separated by `|` and a trailing todo keyword
+// match (`/TODO|WAITING`, `/!` for not done keywords only).
+func CompileTagMatch(expr string) (TagMatch, error) {
+	expr = strings.TrimSpace(expr)
+	tagExpr, todoExpr, hasTodo := expr, "", false
+	if i := slashIndex(expr); i != -1 {
+		tagExpr, todoExpr, hasTodo = strings.TrimSpace(expr[:i]), strings.TrimSpace(expr[i+1:]), true
+	}
+	tagMatch, err := compileTagMatchAlternatives(tagExpr, false)
+	if err != nil {
+		return nil, err
+	}
+	if !hasTodo {
+		return tagMatch, nil
+	}
+	onlyNotDone := strings.HasPrefix(todoExpr, "!")
+	todoMatch, err := compileTagMatchAlternatives(strings.TrimPrefix(todoExpr, "!"), true)
+	if err != nil {
+		return nil, err
+	}
+	return func(d *Document, h Headline) bool {
+		if onlyNotDone && (h.Status == "" || slices.Contains(d.DoneKeywords(), h.Status)) {
+			return false
+		}
+		return tagMatch(d, h) && todoMatch(d, h)
+	}, nil
+}
+
+// MustCompileTagMatch is like CompileTagMatch but panics if the expression cannot be compiled.
+func MustCompileTagMatch(expr string) TagMatch {
+	m, err := CompileTagMatch(expr)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// MatchHeadlines returns all headlines of the document (including nested ones) that are matched by m.
+func (d *Document) MatchHeadlines(m TagMatch) []Headline {
+	headlines := []Headline{}
+	walkNodes(d.Nodes, func(n Node) {
+		if h, ok := n.(Headline); ok && m(d, h) {
+			headlines = append(headlines, h)
+		}
+	})
+	return headlines
+}
+
+func compileTagMatchAlternatives(expr string, isTodo bool) (TagMatch, error) {
+	alternatives := []TagMatch{}
+	for _, alternative := range strings.Split(expr, "|") {
+		m, err := compileTagMatchTerms(strings.TrimSpace(alternative), isTodo)
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, m)
+	}
+	return func(d *Document, h Headline) bool {
+		for _, m := range alternatives {
+			if m(d, h) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func compileTagMatchTerms(expr string, isTodo bool) (TagMatch, error) {
+	terms := []tagMatchTerm{}
+	for rest := expr; rest != ""; rest = strings.TrimSpace(rest) {
+		m := tagMatchFactorRegexp.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("invalid tag match expression %q at %q", expr, rest)
+		}
+		rest = rest[len(m[0]):]
+		term := tagMatchTerm{negated: m[1] == "-"}
+		switch {
+		case m[2] != "":
+			re, err := regexp.Compile(m[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid tag match expression %q: %w", expr, err)
+			}
+			if isTodo {
+				term.match = func(d *Document, h Headline) bool { return re.MatchString(h.Status) }
+			} else {
+				term.match = func(d *Document, h Headline) bool { return slices.ContainsFunc(h.AllTags(d), re.MatchString) }
+			}
+		case m[3] != "":
+			match, err := compilePropertyComparison(m[3], m[4], m[5])
+			if err != nil {
+				return nil, fmt.Errorf("invalid tag match expression %q: %w", expr, err)
+			}
+			term.match = match
+		default:
+			name := m[6]
+			if isTodo {
+				term.match = func(d *Document, h Headline) bool { return h.Status == name }
+			} else {
+				term.match = func(d *Document, h Headline) bool { return slices.Contains(h.AllTags(d), name) }
+			}
+		}
+		terms = append(terms, term)
+	}
+	return func(d *Document, h Headline) bool {
+		for _, t := range terms {
+			if t.match(d, h) == t.negated {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compilePropertyComparison(key, op, value string) (TagMatch, error) {
+	if op == "==" {
+		op = "="
+	}
+	key = strings.ToUpper(key)
+	switch {
+	case strings.HasPrefix(value, "{"):
+		if op != "=" && op != "<>" {
+			return nil, fmt.Errorf("operator %s cannot be used with regexp %s", op, value)
+		}
+		re, err := regexp.Compile(value[1 : len(value)-1])
+		if err != nil {
+			return nil, err
+		}
+		return func(d *Document, h Headline) bool {
+			return re.MatchString(h.propertyValue(d, key)) == (op == "=")
+		}, nil
+	case strings.HasPrefix(value, `"<`) || strings.HasPrefix(value, `"[`):
+		expected, ok := parseTimeValue(value[1:len(value)-1], time.Now())
+		if !ok {
+			return nil, fmt.Errorf("invalid time value %s", value)
+		}
+		return func(d *Document, h Headline) bool {
+			actual, ok := parseTimeValue(h.propertyValue(d, key), time.Now())
+			return ok && compareOrdered(actual.Compare(expected), op)
+		}, nil
+	case strings.HasPrefix(value, `"`):
+		expected := value[1 : len(value)-1]
+		return func(d *Document, h Headline) bool {
+			return compareOrdered(strings.Compare(h.propertyValue(d, key), expected), op)
+		}, nil
+	default:
+		expected, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return func(d *Document, h Headline) bool {
+			actual, _ := strconv.ParseFloat(strings.TrimSpace(h.propertyValue(d, key)), 64)
+			switch {
+			case actual < expected:
+				return compareOrdered(-1, op)
+			case actual > expected:
+				return compareOrdered(1, op)
+			default:
+				return compareOrdered(0, op)
+			}
+		}, nil
+	}
+}
+
+func compareOrdered(cmp int, op string) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// propertyValue returns the value of the (special) property key for matching.
+func (h Headline) propertyValue(d *Document, key string) string {
+	switch key {
+	case "LEVEL":
+		return strconv.Itoa(h.Lvl)
+	case "TODO":
+		return h.Status
+	case "PRIORITY":
+		if h.Priority == "" {
+			return "B"
+		}
+		return h.Priority
+	case "ITEM":
+		return strings.TrimSpace(String(h.Title...))
+	case "TAGS":
+		if len(h.Tags) == 0 {
+			return ""
+		}
+		return ":" + strings.Join(h.Tags, ":") + ":"
+	case "ALLTAGS":
+		if tags := h.AllTags(d); len(tags) != 0 {
+			return ":" + strings.Join(tags, ":") + ":"
+		}
+		return ""
+	case "CATEGORY":
+		return h.Category(d)
+	case "DEADLINE", "SCHEDULED", "CLOSED":
+		if t, ok := h.Planning()[key]; ok {
+			return "<" + t.Time.Format(timestampFormat) + ">"
+		}
+		return ""
+	}
+	v, _ := h.Properties.Get(key)
+	return v
+}
+
+// Category returns the category of the headline of document d, i.e. the CATEGORY property of the headline or its
+// nearest ancestor, the #+CATEGORY of the document or the file name of the document without extension.
+func (h Headline) Category(d *Document) string {
+	if v, ok := h.Properties.Get("CATEGORY"); ok {
+		return v
+	}
+	if section := d.Outline.SectionOf(h); section != nil {
+		for s := section.Parent; s != nil && s.Headline != nil; s = s.Parent {
+			if v, ok := s.Headline.Properties.Get("CATEGORY"); ok {
+				return v
+			}
+		}
+	}
+	if v := d.Get("CATEGORY"); v != "" {
+		return v
+	}
+	return strings.TrimSuffix(filepath.Base(d.Path), filepath.Ext(d.Path))
+}
+
+// Planning returns the timestamps of the planning line directly below the headline by keyword (DEADLINE, SCHEDULED, CLOSED).
+func (h Headline) Planning() map[string]Timestamp {
+	planning := map[string]Timestamp{}
+	if len(h.Children) == 0 {
+		return planning
+	}
+	p, ok := h.Children[0].(Paragraph)
+	if !ok {
+		return planning
+	}
+	key := ""
+	for _, n := range p.InlineNodes() {
+		switch n := n.(type) {
+		case Text:
+			if m := planningRegexp.FindStringSubmatch(n.Content); m != nil {
+				key = m[1]
+			}
+		case Timestamp:
+			if key != "" {
+				planning[key] = n
+				key = ""
+			}
+		case LineBreak:
+			return planning
+		}
+	}
+	return planning
+}
+
+// DoneKeywords returns the TODO keywords that mark a headline as done, i.e. the keywords after the | of each
+// #+TODO, #+SEQ_TODO and #+TYP_TODO sequence.
+func (d *Document) DoneKeywords() []string {
+	var keywords []string
+	for _, key := range []string{"TODO", "SEQ_TODO", "TYP_TODO"} {
+		for sequence := range strings.SplitSeq(d.Get(key), "\n") {
+			if _, done, ok := strings.Cut(sequence, "|"); ok {
+				keywords = append(keywords, trimFastTags(strings.Fields(done))...)
+			}
+		}
+	}
+	return keywords
+}
+
+// parseTimeValue parses timestamps like "<2024-06-01 Sat 10:00>" as well as the relative values
+// "<now>", "<today>", "<tomorrow>" and "<yesterday>".
+func parseTimeValue(s string, now time.Time) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return time.Time{}, false
+	}
+	s = "<" + s[1:len(s)-1] + ">"
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch s {
+	case "<now>":
+		return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 0, 0, time.UTC), true
+	case "<today>":
+		return today, true
+	case "<tomorrow>":
+		return today.AddDate(0, 0, 1), true
+	case "<yesterday>":
+		return today.AddDate(0, 0, -1), true
+	}
+	m := timestampRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, false
+	}
+	hhmm := strings.TrimSpace(m[3])
+	if hhmm == "" {
+		hhmm = "00:00"
+	}
+	t, err := time.Parse(timestampFormat, fmt.Sprintf("%s Mon %s", m[1], hhmm))
+	return t, err == nil
+}
+
+func slashIndex(expr string) int {
+	inString, inRegexp := false, false
+	for i, r := range expr {
+		switch {
+		case r == '"' && !inRegexp:
+			inString = !inString
+		case r == '{' && !inString:
+			inRegexp = true
+		case r == '}' && !inString:
+			inRegexp = false
+		case r == '/' && !inString && !inRegexp:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,65 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+var tagMatchInput = `#+TODO: TODO WAITING | DONE
+* TODO Work :work:
+DEADLINE: <2024-05-20 Mon>
+** WAITING Urgent work :urgent:
+:PROPERTIES:
+:EFFORT: 2
+:END:
+* DONE Laptop :laptop:
+:PROPERTIES:
+:EFFORT: 5
+:END:
+* Home :@home:
+`
+
+var tagMatchTests = map[string][]string{
+	"work":                              {"Work", "Urgent work"},
+	"+work-urgent":                      {"Work"},
+	"work|laptop":                       {"Work", "Urgent work", "Laptop"},
+	"{^@}":                              {"Home"},
+	"EFFORT>3":                          {"Laptop"},
+	"LEVEL=2":                           {"Urgent work"},
+	`ITEM={^Urgent}`:                    {"Urgent work"},
+	`+work+deadline<"<2024-06-01>"`:     {"Work"},
+	`+work+DEADLINE>"<2024-06-01 Sat>"`: {},
+	"/WAITING|DONE":                     {"Urgent work", "Laptop"},
+	"/!":                                {"Work", "Urgent work"},
+	"-work/-TODO":                       {"Laptop", "Home"},
+}
+
+func TestCompileTagMatch(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader(tagMatchInput), "./tags.org")
+	for expr, expected := range tagMatchTests {
+		t.Run(expr, func(t *testing.T) {
+			m, err := CompileTagMatch(expr)
+			if err != nil {
+				t.Fatalf("%s: %s", expr, err)
+			}
+			actual := []string{}
+			for _, h := range d.MatchHeadlines(m) {
+				actual = append(actual, strings.TrimSpace(String(h.Title...)))
+			}
+			if strings.Join(actual, ", ") != strings.Join(expected, ", ") {
+				t.Errorf("%s: got %v, expected %v", expr, actual, expected)
+			}
+		})
+	}
+	if _, err := CompileTagMatch("+work+"); err == nil {
+		t.Errorf("expected error for invalid expression")
+	}
+}
+
+func TestDoneKeywords(t *testing.T) {
+	input := "#+TODO: TODO | DONE(d)\n#+TODO: WAIT | CANCELED\n#+SEQ_TODO: NEXT | FINISHED\n#+TYP_TODO: Fred Sara | FIXED\n#+TYP_TODO: NOBAR\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./done.org")
+	if actual, expected := strings.Join(d.DoneKeywords(), " "), "DONE CANCELED FINISHED FIXED"; actual != expected {
+		t.Errorf("got done keywords %q, expected %q", actual, expected)
+	}
+}
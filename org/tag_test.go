@@ -0,0 +1,63 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTagConfiguration(t *testing.T) {
+	input := `#+TAGS: { @work(w) @home(h) } laptop(l)
+#+TAGS: [ GTD : Control Persp ]
+#+FILETAGS: :project:
+* A :a:
+** B :b:
+*** C :c:a:
+`
+	d := New().Silent().Parse(strings.NewReader(input), "./tags.org")
+	c := d.TagConfiguration()
+	if work, ok := c.Get("@work"); !ok || work.FastKey != 'w' {
+		t.Errorf("bad tag definition for @work: %#v", work)
+	}
+	if laptop, ok := c.Get("laptop"); !ok || laptop.FastKey != 'l' {
+		t.Errorf("bad tag definition for laptop: %#v", laptop)
+	}
+	if exclusive := strings.Join(c.ExclusiveWith("@home"), " "); exclusive != "@work" {
+		t.Errorf("bad exclusive tags for @home: %s", exclusive)
+	}
+	if members := strings.Join(c.GroupMembers("GTD"), " "); members != "Control Persp" {
+		t.Errorf("bad group members for GTD: %s", members)
+	}
+	h := d.Nodes[len(d.Nodes)-1].(Headline).Children[0].(Headline).Children[0].(Headline)
+	if tags := strings.Join(h.AllTags(d), " "); tags != "project a b c" {
+		t.Errorf("bad inherited tags: %s", tags)
+	}
+}
+
+func TestSectionOf(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("* A\n** A1\n* B\n"), "./sectionOf.org")
+	var dropPositions func(n Node) Node // e.g. like for headlines built by hand
+	dropPositions = func(n Node) Node {
+		if h, ok := n.(Headline); ok {
+			h.Pos = Position{}
+			n = h
+		}
+		return mapChildren(n, dropPositions)
+	}
+	for i, n := range d.Nodes {
+		d.Nodes[i] = dropPositions(n)
+	}
+	d.rebuildOutline()
+	a, b := d.Nodes[0].(Headline), d.Nodes[1].(Headline)
+	if s := d.Outline.SectionOf(a); s != d.Outline.Children[0] {
+		t.Errorf("expected section of A, got %v", s)
+	}
+	if s := d.Outline.SectionOf(b); s != d.Outline.Children[1] {
+		t.Errorf("expected section of B, got %v", s)
+	}
+	if s := d.Outline.SectionOf(a.Children[0].(Headline)); s != d.Outline.Children[0].Children[0] {
+		t.Errorf("expected section of A1, got %v", s)
+	}
+	if s := d.Outline.SectionOf(newHeadline(t, 1, "C")); s != nil {
+		t.Errorf("expected headline of another document not to be found, got %v", s)
+	}
+}
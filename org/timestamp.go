@@ -0,0 +1,69 @@
+package org
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var repeaterRegexp = regexp.MustCompile(`^(\+\+|\.\+|\+)(\d+)([hdwmy])$`)
+
+// Occurrences returns the times of the timestamp and its repetitions (see Interval) within from (inclusive) and
+// to (exclusive).
+//
+// Like in Org mode, the repeater determines how repetitions are scheduled once the timestamp is in the past:
+// + (cumulative) repeats every interval starting at the timestamp, ++ (catch-up) skips repetitions that are not after
+// today and .+ (restart) repeats every interval starting today. The timestamp itself is always an occurrence.
+func (t Timestamp) Occurrences(from, to time.Time) []time.Time {
+	return t.occurrences(from, to, time.Now())
+}
+
+func (t Timestamp) occurrences(from, to, now time.Time) []time.Time {
+	occurrences := []time.Time{}
+	add := func(o time.Time) {
+		if !o.Before(from) && o.Before(to) {
+			occurrences = append(occurrences, o)
+		}
+	}
+	add(t.Time)
+	m := repeaterRegexp.FindStringSubmatch(t.Interval)
+	if m == nil {
+		return occurrences
+	}
+	n, _ := strconv.Atoi(m[2])
+	if n == 0 {
+		return occurrences
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), t.Time.Hour(), t.Time.Minute(), 0, 0, t.Time.Location())
+	start, skipUntil := t.Time, time.Time{}
+	switch {
+	case m[1] == "++" && today.After(t.Time):
+		skipUntil = today
+	case m[1] == ".+" && today.After(t.Time):
+		start = today
+	}
+	for k := 1; ; k++ {
+		o := repeat(start, k*n, m[3])
+		if !o.Before(to) {
+			return occurrences
+		} else if o.After(skipUntil) {
+			add(o)
+		}
+	}
+}
+
+// repeat returns t shifted by n units (h, d, w, m or y).
+func repeat(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "d":
+		return t.AddDate(0, 0, n)
+	case "w":
+		return t.AddDate(0, 0, 7*n)
+	case "m":
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(n, 0, 0)
+	}
+}
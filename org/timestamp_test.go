@@ -0,0 +1,46 @@
+package org
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimestampOccurrences(t *testing.T) {
+	date := func(month time.Month, day int) time.Time { return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC) }
+	now := time.Date(2024, 6, 12, 15, 30, 0, 0, time.UTC)
+	for _, c := range []struct {
+		timestamp string
+		from, to  time.Time
+		expected  string
+	}{
+		{"<2024-06-01 Sat>", date(5, 1), date(7, 1), "06-01"},
+		{"<2024-06-01 Sat>", date(6, 2), date(7, 1), ""},
+		{"<2024-06-01 Sat +1w>", date(6, 1), date(7, 1), "06-01 06-08 06-15 06-22 06-29"},
+		{"<2024-06-01 Sat +1w>", date(6, 10), date(6, 20), "06-15"},
+		{"<2024-06-01 Sat ++1w>", date(6, 1), date(7, 1), "06-01 06-15 06-22 06-29"},
+		{"<2024-06-01 Sat .+3d>", date(6, 1), date(6, 25), "06-01 06-15 06-18 06-21 06-24"},
+		{"<2024-06-20 Thu ++1w>", date(6, 1), date(7, 10), "06-20 06-27 07-04"},
+		{"<2024-01-31 Wed +1m>", date(1, 1), date(5, 1), "01-31 03-02 03-31"},
+		{"<2024-06-12 Wed 10:00 +8h>", date(6, 12), date(6, 13), "06-12 10:00 06-12 18:00"},
+		{"<2024-06-01 Sat +0d>", date(6, 1), date(7, 1), "06-01"},
+		{"<2024-06-07 Fri +1w -2d>", date(6, 1), date(6, 20), "06-07 06-14"},
+	} {
+		d := New().Silent().Parse(strings.NewReader(c.timestamp), "./timestamps.org")
+		timestamp := d.Nodes[0].(Paragraph).Children[0].(Timestamp)
+		actual := []string{}
+		for _, o := range timestamp.occurrences(c.from, c.to, now) {
+			if timestamp.IsDate {
+				actual = append(actual, o.Format("01-02"))
+			} else {
+				actual = append(actual, o.Format("01-02 15:04"))
+			}
+		}
+		if actual := strings.Join(actual, " "); actual != c.expected {
+			t.Errorf("%s in %s - %s: got %q, expected %q", c.timestamp, c.from.Format("01-02"), c.to.Format("01-02"), actual, c.expected)
+		}
+		if actual := String(timestamp); actual != c.timestamp {
+			t.Errorf("expected %q to be written unchanged, got %q", c.timestamp, actual)
+		}
+	}
+}
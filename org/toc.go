@@ -0,0 +1,55 @@
+package org
+
+import "strconv"
+
+// TOCItem is a headline entry of the table of contents returned by Document.TOC.
+type TOCItem struct {
+	Title    []Node   // Title is the title of the headline - render it with the writer of your choice.
+	Anchor   string   // Anchor is the id of the headline (see Headline.ID), i.e. its CUSTOM_ID or headline-n.
+	Number   string   // Number is the section number (e.g. "1.2") if headlines are numbered via the num export option.
+	Level    int      // Level is the level of the headline.
+	Status   string   // Status is the todo keyword of the headline unless the todo export option is disabled.
+	Priority string   // Priority is the priority of the headline unless the pri export option is disabled.
+	Tags     []string // Tags are the tags of the headline unless the tags export option is disabled.
+	Section  *Section
+	Children []TOCItem
+}
+
+// TOC returns the table of contents of the document as a tree of entries independent of any writer.
+// Headlines that are excluded from export (see Headline.IsExcluded) are skipped. maxDepth limits the included
+// headline levels - 0 means no limit.
+func (d *Document) TOC(maxDepth int) []TOCItem {
+	num, maxNumbered := d.GetOption("num"), 0
+	if n, err := strconv.Atoi(num); err == nil {
+		maxNumbered = n
+	}
+	numbered := num != "" && num != "nil"
+	todo, pri, tags := d.GetOption("todo") != "nil", d.GetOption("pri") != "nil", d.GetOption("tags") != "nil"
+	var items func(sections []*Section) []TOCItem
+	items = func(sections []*Section) []TOCItem {
+		entries := []TOCItem{}
+		for _, section := range sections {
+			h := section.Headline
+			if (maxDepth != 0 && h.Lvl > maxDepth) || h.IsExcluded(d) {
+				continue
+			}
+			item := TOCItem{Title: h.Title, Anchor: h.ID(), Level: h.Lvl, Section: section}
+			if numbered && (maxNumbered == 0 || h.Lvl <= maxNumbered) {
+				item.Number = section.Number()
+			}
+			if todo {
+				item.Status = h.Status
+			}
+			if pri {
+				item.Priority = h.Priority
+			}
+			if tags {
+				item.Tags = h.Tags
+			}
+			item.Children = items(section.Children)
+			entries = append(entries, item)
+		}
+		return entries
+	}
+	return items(d.Outline.Children)
+}
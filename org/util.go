@@ -75,6 +75,168 @@ func IsNewLineChar(r rune) bool {
 	return r == '\n' || r == '\r'
 }
 
+// walkNodes calls f for each node and its descendants in document order.
+// Unlike Range it also descends into headline titles, link descriptions and inline footnote definitions.
+func walkNodes(nodes []Node, f func(Node)) {
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+		f(n)
+		switch n := n.(type) {
+		case Headline:
+			walkNodes(n.Title, f)
+		case RegularLink:
+			walkNodes(n.Description, f)
+		case FootnoteLink:
+			if n.Definition != nil {
+				walkNodes(n.Definition.Children, f)
+			}
+		}
+		n.Range(func(child Node) bool {
+			walkNodes([]Node{child}, f)
+			return true
+		})
+	}
+}
+
+// mapChildren returns a copy of n with each child replaced by f(child). Children include headline titles and
+// properties, link descriptions, inline footnote definitions, captions and table cells. Child slices are updated in place.
+// Children for which f returns nil are removed.
+func mapChildren(n Node, f func(Node) Node) Node {
+	mapNodes := func(nodes []Node) []Node {
+		mapped := nodes[:0]
+		for _, child := range nodes {
+			if child != nil {
+				child = f(child)
+			}
+			if child != nil {
+				mapped = append(mapped, child)
+			}
+		}
+		return mapped
+	}
+	switch n := n.(type) {
+	case Headline:
+		if n.Properties != nil {
+			if properties, ok := f(*n.Properties).(PropertyDrawer); ok {
+				n.Properties = &properties
+			} else {
+				n.Properties = nil
+			}
+		}
+		n.Title, n.Children = mapNodes(n.Title), mapNodes(n.Children)
+		return n
+	case Block:
+		n.Children = mapNodes(n.Children)
+		if n.Result != nil {
+			n.Result = f(n.Result)
+		}
+		return n
+	case Result:
+		if n.Node != nil {
+			n.Node = f(n.Node)
+		}
+		return n
+	case Example:
+		n.Children = mapNodes(n.Children)
+		return n
+	case LatexBlock:
+		n.Content = mapNodes(n.Content)
+		return n
+	case Drawer:
+		n.Children = mapNodes(n.Children)
+		return n
+	case FootnoteDefinition:
+		n.Children = mapNodes(n.Children)
+		return n
+	case List:
+		n.Items = mapNodes(n.Items)
+		return n
+	case ListItem:
+		n.Children = mapNodes(n.Children)
+		return n
+	case DescriptiveListItem:
+		n.Term, n.Details = mapNodes(n.Term), mapNodes(n.Details)
+		return n
+	case Table:
+		for _, row := range n.Rows {
+			for j := range row.Columns {
+				row.Columns[j].Children = mapNodes(row.Columns[j].Children)
+			}
+		}
+		return n
+	case Paragraph:
+		n.Children, n.lazy = mapNodes(n.InlineNodes()), nil
+		return n
+	case NodeWithName:
+		n.Node = f(n.Node)
+		return n
+	case NodeWithMeta:
+		for i := range n.Meta.Caption {
+			n.Meta.Caption[i] = mapNodes(n.Meta.Caption[i])
+		}
+		n.Node = f(n.Node)
+		return n
+	case Emphasis:
+		n.Content = mapNodes(n.Content)
+		return n
+	case InlineBlock:
+		n.Children = mapNodes(n.Children)
+		return n
+	case LatexFragment:
+		n.Content = mapNodes(n.Content)
+		return n
+	case FootnoteLink:
+		if n.Definition != nil {
+			if definition, ok := f(*n.Definition).(FootnoteDefinition); ok {
+				n.Definition = &definition
+			} else {
+				n.Definition = nil
+			}
+		}
+		return n
+	case RegularLink:
+		n.Description = mapNodes(n.Description)
+		return n
+	}
+	return n
+}
+
+// PlainText returns the text content of the given inline nodes without any markup.
+// Links without description are represented by their URL, footnote references are omitted.
+func PlainText(nodes ...Node) string {
+	text := strings.Builder{}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case Text:
+			text.WriteString(n.Content)
+		case LineBreak, ExplicitLineBreak:
+			text.WriteString(" ")
+		case RegularLink:
+			if n.Description == nil {
+				text.WriteString(n.URL)
+			} else {
+				text.WriteString(PlainText(n.Description...))
+			}
+		case Emphasis:
+			text.WriteString(PlainText(n.Content...))
+		case LatexFragment:
+			text.WriteString(PlainText(n.Content...))
+		case StatisticToken:
+			text.WriteString("[" + n.Content + "]")
+		case Timestamp:
+			text.WriteString(String(n))
+		case InlineBlock, FootnoteLink, Macro:
+		default:
+			if n != nil {
+				text.WriteString(String(n))
+			}
+		}
+	}
+	return text.String()
+}
+
 // PrintNodeTree returns a string representation of an org.Node hierarchy as a tree showing types, positions, and string representations.
 func PrintNodeTree(nodes []Node, indent string) string {
 	var builder strings.Builder
@@ -0,0 +1,140 @@
+package org
+
+import "fmt"
+
+// Validate checks the structure of the nodes of the document - e.g. of nodes that were constructed programmatically
+// (see NewHeadline) or deserialized rather than parsed - and returns the violations found. Validate checks that
+//
+//   - child slices do not contain nil nodes
+//   - inline containers like paragraphs, headline titles and table columns only contain inline nodes
+//   - headlines are only part of the document or other headlines and have a higher level than their parent headline
+//   - list items are only part of lists of the matching kind
+//   - table columns have a ColumnInfo
+//   - footnote definitions and the ID and CUSTOM_ID properties of headlines are unique
+//
+// Duplicates are reported with SeverityWarning - like Parse, which reports duplicate footnote definitions as well.
+// All other violations are errors of type ErrorTypeInvalidStructure that may make writers fail. The returned errors
+// are not added to Errors.
+func (d *Document) Validate() []*ParseError {
+	v := &validator{d: d, errors: []*ParseError{}, footnotes: map[string]bool{}, ids: map[string]bool{}}
+	v.validate(d.Nodes, nil, 0, false)
+	return v.errors
+}
+
+type validator struct {
+	d         *Document
+	errors    []*ParseError
+	footnotes map[string]bool
+	ids       map[string]bool
+}
+
+// validate validates nodes - the children of parent (nil for the document). lvl is the level of the closest ancestor
+// headline and inline is true if parent only allows inline nodes.
+func (v *validator) validate(nodes []Node, parent Node, lvl int, inline bool) {
+	for _, n := range nodes {
+		if n == nil {
+			v.add(ErrorTypeInvalidStructure, parent, fmt.Sprintf("nil node in %s", nodeName(parent)), "remove nil nodes")
+			continue
+		} else if inline && !isInlineNode(n) {
+			v.add(ErrorTypeInvalidStructure, n, fmt.Sprintf("%T cannot be part of inline content of %s", n, nodeName(parent)), "")
+			continue
+		}
+		switch n := n.(type) {
+		case Headline:
+			if _, ok := parent.(Headline); !ok && parent != nil {
+				v.add(ErrorTypeInvalidStructure, n, fmt.Sprintf("headline cannot be part of %s", nodeName(parent)), "")
+			} else if n.Lvl < 1 {
+				v.add(ErrorTypeInvalidStructure, n, fmt.Sprintf("invalid headline level %d", n.Lvl), "")
+			} else if n.Lvl <= lvl {
+				message := fmt.Sprintf("headline of level %d cannot be a child of a headline of level %d", n.Lvl, lvl)
+				v.add(ErrorTypeInvalidStructure, n, message, "child headlines must have a higher level")
+			}
+			for _, key := range []string{"ID", "CUSTOM_ID"} {
+				if id, ok := n.Properties.Get(key); ok {
+					v.unique(v.ids, key+" "+id, n, fmt.Sprintf("duplicate %s property: %s", key, id))
+				}
+			}
+			v.validate(n.Title, n, lvl, true)
+			v.validate(n.Children, n, n.Lvl, false)
+		case List:
+			for _, item := range n.Items {
+				_, isDescriptive := item.(DescriptiveListItem)
+				if _, isItem := item.(ListItem); item != nil && !isItem && !isDescriptive {
+					v.add(ErrorTypeInvalidStructure, item, fmt.Sprintf("%T cannot be an item of a list", item), "")
+				} else if item != nil && isDescriptive != (n.Kind == DescriptiveList) {
+					v.add(ErrorTypeInvalidStructure, item, fmt.Sprintf("%T cannot be an item of a list of kind %d", item, n.Kind), "")
+				}
+			}
+			v.validate(n.Items, n, lvl, false)
+		case ListItem:
+			if _, ok := parent.(List); !ok {
+				v.add(ErrorTypeInvalidStructure, n, fmt.Sprintf("list item cannot be part of %s", nodeName(parent)), "")
+			}
+			v.validate(n.Children, n, lvl, false)
+		case DescriptiveListItem:
+			if _, ok := parent.(List); !ok {
+				v.add(ErrorTypeInvalidStructure, n, fmt.Sprintf("list item cannot be part of %s", nodeName(parent)), "")
+			}
+			v.validate(n.Term, n, lvl, true)
+			v.validate(n.Details, n, lvl, false)
+		case Table:
+			for _, row := range n.Rows {
+				for _, column := range row.Columns {
+					if column.ColumnInfo == nil {
+						v.add(ErrorTypeInvalidStructure, n, "table column without ColumnInfo", "see AlignTable")
+					}
+					v.validate(column.Children, n, lvl, true)
+				}
+			}
+		case FootnoteDefinition:
+			if !n.Inline {
+				v.unique(v.footnotes, n.Name, n, fmt.Sprintf("duplicate definition of footnote [fn:%s]", n.Name))
+			}
+			v.validate(n.Children, n, lvl, false)
+		case Paragraph:
+			v.validate(n.InlineNodes(), n, lvl, true)
+		case Emphasis:
+			v.validate(n.Content, n, lvl, true)
+		case RegularLink:
+			v.validate(n.Description, n, lvl, true)
+		default:
+			v.validate(children(n), n, lvl, inline)
+		}
+	}
+}
+
+// unique adds a duplicate node warning unless key has not been seen before.
+func (v *validator) unique(seen map[string]bool, key string, n Node, message string) {
+	if seen[key] {
+		v.add(ErrorTypeDuplicateNode, n, message, "must be unique").Severity = SeverityWarning
+	}
+	seen[key] = true
+}
+
+func (v *validator) add(typ ErrorType, n Node, message, hint string) *ParseError {
+	pos := Position{}
+	if n != nil {
+		pos = n.Position()
+	}
+	err := NewParseError(typ, message, v.d.Path, pos, nilToken, nil)
+	err.Context = hint
+	v.errors = append(v.errors, err)
+	return err
+}
+
+// nodeName returns a description of the node for error messages.
+func nodeName(n Node) string {
+	if n == nil {
+		return "document"
+	}
+	return fmt.Sprintf("%T", n)
+}
+
+// isInlineNode returns true if n can be part of inline content, e.g. of a paragraph.
+func isInlineNode(n Node) bool {
+	switch n.(type) {
+	case Text, LineBreak, ExplicitLineBreak, StatisticToken, Timestamp, Emphasis, InlineBlock, LatexFragment, FootnoteLink, RegularLink, Macro:
+		return true
+	}
+	return false
+}
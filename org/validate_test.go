@@ -0,0 +1,58 @@
+package org
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateParsedDocuments(t *testing.T) {
+	paths, _ := filepath.Glob("./testdata/*.org")
+	for _, path := range paths {
+		input, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := New().Silent().Parse(strings.NewReader(string(input)), path)
+		for _, err := range d.Validate() {
+			if err.Type == ErrorTypeInvalidStructure {
+				t.Errorf("%s: unexpected validation error: %s", path, err)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("* A\n:PROPERTIES:\n:ID: x\n:END:\n* B\n:PROPERTIES:\n:ID: x\n:END:\n"), "./validate.org")
+	parent := newHeadline(t, 2, "Parent", newHeadline(t, 2, "Sibling"))
+	d.Nodes = append(d.Nodes, parent, nil,
+		Paragraph{Children: []Node{Text{Content: "text"}, Table{}}},
+		List{Kind: UnorderedList, Items: []Node{ListItem{Bullet: "-"}, DescriptiveListItem{Bullet: "-"}, Paragraph{}}},
+		ListItem{Bullet: "-"},
+		Table{Rows: []Row{{Columns: []Column{{}}}}},
+		FootnoteDefinition{Name: "1"}, FootnoteDefinition{Name: "1"},
+		List{Items: []Node{ListItem{Children: []Node{newHeadline(t, 3, "Nested")}}}},
+	)
+	expected := []string{
+		"duplicate ID property: x",
+		"headline of level 2 cannot be a child of a headline of level 2",
+		"nil node in document",
+		"org.Table cannot be part of inline content of org.Paragraph",
+		"org.DescriptiveListItem cannot be an item of a list of kind 0",
+		"org.Paragraph cannot be an item of a list",
+		"list item cannot be part of document",
+		"table column without ColumnInfo",
+		"duplicate definition of footnote [fn:1]",
+		"headline cannot be part of org.ListItem",
+	}
+	errs := d.Validate()
+	if len(errs) != len(expected) {
+		t.Errorf("expected %d errors got %d: %v", len(expected), len(errs), errs)
+	}
+	for i := 0; i < len(errs) && i < len(expected); i++ {
+		if errs[i].Message != expected[i] {
+			t.Errorf("error %d: expected %q got %q", i, expected[i], errs[i].Message)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package org
+
+// Visitor contains the callbacks of Walk. Both callbacks are optional.
+type Visitor struct {
+	Enter func(c *Cursor) // Enter is called before the children of the node are visited.
+	Exit  func(c *Cursor) // Exit is called after the children of the node have been visited.
+}
+
+// Cursor describes the node that is currently visited by Walk. It is only valid during the callback.
+type Cursor struct {
+	node         Node
+	parents      []Node
+	skipChildren bool
+}
+
+// Node returns the current node - or nil if it was deleted.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current node - or nil for top level nodes.
+func (c *Cursor) Parent() Node {
+	if len(c.parents) == 0 {
+		return nil
+	}
+	return c.parents[len(c.parents)-1]
+}
+
+// Parents returns the ancestors of the current node - from the outermost (top level) node to the parent.
+// Ancestors are passed as they were entered, i.e. before their children were rebuilt.
+func (c *Cursor) Parents() []Node { return append([]Node(nil), c.parents...) }
+
+// Replace replaces the current node with n. If called from Enter, the children of n are visited instead.
+func (c *Cursor) Replace(n Node) { c.node = n }
+
+// Delete removes the current node. If called from Enter, neither its children nor Exit are visited.
+func (c *Cursor) Delete() { c.node = nil }
+
+// SkipChildren prevents the children of the current node from being visited. Only has an effect in Enter.
+func (c *Cursor) SkipChildren() { c.skipChildren = true }
+
+// Walk visits the nodes of the document and their descendants in document order and returns the rebuilt nodes.
+// Unlike Range it also descends into headline titles and properties, link descriptions, inline footnote definitions,
+// captions and table cells - and allows the visitor to replace or delete the visited nodes.
+// Walk works on a copy of the nodes, i.e. the document itself is not modified.
+func Walk(d *Document, v Visitor) []Node {
+	w := &walker{visitor: v}
+	nodes := []Node{}
+	for _, n := range CopyNodes(d.Nodes) {
+		if n = w.visit(n); n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+type walker struct {
+	visitor Visitor
+	parents []Node
+}
+
+func (w *walker) visit(n Node) Node {
+	c := &Cursor{node: n, parents: w.parents}
+	if w.visitor.Enter != nil {
+		w.visitor.Enter(c)
+	}
+	if c.node == nil {
+		return nil
+	}
+	if !c.skipChildren {
+		w.parents = append(w.parents, c.node)
+		c.node = mapChildren(c.node, w.visit)
+		w.parents = w.parents[:len(w.parents)-1]
+		switch n := c.node.(type) {
+		case NodeWithName:
+			if n.Node == nil {
+				return nil // deleting the named node also deletes its name
+			}
+		case NodeWithMeta:
+			if n.Node == nil {
+				return nil
+			}
+		}
+	}
+	if w.visitor.Exit != nil {
+		w.visitor.Exit(c)
+	}
+	return c.node
+}
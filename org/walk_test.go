@@ -0,0 +1,63 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	input := "* Headline *bold*\n:PROPERTIES:\n:ID: x\n:END:\nSome /italic/ text\n#+NAME: drop\n- item\n** Child\n#+BEGIN_SRC go\ncode\n#+END_SRC\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./walk.org")
+	original := String(d.Nodes...)
+	entered, exited := []string{}, []string{}
+	nodes := Walk(d, Visitor{
+		Enter: func(c *Cursor) {
+			switch n := c.Node().(type) {
+			case Emphasis:
+				if n.Kind == "*" {
+					c.Replace(Emphasis{Kind: "_", Content: n.Content})
+				}
+			case Text:
+				if _, ok := c.Parent().(Emphasis); ok {
+					c.Replace(Text{Content: strings.ToUpper(n.Content)})
+				}
+			case List, PropertyDrawer:
+				c.Delete()
+			case Block:
+				c.SkipChildren()
+			}
+			if c.Node() != nil {
+				entered = append(entered, typeName(c.Node()))
+			}
+		},
+		Exit: func(c *Cursor) {
+			if h, ok := c.Node().(Headline); ok {
+				h.Status = "DONE"
+				c.Replace(h)
+				exited = append(exited, String(h.Title...)+" "+typeName(c.Parents()...))
+			}
+		},
+	})
+	expected := "* DONE Headline _BOLD_\nSome /ITALIC/ text\n** DONE Child\n#+BEGIN_SRC go\ncode\n#+END_SRC\n"
+	if actual := String(nodes...); actual != expected {
+		t.Errorf("got:\n%s", diff(actual, expected))
+	}
+	if actual := String(d.Nodes...); actual != original {
+		t.Errorf("expected document to be unchanged:\n%s", diff(actual, original))
+	}
+	if actual, expected := strings.Join(entered, " "), "Headline Text Emphasis Text Paragraph Text Emphasis Text Text NodeWithName Headline Text Block"; actual != expected {
+		t.Errorf("entered %q, expected %q", actual, expected)
+	}
+	if actual, expected := strings.Join(exited, ", "), "Child Headline, Headline _BOLD_ "; actual != expected {
+		t.Errorf("exited %q, expected %q", actual, expected)
+	}
+}
+
+func typeName(nodes ...Node) string {
+	names := []string{}
+	for _, n := range nodes {
+		names = append(names, strings.TrimPrefix(fmt.Sprintf("%T", n), "org."))
+	}
+	return strings.Join(names, " ")
+}
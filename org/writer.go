@@ -1,15 +1,29 @@
 package org
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	u "net/url"
+	"runtime/debug"
+	"strings"
+)
 
 // Writer is the interface that is used to export a parsed document into a new format. See Document.Write().
 type Writer interface {
 	Before(*Document) // Before is called before any nodes are passed to the writer.
 	After(*Document)  // After is called after all nodes have been passed to the writer.
 	String() string   // String is called at the very end to retrieve the final output.
+	// Backend returns the name of the export backend of the writer, e.g. "html". Export blocks (#+BEGIN_EXPORT backend)
+	// and export snippets (@@backend:...@@) of other backends are not passed to the writer. Writers that reproduce
+	// the source (e.g. the OrgWriter) return "" to receive those of all backends.
+	Backend() string
 
 	WriterWithExtensions() Writer
 	WriteNodesAsString(...Node) string
+	// WriteNodesTo writes the pending output and the nodes to the io.Writer - moving the output to it after each node
+	// rather than accumulating it. Panics during writing are returned as *WriterPanic errors. See Document.WriteStream.
+	WriteNodesTo(io.Writer, ...Node) error
 
 	WriteKeyword(Keyword)
 	WriteInclude(Include)
@@ -43,6 +57,95 @@ type Writer interface {
 	WriteFootnoteDefinition(FootnoteDefinition)
 }
 
+// WriterV2 is the error returning variant of Writer: the errors returned by its methods are propagated by
+// Document.WriteV2 instead of being raised as panics. Use NewWriterV2 to adapt a Writer.
+type WriterV2 interface {
+	Before(*Document) error   // Before is called before any nodes are passed to the writer.
+	After(*Document) error    // After is called after all nodes have been passed to the writer.
+	String() string           // String is called at the very end to retrieve the final output.
+	WriteNodes(...Node) error // WriteNodes writes the nodes - see WriteNodes.
+}
+
+// ParallelWriter is implemented by writers that can write the top level sections of a document concurrently.
+// See Document.WriteParallel.
+type ParallelWriter interface {
+	Writer
+	// Fork is called after Before and returns a writer for each section (i.e. run of top level nodes). Each fork is
+	// used by a single goroutine to write its section. Fork returns nil if the writer cannot be forked.
+	Fork(sections [][]Node) []Writer
+	// Join appends the output of a fork to the writer. Forks are joined in document order. Join returns false if the
+	// output of the fork depends on state of the writer that was not known when forking - the section of the fork
+	// is then written by the writer itself.
+	Join(fork Writer) bool
+}
+
+// CustomNodeWriter is implemented by writers that can write nodes of types unknown to this package - e.g. the nodes
+// of InlineParsers. WriteNodes passes such nodes to WriteCustomNode and panics if it returns false.
+type CustomNodeWriter interface {
+	WriteCustomNode(Node) bool
+}
+
+// WriterPanic is the error returned for a panic during writing - e.g. of a Writer adapted by NewWriterV2.
+type WriterPanic struct {
+	Value any    // Value is the value passed to panic.
+	Stack []byte // Stack is the stack trace of the panicking goroutine at the time of the panic.
+}
+
+func (e *WriterPanic) Error() string { return fmt.Sprintf("could not write output: %v", e.Value) }
+
+// NewWriterV2 returns a WriterV2 for w. The errors of the built-in writers are returned as they are reported, panics of
+// other writers are returned as *WriterPanic errors.
+func NewWriterV2(w Writer) WriterV2 { return writerV2{w} }
+
+type writerV2 struct{ w Writer }
+
+func (w writerV2) Before(d *Document) (err error) {
+	defer recoverWriterPanic(&err)
+	w.w.Before(d)
+	return reportedError(w.w)
+}
+
+func (w writerV2) After(d *Document) (err error) {
+	defer recoverWriterPanic(&err)
+	return after(w.w, d)
+}
+
+func (w writerV2) String() string { return w.w.String() }
+
+func (w writerV2) WriteNodes(nodes ...Node) (err error) {
+	defer recoverWriterPanic(&err)
+	WriteNodes(w.w, nodes...)
+	return reportedError(w.w)
+}
+
+// errorReporter is implemented by writers that report their errors (e.g. of executing HTMLWriter.Template or of nodes
+// they cannot write) rather than panicking. Writers only keep their first error and reset it in Before.
+type errorReporter interface {
+	writeError() error
+	reportError(error)
+}
+
+// reportedError returns the error reported by w - see errorReporter.
+func reportedError(w Writer) error {
+	if w, ok := w.(errorReporter); ok {
+		return w.writeError()
+	}
+	return nil
+}
+
+// after calls w.After and returns the error reported by w - see errorReporter.
+func after(w Writer, d *Document) error {
+	w.After(d)
+	return reportedError(w)
+}
+
+// recoverWriterPanic stores a *WriterPanic in err if the calling function panics. It must be deferred.
+func recoverWriterPanic(err *error) {
+	if recovered := recover(); recovered != nil {
+		*err = &WriterPanic{recovered, debug.Stack()}
+	}
+}
+
 func WriteNodes(w Writer, nodes ...Node) {
 	w = w.WriterWithExtensions()
 	for _, n := range nodes {
@@ -60,12 +163,18 @@ func WriteNodes(w Writer, nodes ...Node) {
 		case Headline:
 			w.WriteHeadline(n)
 		case Block:
+			if n.Name == "EXPORT" && !exportsTo(w, n.Parameters) {
+				continue
+			}
 			w.WriteBlock(n)
 		case Result:
 			w.WriteResult(n)
 		case LatexBlock:
 			w.WriteLatexBlock(n)
 		case InlineBlock:
+			if n.Name == "export" && !exportsTo(w, n.Parameters) {
+				continue
+			}
 			w.WriteInlineBlock(n)
 		case Example:
 			w.WriteExample(n)
@@ -108,9 +217,104 @@ func WriteNodes(w Writer, nodes ...Node) {
 		case FootnoteDefinition:
 			w.WriteFootnoteDefinition(n)
 		default:
-			if n != nil {
+			if cw, ok := w.(CustomNodeWriter); ok && n != nil && cw.WriteCustomNode(n) {
+				continue
+			} else if er, ok := w.(errorReporter); ok && n != nil {
+				er.reportError(fmt.Errorf("bad node %T %#v", n, n))
+			} else if n != nil {
 				panic(fmt.Sprintf("bad node %T %#v", n, n))
 			}
 		}
 	}
 }
+
+// exportsTo returns true if the export block or snippet with the given parameters (i.e. backend) is written by w.
+// See Writer.Backend.
+func exportsTo(w Writer, parameters []string) bool {
+	backend := w.Backend()
+	return backend == "" || len(parameters) != 0 && strings.EqualFold(parameters[0], backend)
+}
+
+// expandMacro returns the nodes of the macro call m - or nil if the macro is not defined via #+MACRO.
+func (d *Document) expandMacro(m Macro) []Node {
+	macro := d.Macros[m.Name]
+	if macro == "" {
+		return nil
+	}
+	for i, param := range m.Parameters {
+		macro = strings.Replace(macro, fmt.Sprintf("$%d", i+1), param, -1)
+	}
+	macroDocument := d.Parse(strings.NewReader(macro), d.Path)
+	if errs := macroDocument.errorsWithoutFootnotes(); len(errs) != 0 {
+		d.logf(slog.LevelWarn, m, "bad macro: %s -> %s: %v", m.Name, macro, errs[0])
+	}
+	return macroDocument.Nodes
+}
+
+// inlineContent returns the inline nodes of nodes that consist of a single paragraph (e.g. of an expanded macro) -
+// and nodes otherwise.
+func inlineContent(nodes []Node) []Node {
+	if len(nodes) == 1 {
+		if p, ok := nodes[0].(Paragraph); ok {
+			return p.InlineNodes()
+		}
+	}
+	return nodes
+}
+
+// exportURL returns the (unescaped) url of l for writers whose output files have the extension ext (e.g. ".md"):
+// links to Org files are rewritten to ext and link abbreviations (#+LINK) are expanded.
+func (d *Document) exportURL(l RegularLink, ext string) string {
+	url := l.URL
+	if l.Protocol == "file" {
+		url = url[len("file:"):]
+	}
+	if isRelative := l.Protocol == "file" || l.Protocol == ""; isRelative && strings.HasSuffix(url, ".org") {
+		url = strings.TrimSuffix(url, ".org") + ext
+	}
+	if prefix := d.Links[l.Protocol]; prefix != "" {
+		if tag := strings.TrimPrefix(l.URL, l.Protocol+":"); strings.Contains(prefix, "%s") || strings.Contains(prefix, "%h") {
+			url = strings.ReplaceAll(strings.ReplaceAll(prefix, "%s", tag), "%h", u.QueryEscape(tag))
+		} else {
+			url = prefix + tag
+		}
+	} else if prefix := d.Links[l.URL]; prefix != "" {
+		url = strings.ReplaceAll(strings.ReplaceAll(prefix, "%s", ""), "%h", "")
+	}
+	return url
+}
+
+// stringBuilder is the output buffer of a writer, i.e. a strings.Builder or an OrgWriter.
+type stringBuilder interface {
+	io.StringWriter
+	String() string
+	Reset()
+}
+
+// writeNodesTo writes the nodes using w and moves the output accumulated in builder to out after each node. hold
+// returns the number of bytes at the end of the output that must be kept in builder - e.g. because the writer may
+// still modify them. last is true once all nodes have been written.
+func writeNodesTo(w Writer, builder stringBuilder, out io.Writer, hold func(s string, last bool) int, nodes []Node) (err error) {
+	defer recoverWriterPanic(&err)
+	flush := func(last bool) error {
+		s := builder.String()
+		n := len(s) - hold(s, last)
+		if n == 0 {
+			return nil
+		} else if _, err := io.WriteString(out, s[:n]); err != nil {
+			return fmt.Errorf("could not write output: %w", err)
+		}
+		builder.Reset()
+		builder.WriteString(s[n:])
+		return nil
+	}
+	for _, n := range nodes {
+		WriteNodes(w, n)
+		if err := reportedError(w); err != nil {
+			return err
+		} else if err := flush(false); err != nil {
+			return err
+		}
+	}
+	return flush(true)
+}
@@ -0,0 +1,308 @@
+package org
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestWriteStream(t *testing.T) {
+	writers := map[string]func() Writer{
+		"org":  func() Writer { return NewOrgWriter() },
+		"html": func() Writer { return NewHTMLWriter() },
+		"org sections": func() Writer {
+			w := NewOrgWriter()
+			w.BlankLinesBetweenSections = 1
+			return w
+		},
+		"html template": func() Writer {
+			w := NewHTMLWriter()
+			w.Template = template.Must(template.New("page").Parse("<title>{{.Title}}</title>\n{{.Content}}"))
+			return w
+		},
+	}
+	for _, path := range orgTestFiles() {
+		for name, newWriter := range writers {
+			d := New().Silent().Parse(strings.NewReader(fileString(t, path)), path)
+			expected, err := d.Write(newWriter())
+			if err != nil {
+				continue
+			}
+			out := &strings.Builder{}
+			if err := d.WriteStream(out, newWriter()); err != nil {
+				t.Errorf("%s %s: unexpected error: %s", path, name, err)
+			} else if out.String() != expected {
+				t.Errorf("%s %s: streamed output differs:\n%s", path, name, diff(out.String(), expected))
+			}
+		}
+	}
+}
+
+func TestWriteParallel(t *testing.T) {
+	writers := map[string]func() Writer{
+		"org": func() Writer { return NewOrgWriter() },
+		"org sections": func() Writer {
+			w := NewOrgWriter()
+			w.BlankLinesBetweenSections = 1
+			return w
+		},
+		"html": func() Writer {
+			w := NewHTMLWriter()
+			w.DataPos, w.SourceMap = true, true
+			return w
+		},
+		"html sidenotes": func() Writer {
+			w := NewHTMLWriter()
+			w.FootnoteMode = FootnoteModeSidenotes
+			return w
+		},
+		"html section footnotes": func() Writer {
+			w := NewHTMLWriter()
+			w.FootnoteMode = FootnoteModeSections
+			return w
+		},
+		"html subsection footnotes": func() Writer {
+			w := NewHTMLWriter()
+			w.FootnoteMode, w.FootnoteSectionLevel = FootnoteModeSections, 2
+			return w
+		},
+	}
+	for _, path := range orgTestFiles() {
+		for name, newWriter := range writers {
+			d := New().Silent().Parse(strings.NewReader(fileString(t, path)), path)
+			w, parallelW := newWriter(), newWriter()
+			expected, err := d.Write(w)
+			if err != nil {
+				continue
+			}
+			if actual, err := d.WriteParallel(parallelW); err != nil {
+				t.Errorf("%s %s: unexpected error: %s", path, name, err)
+			} else if actual != expected {
+				t.Errorf("%s %s: parallel output differs:\n%s", path, name, diff(actual, expected))
+			}
+			if w, ok := w.(*HTMLWriter); ok {
+				expected, _ := w.SourceMapJSON()
+				if actual, _ := parallelW.(*HTMLWriter).SourceMapJSON(); string(actual) != string(expected) {
+					t.Errorf("%s %s: parallel source map differs:\n%s", path, name, diff(string(actual), string(expected)))
+				}
+			}
+		}
+	}
+
+	d := New().Silent().Parse(strings.NewReader(fileString(t, "./testdata/footnotes.org")), "./testdata/footnotes.org")
+	w := NewHTMLWriter()
+	w.Before(d)
+	sections := splitSections(d.Nodes)
+	for i, fork := range w.Fork(sections) {
+		WriteNodes(fork, sections[i]...)
+		if !w.Join(fork) {
+			t.Errorf("expected footnotes of section %d to be predicted", i)
+			WriteNodes(w, sections[i]...)
+		}
+	}
+}
+
+type failingWriter struct{ written int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written += len(p); w.written > 10 {
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+func TestWriteStreamErrors(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("* A\ntext\n* B\nmore text\n"), "./stream.org")
+	if err := d.WriteStream(&failingWriter{}, NewOrgWriter()); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("expected write error got %v", err)
+	}
+	if err := NewHTMLWriter().WriteNodesTo(&strings.Builder{}, Paragraph{Children: []Node{struct{ Text }{}}}); err == nil {
+		t.Errorf("expected error for unknown node")
+	}
+}
+
+type errorWriter struct {
+	*OrgWriter
+	fail string
+}
+
+func (w errorWriter) Before(d *Document) error { w.OrgWriter.Before(d); return nil }
+func (w errorWriter) After(d *Document) error  { w.OrgWriter.After(d); return nil }
+
+func (w errorWriter) WriteNodes(nodes ...Node) error {
+	for _, n := range nodes {
+		if h, ok := n.(Headline); ok && String(h.Title...) == w.fail {
+			return errors.New("cannot write " + w.fail)
+		}
+		WriteNodes(w.OrgWriter, n)
+	}
+	return nil
+}
+
+func TestWriteV2(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("* A\n* B\n"), "./v2.org")
+	if out, err := d.WriteV2(errorWriter{NewOrgWriter(), "C"}); err != nil || out != "* A\n* B\n" {
+		t.Errorf("unexpected result %q %v", out, err)
+	}
+	if _, err := d.WriteV2(errorWriter{NewOrgWriter(), "B"}); err == nil || err.Error() != "cannot write B" {
+		t.Errorf("expected error of writer got %v", err)
+	}
+
+	w := NewHTMLWriter()
+	w.HighlightCodeBlock = func(source, lang string, inline bool, params map[string]string) string { panic("cannot highlight") }
+	_, err := New().Silent().Parse(strings.NewReader("src_go{x}"), "./v2.org").Write(w)
+	var writerPanic *WriterPanic
+	if !errors.As(err, &writerPanic) || !strings.Contains(string(writerPanic.Stack), "HTMLWriter).WriteInlineBlock") {
+		t.Errorf("expected writer panic with stack trace got %v", err)
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("* A\ntext\n"), "./errors.org")
+	d.Nodes = append(d.Nodes, Paragraph{Children: []Node{Emphasis{Kind: "??"}}}, List{Kind: ListKind(-1)}, struct{ Text }{})
+	var writerPanic *WriterPanic
+	for _, w := range []Writer{NewOrgWriter(), NewHTMLWriter()} {
+		if _, err := d.Write(w); err == nil || errors.As(err, &writerPanic) || !strings.HasPrefix(err.Error(), "bad ") {
+			t.Errorf("%T: expected error without panic got %v", w, err)
+		}
+		if _, err := d.WriteParallel(w); err == nil || errors.As(err, &writerPanic) {
+			t.Errorf("%T: expected error without panic got %v", w, err)
+		}
+		if err := d.WriteStream(&strings.Builder{}, w); err == nil || errors.As(err, &writerPanic) {
+			t.Errorf("%T: expected error without panic got %v", w, err)
+		}
+	}
+}
+
+func TestTemplateError(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("* A\n"), "./template.org")
+	w := NewHTMLWriter()
+	w.Template = template.Must(template.New("page").Parse("{{.Missing.Field}}"))
+	var writerPanic *WriterPanic
+	if _, err := d.Write(w); err == nil || errors.As(err, &writerPanic) || !strings.HasPrefix(err.Error(), "could not execute template: ") {
+		t.Errorf("expected template error got %v", err)
+	}
+	if _, err := d.WriteParallel(w); err == nil || errors.As(err, &writerPanic) {
+		t.Errorf("expected template error got %v", err)
+	}
+	out := &strings.Builder{}
+	if err := d.WriteStream(out, w); err == nil || errors.As(err, &writerPanic) || out.Len() != 0 {
+		t.Errorf("expected template error and no output got %v %q", err, out)
+	}
+	w.Template = template.Must(template.New("page").Parse("{{.Title}}"))
+	if out, err := d.Write(w); err != nil || out != "" {
+		t.Errorf("expected the error to be reset got %q %v", out, err)
+	}
+}
+
+func TestLazyInline(t *testing.T) {
+	lazy := New().Silent()
+	lazy.LazyInline = true
+	for _, path := range orgTestFiles() {
+		input := fileString(t, path)
+		expected, d := New().Silent().Parse(strings.NewReader(input), path), lazy.Parse(strings.NewReader(input), path)
+		for _, w := range []func() Writer{func() Writer { return NewOrgWriter() }, func() Writer { return NewHTMLWriter() }} {
+			actual, err := d.Write(w())
+			if expected, _ := expected.Write(w()); err != nil || actual != expected {
+				t.Errorf("%s: %v\n%s", path, err, diff(actual, expected))
+			}
+		}
+		if d.ParseInline(); dumpDocument(d) != dumpDocument(expected) {
+			t.Errorf("%s:\n%s", path, diff(dumpDocument(d), dumpDocument(expected)))
+		}
+	}
+
+	d := lazy.Parse(strings.NewReader("* headline\nsome *bold* text\n\nmore text\n"), "./lazyTests.org")
+	p := d.Nodes[0].(Headline).Children[0].(Paragraph)
+	if p.Children != nil || len(p.InlineNodes()) != 3 {
+		t.Fatalf("expected lazily parsed paragraph, got %#v", p)
+	}
+	d.Reparse(TextEdit{StartLine: 0, EndLine: 0, NewText: "#+TITLE: title\n\n"})
+	p = d.Nodes[2].(Headline).Children[1].(Paragraph)
+	if text := p.InlineNodes()[1]; text.Position().StartLine != 5 || d.Source(text) != "more text" {
+		t.Errorf("expected shifted lazy paragraph, got %#v", text)
+	}
+}
+
+type latexWriter struct{ *OrgWriter }
+
+func (w *latexWriter) Backend() string { return "latex" }
+
+func TestBackendFiltering(t *testing.T) {
+	input := "#+BEGIN_EXPORT html\n<b>html</b>\n#+END_EXPORT\n#+BEGIN_EXPORT LaTeX\n\\textbf{latex}\n#+END_EXPORT\n" +
+		"@@html:<i>html</i>@@ @@latex:\\emph{latex}@@\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./backends.org")
+	latex := &latexWriter{NewOrgWriter()}
+	latex.ExtendingWriter = latex
+	expected := "#+BEGIN_EXPORT LaTeX\n\\textbf{latex}\n#+END_EXPORT\n @@latex:\\emph{latex}@@\n"
+	if out, err := d.Write(latex); err != nil || out != expected {
+		t.Errorf("latex writer: got %q (%v), expected %q", out, err, expected)
+	}
+	if out, err := d.Write(NewOrgWriter()); err != nil || out != input {
+		t.Errorf("org writer: got %q (%v), expected %q", out, err, input)
+	}
+	expected = "<b>html</b>\n<p><i>html</i> </p>\n"
+	if out, err := d.Write(NewHTMLWriter()); err != nil || out != expected {
+		t.Errorf("html writer: got %q (%v), expected %q", out, err, expected)
+	}
+	html := NewHTMLWriter()
+	for _, n := range d.Nodes {
+		if b, ok := n.(Block); ok {
+			html.WriteBlock(b) // e.g. called by an ExtendingWriter
+		}
+	}
+	html.WriteInlineBlock(d.Nodes[len(d.Nodes)-1].(Paragraph).Children[2].(InlineBlock))
+	if out := html.String(); out != "<b>html</b>\n" {
+		t.Errorf("html writer methods: got %q", out)
+	}
+}
+
+func TestInlineExportBlock(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("a @@ b @@html:<b>x</b>@@ c\n"), "./snippets.org")
+	children := d.Nodes[0].(Paragraph).Children
+	if len(children) != 3 || String(children[0]) != "a @@ b " || String(children[1]) != "@@html:<b>x</b>@@" || String(children[2]) != " c" {
+		t.Errorf("expected the snippet to start at its own @@, got %s", dumpNodes(children))
+	}
+	if out, err := d.Write(NewHTMLWriter()); err != nil || out != "<p>a @@ b <b>x</b> c</p>\n" {
+		t.Errorf("got %q (%v)", out, err)
+	}
+}
+
+type emoji struct{ Name string }
+
+func (e emoji) String() string        { return ":" + e.Name + ":" }
+func (e emoji) Copy() Node            { return e }
+func (e emoji) Range(func(Node) bool) {}
+func (e emoji) Position() Position    { return Position{} }
+
+func TestInlineParsers(t *testing.T) {
+	emojis := map[string]string{"smile": "😄", "tada": "🎉"}
+	c := New().Silent().AddInlineParser(':', func(input string, start int) (int, Node) {
+		end := strings.IndexByte(input[start+1:], ':')
+		if name := input[start+1 : start+1+max(end, 0)]; end > 0 && emojis[name] != "" {
+			return end + 2, emoji{name}
+		}
+		return 0, nil
+	})
+	input := "*done* :tada: see https://example.com :unknown: :smile:"
+	d := c.Parse(strings.NewReader(input), "./inlineParsers.org")
+
+	html := NewHTMLWriter().AddNodeWriter(emoji{}, func(w *HTMLWriter, n Node) {
+		w.WriteString(`<span class="emoji" title="` + n.(emoji).Name + `">` + emojis[n.(emoji).Name] + "</span>")
+	})
+	expected := `<p><strong>done</strong> <span class="emoji" title="tada">🎉</span> see <a href="https://example.com">https://example.com</a> :unknown: <span class="emoji" title="smile">😄</span></p>`
+	if actual, err := d.Write(html); err != nil || strings.TrimSpace(actual) != expected {
+		t.Errorf("got %q (%v), expected %q", actual, err, expected)
+	}
+	org := NewOrgWriter().AddNodeWriter(emoji{}, func(w *OrgWriter, n Node) { w.WriteString(n.String()) })
+	if actual, err := d.Write(org); err != nil || actual != input+"\n" {
+		t.Errorf("got %q (%v), expected %q", actual, err, input+"\n")
+	}
+	if actual, err := d.Write(NewOrgWriter()); err != nil || actual != input+"\n" || String(d.Nodes...) != input+"\n" {
+		t.Errorf("expected custom nodes to be written as their source without NodeWriters, got %q (%v)", actual, err)
+	}
+	if _, err := d.Write(NewHTMLWriter()); err == nil || !strings.Contains(err.Error(), "bad node org.emoji") {
+		t.Errorf("expected bad node error for writer without NodeWriters, got %v", err)
+	}
+}
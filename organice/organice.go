@@ -0,0 +1,705 @@
+// Package organice converts documents from and to the JSON document shape of the organice web Org editor (and its
+// predecessor org-web) - e.g. to use go-org as the backend parser of a web Org editor.
+//
+//	data, err := json.Marshal(organice.Export(d))
+//	d, err := org.New().Read(organice.Importer{}, bytes.NewReader(data), "./notes.org")
+//
+// Documents are represented as a flat list of headers in document order with their title, todo keyword, tags,
+// planning items (DEADLINE, SCHEDULED, CLOSED), properties and description. Descriptions and titles are available
+// both raw and as a list of parts (text, inline markup, links, timestamps, lists including their checkbox states,
+// tables and statistic cookies). Other elements are represented as text parts containing their Org mode markup.
+package organice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+// File is the JSON representation of a document.
+type File struct {
+	Headers             []Header         `json:"headers"`
+	TodoKeywordSets     []TodoKeywordSet `json:"todoKeywordSets"`
+	FileConfigLines     []string         `json:"fileConfigLines"`     // FileConfigLines are the #+ keyword lines of the document.
+	LinesBeforeHeadings []string         `json:"linesBeforeHeadings"` // LinesBeforeHeadings are the lines before the first headline.
+}
+
+// TodoKeywordSet is a sequence of todo keywords, e.g. of #+TODO: TODO NEXT | DONE.
+type TodoKeywordSet struct {
+	KeywordsStringRepresentation string   `json:"keywordsStringRepresentation"`
+	ActiveKeywords               []string `json:"activeKeywords"`
+	CompletedKeywords            []string `json:"completedKeywords"`
+	ConfigLine                   string   `json:"configLine"` // ConfigLine is the #+TODO line - empty for the configured default.
+}
+
+// Header is a headline with its metadata and description, i.e. the content of its section before the first subheadline.
+type Header struct {
+	ID                int                `json:"id"`
+	NestingLevel      int                `json:"nestingLevel"`
+	TitleLine         TitleLine          `json:"titleLine"`
+	Description       []Part             `json:"description"`
+	RawDescription    string             `json:"rawDescription"`
+	Opened            bool               `json:"opened"`
+	PlanningItems     []PlanningItem     `json:"planningItems"`
+	PropertyListItems []PropertyListItem `json:"propertyListItems"`
+}
+
+// TitleLine is the title of a header. RawTitle contains the priority cookie and COMMENT keyword of the headline but
+// neither its todo keyword nor its tags.
+type TitleLine struct {
+	Title       []Part   `json:"title"`
+	RawTitle    string   `json:"rawTitle"`
+	TodoKeyword string   `json:"todoKeyword,omitempty"`
+	Tags        []string `json:"tags"`
+}
+
+// PlanningItem is a DEADLINE, SCHEDULED or CLOSED timestamp of a header.
+type PlanningItem struct {
+	ID        int       `json:"id"`
+	Type      string    `json:"type"`
+	Timestamp Timestamp `json:"timestamp"`
+}
+
+// PropertyListItem is a property of the property drawer of a header.
+type PropertyListItem struct {
+	ID       int    `json:"id"`
+	Property string `json:"property"`
+	Value    []Part `json:"value"`
+}
+
+// Timestamp is a timestamp split into its components - all of them strings like in organice, e.g. Month "01".
+// Time, repeater and delay components are empty if the timestamp does not have them.
+type Timestamp struct {
+	IsActive      bool   `json:"isActive"`
+	Year          string `json:"year"`
+	Month         string `json:"month"`
+	Day           string `json:"day"`
+	DayName       string `json:"dayName"`
+	StartHour     string `json:"startHour,omitempty"`
+	StartMinute   string `json:"startMinute,omitempty"`
+	EndHour       string `json:"endHour,omitempty"`
+	EndMinute     string `json:"endMinute,omitempty"`
+	RepeaterType  string `json:"repeaterType,omitempty"` // RepeaterType is "+", "++" or ".+".
+	RepeaterValue string `json:"repeaterValue,omitempty"`
+	RepeaterUnit  string `json:"repeaterUnit,omitempty"`
+	DelayType     string `json:"delayType,omitempty"` // DelayType is "-" or "--".
+	DelayValue    string `json:"delayValue,omitempty"`
+	DelayUnit     string `json:"delayUnit,omitempty"`
+}
+
+// Part is an element of a title or description. The fields used depend on Type:
+//   - "text": Text (encoded as contents)
+//   - "inline-markup": MarkupType (e.g. "inline-bold") and Contents
+//   - "link": URI and Contents (the description of the link)
+//   - "timestamp": FirstTimestamp and - for ranges - SecondTimestamp
+//   - "list": Items, BulletType and IsOrdered
+//   - "table": Rows (encoded as contents)
+//   - "percentage-cookie": Percentage, "fraction-cookie": Fraction
+type Part struct {
+	ID              int
+	Type            string
+	Text            string
+	MarkupType      string
+	URI             string
+	Contents        []Part
+	FirstTimestamp  *Timestamp
+	SecondTimestamp *Timestamp
+	Items           []ListItem
+	BulletType      string
+	IsOrdered       bool
+	Rows            []TableRow
+	Percentage      int
+	Fraction        [2]int
+}
+
+// ListItem is an item of a list part. TitleLine is the first line of the item and Contents the rest of it.
+type ListItem struct {
+	ID            int    `json:"id"`
+	TitleLine     []Part `json:"titleLine"`
+	Contents      []Part `json:"contents"`
+	ForceNumber   string `json:"forceNumber,omitempty"` // ForceNumber is the [@number] of the item.
+	IsCheckbox    bool   `json:"isCheckbox"`
+	CheckboxState string `json:"checkboxState,omitempty"` // CheckboxState is "checked", "unchecked" or "partial".
+}
+
+// TableRow is a row of a table part. Separator rows are not included.
+type TableRow struct {
+	ID    int         `json:"id"`
+	Cells []TableCell `json:"cells"`
+}
+
+// TableCell is a cell of a table row.
+type TableCell struct {
+	ID          int    `json:"id"`
+	Contents    []Part `json:"contents"`
+	RawContents string `json:"rawContents"`
+}
+
+// jsonPart is the JSON representation of a Part - the type of contents depends on the type of the part.
+type jsonPart struct {
+	ID              int             `json:"id"`
+	Type            string          `json:"type"`
+	Contents        json.RawMessage `json:"contents,omitempty"`
+	MarkupType      string          `json:"markupType,omitempty"`
+	URI             string          `json:"uri,omitempty"`
+	FirstTimestamp  *Timestamp      `json:"firstTimestamp,omitempty"`
+	SecondTimestamp *Timestamp      `json:"secondTimestamp,omitempty"`
+	Items           []ListItem      `json:"items,omitempty"`
+	BulletType      string          `json:"bulletType,omitempty"`
+	IsOrdered       bool            `json:"isOrdered,omitempty"`
+	Percentage      *int            `json:"percentage,omitempty"`
+	Fraction        []int           `json:"fraction,omitempty"`
+}
+
+var markupTypes = map[string]string{
+	"*": "inline-bold", "/": "inline-italic", "_": "inline-underline", "+": "inline-strikethrough",
+	"=": "inline-verbatim", "~": "inline-code",
+}
+
+var checkboxStates = map[string]string{" ": "unchecked", "X": "checked", "-": "partial"}
+
+// MarshalJSON implements json.Marshaler.
+func (p Part) MarshalJSON() ([]byte, error) {
+	jp := jsonPart{ID: p.ID, Type: p.Type, MarkupType: p.MarkupType, URI: p.URI, FirstTimestamp: p.FirstTimestamp,
+		SecondTimestamp: p.SecondTimestamp, Items: p.Items, BulletType: p.BulletType, IsOrdered: p.IsOrdered}
+	var contents any
+	switch p.Type {
+	case "text":
+		contents = p.Text
+	case "table":
+		contents = p.Rows
+	case "percentage-cookie":
+		jp.Percentage = &p.Percentage
+	case "fraction-cookie":
+		jp.Fraction = p.Fraction[:]
+	default:
+		if p.Contents != nil {
+			contents = p.Contents
+		}
+	}
+	if contents != nil {
+		data, err := json.Marshal(contents)
+		if err != nil {
+			return nil, err
+		}
+		jp.Contents = data
+	}
+	return json.Marshal(jp)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *Part) UnmarshalJSON(data []byte) error {
+	jp := jsonPart{}
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+	*p = Part{ID: jp.ID, Type: jp.Type, MarkupType: jp.MarkupType, URI: jp.URI, FirstTimestamp: jp.FirstTimestamp,
+		SecondTimestamp: jp.SecondTimestamp, Items: jp.Items, BulletType: jp.BulletType, IsOrdered: jp.IsOrdered}
+	if jp.Percentage != nil {
+		p.Percentage = *jp.Percentage
+	}
+	if len(jp.Fraction) == 2 {
+		p.Fraction = [2]int{jp.Fraction[0], jp.Fraction[1]}
+	}
+	if len(jp.Contents) == 0 || string(jp.Contents) == "null" {
+		return nil
+	}
+	switch p.Type {
+	case "text":
+		return json.Unmarshal(jp.Contents, &p.Text)
+	case "table":
+		return json.Unmarshal(jp.Contents, &p.Rows)
+	default:
+		return json.Unmarshal(jp.Contents, &p.Contents)
+	}
+}
+
+// Export returns the JSON representation of the document d.
+func Export(d *org.Document) *File {
+	e := &exporter{}
+	f := &File{Headers: []Header{}, FileConfigLines: []string{}, LinesBeforeHeadings: []string{}}
+	if before := org.String(d.Outline.Body()...); before != "" {
+		f.LinesBeforeHeadings = strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	}
+	configLine := ""
+	for _, n := range d.Outline.Body() {
+		if k, ok := n.(org.Keyword); ok {
+			line := strings.TrimSuffix(org.String(k), "\n")
+			f.FileConfigLines = append(f.FileConfigLines, line)
+			if k.Key == "TODO" && configLine == "" {
+				configLine = line
+			}
+		}
+	}
+	f.TodoKeywordSets = []TodoKeywordSet{todoKeywordSet(d.Get("TODO"), configLine)}
+	var walk func(*org.Section)
+	walk = func(s *org.Section) {
+		if s.Headline != nil {
+			f.Headers = append(f.Headers, e.header(*s.Headline, s.Body()))
+		}
+		for _, child := range s.Children {
+			walk(child)
+		}
+	}
+	walk(d.Outline.Section)
+	return f
+}
+
+func todoKeywordSet(keywords, configLine string) TodoKeywordSet {
+	set := TodoKeywordSet{KeywordsStringRepresentation: keywords, ActiveKeywords: []string{}, CompletedKeywords: []string{}, ConfigLine: configLine}
+	active, completed, hasSeparator := strings.Cut(keywords, "|")
+	set.ActiveKeywords = append(set.ActiveKeywords, trimFastAccess(strings.Fields(active))...)
+	if hasSeparator {
+		set.CompletedKeywords = append(set.CompletedKeywords, trimFastAccess(strings.Fields(completed))...)
+	} else if n := len(set.ActiveKeywords); n > 1 {
+		set.ActiveKeywords, set.CompletedKeywords = set.ActiveKeywords[:n-1], set.ActiveKeywords[n-1:]
+	}
+	return set
+}
+
+// trimFastAccess removes the fast access keys from todo keywords, e.g. TODO(t) becomes TODO.
+func trimFastAccess(keywords []string) []string {
+	for i, k := range keywords {
+		if j := strings.LastIndex(k, "("); j > 0 && strings.HasSuffix(k, ")") {
+			keywords[i] = k[:j]
+		}
+	}
+	return keywords
+}
+
+type exporter struct {
+	id int
+}
+
+func (e *exporter) nextID() int {
+	e.id++
+	return e.id
+}
+
+func (e *exporter) header(h org.Headline, body []org.Node) Header {
+	header := Header{
+		ID:                e.nextID(),
+		NestingLevel:      h.Lvl,
+		PlanningItems:     []PlanningItem{},
+		PropertyListItems: []PropertyListItem{},
+	}
+	rawTitle := org.String(h.Title...)
+	if h.IsComment {
+		rawTitle = "COMMENT " + rawTitle
+	}
+	if h.Priority != "" {
+		rawTitle = "[#" + h.Priority + "] " + rawTitle
+	}
+	header.TitleLine = TitleLine{Title: e.parts(h.Title), RawTitle: rawTitle, TodoKeyword: h.Status, Tags: append([]string{}, h.Tags...)}
+	if planning := h.Planning(); len(planning) != 0 {
+		for _, key := range []string{"DEADLINE", "SCHEDULED", "CLOSED"} {
+			if t, ok := planning[key]; ok {
+				header.PlanningItems = append(header.PlanningItems, PlanningItem{ID: e.nextID(), Type: key, Timestamp: timestamp(t)})
+			}
+		}
+		body = withoutPlanningLine(body)
+	}
+	properties := h.Properties
+	if properties == nil && len(body) != 0 {
+		if p, ok := body[0].(org.PropertyDrawer); ok {
+			properties, body = &p, body[1:]
+		}
+	}
+	if properties != nil {
+		for _, kv := range properties.Properties {
+			item := PropertyListItem{ID: e.nextID(), Property: kv[0]}
+			item.Value = []Part{{ID: e.nextID(), Type: "text", Text: kv[1]}}
+			header.PropertyListItems = append(header.PropertyListItems, item)
+		}
+	}
+	header.RawDescription = org.String(body...)
+	header.Description = e.parts(body)
+	return header
+}
+
+// planningKeywordRegexp matches the planning keyword preceding a planning timestamp.
+var planningKeywordRegexp = regexp.MustCompile(`(DEADLINE|SCHEDULED|CLOSED):\s*$`)
+
+// withoutPlanningLine returns the nodes of the body of a headline with the planning keywords and timestamps removed
+// from the first line of its first paragraph. Other content of the planning line is kept.
+func withoutPlanningLine(body []org.Node) []org.Node {
+	p := body[0].(org.Paragraph)
+	line, rest := p.InlineNodes(), []org.Node{}
+	for i, n := range line {
+		if _, ok := n.(org.LineBreak); ok {
+			line, rest = line[:i], line[i:]
+			break
+		}
+	}
+	remainder, planning, removed := []org.Node{}, false, true
+	for _, n := range line {
+		switch t := n.(type) {
+		case org.Text:
+			if m := planningKeywordRegexp.FindStringIndex(t.Content); m != nil {
+				t.Content, planning = t.Content[:m[0]], true
+			}
+			if removed {
+				t.Content = strings.TrimLeft(t.Content, " \t") // the whitespace following removed planning items
+			}
+			if t.Content == "" {
+				continue
+			}
+			n = t
+		case org.Timestamp:
+			if planning {
+				planning, removed = false, true
+				continue
+			}
+		}
+		remainder, removed = append(remainder, n), false
+	}
+	if len(remainder) == 0 && len(rest) != 0 {
+		remainder, rest = rest[1:], nil // drop the line break
+	}
+	if children := append(remainder, rest...); len(children) != 0 {
+		return append([]org.Node{org.Paragraph{Children: children, Pos: p.Pos}}, body[1:]...)
+	}
+	return body[1:]
+}
+
+func timestamp(t org.Timestamp) Timestamp {
+	ts := Timestamp{
+		IsActive: true,
+		Year:     t.Time.Format("2006"),
+		Month:    t.Time.Format("01"),
+		Day:      t.Time.Format("02"),
+		DayName:  t.Time.Format("Mon"),
+	}
+	if !t.IsDate {
+		ts.StartHour, ts.StartMinute = t.Time.Format("15"), t.Time.Format("04")
+	}
+	if t.Interval != "" {
+		value := strings.TrimLeft(t.Interval, "+.")
+		ts.RepeaterType, ts.RepeaterValue, ts.RepeaterUnit = t.Interval[:len(t.Interval)-len(value)], value[:len(value)-1], value[len(value)-1:]
+	}
+	if t.Delay != "" {
+		value := strings.TrimLeft(t.Delay, "-")
+		ts.DelayType, ts.DelayValue, ts.DelayUnit = t.Delay[:len(t.Delay)-len(value)], value[:len(value)-1], value[len(value)-1:]
+	}
+	return ts
+}
+
+func (e *exporter) parts(nodes []org.Node) []Part {
+	parts := []Part{}
+	text := func(s string) {
+		if n := len(parts); n != 0 && parts[n-1].Type == "text" {
+			parts[n-1].Text += s
+		} else if s != "" {
+			parts = append(parts, Part{ID: e.nextID(), Type: "text", Text: s})
+		}
+	}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case org.Text:
+			text(n.Content)
+		case org.LineBreak:
+			text(strings.Repeat("\n", n.Count))
+		case org.Paragraph:
+			parts = append(parts, e.parts(n.InlineNodes())...)
+			text("\n")
+		case org.Emphasis:
+			if markupType, ok := markupTypes[n.Kind]; ok {
+				parts = append(parts, Part{ID: e.nextID(), Type: "inline-markup", MarkupType: markupType, Contents: e.parts(n.Content)})
+			} else {
+				text(org.String(n))
+			}
+		case org.RegularLink:
+			if n.AutoLink {
+				text(org.String(n))
+			} else {
+				parts = append(parts, Part{ID: e.nextID(), Type: "link", URI: n.URL, Contents: e.parts(n.Description)})
+			}
+		case org.Timestamp:
+			t := timestamp(n)
+			parts = append(parts, Part{ID: e.nextID(), Type: "timestamp", FirstTimestamp: &t})
+		case org.StatisticToken:
+			parts = append(parts, e.cookie(n))
+		case org.List:
+			if n.Kind == org.DescriptiveList {
+				text(org.String(n))
+			} else {
+				parts = append(parts, e.list(n))
+			}
+		case org.Table:
+			parts = append(parts, e.table(n))
+		default:
+			text(org.String(n))
+		}
+	}
+	return parts
+}
+
+func (e *exporter) cookie(n org.StatisticToken) Part {
+	if a, b, ok := strings.Cut(n.Content, "/"); ok {
+		done, _ := strconv.Atoi(a)
+		total, _ := strconv.Atoi(b)
+		return Part{ID: e.nextID(), Type: "fraction-cookie", Fraction: [2]int{done, total}}
+	}
+	percentage, _ := strconv.Atoi(strings.TrimSuffix(n.Content, "%"))
+	return Part{ID: e.nextID(), Type: "percentage-cookie", Percentage: percentage}
+}
+
+func (e *exporter) list(l org.List) Part {
+	part := Part{ID: e.nextID(), Type: "list", Items: []ListItem{}, IsOrdered: l.Kind == org.OrderedList}
+	for _, n := range l.Items {
+		item, ok := n.(org.ListItem)
+		if !ok {
+			continue
+		}
+		if part.BulletType == "" {
+			part.BulletType = item.Bullet
+			if part.IsOrdered {
+				part.BulletType = item.Bullet[len(item.Bullet)-1:]
+			}
+		}
+		children, title := item.Children, []org.Node{}
+		if len(children) != 0 {
+			if p, ok := children[0].(org.Paragraph); ok {
+				title, children = p.InlineNodes(), children[1:]
+			}
+		}
+		id := e.nextID()
+		part.Items = append(part.Items, ListItem{
+			ID:            id,
+			TitleLine:     e.parts(title),
+			Contents:      e.parts(children),
+			ForceNumber:   item.Value,
+			IsCheckbox:    item.Status != "",
+			CheckboxState: checkboxStates[item.Status],
+		})
+	}
+	return part
+}
+
+func (e *exporter) table(t org.Table) Part {
+	part := Part{ID: e.nextID(), Type: "table", Rows: []TableRow{}}
+	for _, row := range t.Rows {
+		if row.IsSpecial || len(row.Columns) == 0 {
+			continue
+		}
+		r := TableRow{ID: e.nextID(), Cells: []TableCell{}}
+		for _, column := range row.Columns {
+			r.Cells = append(r.Cells, TableCell{ID: e.nextID(), Contents: e.parts(column.Children), RawContents: org.String(column.Children...)})
+		}
+		part.Rows = append(part.Rows, r)
+	}
+	return part
+}
+
+// Importer converts the JSON representation of documents into Org mode. The zero value is ready to use.
+// Descriptions are taken from RawDescription - Description is only used if RawDescription is empty.
+type Importer struct{}
+
+// Convert returns the Org mode markup of the JSON representation of a document read from r.
+func (i Importer) Convert(r io.Reader) (string, error) {
+	f := File{}
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return "", fmt.Errorf("could not decode organice json: %w", err)
+	}
+	return f.Org(), nil
+}
+
+// Read implements org.Reader: it returns the document parsed with c from the Org mode markup of the JSON read from
+// input. See Convert.
+func (i Importer) Read(c *org.Configuration, input io.Reader, path string) (*org.Document, error) {
+	text, err := i.Convert(input)
+	if err != nil {
+		return nil, err
+	}
+	d := c.Parse(strings.NewReader(text), path)
+	if d.FatalError != nil {
+		return d, fmt.Errorf("could not import organice json: %w", d.FatalError)
+	}
+	return d, nil
+}
+
+// Org returns the Org mode markup of the file. FileConfigLines that are not part of LinesBeforeHeadings
+// are added before them.
+func (f File) Org() string {
+	b := &strings.Builder{}
+	before := "\n" + strings.Join(f.LinesBeforeHeadings, "\n") + "\n"
+	for _, line := range f.FileConfigLines {
+		if !strings.Contains(before, "\n"+line+"\n") {
+			b.WriteString(line + "\n")
+		}
+	}
+	for _, line := range f.LinesBeforeHeadings {
+		b.WriteString(line + "\n")
+	}
+	for _, h := range f.Headers {
+		h.writeOrg(b)
+	}
+	return b.String()
+}
+
+func (h Header) writeOrg(b *strings.Builder) {
+	b.WriteString(strings.Repeat("*", max(h.NestingLevel, 1)))
+	if h.TitleLine.TodoKeyword != "" {
+		b.WriteString(" " + h.TitleLine.TodoKeyword)
+	}
+	title := h.TitleLine.RawTitle
+	if title == "" {
+		title = writeParts(h.TitleLine.Title)
+	}
+	if title != "" {
+		b.WriteString(" " + title)
+	}
+	if len(h.TitleLine.Tags) != 0 {
+		b.WriteString(" :" + strings.Join(h.TitleLine.Tags, ":") + ":")
+	}
+	b.WriteString("\n")
+	if len(h.PlanningItems) != 0 {
+		items := []string{}
+		for _, p := range h.PlanningItems {
+			items = append(items, p.Type+": "+p.Timestamp.String())
+		}
+		b.WriteString(strings.Join(items, " ") + "\n")
+	}
+	if len(h.PropertyListItems) != 0 {
+		b.WriteString(":PROPERTIES:\n")
+		for _, p := range h.PropertyListItems {
+			b.WriteString(strings.TrimRight(":"+p.Property+": "+writeParts(p.Value), " ") + "\n")
+		}
+		b.WriteString(":END:\n")
+	}
+	description := h.RawDescription
+	if description == "" {
+		description = writeParts(h.Description)
+	}
+	if description != "" && !strings.HasSuffix(description, "\n") {
+		description += "\n"
+	}
+	b.WriteString(description)
+}
+
+// String returns the Org mode markup of the timestamp, e.g. <2024-01-01 Mon 10:00-11:00 +1w>.
+func (t Timestamp) String() string {
+	open, close := "[", "]"
+	if t.IsActive {
+		open, close = "<", ">"
+	}
+	s := open + t.Year + "-" + t.Month + "-" + t.Day
+	if t.DayName != "" {
+		s += " " + t.DayName
+	}
+	if t.StartHour != "" {
+		s += " " + t.StartHour + ":" + t.StartMinute
+		if t.EndHour != "" {
+			s += "-" + t.EndHour + ":" + t.EndMinute
+		}
+	}
+	if t.RepeaterType != "" {
+		s += " " + t.RepeaterType + t.RepeaterValue + t.RepeaterUnit
+	}
+	if t.DelayType != "" {
+		s += " " + t.DelayType + t.DelayValue + t.DelayUnit
+	}
+	return s + close
+}
+
+func writeParts(parts []Part) string {
+	b := &strings.Builder{}
+	for _, p := range parts {
+		p.writeOrg(b, "")
+	}
+	return b.String()
+}
+
+// writeOrg writes the Org mode markup of the part. Lines following the first line of list parts are indented by indent.
+func (p Part) writeOrg(b *strings.Builder, indent string) {
+	switch p.Type {
+	case "text":
+		b.WriteString(p.Text)
+	case "inline-markup":
+		marker := ""
+		for m, markupType := range markupTypes {
+			if markupType == p.MarkupType {
+				marker = m
+			}
+		}
+		b.WriteString(marker + writeParts(p.Contents) + marker)
+	case "link":
+		b.WriteString("[[" + p.URI + "]")
+		if description := writeParts(p.Contents); description != "" {
+			b.WriteString("[" + description + "]")
+		}
+		b.WriteString("]")
+	case "timestamp":
+		if p.FirstTimestamp != nil {
+			b.WriteString(p.FirstTimestamp.String())
+		}
+		if p.SecondTimestamp != nil {
+			b.WriteString("--" + p.SecondTimestamp.String())
+		}
+	case "percentage-cookie":
+		b.WriteString("[" + strconv.Itoa(p.Percentage) + "%]")
+	case "fraction-cookie":
+		b.WriteString("[" + strconv.Itoa(p.Fraction[0]) + "/" + strconv.Itoa(p.Fraction[1]) + "]")
+	case "list":
+		p.writeList(b, indent)
+	case "table":
+		for _, row := range p.Rows {
+			b.WriteString(indent + "|")
+			for _, cell := range row.Cells {
+				content := cell.RawContents
+				if content == "" {
+					content = writeParts(cell.Contents)
+				}
+				b.WriteString(" " + content + " |")
+			}
+			b.WriteString("\n")
+		}
+	}
+}
+
+func (p Part) writeList(b *strings.Builder, indent string) {
+	for i, item := range p.Items {
+		bullet := p.BulletType
+		if bullet == "" {
+			bullet = "-"
+		}
+		if p.IsOrdered {
+			bullet = strconv.Itoa(i+1) + bullet
+		}
+		b.WriteString(indent + bullet + " ")
+		if item.ForceNumber != "" {
+			b.WriteString("[@" + item.ForceNumber + "] ")
+		}
+		if item.IsCheckbox {
+			state := " "
+			for s, checkboxState := range checkboxStates {
+				if checkboxState == item.CheckboxState {
+					state = s
+				}
+			}
+			b.WriteString("[" + state + "] ")
+		}
+		b.WriteString(strings.TrimSuffix(writeParts(item.TitleLine), "\n") + "\n")
+		contentIndent := indent + strings.Repeat(" ", len(bullet)+1)
+		for _, c := range item.Contents {
+			if c.Type == "list" || c.Type == "table" {
+				c.writeOrg(b, contentIndent)
+				continue
+			}
+			content := &strings.Builder{}
+			c.writeOrg(content, contentIndent)
+			for _, line := range strings.SplitAfter(content.String(), "\n") {
+				if strings.TrimSpace(line) != "" {
+					b.WriteString(contentIndent + line)
+				} else {
+					b.WriteString(line)
+				}
+			}
+		}
+	}
+}
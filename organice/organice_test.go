@@ -0,0 +1,115 @@
+package organice
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alexispurslane/go-org/org"
+)
+
+const input = `#+TITLE: Notes
+#+TODO: TODO NEXT | DONE
+intro text
+* TODO [#A] Buy *milk* :home:errand:
+DEADLINE: <2024-01-02 Tue> SCHEDULED: <2024-01-01 Mon 10:00 +1w -2d>
+:PROPERTIES:
+:EFFORT: 1:00
+:END:
+See [[https://example.com][the shop]] [1/2]
+- [X] milk
+- [ ] eggs
+** DONE Sub
+| a | b |
+`
+
+func TestExport(t *testing.T) {
+	d := org.New().Silent().Parse(strings.NewReader(input), "./notes.org")
+	f := Export(d)
+	if actual, expected := strings.Join(f.LinesBeforeHeadings, "|"), "#+TITLE: Notes|#+TODO: TODO NEXT | DONE|intro text"; actual != expected {
+		t.Errorf("got lines %q, expected %q", actual, expected)
+	}
+	if s := f.TodoKeywordSets[0]; strings.Join(s.ActiveKeywords, " ") != "TODO NEXT" || strings.Join(s.CompletedKeywords, " ") != "DONE" || s.ConfigLine != "#+TODO: TODO NEXT | DONE" {
+		t.Errorf("unexpected todo keywords %#v", s)
+	}
+	if len(f.Headers) != 2 || f.Headers[1].NestingLevel != 2 || f.Headers[1].TitleLine.TodoKeyword != "DONE" {
+		t.Fatalf("unexpected headers %#v", f.Headers)
+	}
+	h := f.Headers[0]
+	if l := h.TitleLine; l.RawTitle != "[#A] Buy *milk*" || l.TodoKeyword != "TODO" || strings.Join(l.Tags, ":") != "home:errand" ||
+		len(l.Title) != 2 || l.Title[1].Type != "inline-markup" || l.Title[1].MarkupType != "inline-bold" {
+		t.Errorf("unexpected title line %#v", l)
+	}
+	if p := h.PlanningItems; len(p) != 2 || p[0].Type != "DEADLINE" || p[0].Timestamp.String() != "<2024-01-02 Tue>" ||
+		p[1].Type != "SCHEDULED" || p[1].Timestamp.String() != "<2024-01-01 Mon 10:00 +1w -2d>" || p[1].Timestamp.RepeaterUnit != "w" {
+		t.Errorf("unexpected planning items %#v", p)
+	}
+	if p := h.PropertyListItems; len(p) != 1 || p[0].Property != "EFFORT" || writeParts(p[0].Value) != "1:00" {
+		t.Errorf("unexpected properties %#v", p)
+	}
+	if expected := "See [[https://example.com][the shop]] [1/2]\n- [X] milk\n- [ ] eggs\n"; h.RawDescription != expected {
+		t.Errorf("got raw description %q, expected %q", h.RawDescription, expected)
+	}
+	types := []string{}
+	for _, p := range h.Description {
+		types = append(types, p.Type)
+	}
+	if actual, expected := strings.Join(types, " "), "text link text fraction-cookie text list"; actual != expected {
+		t.Errorf("got description %s, expected %s", actual, expected)
+	}
+	if items := h.Description[5].Items; len(items) != 2 || items[0].CheckboxState != "checked" || items[1].CheckboxState != "unchecked" || !items[1].IsCheckbox {
+		t.Errorf("unexpected list items %#v", items)
+	}
+	if table := f.Headers[1].Description[0]; table.Type != "table" || len(table.Rows) != 1 || table.Rows[0].Cells[1].RawContents != "b" {
+		t.Errorf("unexpected table %#v", table)
+	}
+}
+
+func TestImport(t *testing.T) {
+	d := org.New().Silent().Parse(strings.NewReader(input), "./notes.org")
+	data, err := json.Marshal(Export(d))
+	if err != nil {
+		t.Fatalf("could not marshal: %s", err)
+	}
+	if !bytes.Contains(data, []byte(`{"id":2,"type":"text","contents":"Buy "}`)) || !bytes.Contains(data, []byte(`"checkboxState":"checked"`)) {
+		t.Errorf("unexpected json %s", data)
+	}
+	imported, err := org.New().Silent().Read(Importer{}, bytes.NewReader(data), "./notes.org")
+	if err != nil {
+		t.Fatalf("could not import: %s", err)
+	}
+	if actual, expected := org.String(imported.Nodes...), org.String(d.Nodes...); actual != expected {
+		t.Errorf("got\n%s\nexpected\n%s", actual, expected)
+	}
+
+	f := File{Headers: []Header{{NestingLevel: 1, TitleLine: TitleLine{RawTitle: "List"}, Description: []Part{
+		{Type: "text", Text: "before\n"},
+		{Type: "list", BulletType: ".", IsOrdered: true, Items: []ListItem{
+			{TitleLine: []Part{{Type: "text", Text: "done"}}, IsCheckbox: true, CheckboxState: "checked"},
+			{TitleLine: []Part{{Type: "text", Text: "half"}}, IsCheckbox: true, CheckboxState: "partial",
+				Contents: []Part{{Type: "text", Text: "details\n"}}},
+		}},
+	}}}}
+	if actual, expected := f.Org(), "* List\nbefore\n1. [X] done\n2. [-] half\n   details\n"; actual != expected {
+		t.Errorf("got %q, expected %q", actual, expected)
+	}
+	if _, err := (Importer{}).Convert(strings.NewReader("{")); err == nil || !strings.HasPrefix(err.Error(), "could not decode organice json") {
+		t.Errorf("expected decode error, got %v", err)
+	}
+}
+
+func TestPlanningLineRemainder(t *testing.T) {
+	cases := []struct{ input, description string }{
+		{"* A\nSCHEDULED: <2024-01-01 Mon> and more text\n", "and more text\n"},
+		{"* A\nDEADLINE: <2024-01-02 Tue> SCHEDULED: <2024-01-01 Mon>\nnext line\n", "next line\n"},
+		{"* A\nnote SCHEDULED: <2024-01-01 Mon> <2024-01-03 Wed>\nnext line\n", "note <2024-01-03 Wed>\nnext line\n"},
+		{"* A\nSCHEDULED: <2024-01-01 Mon>\n", ""},
+	}
+	for _, c := range cases {
+		h := Export(org.New().Silent().Parse(strings.NewReader(c.input), "./planning.org")).Headers[0]
+		if len(h.PlanningItems) == 0 || h.RawDescription != c.description {
+			t.Errorf("%q: got description %q with planning items %v, expected %q", c.input, h.RawDescription, h.PlanningItems, c.description)
+		}
+	}
+}